@@ -14,6 +14,7 @@ import (
 	"github.com/golang-migrate/migrate/v4"
 	migratedatabase "github.com/golang-migrate/migrate/v4/database"
 	_ "github.com/golang-migrate/migrate/v4/database/pgx/v5"
+	_ "github.com/golang-migrate/migrate/v4/database/sqlite"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 	"github.com/spf13/cobra"
 )
@@ -43,9 +44,9 @@ func newMigrateCommand() *cobra.Command {
 		},
 	}
 
-	migrateCmd.PersistentFlags().StringVar(&cfg.Driver, "driver", cfg.Driver, "Source-of-truth backend driver. Supported: postgres.")
+	migrateCmd.PersistentFlags().StringVar(&cfg.Driver, "driver", cfg.Driver, "Source-of-truth backend driver. Supported: postgres, sqlite.")
 	migrateCmd.PersistentFlags().StringVar(&cfg.DatabaseURL, "database-url", "", "Database connection URL. Can also be set via OPENAUTH_MIGRATE_DATABASE_URL.")
-	migrateCmd.PersistentFlags().StringVar(&cfg.MigrationsTable, "migrations-table", cfg.MigrationsTable, "Migrations version table name. Supports table or schema.table format. Can also be set via OPENAUTH_MIGRATE_MIGRATIONS_TABLE.")
+	migrateCmd.PersistentFlags().StringVar(&cfg.MigrationsTable, "migrations-table", cfg.MigrationsTable, "Migrations version table name. Supports table or schema.table format (sqlite uses the table part only). Can also be set via OPENAUTH_MIGRATE_MIGRATIONS_TABLE.")
 	migrateCmd.PersistentFlags().StringVar(&cfg.MigrationsPath, "migrations-path", "", "Path or source URL for migration files. Defaults by driver under pkg/storage/<driver>/migrations.")
 
 	migrateCmd.AddCommand(&cobra.Command{
@@ -327,7 +328,8 @@ func resolveMigrationsTable(flagValue string) string {
 }
 
 func applyMigrationsTable(databaseURL string, driver string, table string) (string, error) {
-	if strings.ToLower(strings.TrimSpace(driver)) != "postgres" {
+	normalizedDriver := strings.ToLower(strings.TrimSpace(driver))
+	if normalizedDriver != "postgres" && normalizedDriver != "sqlite" {
 		return databaseURL, nil
 	}
 	spec, err := parseMigrationsTableSpec(table)
@@ -337,6 +339,10 @@ func applyMigrationsTable(databaseURL string, driver string, table string) (stri
 	if spec.Table == "" {
 		return databaseURL, nil
 	}
+	if normalizedDriver == "sqlite" {
+		// SQLite has no schemas; a qualifier would address an attached database.
+		spec.Schema = ""
+	}
 
 	parsed, err := url.Parse(databaseURL)
 	if err != nil {
@@ -457,13 +463,13 @@ func resolveMigrationsSourceURL(driver string, migrationsPath string) (string, e
 		normalizedDriver = "postgres"
 	}
 
-	if normalizedDriver != "postgres" {
-		return "", fmt.Errorf("unsupported --driver %q: only postgres is currently supported by CLI runner", normalizedDriver)
+	if normalizedDriver != "postgres" && normalizedDriver != "sqlite" {
+		return "", fmt.Errorf("unsupported --driver %q: only postgres and sqlite are currently supported by CLI runner", normalizedDriver)
 	}
 
 	pathOrURL := strings.TrimSpace(migrationsPath)
 	if pathOrURL == "" {
-		pathOrURL = "pkg/storage/postgres/migrations"
+		pathOrURL = "pkg/storage/" + normalizedDriver + "/migrations"
 	}
 
 	if strings.Contains(pathOrURL, "://") {
@@ -535,7 +541,11 @@ func isDroppedMigrationsTableError(err error, migrationsTable string) bool {
 }
 
 func migrationDriverURL(databaseURL string, driver string) (string, error) {
-	if strings.ToLower(strings.TrimSpace(driver)) != "postgres" {
+	switch strings.ToLower(strings.TrimSpace(driver)) {
+	case "postgres":
+	case "sqlite":
+		return sqliteMigrationDriverURL(databaseURL)
+	default:
 		return databaseURL, nil
 	}
 
@@ -556,6 +566,24 @@ func migrationDriverURL(databaseURL string, driver string) (string, error) {
 	return parsed.String(), nil
 }
 
+func sqliteMigrationDriverURL(databaseURL string) (string, error) {
+	parsed, err := url.Parse(databaseURL)
+	if err != nil {
+		return "", fmt.Errorf("parse --database-url: %w", err)
+	}
+
+	switch strings.ToLower(strings.TrimSpace(parsed.Scheme)) {
+	case "sqlite", "sqlite3":
+		parsed.Scheme = "sqlite"
+		return parsed.String(), nil
+	case "":
+		// Bare file paths are accepted for convenience (for example ./openauth.db).
+		return "sqlite://" + databaseURL, nil
+	default:
+		return "", fmt.Errorf("unsupported sqlite database URL scheme %q", parsed.Scheme)
+	}
+}
+
 func quoteIdentifier(value string) string {
 	return `"` + escapeDoubleQuote(value) + `"`
 }
@@ -1,6 +1,9 @@
 package cmd
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 func TestParseMigrationStepsArg(t *testing.T) {
 	t.Run("missing steps is optional", func(t *testing.T) {
@@ -103,3 +106,59 @@ func TestParseGotoVersionArg(t *testing.T) {
 		})
 	}
 }
+
+func TestMigrationDriverURLSQLite(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{name: "keeps sqlite scheme", input: "sqlite:///var/lib/openauth.db", want: "sqlite:///var/lib/openauth.db"},
+		{name: "normalizes sqlite3 scheme", input: "sqlite3://openauth.db", want: "sqlite://openauth.db"},
+		{name: "accepts bare path", input: "./openauth.db?x-migrations-table=schema_migrations", want: "sqlite://./openauth.db?x-migrations-table=schema_migrations"},
+		{name: "rejects foreign scheme", input: "postgres://localhost/openauth", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := migrationDriverURL(tt.input, "sqlite")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("migrationDriverURL returned error: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestApplyMigrationsTableSQLiteDropsSchema(t *testing.T) {
+	got, err := applyMigrationsTable("sqlite:///tmp/openauth.db", "sqlite", "openauth.schema_migrations")
+	if err != nil {
+		t.Fatalf("applyMigrationsTable returned error: %v", err)
+	}
+	if got != "sqlite:///tmp/openauth.db?x-migrations-table=schema_migrations" {
+		t.Fatalf("unexpected url %q", got)
+	}
+}
+
+func TestResolveMigrationsSourceURLDefaultsByDriver(t *testing.T) {
+	got, err := resolveMigrationsSourceURL("sqlite", "")
+	if err != nil {
+		t.Fatalf("resolveMigrationsSourceURL returned error: %v", err)
+	}
+	if !strings.HasSuffix(got, "/pkg/storage/sqlite/migrations") {
+		t.Fatalf("expected sqlite migrations path, got %q", got)
+	}
+
+	if _, err := resolveMigrationsSourceURL("mysql", ""); err == nil {
+		t.Fatalf("expected unsupported driver error")
+	}
+}
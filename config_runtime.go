@@ -5,12 +5,18 @@ import (
 	"database/sql"
 	stderrors "errors"
 	"fmt"
+	"net/url"
+	"strings"
 	"time"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
 	memorycache "github.com/porthorian/openauth/pkg/cache/memory"
 	rediscache "github.com/porthorian/openauth/pkg/cache/redis"
+	filekeystore "github.com/porthorian/openauth/pkg/keystore/file"
+	"github.com/porthorian/openauth/pkg/session/jwt"
 	"github.com/porthorian/openauth/pkg/storage/postgres"
+	"github.com/porthorian/openauth/pkg/storage/sqlite"
+	_ "modernc.org/sqlite"
 )
 
 type StorageBackend string
@@ -25,6 +31,7 @@ type KeyStoreBackend string
 
 const (
 	KeyStoreBackendNone KeyStoreBackend = "none"
+	KeyStoreBackendFile KeyStoreBackend = "file"
 )
 
 type CacheBackend string
@@ -44,6 +51,7 @@ type RuntimeConfig struct {
 type StorageConfig struct {
 	Backend  StorageBackend
 	Postgres PostgresConfig
+	SQLite   SQLiteConfig
 }
 
 type PostgresConfig struct {
@@ -57,6 +65,15 @@ type PostgresConfig struct {
 	OpenDB          func(driverName string, dsn string) (*sql.DB, error)
 }
 
+type SQLiteConfig struct {
+	DriverName      string
+	DSN             string
+	MaxOpenConns    int
+	ConnMaxLifetime time.Duration
+	PingTimeout     time.Duration
+	OpenDB          func(driverName string, dsn string) (*sql.DB, error)
+}
+
 type CacheConfig struct {
 	Backend CacheBackend
 	Memory  MemoryCacheConfig
@@ -72,11 +89,19 @@ type RedisCacheConfig struct {
 	Database    int
 	Namespace   string
 	DialTimeout time.Duration
+	PoolSize    int
 }
 
 type KeyStoreConfig struct {
 	Backend KeyStoreBackend
-	URI     string
+	// URI locates the key material. For the file backend it is a directory
+	// or bundle path, optionally written as file:///path.
+	URI string
+	// MaxTokenTTL is how long keys removed from signing by their not_after
+	// keep verifying tokens. Zero uses jwt.DefaultMaxTokenTTL.
+	MaxTokenTTL  time.Duration
+	PublishAhead time.Duration
+	PollInterval time.Duration
 }
 
 func (c Config) initialize(ctx context.Context) (func() error, Config, error) {
@@ -87,22 +112,24 @@ func (c Config) initialize(ctx context.Context) (func() error, Config, error) {
 	config := c
 	config.Logger = resolveLogger(config.Logger)
 
-	if err := validateKeyStoreBackend(config.Runtime.KeyStore.Backend); err != nil {
+	closeKeyStore, config, err := initializeKeyStore(config)
+	if err != nil {
 		return nil, Config{}, err
 	}
 
 	closeStorage, config, err := initializeStorage(ctx, config)
 	if err != nil {
+		_ = closeKeyStore()
 		return nil, Config{}, err
 	}
 
 	closeCache, config, err := initializeCache(config)
 	if err != nil {
-		_ = closeStorage()
+		_ = joinClosers(closeKeyStore, closeStorage)()
 		return nil, Config{}, err
 	}
 
-	return joinClosers(closeStorage, closeCache), config, nil
+	return joinClosers(closeKeyStore, closeStorage, closeCache), config, nil
 }
 
 func initializeStorage(ctx context.Context, config Config) (func() error, Config, error) {
@@ -117,7 +144,7 @@ func initializeStorage(ctx context.Context, config Config) (func() error, Config
 	case StorageBackendPostgres:
 		return initializePostgres(ctx, config)
 	case StorageBackendSQLite:
-		return nil, Config{}, fmt.Errorf("openauth config: runtime.storage.backend %q is not implemented yet", StorageBackendSQLite)
+		return initializeSQLite(ctx, config)
 	default:
 		return nil, Config{}, fmt.Errorf("openauth config: unsupported runtime.storage.backend %q", backend)
 	}
@@ -153,6 +180,12 @@ func initializeMemoryCache(config Config) (func() error, Config, error) {
 	if config.CacheStore.Permission == nil {
 		config.CacheStore.Permission = adapter
 	}
+	if config.CacheStore.Attempt == nil {
+		config.CacheStore.Attempt = adapter
+	}
+	if config.RevocationStore == nil {
+		config.RevocationStore = adapter
+	}
 
 	config.Logger.V(1).Info("initialized memory cache backend")
 	return noopCloser, config, nil
@@ -174,6 +207,7 @@ func initializeRedisCache(config Config) (func() error, Config, error) {
 		Database:    redisConfig.Database,
 		Namespace:   redisConfig.Namespace,
 		DialTimeout: redisConfig.DialTimeout,
+		PoolSize:    redisConfig.PoolSize,
 	})
 
 	if config.CacheStore.Token == nil {
@@ -185,10 +219,16 @@ func initializeRedisCache(config Config) (func() error, Config, error) {
 	if config.CacheStore.Permission == nil {
 		config.CacheStore.Permission = adapter
 	}
+	if config.CacheStore.Attempt == nil {
+		config.CacheStore.Attempt = adapter
+	}
+	if config.RevocationStore == nil {
+		config.RevocationStore = adapter
+	}
 
 	config.Runtime.Cache.Redis = redisConfig
 	config.Logger.V(1).Info("initialized redis cache backend", "address", redisConfig.Address, "database", redisConfig.Database, "namespace", redisConfig.Namespace)
-	return noopCloser, config, nil
+	return adapter.Close, config, nil
 }
 
 func initializePostgres(ctx context.Context, config Config) (func() error, Config, error) {
@@ -258,6 +298,15 @@ func initializePostgres(ctx context.Context, config Config) (func() error, Confi
 	if config.AuthdStore.Permission == nil {
 		config.AuthdStore.Permission = adapter
 	}
+	if config.RevocationStore == nil {
+		config.RevocationStore = adapter
+	}
+	if config.SessionStore == nil {
+		config.SessionStore = adapter
+	}
+	if config.OAuthClientStore == nil {
+		config.OAuthClientStore = adapter
+	}
 
 	closeResource := func() error {
 		return db.Close()
@@ -268,11 +317,161 @@ func initializePostgres(ctx context.Context, config Config) (func() error, Confi
 	return closeResource, config, nil
 }
 
-func validateKeyStoreBackend(backend KeyStoreBackend) error {
-	if backend == "" || backend == KeyStoreBackendNone {
-		return nil
+func initializeSQLite(ctx context.Context, config Config) (func() error, Config, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	sqliteConfig := config.Runtime.Storage.SQLite
+	if sqliteConfig.DSN == "" {
+		return nil, Config{}, fmt.Errorf("openauth config: runtime.storage.sqlite.dsn is required")
+	}
+
+	if sqliteConfig.DriverName == "" {
+		sqliteConfig.DriverName = "sqlite"
+	}
+	// SQLite serialises writers; a single connection avoids SQLITE_BUSY
+	// under concurrent writes and keeps ":memory:" databases shared.
+	if sqliteConfig.MaxOpenConns <= 0 {
+		sqliteConfig.MaxOpenConns = 1
+	}
+	if sqliteConfig.PingTimeout <= 0 {
+		sqliteConfig.PingTimeout = 5 * time.Second
+	}
+	if sqliteConfig.OpenDB == nil {
+		sqliteConfig.OpenDB = sql.Open
+	}
+
+	db, err := sqliteConfig.OpenDB(sqliteConfig.DriverName, sqliteConfig.DSN)
+	if err != nil {
+		return nil, Config{}, fmt.Errorf("openauth config: failed to open sqlite database: %w", err)
+	}
+
+	db.SetMaxOpenConns(sqliteConfig.MaxOpenConns)
+	if sqliteConfig.ConnMaxLifetime > 0 {
+		db.SetConnMaxLifetime(sqliteConfig.ConnMaxLifetime)
+	}
+
+	pingCtx, cancel := context.WithTimeout(ctx, sqliteConfig.PingTimeout)
+	defer cancel()
+
+	if err := db.PingContext(pingCtx); err != nil {
+		_ = db.Close()
+		return nil, Config{}, fmt.Errorf("openauth config: failed to ping sqlite database: %w", err)
+	}
+
+	adapter, err := sqlite.NewAdapter(db)
+	if err != nil {
+		_ = db.Close()
+		return nil, Config{}, fmt.Errorf("openauth config: failed to initialize sqlite adapter: %w", err)
+	}
+
+	if config.AuthStore.Auth == nil {
+		config.AuthStore.Auth = adapter
+	}
+	if config.AuthStore.SubjectAuth == nil {
+		config.AuthStore.SubjectAuth = adapter
+	}
+	if config.AuthStore.AuthLog == nil {
+		config.AuthStore.AuthLog = adapter
+	}
+	if config.AuthdStore.Role == nil {
+		config.AuthdStore.Role = adapter
+	}
+	if config.AuthdStore.Permission == nil {
+		config.AuthdStore.Permission = adapter
+	}
+	if config.RevocationStore == nil {
+		config.RevocationStore = adapter
+	}
+	if config.SessionStore == nil {
+		config.SessionStore = adapter
+	}
+	if config.OAuthClientStore == nil {
+		config.OAuthClientStore = adapter
+	}
+
+	closeResource := func() error {
+		return stderrors.Join(adapter.Close(), db.Close())
+	}
+
+	config.Runtime.Storage.SQLite = sqliteConfig
+	config.Logger.V(1).Info("initialized sqlite storage backend", "driver", sqliteConfig.DriverName, "max_open_conns", sqliteConfig.MaxOpenConns)
+	return closeResource, config, nil
+}
+
+func initializeKeyStore(config Config) (func() error, Config, error) {
+	backend := config.Runtime.KeyStore.Backend
+	if backend == "" {
+		backend = KeyStoreBackendNone
+	}
+
+	switch backend {
+	case KeyStoreBackendNone:
+		return noopCloser, config, nil
+	case KeyStoreBackendFile:
+		return initializeFileKeyStore(config)
+	default:
+		return nil, Config{}, fmt.Errorf("openauth config: unsupported runtime.keystore.backend %q", backend)
+	}
+}
+
+func initializeFileKeyStore(config Config) (func() error, Config, error) {
+	keyStoreConfig := config.Runtime.KeyStore
+	path, err := keyStoreFilePath(keyStoreConfig.URI)
+	if err != nil {
+		return nil, Config{}, err
+	}
+
+	maxTokenTTL := keyStoreConfig.MaxTokenTTL
+	if maxTokenTTL == 0 {
+		maxTokenTTL = jwt.DefaultMaxTokenTTL
+	}
+
+	store, err := filekeystore.New(filekeystore.Config{
+		Path:         path,
+		Keyring:      config.Keyring,
+		MaxTokenTTL:  maxTokenTTL,
+		PublishAhead: keyStoreConfig.PublishAhead,
+		PollInterval: keyStoreConfig.PollInterval,
+		Logger:       config.Logger,
+	})
+	if err != nil {
+		return nil, Config{}, fmt.Errorf("openauth config: failed to load file keystore: %w", err)
+	}
+	if err := store.Watch(); err != nil {
+		_ = store.Close()
+		return nil, Config{}, fmt.Errorf("openauth config: failed to watch file keystore: %w", err)
+	}
+
+	config.Keyring = store.Keyring()
+	config.Logger.V(1).Info("initialized file keystore backend", "path", path, "keys", len(config.Keyring.Entries()))
+	return store.Close, config, nil
+}
+
+func keyStoreFilePath(uri string) (string, error) {
+	uri = strings.TrimSpace(uri)
+	if uri == "" {
+		return "", fmt.Errorf("openauth config: runtime.keystore.uri is required")
+	}
+	if !strings.Contains(uri, "://") {
+		return uri, nil
+	}
+
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return "", fmt.Errorf("openauth config: invalid runtime.keystore.uri: %w", err)
+	}
+	if parsed.Scheme != "file" {
+		return "", fmt.Errorf("openauth config: runtime.keystore.uri scheme %q is not supported by the file backend", parsed.Scheme)
+	}
+	if parsed.Host != "" && parsed.Host != "localhost" {
+		return "", fmt.Errorf("openauth config: runtime.keystore.uri host %q is not supported by the file backend", parsed.Host)
+	}
+	if parsed.Path == "" {
+		return "", fmt.Errorf("openauth config: runtime.keystore.uri is missing a path")
 	}
-	return fmt.Errorf("openauth config: unsupported runtime.keystore.backend %q", backend)
+	return parsed.Path, nil
 }
 
 func joinClosers(closers ...func() error) func() error {
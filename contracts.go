@@ -3,11 +3,16 @@ package openauth
 import (
 	"context"
 	"errors"
+	"net/url"
+	"slices"
 	"strings"
 	"time"
+	"unicode"
 
 	"github.com/porthorian/openauth/pkg/authz"
 	oerrors "github.com/porthorian/openauth/pkg/errors"
+	"github.com/porthorian/openauth/pkg/mfa/webauthn"
+	"github.com/porthorian/openauth/pkg/protocol/oauth"
 	"github.com/porthorian/openauth/pkg/storage"
 )
 
@@ -34,36 +39,273 @@ type Principal struct {
 	PermissionMask  PermissionMask
 	Claims          Claims    // Claims carries contextual identity attributes needed for policy evaluation and token enrichment.
 	AuthenticatedAt time.Time // AuthenticatedAt preserves auth time for freshness controls, TTL policies, and auditing.
+	Degraded        bool      // Degraded marks a principal served from a cached snapshot because storage was unavailable under a fail-open policy.
 }
 
 type InputType string
 
 const (
-	InputTypePassword InputType = "password"
-	InputTypeToken    InputType = "token"
+	InputTypePassword     InputType = "password"
+	InputTypeToken        InputType = "token"
+	InputTypeTOTP         InputType = "totp"
+	InputTypeRecoveryCode InputType = "recovery_code"
+	InputTypeWebAuthn     InputType = "webauthn"
+	// InputTypeAPIKey authenticates with an API key in Value. UserID is
+	// optional; the key identifies its subject.
+	InputTypeAPIKey InputType = "api_key"
 )
 
 type AuthInput struct {
-	UserID   string
-	Tenant   string
-	Type     InputType
-	Value    string
-	Metadata map[string]string
+	UserID string
+	Tenant string
+	Type   InputType
+	Value  string
+	// Source identifies where the attempt came from, such as a client IP.
+	// Lockout counts failures per subject and per subject and source.
+	Source string
+	// Challenge carries the MFA challenge token from the first step when
+	// Type is a second factor such as InputTypeTOTP, or the WebAuthnLogin
+	// challenge when Type is InputTypeWebAuthn.
+	Challenge string
+	Metadata  map[string]string
 }
 
 type CreateAuthInput struct {
-	UserID    string
-	Value     string
+	UserID string
+	Value  string
+	// Profile selects the persistence policy the auth is created under.
+	// Empty uses storage.AuthProfilePasswordBasic; profiles missing from the
+	// policy matrix fall back to Config.DefaultPolicy.
+	Profile   storage.AuthProfile
+	ExpiresAt *time.Time // ExpiresAt may only be nil when the policy allows non-expiring material or sets a DefaultTTL.
+	Metadata  map[string]string
+}
+
+type RotatePasswordInput struct {
+	UserID       string
+	CurrentValue string // CurrentValue must match the active password.
+	NewValue     string
+	ExpiresAt    *time.Time
+	Metadata     map[string]string
+}
+
+type PasswordManager interface {
+	RotatePassword(ctx context.Context, input RotatePasswordInput) error
+}
+
+// Credential describes stored auth material without its hash.
+type Credential struct {
+	AuthID       string
+	Subject      string
+	Type         storage.AuthMaterialType
+	Status       storage.AuthStatus
+	DateAdded    time.Time
+	DateModified *time.Time
+	ExpiresAt    *time.Time
+	RevokedAt    *time.Time
+	Metadata     map[string]string
+}
+
+type RotateAuthInput struct {
+	AuthID    string
+	Value     string // Value replaces the password; the current value is not required.
 	ExpiresAt *time.Time
 	Metadata  map[string]string
 }
 
+type CreateAPIKeyInput struct {
+	UserID    string
+	Tenant    string
+	Name      string     // Name labels the key in credential listings.
+	ExpiresAt *time.Time // ExpiresAt may be nil; API keys are allowed to never expire.
+	Metadata  map[string]string
+}
+
+type APIKey struct {
+	Key       string // Key is only available here; storage keeps a hash of its secret.
+	AuthID    string
+	Display   string // Display identifies the key without its secret, such as oak_1a2b3c4d.
+	ExpiresAt *time.Time
+}
+
+type APIKeyManager interface {
+	CreateAPIKey(ctx context.Context, input CreateAPIKeyInput) (APIKey, error)
+	AuthenticateAPIKey(ctx context.Context, key string) (Principal, error)
+}
+
+type RegisterOAuthClientInput struct {
+	ClientID     string // ClientID is generated when empty.
+	Name         string
+	Tenant       string
+	GrantTypes   []string // GrantTypes are oauth.GrantType values; empty allows client_credentials only.
+	Scopes       []string
+	RedirectURIs []string // RedirectURIs are required for the authorization_code grant.
+}
+
+type OAuthClient struct {
+	ClientID     string
+	Name         string
+	Tenant       string
+	GrantTypes   []string
+	Scopes       []string
+	RedirectURIs []string
+	DateAdded    time.Time
+	DateModified *time.Time
+}
+
+type CreateClientSecretInput struct {
+	ClientID string
+	TTL      time.Duration // Zero uses the client_secret policy's DefaultTTL; client secrets always expire.
+}
+
+type ClientSecret struct {
+	Secret    string // Secret is only available here; storage keeps a hash.
+	AuthID    string
+	ExpiresAt time.Time
+}
+
+type AuthenticateClientInput struct {
+	ClientID  string
+	Secret    string
+	GrantType string   // GrantType defaults to client_credentials.
+	Scopes    []string // Scopes must be registered for the client; empty grants all of them.
+	Source    string
+}
+
+type OAuthClientManager interface {
+	RegisterOAuthClient(ctx context.Context, input RegisterOAuthClientInput) (OAuthClient, error)
+	GetOAuthClient(ctx context.Context, clientID string) (OAuthClient, error)
+	DeleteOAuthClient(ctx context.Context, clientID string) error
+	CreateClientSecret(ctx context.Context, input CreateClientSecretInput) (ClientSecret, error)
+	AuthenticateClient(ctx context.Context, input AuthenticateClientInput) (Principal, error)
+}
+
+type CredentialManager interface {
+	ListCredentials(ctx context.Context, subject string) ([]Credential, error)
+	RevokeAuth(ctx context.Context, authID string, reason string) error
+	DeactivateAuth(ctx context.Context, authID string) error
+	RotateAuth(ctx context.Context, input RotateAuthInput) (Credential, error)
+}
+
 type Authenticator interface {
 	Authorize(ctx context.Context, input AuthInput) (Principal, error)
 	CreateAuth(ctx context.Context, input CreateAuthInput) error
 	ValidateToken(ctx context.Context, token string) (Principal, error)
 }
 
+type IssueRefreshTokenInput struct {
+	UserID   string
+	Tenant   string
+	TTL      time.Duration // TTL bounds the whole token family; rotation keeps the original expiry. Zero uses a 30 day default.
+	Metadata map[string]string
+}
+
+type RefreshToken struct {
+	Token     string // Token is the opaque value handed to the client; only a hash of its secret is stored.
+	AuthID    string
+	FamilyID  string
+	ExpiresAt time.Time
+}
+
+type RefreshResult struct {
+	Principal    Principal
+	RefreshToken RefreshToken
+}
+
+type RefreshTokenManager interface {
+	IssueRefreshToken(ctx context.Context, input IssueRefreshTokenInput) (RefreshToken, error)
+	RefreshAuth(ctx context.Context, token string) (RefreshResult, error)
+}
+
+type EnrollTOTPInput struct {
+	UserID      string
+	AccountName string // AccountName labels the enrolment in authenticator apps. Defaults to UserID.
+}
+
+// TOTPEnrollment is returned once; only the encrypted secret is stored.
+type TOTPEnrollment struct {
+	AuthID string
+	Secret string // Secret is the base32 secret for manual entry.
+	URI    string // URI is the otpauth:// URI for QR codes.
+}
+
+type ConfirmTOTPInput struct {
+	UserID string
+	AuthID string
+	Code   string
+}
+
+type GenerateRecoveryCodesInput struct {
+	UserID string
+	Count  int // Count defaults to 10.
+}
+
+// RecoveryCodes is returned once; only a hash of each code is stored.
+type RecoveryCodes struct {
+	Codes     []string
+	Remaining int
+}
+
+type MFAManager interface {
+	EnrollTOTP(ctx context.Context, input EnrollTOTPInput) (TOTPEnrollment, error)
+	ConfirmTOTP(ctx context.Context, input ConfirmTOTPInput) error
+	GenerateRecoveryCodes(ctx context.Context, input GenerateRecoveryCodesInput) (RecoveryCodes, error)
+	CountRecoveryCodes(ctx context.Context, userID string) (int, error)
+}
+
+type BeginWebAuthnRegistrationInput struct {
+	UserID      string
+	UserName    string // UserName is shown by authenticators. Defaults to UserID.
+	DisplayName string // DisplayName defaults to UserName.
+}
+
+// WebAuthnRegistration starts a registration ceremony. Send Options to the
+// browser and pass Challenge back with its response.
+type WebAuthnRegistration struct {
+	Challenge string
+	Options   webauthn.CreationOptions
+	ExpiresAt time.Time
+}
+
+type FinishWebAuthnRegistrationInput struct {
+	UserID    string
+	Challenge string
+	Response  string // Response is the JSON PublicKeyCredential from navigator.credentials.create.
+}
+
+type WebAuthnCredential struct {
+	AuthID       string
+	CredentialID string // CredentialID is base64url encoded.
+}
+
+type BeginWebAuthnLoginInput struct {
+	UserID string
+}
+
+// WebAuthnLogin starts an authentication ceremony. Finish it with Authorize
+// using InputTypeWebAuthn, Challenge, and the JSON PublicKeyCredential from
+// navigator.credentials.get as Value.
+type WebAuthnLogin struct {
+	Challenge string
+	Options   webauthn.RequestOptions
+	ExpiresAt time.Time
+}
+
+type WebAuthnManager interface {
+	BeginWebAuthnRegistration(ctx context.Context, input BeginWebAuthnRegistrationInput) (WebAuthnRegistration, error)
+	FinishWebAuthnRegistration(ctx context.Context, input FinishWebAuthnRegistrationInput) (WebAuthnCredential, error)
+	BeginWebAuthnLogin(ctx context.Context, input BeginWebAuthnLoginInput) (WebAuthnLogin, error)
+}
+
+type UnlockSubjectInput struct {
+	Subject string
+	Source  string // Source additionally clears the lock for one subject and source pair.
+}
+
+type LockoutManager interface {
+	UnlockSubject(ctx context.Context, input UnlockSubjectInput) error
+}
+
 type SetSubjectRolesInput struct {
 	Subject  string
 	Tenant   string
@@ -97,6 +339,14 @@ func (a InputType) GetMaterialType() storage.AuthMaterialType {
 	switch a {
 	case InputTypePassword:
 		return storage.AuthMaterialTypePassword
+	case InputTypeTOTP:
+		return storage.AuthMaterialTypeTOTP
+	case InputTypeRecoveryCode:
+		return storage.AuthMaterialTypeRecoveryCode
+	case InputTypeWebAuthn:
+		return storage.AuthMaterialTypeWebAuthn
+	case InputTypeAPIKey:
+		return storage.AuthMaterialTypeAPIKey
 	case InputTypeToken:
 		// TODO: consider supporting multiple token types (e.g. bearer, mac) and encoding them in the input type or metadata for more flexible token handling
 		return ""
@@ -112,17 +362,21 @@ func (a CreateAuthInput) Normalize() CreateAuthInput {
 	userID := strings.TrimSpace(a.UserID)
 	value := strings.TrimSpace(a.Value)
 
+	profile := storage.AuthProfile(strings.TrimSpace(string(a.Profile)))
+	if profile == "" {
+		profile = storage.AuthProfilePasswordBasic
+	}
+
 	var expiresAt *time.Time
 	if a.ExpiresAt != nil {
 		exp := a.ExpiresAt.UTC()
-		if exp.After(time.Now().UTC()) {
-			expiresAt = &exp
-		}
+		expiresAt = &exp
 	}
 
 	return CreateAuthInput{
 		UserID:    userID,
 		Value:     value,
+		Profile:   profile,
 		ExpiresAt: expiresAt,
 		Metadata:  a.Metadata,
 	}
@@ -132,6 +386,9 @@ func (a CreateAuthInput) Validate() error {
 	if a.UserID == "" {
 		return oerrors.New(oerrors.CodeInvalidCredentials, "user_id is required")
 	}
+	if strings.HasPrefix(a.UserID, OAuthClientSubjectPrefix) {
+		return oerrors.New(oerrors.CodeInvalidCredentials, "user_id must not start with "+OAuthClientSubjectPrefix)
+	}
 
 	if a.Value == "" {
 		return oerrors.New(oerrors.CodeInvalidCredentials, "auth value is required")
@@ -144,6 +401,322 @@ func (a CreateAuthInput) Validate() error {
 	return nil
 }
 
+func (input RotatePasswordInput) Normalize() RotatePasswordInput {
+	normalized := CreateAuthInput{
+		UserID:    input.UserID,
+		Value:     input.NewValue,
+		ExpiresAt: input.ExpiresAt,
+		Metadata:  input.Metadata,
+	}.Normalize()
+
+	return RotatePasswordInput{
+		UserID:       normalized.UserID,
+		CurrentValue: strings.TrimSpace(input.CurrentValue),
+		NewValue:     normalized.Value,
+		ExpiresAt:    normalized.ExpiresAt,
+		Metadata:     normalized.Metadata,
+	}
+}
+
+func (input RotatePasswordInput) Validate() error {
+	if input.CurrentValue == "" {
+		return oerrors.New(oerrors.CodeInvalidCredentials, "current auth value is required")
+	}
+	return CreateAuthInput{
+		UserID:    input.UserID,
+		Value:     input.NewValue,
+		ExpiresAt: input.ExpiresAt,
+	}.Validate()
+}
+
+func (input CreateAPIKeyInput) Normalize() CreateAPIKeyInput {
+	var expiresAt *time.Time
+	if input.ExpiresAt != nil {
+		exp := input.ExpiresAt.UTC()
+		expiresAt = &exp
+	}
+
+	return CreateAPIKeyInput{
+		UserID:    strings.TrimSpace(input.UserID),
+		Tenant:    strings.TrimSpace(input.Tenant),
+		Name:      strings.TrimSpace(input.Name),
+		ExpiresAt: expiresAt,
+		Metadata:  input.Metadata,
+	}
+}
+
+func (input CreateAPIKeyInput) Validate() error {
+	if input.UserID == "" {
+		return oerrors.New(oerrors.CodeInvalidCredentials, "user_id is required")
+	}
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(time.Now().UTC()) {
+		return oerrors.New(oerrors.CodeInvalidCredentials, "api key expiry must be in the future")
+	}
+	return nil
+}
+
+func (input RegisterOAuthClientInput) Normalize() RegisterOAuthClientInput {
+	grantTypes := normalizeStringKeys(input.GrantTypes)
+	if len(grantTypes) == 0 {
+		grantTypes = []string{oauth.GrantTypeClientCredentials}
+	}
+
+	return RegisterOAuthClientInput{
+		ClientID:     strings.TrimSpace(input.ClientID),
+		Name:         strings.TrimSpace(input.Name),
+		Tenant:       strings.TrimSpace(input.Tenant),
+		GrantTypes:   grantTypes,
+		Scopes:       normalizeStringKeys(input.Scopes),
+		RedirectURIs: normalizeStringKeys(input.RedirectURIs),
+	}
+}
+
+func (input RegisterOAuthClientInput) Validate() error {
+	if strings.ContainsFunc(input.ClientID, unicode.IsSpace) || strings.Contains(input.ClientID, ":") {
+		return oerrors.New(oerrors.CodeInvalidCredentials, "client_id must not contain whitespace or colons")
+	}
+	for _, grantType := range input.GrantTypes {
+		switch grantType {
+		case oauth.GrantTypeAuthorizationCode, oauth.GrantTypeClientCredentials, oauth.GrantTypeRefreshToken:
+		default:
+			return oerrors.New(oerrors.CodeInvalidCredentials, "unsupported grant type: "+grantType)
+		}
+	}
+	for _, scope := range input.Scopes {
+		if strings.ContainsFunc(scope, unicode.IsSpace) {
+			return oerrors.New(oerrors.CodeInvalidCredentials, "scopes must not contain whitespace")
+		}
+	}
+	for _, redirectURI := range input.RedirectURIs {
+		parsed, err := url.Parse(redirectURI)
+		if err != nil || !parsed.IsAbs() || parsed.Host == "" || parsed.Fragment != "" {
+			return oerrors.New(oerrors.CodeInvalidCredentials, "redirect uris must be absolute without a fragment")
+		}
+	}
+	if slices.Contains(input.GrantTypes, oauth.GrantTypeAuthorizationCode) && len(input.RedirectURIs) == 0 {
+		return oerrors.New(oerrors.CodeInvalidCredentials, "authorization_code clients need a redirect uri")
+	}
+	return nil
+}
+
+func (input CreateClientSecretInput) Normalize() CreateClientSecretInput {
+	return CreateClientSecretInput{
+		ClientID: strings.TrimSpace(input.ClientID),
+		TTL:      input.TTL,
+	}
+}
+
+func (input CreateClientSecretInput) Validate() error {
+	if input.ClientID == "" {
+		return oerrors.New(oerrors.CodeInvalidCredentials, "client_id is required")
+	}
+	if input.TTL < 0 {
+		return oerrors.New(oerrors.CodeInvalidCredentials, "client secret ttl must not be negative")
+	}
+	return nil
+}
+
+func (input AuthenticateClientInput) Normalize() AuthenticateClientInput {
+	grantType := strings.TrimSpace(input.GrantType)
+	if grantType == "" {
+		grantType = oauth.GrantTypeClientCredentials
+	}
+
+	return AuthenticateClientInput{
+		ClientID:  strings.TrimSpace(input.ClientID),
+		Secret:    strings.TrimSpace(input.Secret),
+		GrantType: grantType,
+		Scopes:    normalizeStringKeys(input.Scopes),
+		Source:    strings.TrimSpace(input.Source),
+	}
+}
+
+func (input AuthenticateClientInput) Validate() error {
+	if input.ClientID == "" {
+		return oerrors.New(oerrors.CodeInvalidCredentials, "client_id is required")
+	}
+	if input.Secret == "" {
+		return oerrors.New(oerrors.CodeInvalidCredentials, "client secret is required")
+	}
+	return nil
+}
+
+func (input RotateAuthInput) Normalize() RotateAuthInput {
+	normalized := CreateAuthInput{
+		UserID:    input.AuthID,
+		Value:     input.Value,
+		ExpiresAt: input.ExpiresAt,
+		Metadata:  input.Metadata,
+	}.Normalize()
+
+	return RotateAuthInput{
+		AuthID:    normalized.UserID,
+		Value:     normalized.Value,
+		ExpiresAt: normalized.ExpiresAt,
+		Metadata:  normalized.Metadata,
+	}
+}
+
+func (input RotateAuthInput) Validate() error {
+	if input.AuthID == "" {
+		return oerrors.New(oerrors.CodeInvalidCredentials, "auth_id is required")
+	}
+	if input.Value == "" {
+		return oerrors.New(oerrors.CodeInvalidCredentials, "auth value is required")
+	}
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(time.Now().UTC()) {
+		return oerrors.New(oerrors.CodeInvalidCredentials, "expires_at must be in the future")
+	}
+	return nil
+}
+
+func (input IssueRefreshTokenInput) Normalize() IssueRefreshTokenInput {
+	return IssueRefreshTokenInput{
+		UserID:   strings.TrimSpace(input.UserID),
+		Tenant:   strings.TrimSpace(input.Tenant),
+		TTL:      input.TTL,
+		Metadata: input.Metadata,
+	}
+}
+
+func (input IssueRefreshTokenInput) Validate() error {
+	if input.UserID == "" {
+		return oerrors.New(oerrors.CodeInvalidCredentials, "user_id is required")
+	}
+	if input.TTL < 0 {
+		return oerrors.New(oerrors.CodeInvalidCredentials, "refresh token ttl must not be negative")
+	}
+	return nil
+}
+
+func (input EnrollTOTPInput) Normalize() EnrollTOTPInput {
+	normalized := EnrollTOTPInput{
+		UserID:      strings.TrimSpace(input.UserID),
+		AccountName: strings.TrimSpace(input.AccountName),
+	}
+	if normalized.AccountName == "" {
+		normalized.AccountName = normalized.UserID
+	}
+	return normalized
+}
+
+func (input EnrollTOTPInput) Validate() error {
+	if input.UserID == "" {
+		return oerrors.New(oerrors.CodeInvalidCredentials, "user_id is required")
+	}
+	return nil
+}
+
+func (input ConfirmTOTPInput) Normalize() ConfirmTOTPInput {
+	return ConfirmTOTPInput{
+		UserID: strings.TrimSpace(input.UserID),
+		AuthID: strings.TrimSpace(input.AuthID),
+		Code:   strings.TrimSpace(input.Code),
+	}
+}
+
+func (input ConfirmTOTPInput) Validate() error {
+	if input.UserID == "" {
+		return oerrors.New(oerrors.CodeInvalidCredentials, "user_id is required")
+	}
+	if input.AuthID == "" {
+		return oerrors.New(oerrors.CodeInvalidCredentials, "auth_id is required")
+	}
+	if input.Code == "" {
+		return oerrors.New(oerrors.CodeInvalidCredentials, "totp code is required")
+	}
+	return nil
+}
+
+func (input GenerateRecoveryCodesInput) Normalize() GenerateRecoveryCodesInput {
+	normalized := GenerateRecoveryCodesInput{
+		UserID: strings.TrimSpace(input.UserID),
+		Count:  input.Count,
+	}
+	if normalized.Count == 0 {
+		normalized.Count = defaultRecoveryCodeCount
+	}
+	return normalized
+}
+
+func (input GenerateRecoveryCodesInput) Validate() error {
+	if input.UserID == "" {
+		return oerrors.New(oerrors.CodeInvalidCredentials, "user_id is required")
+	}
+	if input.Count < 1 || input.Count > maxRecoveryCodeCount {
+		return oerrors.New(oerrors.CodeInvalidCredentials, "recovery code count is out of range")
+	}
+	return nil
+}
+
+func (input BeginWebAuthnRegistrationInput) Normalize() BeginWebAuthnRegistrationInput {
+	normalized := BeginWebAuthnRegistrationInput{
+		UserID:      strings.TrimSpace(input.UserID),
+		UserName:    strings.TrimSpace(input.UserName),
+		DisplayName: strings.TrimSpace(input.DisplayName),
+	}
+	if normalized.UserName == "" {
+		normalized.UserName = normalized.UserID
+	}
+	if normalized.DisplayName == "" {
+		normalized.DisplayName = normalized.UserName
+	}
+	return normalized
+}
+
+func (input BeginWebAuthnRegistrationInput) Validate() error {
+	if input.UserID == "" {
+		return oerrors.New(oerrors.CodeInvalidCredentials, "user_id is required")
+	}
+	return nil
+}
+
+func (input FinishWebAuthnRegistrationInput) Normalize() FinishWebAuthnRegistrationInput {
+	return FinishWebAuthnRegistrationInput{
+		UserID:    strings.TrimSpace(input.UserID),
+		Challenge: strings.TrimSpace(input.Challenge),
+		Response:  strings.TrimSpace(input.Response),
+	}
+}
+
+func (input FinishWebAuthnRegistrationInput) Validate() error {
+	if input.UserID == "" {
+		return oerrors.New(oerrors.CodeInvalidCredentials, "user_id is required")
+	}
+	if input.Challenge == "" {
+		return oerrors.New(oerrors.CodeInvalidCredentials, "webauthn challenge is required")
+	}
+	if input.Response == "" {
+		return oerrors.New(oerrors.CodeInvalidCredentials, "webauthn response is required")
+	}
+	return nil
+}
+
+func (input BeginWebAuthnLoginInput) Normalize() BeginWebAuthnLoginInput {
+	return BeginWebAuthnLoginInput{UserID: strings.TrimSpace(input.UserID)}
+}
+
+func (input BeginWebAuthnLoginInput) Validate() error {
+	if input.UserID == "" {
+		return oerrors.New(oerrors.CodeInvalidCredentials, "user_id is required")
+	}
+	return nil
+}
+
+func (input UnlockSubjectInput) Normalize() UnlockSubjectInput {
+	return UnlockSubjectInput{
+		Subject: strings.TrimSpace(input.Subject),
+		Source:  strings.TrimSpace(input.Source),
+	}
+}
+
+func (input UnlockSubjectInput) Validate() error {
+	if input.Subject == "" {
+		return oerrors.New(oerrors.CodeInvalidCredentials, "subject is required")
+	}
+	return nil
+}
+
 func (input SetSubjectRolesInput) Normalize() SetSubjectRolesInput {
 	return SetSubjectRolesInput{
 		Subject:  strings.TrimSpace(input.Subject),
@@ -9,6 +9,9 @@ import (
 	ocache "github.com/porthorian/openauth/pkg/cache"
 	ocrypto "github.com/porthorian/openauth/pkg/crypto"
 	oerrors "github.com/porthorian/openauth/pkg/errors"
+	"github.com/porthorian/openauth/pkg/password"
+	"github.com/porthorian/openauth/pkg/session"
+	"github.com/porthorian/openauth/pkg/session/jwt"
 	"github.com/porthorian/openauth/pkg/storage"
 )
 
@@ -20,16 +23,54 @@ type Config struct {
 	Hasher               ocrypto.Hasher
 	PolicyMatrix         storage.PersistencePolicyMatrix
 	DefaultPolicy        storage.AuthProfile
+	TokenProfile         storage.AuthProfile
 	Authorization        AuthorizationConfig
 	ApproachRegistry     *approach.Registry
 	DefaultTokenApproach string
 	Runtime              RuntimeConfig
+	// Keyring holds signing keys loaded by the runtime keystore backend. It
+	// is created by the file backend when nil; read it back with
+	// Client.Keyring and pass it to jwt.Config.KeyResolver.
+	Keyring *jwt.Keyring
+	// RevocationStore shares revoked token IDs across replicas. It is filled
+	// from the storage backend, or the cache backend when there is none;
+	// pass it to jwt.Config.RevocationStore.
+	RevocationStore session.RevocationStore
+	// SessionStore persists server-side sessions. It is filled from the
+	// storage backend; pass it to opaque.Config.Store.
+	SessionStore storage.SessionStore
+	// OAuthClientStore persists registered OAuth clients. It is filled from
+	// the storage backend.
+	OAuthClientStore storage.OAuthClientStore
+	// Lockout throttles repeated Authorize failures. The zero value uses
+	// DefaultLockoutPolicy; set Disabled to turn lockout off.
+	Lockout LockoutPolicy
+	// MFA configures second factors such as TOTP.
+	MFA MFAConfig
+	// WebAuthn configures passkey registration and passwordless login.
+	WebAuthn WebAuthnConfig
+	// APIKeys shapes keys issued by CreateAPIKey.
+	APIKeys APIKeyConfig
+	// PasswordPolicy is checked by CreateAuth before a password is hashed.
+	// Nil accepts any non-empty value; see password.DefaultRules.
+	PasswordPolicy password.Policy
+	// PasswordHistory is how many retired passwords a new password is
+	// compared against, in addition to the active ones. Zero disables it.
+	PasswordHistory int
 }
 
 type ClientDependencies struct {
 	Authenticator        Authenticator
 	AuthorizationManager AuthorizationManager
 	AuthorizationChecker AuthorizationChecker
+	RefreshTokenManager  RefreshTokenManager
+	LockoutManager       LockoutManager
+	MFAManager           MFAManager
+	WebAuthnManager      WebAuthnManager
+	PasswordManager      PasswordManager
+	CredentialManager    CredentialManager
+	APIKeyManager        APIKeyManager
+	OAuthClientManager   OAuthClientManager
 }
 
 type ClientBuilder func(resolved Config) (ClientDependencies, error)
@@ -37,7 +78,16 @@ type ClientBuilder func(resolved Config) (ClientDependencies, error)
 type Client struct {
 	authzManager  AuthorizationManager
 	authzChecker  AuthorizationChecker
+	refresh       RefreshTokenManager
+	lockout       LockoutManager
+	mfa           MFAManager
+	webAuthn      WebAuthnManager
+	passwords     PasswordManager
+	credentials   CredentialManager
+	apiKeys       APIKeyManager
+	oauthClients  OAuthClientManager
 	auth          Authenticator
+	keyring       *jwt.Keyring
 	logger        logr.Logger
 	closeResource func() error
 }
@@ -63,6 +113,14 @@ func NewDefault(config Config) (*Client, error) {
 			Authenticator:        authService,
 			AuthorizationManager: authService,
 			AuthorizationChecker: authService,
+			RefreshTokenManager:  authService,
+			LockoutManager:       authService,
+			MFAManager:           authService,
+			WebAuthnManager:      authService,
+			PasswordManager:      authService,
+			CredentialManager:    authService,
+			APIKeyManager:        authService,
+			OAuthClientManager:   authService,
 		}, nil
 	})
 }
@@ -83,9 +141,27 @@ func newClientWithBuilder(resolvedConfig Config, closeResource func() error, bui
 		return nil, oerrors.ErrMissingAuthenticator
 	}
 
-	return newClient(dependencies, resolvedConfig.Logger, closeResource), nil
+	client := newClient(dependencies, resolvedConfig.Logger, closeResource)
+	client.keyring = resolvedConfig.Keyring
+	return client, nil
 }
 
+// Keyring returns the keyring the runtime keystore backend loads keys into,
+// including one it created because Config.Keyring was nil. It is nil when
+// no keystore backend is configured and no keyring was supplied.
+func (c *Client) Keyring() *jwt.Keyring {
+	if c == nil {
+		return nil
+	}
+	return c.keyring
+}
+
+// Authorize verifies input and returns the authenticated principal. A
+// locked subject or source fails with CodeAccountLocked and
+// oerrors.RetryAfter reports when to try again; login handlers should answer
+// with 423 Locked or 429 Too Many Requests and a Retry-After header. The
+// HTTP middleware only validates tokens and API keys, which are not subject
+// to lockout.
 func (c *Client) Authorize(ctx context.Context, input AuthInput) (Principal, error) {
 	if c == nil || c.auth == nil {
 		return Principal{}, oerrors.ErrMissingAuthenticator
@@ -93,6 +169,21 @@ func (c *Client) Authorize(ctx context.Context, input AuthInput) (Principal, err
 
 	p, err := c.auth.Authorize(ctx, input)
 	if err != nil {
+		// Locked accounts keep their code and retry hint so transports can
+		// answer with a throttling status, and MFA challenges must reach the
+		// caller to start the second step. Expired credentials are only
+		// reported after the secret matched, so callers can send the user to
+		// rotation without revealing anything to a guesser.
+		if oerrors.IsCode(err, oerrors.CodeAccountLocked) || oerrors.IsCode(err, oerrors.CodeMFARequired) ||
+			oerrors.IsCode(err, oerrors.CodeCredentialsExpired) {
+			return Principal{}, err
+		}
+		// Unknown user IDs and wrong credentials must look the same to
+		// callers, so the cause is only logged.
+		if oerrors.IsCode(err, oerrors.CodeNotFound) || oerrors.IsCode(err, oerrors.CodeInvalidCredentials) {
+			c.logger.V(1).Info("authorization rejected", "reason", err.Error())
+			return Principal{}, oerrors.New(oerrors.CodeUnauthenticated, "failed to authorize")
+		}
 		return Principal{}, oerrors.Wrap(oerrors.CodeUnauthenticated, "failed to authorize", err)
 	}
 	return p, nil
@@ -117,11 +208,434 @@ func (c *Client) CreateAuth(ctx context.Context, input CreateAuthInput) error {
 
 	err := c.auth.CreateAuth(ctx, input)
 	if err != nil {
+		// Policy violations keep their code and details so callers can show
+		// every broken rule.
+		if oerrors.IsCode(err, oerrors.CodePolicyViolation) {
+			return err
+		}
 		return oerrors.Wrap(oerrors.CodeUnknown, "failed to create auth", err)
 	}
 	return nil
 }
 
+func (c *Client) RotatePassword(ctx context.Context, input RotatePasswordInput) error {
+	if c == nil {
+		return oerrors.ErrMissingAuthenticator
+	}
+	if c.passwords == nil {
+		if c.auth == nil {
+			return oerrors.ErrMissingAuthenticator
+		}
+		return oerrors.New(oerrors.CodeNotImplemented, "password manager is not configured")
+	}
+
+	err := c.passwords.RotatePassword(ctx, input)
+	if err != nil {
+		if oerrors.IsCode(err, oerrors.CodePolicyViolation) || oerrors.IsCode(err, oerrors.CodeAccountLocked) {
+			return err
+		}
+		return oerrors.Wrap(oerrors.CodeInvalidCredentials, "failed to rotate password", err)
+	}
+	return nil
+}
+
+func (c *Client) ListCredentials(ctx context.Context, subject string) ([]Credential, error) {
+	if c == nil {
+		return nil, oerrors.ErrMissingAuthenticator
+	}
+	if c.credentials == nil {
+		if c.auth == nil {
+			return nil, oerrors.ErrMissingAuthenticator
+		}
+		return nil, oerrors.New(oerrors.CodeNotImplemented, "credential manager is not configured")
+	}
+
+	credentials, err := c.credentials.ListCredentials(ctx, subject)
+	if err != nil {
+		return nil, oerrors.Wrap(oerrors.CodeUnknown, "failed to list credentials", err)
+	}
+	return credentials, nil
+}
+
+func (c *Client) RevokeAuth(ctx context.Context, authID string, reason string) error {
+	if c == nil {
+		return oerrors.ErrMissingAuthenticator
+	}
+	if c.credentials == nil {
+		if c.auth == nil {
+			return oerrors.ErrMissingAuthenticator
+		}
+		return oerrors.New(oerrors.CodeNotImplemented, "credential manager is not configured")
+	}
+
+	if err := c.credentials.RevokeAuth(ctx, authID, reason); err != nil {
+		return oerrors.Wrap(oerrors.CodeUnknown, "failed to revoke auth", err)
+	}
+	return nil
+}
+
+func (c *Client) DeactivateAuth(ctx context.Context, authID string) error {
+	if c == nil {
+		return oerrors.ErrMissingAuthenticator
+	}
+	if c.credentials == nil {
+		if c.auth == nil {
+			return oerrors.ErrMissingAuthenticator
+		}
+		return oerrors.New(oerrors.CodeNotImplemented, "credential manager is not configured")
+	}
+
+	if err := c.credentials.DeactivateAuth(ctx, authID); err != nil {
+		return oerrors.Wrap(oerrors.CodeUnknown, "failed to deactivate auth", err)
+	}
+	return nil
+}
+
+func (c *Client) RotateAuth(ctx context.Context, input RotateAuthInput) (Credential, error) {
+	if c == nil {
+		return Credential{}, oerrors.ErrMissingAuthenticator
+	}
+	if c.credentials == nil {
+		if c.auth == nil {
+			return Credential{}, oerrors.ErrMissingAuthenticator
+		}
+		return Credential{}, oerrors.New(oerrors.CodeNotImplemented, "credential manager is not configured")
+	}
+
+	credential, err := c.credentials.RotateAuth(ctx, input)
+	if err != nil {
+		if oerrors.IsCode(err, oerrors.CodePolicyViolation) {
+			return Credential{}, err
+		}
+		return Credential{}, oerrors.Wrap(oerrors.CodeUnknown, "failed to rotate auth", err)
+	}
+	return credential, nil
+}
+
+func (c *Client) IssueRefreshToken(ctx context.Context, input IssueRefreshTokenInput) (RefreshToken, error) {
+	if c == nil {
+		return RefreshToken{}, oerrors.ErrMissingAuthenticator
+	}
+	if c.refresh == nil {
+		if c.auth == nil {
+			return RefreshToken{}, oerrors.ErrMissingAuthenticator
+		}
+		return RefreshToken{}, oerrors.New(oerrors.CodeNotImplemented, "refresh token manager is not configured")
+	}
+
+	token, err := c.refresh.IssueRefreshToken(ctx, input)
+	if err != nil {
+		return RefreshToken{}, oerrors.Wrap(oerrors.CodeUnknown, "failed to issue refresh token", err)
+	}
+	return token, nil
+}
+
+func (c *Client) RefreshAuth(ctx context.Context, token string) (RefreshResult, error) {
+	if c == nil {
+		return RefreshResult{}, oerrors.ErrMissingAuthenticator
+	}
+	if c.refresh == nil {
+		if c.auth == nil {
+			return RefreshResult{}, oerrors.ErrMissingAuthenticator
+		}
+		return RefreshResult{}, oerrors.New(oerrors.CodeNotImplemented, "refresh token manager is not configured")
+	}
+
+	result, err := c.refresh.RefreshAuth(ctx, token)
+	if err != nil {
+		return RefreshResult{}, oerrors.Wrap(oerrors.CodeInvalidToken, "failed to refresh auth", err)
+	}
+	return result, nil
+}
+
+func (c *Client) CreateAPIKey(ctx context.Context, input CreateAPIKeyInput) (APIKey, error) {
+	if c == nil {
+		return APIKey{}, oerrors.ErrMissingAuthenticator
+	}
+	if c.apiKeys == nil {
+		if c.auth == nil {
+			return APIKey{}, oerrors.ErrMissingAuthenticator
+		}
+		return APIKey{}, oerrors.New(oerrors.CodeNotImplemented, "api key manager is not configured")
+	}
+
+	key, err := c.apiKeys.CreateAPIKey(ctx, input)
+	if err != nil {
+		return APIKey{}, oerrors.Wrap(oerrors.CodeUnknown, "failed to create api key", err)
+	}
+	return key, nil
+}
+
+// AuthenticateAPIKey matches the httptransport.APIKeyAuthenticator contract.
+func (c *Client) AuthenticateAPIKey(ctx context.Context, key string) (Principal, error) {
+	if c == nil {
+		return Principal{}, oerrors.ErrMissingAuthenticator
+	}
+	if c.apiKeys == nil {
+		if c.auth == nil {
+			return Principal{}, oerrors.ErrMissingAuthenticator
+		}
+		return Principal{}, oerrors.New(oerrors.CodeNotImplemented, "api key manager is not configured")
+	}
+
+	p, err := c.apiKeys.AuthenticateAPIKey(ctx, key)
+	if err != nil {
+		if oerrors.IsCode(err, oerrors.CodeNotFound) || oerrors.IsCode(err, oerrors.CodeInvalidCredentials) {
+			c.logger.V(1).Info("api key rejected", "reason", err.Error())
+			return Principal{}, oerrors.New(oerrors.CodeUnauthenticated, "failed to authenticate api key")
+		}
+		return Principal{}, oerrors.Wrap(oerrors.CodeUnauthenticated, "failed to authenticate api key", err)
+	}
+	return p, nil
+}
+
+func (c *Client) RegisterOAuthClient(ctx context.Context, input RegisterOAuthClientInput) (OAuthClient, error) {
+	if c == nil {
+		return OAuthClient{}, oerrors.ErrMissingAuthenticator
+	}
+	if c.oauthClients == nil {
+		if c.auth == nil {
+			return OAuthClient{}, oerrors.ErrMissingAuthenticator
+		}
+		return OAuthClient{}, oerrors.New(oerrors.CodeNotImplemented, "oauth client manager is not configured")
+	}
+
+	client, err := c.oauthClients.RegisterOAuthClient(ctx, input)
+	if err != nil {
+		return OAuthClient{}, oerrors.Wrap(oerrors.CodeUnknown, "failed to register oauth client", err)
+	}
+	return client, nil
+}
+
+func (c *Client) GetOAuthClient(ctx context.Context, clientID string) (OAuthClient, error) {
+	if c == nil {
+		return OAuthClient{}, oerrors.ErrMissingAuthenticator
+	}
+	if c.oauthClients == nil {
+		if c.auth == nil {
+			return OAuthClient{}, oerrors.ErrMissingAuthenticator
+		}
+		return OAuthClient{}, oerrors.New(oerrors.CodeNotImplemented, "oauth client manager is not configured")
+	}
+
+	client, err := c.oauthClients.GetOAuthClient(ctx, clientID)
+	if err != nil {
+		return OAuthClient{}, oerrors.Wrap(oerrors.CodeUnknown, "failed to get oauth client", err)
+	}
+	return client, nil
+}
+
+func (c *Client) DeleteOAuthClient(ctx context.Context, clientID string) error {
+	if c == nil {
+		return oerrors.ErrMissingAuthenticator
+	}
+	if c.oauthClients == nil {
+		if c.auth == nil {
+			return oerrors.ErrMissingAuthenticator
+		}
+		return oerrors.New(oerrors.CodeNotImplemented, "oauth client manager is not configured")
+	}
+
+	if err := c.oauthClients.DeleteOAuthClient(ctx, clientID); err != nil {
+		return oerrors.Wrap(oerrors.CodeUnknown, "failed to delete oauth client", err)
+	}
+	return nil
+}
+
+func (c *Client) CreateClientSecret(ctx context.Context, input CreateClientSecretInput) (ClientSecret, error) {
+	if c == nil {
+		return ClientSecret{}, oerrors.ErrMissingAuthenticator
+	}
+	if c.oauthClients == nil {
+		if c.auth == nil {
+			return ClientSecret{}, oerrors.ErrMissingAuthenticator
+		}
+		return ClientSecret{}, oerrors.New(oerrors.CodeNotImplemented, "oauth client manager is not configured")
+	}
+
+	secret, err := c.oauthClients.CreateClientSecret(ctx, input)
+	if err != nil {
+		return ClientSecret{}, oerrors.Wrap(oerrors.CodeUnknown, "failed to create client secret", err)
+	}
+	return secret, nil
+}
+
+// AuthenticateClient returns a principal for a service account. Unknown
+// clients and bad secrets are reported the same way; disallowed grant types
+// and scopes keep CodePermissionDenied.
+func (c *Client) AuthenticateClient(ctx context.Context, input AuthenticateClientInput) (Principal, error) {
+	if c == nil {
+		return Principal{}, oerrors.ErrMissingAuthenticator
+	}
+	if c.oauthClients == nil {
+		if c.auth == nil {
+			return Principal{}, oerrors.ErrMissingAuthenticator
+		}
+		return Principal{}, oerrors.New(oerrors.CodeNotImplemented, "oauth client manager is not configured")
+	}
+
+	p, err := c.oauthClients.AuthenticateClient(ctx, input)
+	if err != nil {
+		if oerrors.IsCode(err, oerrors.CodePermissionDenied) {
+			return Principal{}, err
+		}
+		if oerrors.IsCode(err, oerrors.CodeNotFound) || oerrors.IsCode(err, oerrors.CodeInvalidCredentials) {
+			c.logger.V(1).Info("oauth client rejected", "client_id", input.ClientID, "reason", err.Error())
+			return Principal{}, oerrors.New(oerrors.CodeUnauthenticated, "failed to authenticate client")
+		}
+		return Principal{}, oerrors.Wrap(oerrors.CodeUnauthenticated, "failed to authenticate client", err)
+	}
+	return p, nil
+}
+
+func (c *Client) UnlockSubject(ctx context.Context, input UnlockSubjectInput) error {
+	if c == nil {
+		return oerrors.ErrMissingAuthenticator
+	}
+	if c.lockout == nil {
+		if c.auth == nil {
+			return oerrors.ErrMissingAuthenticator
+		}
+		return oerrors.New(oerrors.CodeNotImplemented, "lockout manager is not configured")
+	}
+
+	if err := c.lockout.UnlockSubject(ctx, input); err != nil {
+		return oerrors.Wrap(oerrors.CodeUnknown, "failed to unlock subject", err)
+	}
+	return nil
+}
+
+func (c *Client) EnrollTOTP(ctx context.Context, input EnrollTOTPInput) (TOTPEnrollment, error) {
+	if c == nil {
+		return TOTPEnrollment{}, oerrors.ErrMissingAuthenticator
+	}
+	if c.mfa == nil {
+		if c.auth == nil {
+			return TOTPEnrollment{}, oerrors.ErrMissingAuthenticator
+		}
+		return TOTPEnrollment{}, oerrors.New(oerrors.CodeNotImplemented, "mfa manager is not configured")
+	}
+
+	enrollment, err := c.mfa.EnrollTOTP(ctx, input)
+	if err != nil {
+		return TOTPEnrollment{}, oerrors.Wrap(oerrors.CodeUnknown, "failed to enroll totp", err)
+	}
+	return enrollment, nil
+}
+
+func (c *Client) ConfirmTOTP(ctx context.Context, input ConfirmTOTPInput) error {
+	if c == nil {
+		return oerrors.ErrMissingAuthenticator
+	}
+	if c.mfa == nil {
+		if c.auth == nil {
+			return oerrors.ErrMissingAuthenticator
+		}
+		return oerrors.New(oerrors.CodeNotImplemented, "mfa manager is not configured")
+	}
+
+	if err := c.mfa.ConfirmTOTP(ctx, input); err != nil {
+		return oerrors.Wrap(oerrors.CodeInvalidCredentials, "failed to confirm totp", err)
+	}
+	return nil
+}
+
+func (c *Client) GenerateRecoveryCodes(ctx context.Context, input GenerateRecoveryCodesInput) (RecoveryCodes, error) {
+	if c == nil {
+		return RecoveryCodes{}, oerrors.ErrMissingAuthenticator
+	}
+	if c.mfa == nil {
+		if c.auth == nil {
+			return RecoveryCodes{}, oerrors.ErrMissingAuthenticator
+		}
+		return RecoveryCodes{}, oerrors.New(oerrors.CodeNotImplemented, "mfa manager is not configured")
+	}
+
+	codes, err := c.mfa.GenerateRecoveryCodes(ctx, input)
+	if err != nil {
+		return RecoveryCodes{}, oerrors.Wrap(oerrors.CodeUnknown, "failed to generate recovery codes", err)
+	}
+	return codes, nil
+}
+
+func (c *Client) CountRecoveryCodes(ctx context.Context, userID string) (int, error) {
+	if c == nil {
+		return 0, oerrors.ErrMissingAuthenticator
+	}
+	if c.mfa == nil {
+		if c.auth == nil {
+			return 0, oerrors.ErrMissingAuthenticator
+		}
+		return 0, oerrors.New(oerrors.CodeNotImplemented, "mfa manager is not configured")
+	}
+
+	remaining, err := c.mfa.CountRecoveryCodes(ctx, userID)
+	if err != nil {
+		return 0, oerrors.Wrap(oerrors.CodeUnknown, "failed to count recovery codes", err)
+	}
+	return remaining, nil
+}
+
+func (c *Client) BeginWebAuthnRegistration(ctx context.Context, input BeginWebAuthnRegistrationInput) (WebAuthnRegistration, error) {
+	if c == nil {
+		return WebAuthnRegistration{}, oerrors.ErrMissingAuthenticator
+	}
+	if c.webAuthn == nil {
+		if c.auth == nil {
+			return WebAuthnRegistration{}, oerrors.ErrMissingAuthenticator
+		}
+		return WebAuthnRegistration{}, oerrors.New(oerrors.CodeNotImplemented, "webauthn manager is not configured")
+	}
+
+	registration, err := c.webAuthn.BeginWebAuthnRegistration(ctx, input)
+	if err != nil {
+		return WebAuthnRegistration{}, oerrors.Wrap(oerrors.CodeUnknown, "failed to begin webauthn registration", err)
+	}
+	return registration, nil
+}
+
+func (c *Client) FinishWebAuthnRegistration(ctx context.Context, input FinishWebAuthnRegistrationInput) (WebAuthnCredential, error) {
+	if c == nil {
+		return WebAuthnCredential{}, oerrors.ErrMissingAuthenticator
+	}
+	if c.webAuthn == nil {
+		if c.auth == nil {
+			return WebAuthnCredential{}, oerrors.ErrMissingAuthenticator
+		}
+		return WebAuthnCredential{}, oerrors.New(oerrors.CodeNotImplemented, "webauthn manager is not configured")
+	}
+
+	credential, err := c.webAuthn.FinishWebAuthnRegistration(ctx, input)
+	if err != nil {
+		return WebAuthnCredential{}, oerrors.Wrap(oerrors.CodeInvalidCredentials, "failed to finish webauthn registration", err)
+	}
+	return credential, nil
+}
+
+func (c *Client) BeginWebAuthnLogin(ctx context.Context, input BeginWebAuthnLoginInput) (WebAuthnLogin, error) {
+	if c == nil {
+		return WebAuthnLogin{}, oerrors.ErrMissingAuthenticator
+	}
+	if c.webAuthn == nil {
+		if c.auth == nil {
+			return WebAuthnLogin{}, oerrors.ErrMissingAuthenticator
+		}
+		return WebAuthnLogin{}, oerrors.New(oerrors.CodeNotImplemented, "webauthn manager is not configured")
+	}
+
+	login, err := c.webAuthn.BeginWebAuthnLogin(ctx, input)
+	if err != nil {
+		// As with Authorize, unknown subjects and subjects without passkeys
+		// must look the same to callers.
+		if oerrors.IsCode(err, oerrors.CodeNotFound) || oerrors.IsCode(err, oerrors.CodeInvalidCredentials) {
+			c.logger.V(1).Info("webauthn login rejected", "reason", err.Error())
+			return WebAuthnLogin{}, oerrors.New(oerrors.CodeUnauthenticated, "failed to begin webauthn login")
+		}
+		return WebAuthnLogin{}, oerrors.Wrap(oerrors.CodeUnauthenticated, "failed to begin webauthn login", err)
+	}
+	return login, nil
+}
+
 func (c *Client) Close() error {
 	if c == nil || c.closeResource == nil {
 		return nil
@@ -134,6 +648,14 @@ func (c *Client) Close() error {
 	c.closeResource = nil
 	c.authzManager = nil
 	c.authzChecker = nil
+	c.refresh = nil
+	c.lockout = nil
+	c.mfa = nil
+	c.webAuthn = nil
+	c.passwords = nil
+	c.credentials = nil
+	c.apiKeys = nil
+	c.oauthClients = nil
 	c.auth = nil
 	return nil
 }
@@ -274,6 +796,14 @@ func newClient(dependencies ClientDependencies, logger logr.Logger, closeResourc
 	return &Client{
 		authzManager:  dependencies.AuthorizationManager,
 		authzChecker:  dependencies.AuthorizationChecker,
+		refresh:       dependencies.RefreshTokenManager,
+		lockout:       dependencies.LockoutManager,
+		mfa:           dependencies.MFAManager,
+		webAuthn:      dependencies.WebAuthnManager,
+		passwords:     dependencies.PasswordManager,
+		credentials:   dependencies.CredentialManager,
+		apiKeys:       dependencies.APIKeyManager,
+		oauthClients:  dependencies.OAuthClientManager,
 		auth:          dependencies.Authenticator,
 		logger:        logger,
 		closeResource: closeResource,
@@ -2,10 +2,19 @@ package openauth
 
 import (
 	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
 	"errors"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/go-logr/logr"
 	oerrors "github.com/porthorian/openauth/pkg/errors"
+	"github.com/porthorian/openauth/pkg/session/jwt"
 )
 
 type constructorAuthStub struct{}
@@ -254,3 +263,111 @@ func TestNewDefaultSmoke(t *testing.T) {
 		t.Fatalf("expected authorization checker to be set")
 	}
 }
+
+func TestInitializeFileKeyStoreLoadsKeyring(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey returned error: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalPKCS8PrivateKey returned error: %v", err)
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "signing.pem"), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+	retired := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Headers: map[string]string{
+		"Not-Before": time.Now().Add(-2 * time.Hour).UTC().Format(time.RFC3339),
+		"Not-After":  time.Now().Add(-time.Hour).UTC().Format(time.RFC3339),
+	}, Bytes: der})
+	if err := os.WriteFile(filepath.Join(dir, "retired.pem"), retired, 0o600); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+
+	client, err := New(Config{
+		Runtime: RuntimeConfig{KeyStore: KeyStoreConfig{Backend: KeyStoreBackendFile, URI: "file://" + dir}},
+	}, func(resolved Config) (ClientDependencies, error) {
+		return ClientDependencies{Authenticator: authorizeErrorStub{}}, nil
+	})
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+
+	if client.Keyring() == nil {
+		t.Fatalf("expected the keyring created by the file backend to be exposed on the client")
+	}
+	signing, err := client.Keyring().SigningKey(context.Background())
+	if err != nil {
+		t.Fatalf("SigningKey returned error: %v", err)
+	}
+	if signing.ID != "signing" {
+		t.Fatalf("signing key ID = %q, want signing", signing.ID)
+	}
+	if state, _ := client.Keyring().State("retired"); state != jwt.KeyStateRetiring {
+		t.Fatalf("retired key state = %q, want retiring under the default MaxTokenTTL", state)
+	}
+}
+
+func TestInitializeKeyStoreRejectsInvalidConfig(t *testing.T) {
+	cases := []KeyStoreConfig{
+		{Backend: "vault"},
+		{Backend: KeyStoreBackendFile},
+		{Backend: KeyStoreBackendFile, URI: "s3://bucket/keys"},
+		{Backend: KeyStoreBackendFile, URI: filepath.Join(t.TempDir(), "missing")},
+	}
+	for _, keyStore := range cases {
+		if _, _, err := (Config{Runtime: RuntimeConfig{KeyStore: keyStore}}).initialize(context.Background()); err == nil {
+			t.Fatalf("initialize(%+v) expected error", keyStore)
+		}
+	}
+}
+
+type authorizeErrorStub struct {
+	constructorAuthStub
+	err error
+}
+
+func (s authorizeErrorStub) Authorize(ctx context.Context, input AuthInput) (Principal, error) {
+	_ = ctx
+	_ = input
+	return Principal{}, s.err
+}
+
+func TestClientAuthorizeHidesUnknownSubjects(t *testing.T) {
+	authorize := func(cause error) error {
+		client := newClient(ClientDependencies{Authenticator: authorizeErrorStub{err: cause}}, logr.Discard(), noopCloser)
+		_, err := client.Authorize(context.Background(), AuthInput{UserID: "user-1"})
+		return err
+	}
+
+	notFound := authorize(oerrors.New(oerrors.CodeNotFound, "user_id not found"))
+	wrongPassword := authorize(oerrors.New(oerrors.CodeInvalidCredentials, "authentication failed"))
+	expired := authorize(oerrors.New(oerrors.CodeCredentialsExpired, "credentials have expired"))
+	if notFound == nil || wrongPassword == nil || expired == nil {
+		t.Fatalf("expected authorize errors, got %v, %v and %v", notFound, wrongPassword, expired)
+	}
+	if notFound.Error() != wrongPassword.Error() || !oerrors.IsCode(notFound, oerrors.CodeUnauthenticated) || !oerrors.IsCode(wrongPassword, oerrors.CodeUnauthenticated) {
+		t.Fatalf("expected indistinguishable errors, got %q and %q", notFound, wrongPassword)
+	}
+	if !oerrors.IsCode(expired, oerrors.CodeCredentialsExpired) {
+		t.Fatalf("expected expired credentials to pass through, got %q", expired)
+	}
+	if errors.Unwrap(notFound) != nil || errors.Unwrap(wrongPassword) != nil {
+		t.Fatalf("expected rejection causes to stay hidden")
+	}
+
+	storageErr := authorize(oerrors.New(oerrors.CodeStorageUnavailable, "auth storage is not configured"))
+	if !oerrors.IsCode(errors.Unwrap(storageErr), oerrors.CodeStorageUnavailable) {
+		t.Fatalf("expected internal failures to keep their cause, got %v", storageErr)
+	}
+
+	locked := oerrors.New(oerrors.CodeAccountLocked, "account is temporarily locked")
+	locked.RetryAfter = time.Minute
+	lockedErr := authorize(locked)
+	if retryAfter, ok := oerrors.RetryAfter(lockedErr); !oerrors.IsCode(lockedErr, oerrors.CodeAccountLocked) || !ok || retryAfter != time.Minute {
+		t.Fatalf("expected locked errors to pass through with retry hint, got %v", lockedErr)
+	}
+}
@@ -0,0 +1,126 @@
+// Package apikey formats and parses API keys of the form
+// prefix_lookup_secretchecksum. The lookup part finds the stored record
+// without scanning, the secret carries SecretBytes of entropy, and the
+// trailing CRC-32 lets typos and secret scanners reject a key offline.
+package apikey
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"strings"
+)
+
+const (
+	// DefaultPrefix marks keys issued by openauth.
+	DefaultPrefix = "oak"
+	// SecretBytes is the random part of a key.
+	SecretBytes = 32
+
+	separator      = "_"
+	maxPrefixLen   = 16
+	checksumBytes  = 4
+	checksumLength = 7 // base32 of checksumBytes without padding
+	displayLookup  = 8
+)
+
+var (
+	ErrInvalidPrefix = errors.New("apikey: prefix must be 1-16 lowercase letters or digits")
+	ErrInvalidLookup = errors.New("apikey: lookup must be lowercase letters or digits")
+	ErrMalformedKey  = errors.New("apikey: malformed key")
+	ErrChecksum      = errors.New("apikey: checksum mismatch")
+)
+
+var encoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// Key is a parsed API key. Secret excludes the checksum.
+type Key struct {
+	Prefix string
+	Lookup string
+	Secret string
+}
+
+// Generate returns a key with a fresh secret for lookup.
+func Generate(prefix string, lookup string) (Key, error) {
+	if err := ValidatePrefix(prefix); err != nil {
+		return Key{}, err
+	}
+	if !isToken(lookup) {
+		return Key{}, ErrInvalidLookup
+	}
+
+	secret := make([]byte, SecretBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return Key{}, err
+	}
+	return Key{Prefix: prefix, Lookup: lookup, Secret: encoding.EncodeToString(secret)}, nil
+}
+
+// Parse splits value into its parts after checking the checksum. It does
+// not check the prefix against an expected one.
+func Parse(value string) (Key, error) {
+	parts := strings.Split(strings.TrimSpace(value), separator)
+	if len(parts) != 3 {
+		return Key{}, ErrMalformedKey
+	}
+	prefix, lookup, tail := parts[0], parts[1], parts[2]
+	if ValidatePrefix(prefix) != nil || !isToken(lookup) || len(tail) <= checksumLength {
+		return Key{}, ErrMalformedKey
+	}
+
+	key := Key{Prefix: prefix, Lookup: lookup, Secret: tail[:len(tail)-checksumLength]}
+	if !isToken(key.Secret) {
+		return Key{}, ErrMalformedKey
+	}
+	if tail[len(tail)-checksumLength:] != key.checksum() {
+		return Key{}, ErrChecksum
+	}
+	return key, nil
+}
+
+// String returns the full key. It is only shown to the caller once.
+func (k Key) String() string {
+	return k.body() + k.checksum()
+}
+
+// Display identifies the key in listings without revealing the secret.
+func (k Key) Display() string {
+	lookup := k.Lookup
+	if len(lookup) > displayLookup {
+		lookup = lookup[:displayLookup]
+	}
+	return k.Prefix + separator + lookup
+}
+
+// ValidatePrefix reports whether prefix can be used for new keys.
+func ValidatePrefix(prefix string) error {
+	if prefix == "" || len(prefix) > maxPrefixLen || !isToken(prefix) {
+		return ErrInvalidPrefix
+	}
+	return nil
+}
+
+func (k Key) body() string {
+	return k.Prefix + separator + k.Lookup + separator + k.Secret
+}
+
+func (k Key) checksum() string {
+	var sum [checksumBytes]byte
+	binary.BigEndian.PutUint32(sum[:], crc32.ChecksumIEEE([]byte(k.body())))
+	return encoding.EncodeToString(sum[:])
+}
+
+func isToken(value string) bool {
+	if value == "" {
+		return false
+	}
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
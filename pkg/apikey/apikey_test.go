@@ -0,0 +1,72 @@
+package apikey
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestGenerateAndParseRoundTrip(t *testing.T) {
+	key, err := Generate(DefaultPrefix, "0123456789abcdef0123456789abcdef")
+	if err != nil {
+		t.Fatalf("Generate returned error: %v", err)
+	}
+	if !strings.HasPrefix(key.String(), "oak_0123456789abcdef0123456789abcdef_") {
+		t.Fatalf("unexpected key format: %s", key)
+	}
+	if key.Display() != "oak_01234567" {
+		t.Fatalf("Display() = %s", key.Display())
+	}
+
+	parsed, err := Parse(key.String())
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if parsed != key {
+		t.Fatalf("Parse() = %+v, want %+v", parsed, key)
+	}
+
+	other, err := Generate(DefaultPrefix, key.Lookup)
+	if err != nil {
+		t.Fatalf("Generate returned error: %v", err)
+	}
+	if other.Secret == key.Secret {
+		t.Fatalf("expected fresh secrets per key")
+	}
+}
+
+func TestParseRejectsDamagedKeys(t *testing.T) {
+	key, err := Generate("test", "lookup1")
+	if err != nil {
+		t.Fatalf("Generate returned error: %v", err)
+	}
+	value := key.String()
+	last := value[len(value)-1]
+	flipped := byte('a')
+	if last == 'a' {
+		flipped = 'b'
+	}
+
+	for _, tc := range []struct {
+		value string
+		want  error
+	}{
+		{value[:len(value)-1] + string(flipped), ErrChecksum},
+		{strings.Replace(value, "lookup1", "lookup2", 1), ErrChecksum},
+		{"test_lookup1", ErrMalformedKey},
+		{"test_lookup1_short", ErrMalformedKey},
+		{strings.ToUpper(value), ErrMalformedKey},
+		{"", ErrMalformedKey},
+	} {
+		if _, err := Parse(tc.value); !errors.Is(err, tc.want) {
+			t.Fatalf("Parse(%q) error = %v, want %v", tc.value, err, tc.want)
+		}
+	}
+
+	if _, err := Generate("Bad-Prefix", "lookup"); !errors.Is(err, ErrInvalidPrefix) {
+		t.Fatalf("Generate with invalid prefix error = %v", err)
+	}
+	if _, err := Generate("ok", "has_separator"); !errors.Is(err, ErrInvalidLookup) {
+		t.Fatalf("Generate with invalid lookup error = %v", err)
+	}
+}
@@ -34,8 +34,19 @@ type PermissionCache interface {
 	DeletePermissionMask(ctx context.Context, key string) error
 }
 
+// AttemptCache counts failed authentication attempts and holds temporary
+// locks. A counter expires once window passes without another increment.
+type AttemptCache interface {
+	IncrementAttempts(ctx context.Context, key string, window time.Duration) (int64, error)
+	ResetAttempts(ctx context.Context, key string) error
+	SetLock(ctx context.Context, key string, until time.Time) error
+	GetLock(ctx context.Context, key string) (time.Time, bool, error)
+	DeleteLock(ctx context.Context, key string) error
+}
+
 type Dependencies struct {
 	Token      TokenCache
 	Principal  PrincipalCache
 	Permission PermissionCache
+	Attempt    AttemptCache
 }
@@ -8,6 +8,7 @@ import (
 
 	"github.com/porthorian/openauth/pkg/authz"
 	"github.com/porthorian/openauth/pkg/cache"
+	"github.com/porthorian/openauth/pkg/session"
 )
 
 var (
@@ -24,22 +25,35 @@ type permissionEntry struct {
 	expires time.Time
 }
 
+type attemptEntry struct {
+	count   int64
+	expires time.Time
+}
+
 type Adapter struct {
 	mu                sync.RWMutex
 	tokenEntries      map[string]principalEntry
 	principalEntries  map[string]principalEntry
 	permissionEntries map[string]permissionEntry
+	revokedEntries    map[string]time.Time
+	attemptEntries    map[string]attemptEntry
+	lockEntries       map[string]time.Time
 }
 
 var _ cache.TokenCache = (*Adapter)(nil)
 var _ cache.PrincipalCache = (*Adapter)(nil)
 var _ cache.PermissionCache = (*Adapter)(nil)
+var _ cache.AttemptCache = (*Adapter)(nil)
+var _ session.RevocationStore = (*Adapter)(nil)
 
 func NewAdapter() *Adapter {
 	return &Adapter{
 		tokenEntries:      map[string]principalEntry{},
 		principalEntries:  map[string]principalEntry{},
 		permissionEntries: map[string]permissionEntry{},
+		revokedEntries:    map[string]time.Time{},
+		attemptEntries:    map[string]attemptEntry{},
+		lockEntries:       map[string]time.Time{},
 	}
 }
 
@@ -144,6 +158,113 @@ func (a *Adapter) DeletePermissionMask(ctx context.Context, key string) error {
 	return nil
 }
 
+// RevokeToken keeps tokenID revoked until expiresAt; already expired tokens
+// are not recorded.
+func (a *Adapter) RevokeToken(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	if tokenID == "" {
+		return errors.New("memory cache: token id is required")
+	}
+	if !expiresAt.After(time.Now().UTC()) {
+		return nil
+	}
+
+	a.mu.Lock()
+	if current, ok := a.revokedEntries[tokenID]; !ok || expiresAt.After(current) {
+		a.revokedEntries[tokenID] = expiresAt
+	}
+	a.mu.Unlock()
+	return nil
+}
+
+func (a *Adapter) IsTokenRevoked(ctx context.Context, tokenID string) (bool, error) {
+	now := time.Now().UTC()
+
+	a.mu.RLock()
+	expiresAt, ok := a.revokedEntries[tokenID]
+	a.mu.RUnlock()
+	if !ok {
+		return false, nil
+	}
+
+	if !now.Before(expiresAt) {
+		a.mu.Lock()
+		delete(a.revokedEntries, tokenID)
+		a.mu.Unlock()
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// IncrementAttempts adds a failed attempt for key and restarts its window.
+func (a *Adapter) IncrementAttempts(ctx context.Context, key string, window time.Duration) (int64, error) {
+	if err := validateSetInput(key, window); err != nil {
+		return 0, err
+	}
+
+	now := time.Now().UTC()
+
+	a.mu.Lock()
+	entry := a.attemptEntries[key]
+	if !now.Before(entry.expires) {
+		entry = attemptEntry{}
+	}
+	entry.count++
+	entry.expires = now.Add(window)
+	a.attemptEntries[key] = entry
+	a.mu.Unlock()
+	return entry.count, nil
+}
+
+func (a *Adapter) ResetAttempts(ctx context.Context, key string) error {
+	a.mu.Lock()
+	delete(a.attemptEntries, key)
+	a.mu.Unlock()
+	return nil
+}
+
+// SetLock locks key until the given time; locks in the past are not stored.
+func (a *Adapter) SetLock(ctx context.Context, key string, until time.Time) error {
+	if key == "" {
+		return errors.New("memory cache: key is required")
+	}
+	if !until.After(time.Now().UTC()) {
+		return nil
+	}
+
+	a.mu.Lock()
+	a.lockEntries[key] = until
+	a.mu.Unlock()
+	return nil
+}
+
+func (a *Adapter) GetLock(ctx context.Context, key string) (time.Time, bool, error) {
+	now := time.Now().UTC()
+
+	a.mu.RLock()
+	until, ok := a.lockEntries[key]
+	a.mu.RUnlock()
+	if !ok {
+		return time.Time{}, false, nil
+	}
+
+	if !now.Before(until) {
+		a.mu.Lock()
+		delete(a.lockEntries, key)
+		a.mu.Unlock()
+		return time.Time{}, false, nil
+	}
+
+	return until, true, nil
+}
+
+func (a *Adapter) DeleteLock(ctx context.Context, key string) error {
+	a.mu.Lock()
+	delete(a.lockEntries, key)
+	a.mu.Unlock()
+	return nil
+}
+
 func (a *Adapter) getPrincipalEntry(entries *map[string]principalEntry, key string) (principalEntry, bool) {
 	now := time.Now().UTC()
 
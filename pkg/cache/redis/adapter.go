@@ -1,71 +1,473 @@
 package redis
 
 import (
+	"bufio"
 	"context"
 	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/porthorian/openauth/pkg/authz"
 	"github.com/porthorian/openauth/pkg/cache"
+	"github.com/porthorian/openauth/pkg/session"
+)
+
+const (
+	defaultDialTimeout = 5 * time.Second
+	defaultPoolSize    = 8
+
+	tokenKeyspace      = "token"
+	principalKeyspace  = "principal"
+	permissionKeyspace = "permission"
+	revokedKeyspace    = "revoked"
+	attemptKeyspace    = "attempt"
+	lockKeyspace       = "lock"
 )
 
 var (
-	ErrNotImplemented = errors.New("redis cache adapter: not implemented")
+	ErrInvalidTTL     = errors.New("redis cache adapter: ttl must be greater than zero")
+	ErrMissingKey     = errors.New("redis cache adapter: key is required")
+	ErrMissingAddress = errors.New("redis cache adapter: address is required")
+	ErrClosed         = errors.New("redis cache adapter: adapter is closed")
 )
 
 type Config struct {
-	Address     string
-	Username    string
-	Password    string
-	Database    int
-	Namespace   string
+	Address   string
+	Username  string
+	Password  string
+	Database  int
+	Namespace string
+	// DialTimeout bounds connecting and, when ctx carries no deadline, each
+	// command round trip, so a hung server cannot pin a pooled connection.
 	DialTimeout time.Duration
+	PoolSize    int
 }
 
 type Adapter struct {
 	config Config
+	dialer net.Dialer
+
+	mu     sync.Mutex
+	idle   []*conn
+	closed bool
+}
+
+type conn struct {
+	netConn net.Conn
+	reader  *bufio.Reader
+	writer  *bufio.Writer
+	timeout time.Duration
 }
 
 var _ cache.TokenCache = (*Adapter)(nil)
 var _ cache.PrincipalCache = (*Adapter)(nil)
 var _ cache.PermissionCache = (*Adapter)(nil)
+var _ cache.AttemptCache = (*Adapter)(nil)
+var _ session.RevocationStore = (*Adapter)(nil)
 
 func NewAdapter(config Config) *Adapter {
-	return &Adapter{config: config}
+	if config.DialTimeout <= 0 {
+		config.DialTimeout = defaultDialTimeout
+	}
+	if config.PoolSize <= 0 {
+		config.PoolSize = defaultPoolSize
+	}
+	config.Namespace = strings.TrimSpace(config.Namespace)
+
+	return &Adapter{
+		config: config,
+		dialer: net.Dialer{Timeout: config.DialTimeout},
+	}
+}
+
+// Close releases idle connections. Connections checked out by in-flight
+// calls are closed when they are returned.
+func (a *Adapter) Close() error {
+	if a == nil {
+		return nil
+	}
+
+	a.mu.Lock()
+	idle := a.idle
+	a.idle = nil
+	a.closed = true
+	a.mu.Unlock()
+
+	var errs []error
+	for _, c := range idle {
+		if err := c.netConn.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
 }
 
 func (a *Adapter) SetToken(ctx context.Context, key string, snapshot cache.PrincipalSnapshot, ttl time.Duration) error {
-	return ErrNotImplemented
+	return a.setSnapshot(ctx, tokenKeyspace, key, snapshot, ttl)
 }
 
 func (a *Adapter) GetToken(ctx context.Context, key string) (cache.PrincipalSnapshot, bool, error) {
-	return cache.PrincipalSnapshot{}, false, ErrNotImplemented
+	return a.getSnapshot(ctx, tokenKeyspace, key)
 }
 
 func (a *Adapter) DeleteToken(ctx context.Context, key string) error {
-	return ErrNotImplemented
+	return a.delete(ctx, tokenKeyspace, key)
 }
 
 func (a *Adapter) SetPrincipal(ctx context.Context, key string, snapshot cache.PrincipalSnapshot, ttl time.Duration) error {
-	return ErrNotImplemented
+	return a.setSnapshot(ctx, principalKeyspace, key, snapshot, ttl)
 }
 
 func (a *Adapter) GetPrincipal(ctx context.Context, key string) (cache.PrincipalSnapshot, bool, error) {
-	return cache.PrincipalSnapshot{}, false, ErrNotImplemented
+	return a.getSnapshot(ctx, principalKeyspace, key)
 }
 
 func (a *Adapter) DeletePrincipal(ctx context.Context, key string) error {
-	return ErrNotImplemented
+	return a.delete(ctx, principalKeyspace, key)
 }
 
 func (a *Adapter) SetPermissionMask(ctx context.Context, key string, permissionMask authz.PermissionMask, ttl time.Duration) error {
-	return ErrNotImplemented
+	if err := validateSetInput(key, ttl); err != nil {
+		return err
+	}
+
+	payload, err := encodePermissionMask(permissionMask)
+	if err != nil {
+		return err
+	}
+	return a.set(ctx, permissionKeyspace, key, payload, ttl)
 }
 
 func (a *Adapter) GetPermissionMask(ctx context.Context, key string) (authz.PermissionMask, bool, error) {
-	return authz.PermissionMask{}, false, ErrNotImplemented
+	raw, ok, err := a.get(ctx, permissionKeyspace, key)
+	if err != nil || !ok {
+		return authz.PermissionMask{}, false, err
+	}
+
+	mask, err := decodePermissionMask(raw)
+	if errors.Is(err, errUnsupportedVersion) {
+		return authz.PermissionMask{}, false, nil
+	}
+	if err != nil {
+		return authz.PermissionMask{}, false, fmt.Errorf("redis cache adapter: decode permission mask: %w", err)
+	}
+	return mask, true, nil
 }
 
 func (a *Adapter) DeletePermissionMask(ctx context.Context, key string) error {
-	return ErrNotImplemented
+	return a.delete(ctx, permissionKeyspace, key)
+}
+
+// RevokeToken stores a marker for tokenID that Redis expires together with the
+// token, so revocations are shared by every replica using the same server.
+func (a *Adapter) RevokeToken(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	if tokenID == "" {
+		return ErrMissingKey
+	}
+
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+	return a.set(ctx, revokedKeyspace, tokenID, []byte("1"), ttl)
+}
+
+func (a *Adapter) IsTokenRevoked(ctx context.Context, tokenID string) (bool, error) {
+	_, ok, err := a.get(ctx, revokedKeyspace, tokenID)
+	if err != nil {
+		return false, err
+	}
+	return ok, nil
+}
+
+// incrementAttemptsScript runs INCR and PEXPIRE as one atomic script, so a
+// counter can never be left behind without an expiry.
+const incrementAttemptsScript = `local count = redis.call('INCR', KEYS[1])
+redis.call('PEXPIRE', KEYS[1], ARGV[1])
+return count`
+
+// IncrementAttempts increments the counter and restarts its window in one
+// EVAL, so the window restarts on every failed attempt.
+func (a *Adapter) IncrementAttempts(ctx context.Context, key string, window time.Duration) (int64, error) {
+	if err := validateSetInput(key, window); err != nil {
+		return 0, err
+	}
+
+	reply, err := a.do(ctx, "EVAL", incrementAttemptsScript, "1", a.namespacedKey(attemptKeyspace, key), strconv.FormatInt(ttlMillis(window), 10))
+	if err != nil {
+		return 0, err
+	}
+	count, ok := reply.(int64)
+	if !ok {
+		return 0, fmt.Errorf("%w: unexpected EVAL reply %T", errProtocol, reply)
+	}
+	return count, nil
+}
+
+func (a *Adapter) ResetAttempts(ctx context.Context, key string) error {
+	return a.delete(ctx, attemptKeyspace, key)
+}
+
+// SetLock stores the lock expiry as Unix milliseconds and lets Redis expire
+// the key at the same moment.
+func (a *Adapter) SetLock(ctx context.Context, key string, until time.Time) error {
+	if key == "" {
+		return ErrMissingKey
+	}
+
+	ttl := time.Until(until)
+	if ttl <= 0 {
+		return nil
+	}
+	return a.set(ctx, lockKeyspace, key, []byte(strconv.FormatInt(until.UnixMilli(), 10)), ttl)
+}
+
+func (a *Adapter) GetLock(ctx context.Context, key string) (time.Time, bool, error) {
+	raw, ok, err := a.get(ctx, lockKeyspace, key)
+	if err != nil || !ok {
+		return time.Time{}, false, err
+	}
+
+	millis, err := strconv.ParseInt(string(raw), 10, 64)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("redis cache adapter: decode lock: %w", err)
+	}
+	return time.UnixMilli(millis).UTC(), true, nil
+}
+
+func (a *Adapter) DeleteLock(ctx context.Context, key string) error {
+	return a.delete(ctx, lockKeyspace, key)
+}
+
+func (a *Adapter) setSnapshot(ctx context.Context, keyspace string, key string, snapshot cache.PrincipalSnapshot, ttl time.Duration) error {
+	if err := validateSetInput(key, ttl); err != nil {
+		return err
+	}
+
+	payload, err := encodeSnapshot(snapshot)
+	if err != nil {
+		return err
+	}
+	return a.set(ctx, keyspace, key, payload, ttl)
+}
+
+func (a *Adapter) getSnapshot(ctx context.Context, keyspace string, key string) (cache.PrincipalSnapshot, bool, error) {
+	raw, ok, err := a.get(ctx, keyspace, key)
+	if err != nil || !ok {
+		return cache.PrincipalSnapshot{}, false, err
+	}
+
+	snapshot, err := decodeSnapshot(raw)
+	if errors.Is(err, errUnsupportedVersion) {
+		return cache.PrincipalSnapshot{}, false, nil
+	}
+	if err != nil {
+		return cache.PrincipalSnapshot{}, false, fmt.Errorf("redis cache adapter: decode principal snapshot: %w", err)
+	}
+	return snapshot, true, nil
+}
+
+func (a *Adapter) set(ctx context.Context, keyspace string, key string, payload []byte, ttl time.Duration) error {
+	reply, err := a.do(ctx, "SET", a.namespacedKey(keyspace, key), string(payload), "PX", strconv.FormatInt(ttlMillis(ttl), 10))
+	if err != nil {
+		return err
+	}
+	if status, ok := reply.(string); !ok || status != "OK" {
+		return fmt.Errorf("%w: unexpected SET reply %v", errProtocol, reply)
+	}
+	return nil
+}
+
+func (a *Adapter) get(ctx context.Context, keyspace string, key string) ([]byte, bool, error) {
+	if key == "" {
+		return nil, false, ErrMissingKey
+	}
+
+	reply, err := a.do(ctx, "GET", a.namespacedKey(keyspace, key))
+	if err != nil {
+		return nil, false, err
+	}
+
+	raw, ok := reply.([]byte)
+	if !ok {
+		return nil, false, fmt.Errorf("%w: unexpected GET reply %T", errProtocol, reply)
+	}
+	if raw == nil {
+		return nil, false, nil
+	}
+	return raw, true, nil
+}
+
+func (a *Adapter) delete(ctx context.Context, keyspace string, key string) error {
+	if key == "" {
+		return ErrMissingKey
+	}
+
+	_, err := a.do(ctx, "DEL", a.namespacedKey(keyspace, key))
+	return err
+}
+
+func (a *Adapter) namespacedKey(keyspace string, key string) string {
+	if a.config.Namespace == "" {
+		return keyspace + ":" + key
+	}
+	return a.config.Namespace + ":" + keyspace + ":" + key
+}
+
+// do sends a single command and returns its reply. Server error replies are
+// returned as errors while leaving the connection reusable; any I/O or
+// protocol failure discards the connection.
+func (a *Adapter) do(ctx context.Context, args ...string) (any, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	c, err := a.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := c.roundTrip(ctx, args...)
+	if err != nil {
+		_ = c.netConn.Close()
+		return nil, err
+	}
+	a.release(c)
+
+	if replyErr, ok := reply.(respError); ok {
+		return nil, replyErr
+	}
+	return reply, nil
+}
+
+func (a *Adapter) acquire(ctx context.Context) (*conn, error) {
+	if a == nil {
+		return nil, ErrClosed
+	}
+
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return nil, ErrClosed
+	}
+	if n := len(a.idle); n > 0 {
+		c := a.idle[n-1]
+		a.idle = a.idle[:n-1]
+		a.mu.Unlock()
+		return c, nil
+	}
+	a.mu.Unlock()
+
+	return a.dial(ctx)
+}
+
+func (a *Adapter) release(c *conn) {
+	_ = c.netConn.SetDeadline(time.Time{})
+
+	a.mu.Lock()
+	if a.closed || len(a.idle) >= a.config.PoolSize {
+		a.mu.Unlock()
+		_ = c.netConn.Close()
+		return
+	}
+	a.idle = append(a.idle, c)
+	a.mu.Unlock()
+}
+
+func (a *Adapter) dial(ctx context.Context) (*conn, error) {
+	address := strings.TrimSpace(a.config.Address)
+	if address == "" {
+		return nil, ErrMissingAddress
+	}
+
+	netConn, err := a.dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("redis cache adapter: dial %s: %w", address, err)
+	}
+
+	c := &conn{
+		netConn: netConn,
+		reader:  bufio.NewReader(netConn),
+		writer:  bufio.NewWriter(netConn),
+		timeout: a.config.DialTimeout,
+	}
+
+	if err := a.handshake(ctx, c); err != nil {
+		_ = netConn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (a *Adapter) handshake(ctx context.Context, c *conn) error {
+	if a.config.Password != "" {
+		args := []string{"AUTH", a.config.Password}
+		if a.config.Username != "" {
+			args = []string{"AUTH", a.config.Username, a.config.Password}
+		}
+		if err := c.expectOK(ctx, args...); err != nil {
+			return fmt.Errorf("redis cache adapter: auth: %w", err)
+		}
+	}
+
+	if a.config.Database != 0 {
+		if err := c.expectOK(ctx, "SELECT", strconv.Itoa(a.config.Database)); err != nil {
+			return fmt.Errorf("redis cache adapter: select database %d: %w", a.config.Database, err)
+		}
+	}
+	return nil
+}
+
+func (c *conn) roundTrip(ctx context.Context, args ...string) (any, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(c.timeout)
+	}
+	if err := c.netConn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	if err := writeCommand(c.writer, args...); err != nil {
+		return nil, err
+	}
+	return readReply(c.reader)
+}
+
+func (c *conn) expectOK(ctx context.Context, args ...string) error {
+	reply, err := c.roundTrip(ctx, args...)
+	if err != nil {
+		return err
+	}
+	if replyErr, ok := reply.(respError); ok {
+		return replyErr
+	}
+	if status, ok := reply.(string); !ok || status != "OK" {
+		return fmt.Errorf("%w: unexpected reply %v", errProtocol, reply)
+	}
+	return nil
+}
+
+// ttlMillis converts ttl for PX/PEXPIRE, which have millisecond resolution.
+// Sub-millisecond TTLs round up so a positive TTL never becomes an invalid
+// zero expiry.
+func ttlMillis(ttl time.Duration) int64 {
+	millis := ttl.Milliseconds()
+	if millis <= 0 {
+		millis = 1
+	}
+	return millis
+}
+
+func validateSetInput(key string, ttl time.Duration) error {
+	if key == "" {
+		return ErrMissingKey
+	}
+	if ttl <= 0 {
+		return ErrInvalidTTL
+	}
+	return nil
 }
@@ -0,0 +1,487 @@
+package redis
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/porthorian/openauth/pkg/authz"
+	"github.com/porthorian/openauth/pkg/cache"
+)
+
+func TestAdapterTokenRoundTrip(t *testing.T) {
+	server := newFakeServer(t, "", "")
+	adapter := NewAdapter(Config{Address: server.address(), Namespace: "openauth"})
+	t.Cleanup(func() { _ = adapter.Close() })
+
+	expiresAt := time.Date(2030, 1, 2, 3, 4, 5, 6, time.UTC)
+	snapshot := cache.PrincipalSnapshot{
+		Subject:        "user-1",
+		Tenant:         "tenant-a",
+		RoleMask:       authz.RoleMask{1, 0, 0, 0, 0, 0, 0, 1 << 63},
+		PermissionMask: authz.PermissionMask{3},
+		Claims:         map[string]any{"scope": "read"},
+		ExpiresAt:      expiresAt,
+	}
+
+	ctx := context.Background()
+	if err := adapter.SetToken(ctx, "tok", snapshot, time.Minute); err != nil {
+		t.Fatalf("SetToken returned error: %v", err)
+	}
+
+	got, ok, err := adapter.GetToken(ctx, "tok")
+	if err != nil || !ok {
+		t.Fatalf("GetToken = (%v, %v), want hit", ok, err)
+	}
+	if got.Subject != snapshot.Subject || got.Tenant != snapshot.Tenant {
+		t.Fatalf("unexpected identity: %+v", got)
+	}
+	if got.RoleMask != snapshot.RoleMask || got.PermissionMask != snapshot.PermissionMask {
+		t.Fatalf("unexpected masks: %+v", got)
+	}
+	if !got.ExpiresAt.Equal(expiresAt) {
+		t.Fatalf("expected expires at %v, got %v", expiresAt, got.ExpiresAt)
+	}
+	if got.Claims["scope"] != "read" {
+		t.Fatalf("unexpected claims: %+v", got.Claims)
+	}
+
+	if _, exists := server.rawValue(0, "openauth:token:tok"); !exists {
+		t.Fatalf("expected namespaced key to be stored")
+	}
+
+	if err := adapter.DeleteToken(ctx, "tok"); err != nil {
+		t.Fatalf("DeleteToken returned error: %v", err)
+	}
+	if _, ok, err := adapter.GetToken(ctx, "tok"); err != nil || ok {
+		t.Fatalf("GetToken after delete = (%v, %v), want miss", ok, err)
+	}
+}
+
+func TestAdapterPermissionMaskRoundTrip(t *testing.T) {
+	server := newFakeServer(t, "", "")
+	adapter := NewAdapter(Config{Address: server.address()})
+	t.Cleanup(func() { _ = adapter.Close() })
+
+	ctx := context.Background()
+	mask := authz.PermissionMask{0, 5, 0, 0, 0, 0, 0, 9}
+	if err := adapter.SetPermissionMask(ctx, "user-1", mask, time.Minute); err != nil {
+		t.Fatalf("SetPermissionMask returned error: %v", err)
+	}
+
+	got, ok, err := adapter.GetPermissionMask(ctx, "user-1")
+	if err != nil || !ok {
+		t.Fatalf("GetPermissionMask = (%v, %v), want hit", ok, err)
+	}
+	if got != mask {
+		t.Fatalf("expected %v, got %v", mask, got)
+	}
+
+	if _, exists := server.rawValue(0, "permission:user-1"); !exists {
+		t.Fatalf("expected keyspace-prefixed key without namespace")
+	}
+}
+
+func TestAdapterHonoursTTL(t *testing.T) {
+	server := newFakeServer(t, "", "")
+	adapter := NewAdapter(Config{Address: server.address()})
+	t.Cleanup(func() { _ = adapter.Close() })
+
+	ctx := context.Background()
+	if err := adapter.SetPrincipal(ctx, "user-1", cache.PrincipalSnapshot{Subject: "user-1"}, 1500*time.Millisecond); err != nil {
+		t.Fatalf("SetPrincipal returned error: %v", err)
+	}
+	if ttl := server.lastPX(); ttl != "1500" {
+		t.Fatalf("expected PX 1500, got %q", ttl)
+	}
+
+	server.advance(2 * time.Second)
+	if _, ok, err := adapter.GetPrincipal(ctx, "user-1"); err != nil || ok {
+		t.Fatalf("GetPrincipal after expiry = (%v, %v), want miss", ok, err)
+	}
+}
+
+func TestAdapterRevocationExpiresWithToken(t *testing.T) {
+	server := newFakeServer(t, "", "")
+	adapter := NewAdapter(Config{Address: server.address(), Namespace: "openauth"})
+	t.Cleanup(func() { _ = adapter.Close() })
+
+	ctx := context.Background()
+	if err := adapter.RevokeToken(ctx, "jti-1", time.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("RevokeToken returned error: %v", err)
+	}
+	if _, ok := server.rawValue(0, "openauth:revoked:jti-1"); !ok {
+		t.Fatalf("expected namespaced revocation key")
+	}
+	if revoked, err := adapter.IsTokenRevoked(ctx, "jti-1"); err != nil || !revoked {
+		t.Fatalf("IsTokenRevoked = (%v, %v), want revoked", revoked, err)
+	}
+
+	server.advance(2 * time.Minute)
+	if revoked, err := adapter.IsTokenRevoked(ctx, "jti-1"); err != nil || revoked {
+		t.Fatalf("IsTokenRevoked after exp = (%v, %v), want not revoked", revoked, err)
+	}
+
+	if err := adapter.RevokeToken(ctx, "jti-2", time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("RevokeToken for expired token returned error: %v", err)
+	}
+	if _, ok := server.rawValue(0, "openauth:revoked:jti-2"); ok {
+		t.Fatalf("expired token should not be stored")
+	}
+}
+
+func TestAdapterRejectsInvalidInput(t *testing.T) {
+	adapter := NewAdapter(Config{Address: "127.0.0.1:0"})
+
+	err := adapter.SetToken(context.Background(), "tok", cache.PrincipalSnapshot{}, 0)
+	if !errors.Is(err, ErrInvalidTTL) {
+		t.Fatalf("expected ErrInvalidTTL, got %v", err)
+	}
+
+	err = adapter.SetPermissionMask(context.Background(), "", authz.PermissionMask{}, time.Minute)
+	if !errors.Is(err, ErrMissingKey) {
+		t.Fatalf("expected ErrMissingKey, got %v", err)
+	}
+}
+
+func TestAdapterAuthenticatesAndSelectsDatabase(t *testing.T) {
+	server := newFakeServer(t, "svc", "secret")
+
+	bad := NewAdapter(Config{Address: server.address(), Username: "svc", Password: "wrong"})
+	t.Cleanup(func() { _ = bad.Close() })
+	if _, _, err := bad.GetToken(context.Background(), "tok"); err == nil {
+		t.Fatalf("expected auth failure")
+	}
+
+	adapter := NewAdapter(Config{Address: server.address(), Username: "svc", Password: "secret", Database: 3})
+	t.Cleanup(func() { _ = adapter.Close() })
+
+	if err := adapter.SetToken(context.Background(), "tok", cache.PrincipalSnapshot{Subject: "user-1"}, time.Minute); err != nil {
+		t.Fatalf("SetToken returned error: %v", err)
+	}
+	if _, exists := server.rawValue(3, "token:tok"); !exists {
+		t.Fatalf("expected key in database 3")
+	}
+	if _, exists := server.rawValue(0, "token:tok"); exists {
+		t.Fatalf("expected database 0 to be untouched")
+	}
+}
+
+func TestAdapterTreatsUnknownVersionAsMiss(t *testing.T) {
+	server := newFakeServer(t, "", "")
+	server.put(0, "token:tok", `{"v":99,"sub":"user-1"}`)
+
+	adapter := NewAdapter(Config{Address: server.address()})
+	t.Cleanup(func() { _ = adapter.Close() })
+
+	if _, ok, err := adapter.GetToken(context.Background(), "tok"); err != nil || ok {
+		t.Fatalf("GetToken = (%v, %v), want miss", ok, err)
+	}
+}
+
+func TestAdapterReusesConnections(t *testing.T) {
+	server := newFakeServer(t, "", "")
+	adapter := NewAdapter(Config{Address: server.address()})
+	t.Cleanup(func() { _ = adapter.Close() })
+
+	ctx := context.Background()
+	for i := 0; i < 5; i++ {
+		if _, _, err := adapter.GetToken(ctx, "tok"); err != nil {
+			t.Fatalf("GetToken returned error: %v", err)
+		}
+	}
+	if got := server.connections(); got != 1 {
+		t.Fatalf("expected 1 connection, got %d", got)
+	}
+
+	if err := adapter.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	if _, _, err := adapter.GetToken(ctx, "tok"); !errors.Is(err, ErrClosed) {
+		t.Fatalf("expected ErrClosed, got %v", err)
+	}
+}
+
+func TestAdapterTimesOutHungServerWithoutDeadline(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		// Accept and never answer.
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { _ = conn.Close() })
+		}
+	}()
+
+	adapter := NewAdapter(Config{Address: listener.Addr().String(), DialTimeout: 50 * time.Millisecond})
+	t.Cleanup(func() { _ = adapter.Close() })
+
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := adapter.GetToken(context.Background(), "tok")
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		var netErr net.Error
+		if !errors.As(err, &netErr) || !netErr.Timeout() {
+			t.Fatalf("expected timeout error, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("GetToken blocked on a hung server")
+	}
+}
+
+func TestAdapterAttemptsAndLocks(t *testing.T) {
+	server := newFakeServer(t, "", "")
+	adapter := NewAdapter(Config{Address: server.address(), Namespace: "openauth"})
+	t.Cleanup(func() { _ = adapter.Close() })
+
+	ctx := context.Background()
+	for want := int64(1); want <= 3; want++ {
+		got, err := adapter.IncrementAttempts(ctx, "subject:user-1", time.Minute)
+		if err != nil {
+			t.Fatalf("IncrementAttempts returned error: %v", err)
+		}
+		if got != want {
+			t.Fatalf("IncrementAttempts = %d, want %d", got, want)
+		}
+	}
+	if _, exists := server.rawValue(0, "openauth:attempt:subject:user-1"); !exists {
+		t.Fatalf("expected namespaced attempt key to be stored")
+	}
+
+	server.advance(2 * time.Minute)
+	if got, err := adapter.IncrementAttempts(ctx, "subject:user-1", time.Minute); err != nil || got != 1 {
+		t.Fatalf("IncrementAttempts after window = (%d, %v), want 1", got, err)
+	}
+	if err := adapter.ResetAttempts(ctx, "subject:user-1"); err != nil {
+		t.Fatalf("ResetAttempts returned error: %v", err)
+	}
+	if _, exists := server.rawValue(0, "openauth:attempt:subject:user-1"); exists {
+		t.Fatalf("expected attempt key to be deleted")
+	}
+
+	until := time.Now().Add(time.Minute).Truncate(time.Millisecond).UTC()
+	if err := adapter.SetLock(ctx, "subject:user-1", until); err != nil {
+		t.Fatalf("SetLock returned error: %v", err)
+	}
+	got, ok, err := adapter.GetLock(ctx, "subject:user-1")
+	if err != nil || !ok || !got.Equal(until) {
+		t.Fatalf("GetLock = (%v, %v, %v), want %v", got, ok, err, until)
+	}
+
+	if err := adapter.DeleteLock(ctx, "subject:user-1"); err != nil {
+		t.Fatalf("DeleteLock returned error: %v", err)
+	}
+	if _, ok, err := adapter.GetLock(ctx, "subject:user-1"); err != nil || ok {
+		t.Fatalf("GetLock after delete = (%v, %v), want miss", ok, err)
+	}
+}
+
+type fakeEntry struct {
+	value     string
+	expiresAt time.Time
+}
+
+// fakeServer is a minimal in-process RESP server covering the commands the
+// adapter issues.
+type fakeServer struct {
+	listener net.Listener
+	username string
+	password string
+
+	mu       sync.Mutex
+	dbs      map[int]map[string]fakeEntry
+	now      time.Time
+	px       string
+	accepted int
+}
+
+func newFakeServer(t *testing.T, username string, password string) *fakeServer {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+
+	server := &fakeServer{
+		listener: listener,
+		username: username,
+		password: password,
+		dbs:      map[int]map[string]fakeEntry{},
+		now:      time.Now(),
+	}
+	go server.serve()
+	t.Cleanup(func() { _ = listener.Close() })
+	return server
+}
+
+func (s *fakeServer) address() string {
+	return s.listener.Addr().String()
+}
+
+func (s *fakeServer) advance(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.now = s.now.Add(d)
+}
+
+func (s *fakeServer) lastPX() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.px
+}
+
+func (s *fakeServer) connections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.accepted
+}
+
+func (s *fakeServer) put(db int, key string, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keyspace(db)[key] = fakeEntry{value: value}
+}
+
+func (s *fakeServer) rawValue(db int, key string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.keyspace(db)[key]
+	return entry.value, ok
+}
+
+func (s *fakeServer) keyspace(db int) map[string]fakeEntry {
+	keys, ok := s.dbs[db]
+	if !ok {
+		keys = map[string]fakeEntry{}
+		s.dbs[db] = keys
+	}
+	return keys
+}
+
+func (s *fakeServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.accepted++
+		s.mu.Unlock()
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeServer) handle(conn net.Conn) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	writer := bufio.NewWriter(conn)
+	authenticated := s.password == ""
+	db := 0
+
+	for {
+		reply, err := readReply(reader)
+		if err != nil {
+			return
+		}
+		items, ok := reply.([]any)
+		if !ok || len(items) == 0 {
+			return
+		}
+		args := make([]string, len(items))
+		for i, item := range items {
+			raw, _ := item.([]byte)
+			args[i] = string(raw)
+		}
+
+		command := strings.ToUpper(args[0])
+		switch {
+		case command == "AUTH":
+			user, pass := "default", args[len(args)-1]
+			if len(args) == 3 {
+				user = args[1]
+			}
+			if pass == s.password && (s.username == "" || user == s.username) {
+				authenticated = true
+				writer.WriteString("+OK\r\n")
+			} else {
+				writer.WriteString("-WRONGPASS invalid username-password pair\r\n")
+			}
+		case !authenticated:
+			writer.WriteString("-NOAUTH Authentication required.\r\n")
+		case command == "SELECT":
+			db, _ = strconv.Atoi(args[1])
+			writer.WriteString("+OK\r\n")
+		case command == "SET":
+			s.mu.Lock()
+			entry := fakeEntry{value: args[2]}
+			if len(args) == 5 && strings.ToUpper(args[3]) == "PX" {
+				millis, _ := strconv.ParseInt(args[4], 10, 64)
+				entry.expiresAt = s.now.Add(time.Duration(millis) * time.Millisecond)
+				s.px = args[4]
+			}
+			s.keyspace(db)[args[1]] = entry
+			s.mu.Unlock()
+			writer.WriteString("+OK\r\n")
+		case command == "GET":
+			s.mu.Lock()
+			entry, ok := s.keyspace(db)[args[1]]
+			if ok && !entry.expiresAt.IsZero() && !s.now.Before(entry.expiresAt) {
+				delete(s.keyspace(db), args[1])
+				ok = false
+			}
+			s.mu.Unlock()
+			if !ok {
+				writer.WriteString("$-1\r\n")
+			} else {
+				writer.WriteString("$" + strconv.Itoa(len(entry.value)) + "\r\n" + entry.value + "\r\n")
+			}
+		case command == "EVAL" && args[1] == incrementAttemptsScript:
+			s.mu.Lock()
+			entry, ok := s.keyspace(db)[args[3]]
+			if ok && !entry.expiresAt.IsZero() && !s.now.Before(entry.expiresAt) {
+				entry = fakeEntry{}
+			}
+			count, _ := strconv.ParseInt(entry.value, 10, 64)
+			count++
+			millis, _ := strconv.ParseInt(args[4], 10, 64)
+			entry.value = strconv.FormatInt(count, 10)
+			entry.expiresAt = s.now.Add(time.Duration(millis) * time.Millisecond)
+			s.keyspace(db)[args[3]] = entry
+			s.mu.Unlock()
+			writer.WriteString(":" + entry.value + "\r\n")
+		case command == "DEL":
+			s.mu.Lock()
+			_, ok := s.keyspace(db)[args[1]]
+			delete(s.keyspace(db), args[1])
+			s.mu.Unlock()
+			if ok {
+				writer.WriteString(":1\r\n")
+			} else {
+				writer.WriteString(":0\r\n")
+			}
+		default:
+			writer.WriteString("-ERR unknown command\r\n")
+		}
+		if err := writer.Flush(); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package redis
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// respError is an error reply (`-ERR ...`) returned by the server. It does not
+// indicate a broken connection.
+type respError string
+
+func (e respError) Error() string {
+	return "redis cache adapter: server error: " + string(e)
+}
+
+var errProtocol = errors.New("redis cache adapter: protocol error")
+
+// writeCommand encodes args as a RESP array of bulk strings.
+func writeCommand(w *bufio.Writer, args ...string) error {
+	if _, err := fmt.Fprintf(w, "*%d\r\n", len(args)); err != nil {
+		return err
+	}
+	for _, arg := range args {
+		if _, err := fmt.Fprintf(w, "$%d\r\n", len(arg)); err != nil {
+			return err
+		}
+		if _, err := w.WriteString(arg); err != nil {
+			return err
+		}
+		if _, err := w.WriteString("\r\n"); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+// readReply decodes a single RESP2 reply. Bulk strings are returned as
+// []byte (nil for the null bulk string), simple strings as string, integers
+// as int64, arrays as []any and error replies as respError.
+func readReply(r *bufio.Reader) (any, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 {
+		return nil, fmt.Errorf("%w: empty reply line", errProtocol)
+	}
+
+	payload := string(line[1:])
+	switch line[0] {
+	case '+':
+		return payload, nil
+	case '-':
+		return respError(payload), nil
+	case ':':
+		value, err := strconv.ParseInt(payload, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid integer reply", errProtocol)
+		}
+		return value, nil
+	case '$':
+		size, err := strconv.Atoi(payload)
+		if err != nil || size < -1 {
+			return nil, fmt.Errorf("%w: invalid bulk length", errProtocol)
+		}
+		if size == -1 {
+			return []byte(nil), nil
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		if buf[size] != '\r' || buf[size+1] != '\n' {
+			return nil, fmt.Errorf("%w: bulk string missing terminator", errProtocol)
+		}
+		return buf[:size], nil
+	case '*':
+		count, err := strconv.Atoi(payload)
+		if err != nil || count < -1 {
+			return nil, fmt.Errorf("%w: invalid array length", errProtocol)
+		}
+		if count == -1 {
+			return []any(nil), nil
+		}
+		items := make([]any, 0, count)
+		for i := 0; i < count; i++ {
+			item, err := readReply(r)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("%w: unexpected reply type %q", errProtocol, line[0])
+	}
+}
+
+func readLine(r *bufio.Reader) ([]byte, error) {
+	line, err := r.ReadSlice('\n')
+	if err != nil {
+		if errors.Is(err, bufio.ErrBufferFull) {
+			return nil, fmt.Errorf("%w: reply line too long", errProtocol)
+		}
+		return nil, err
+	}
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("%w: reply line missing CRLF", errProtocol)
+	}
+	return line[:len(line)-2], nil
+}
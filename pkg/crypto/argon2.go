@@ -0,0 +1,189 @@
+package crypto
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const argon2idScheme = "argon2id"
+
+// Argon2idPrefix starts every hash produced by Argon2idHasher.
+const Argon2idPrefix = "$" + argon2idScheme + "$"
+
+// Argon2idOptions configures Argon2id. Memory is in KiB.
+type Argon2idOptions struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltBytes   int
+	KeyBytes    int
+}
+
+// Argon2idHasher hashes passwords with Argon2id and encodes them in the PHC
+// string format: $argon2id$v=19$m=<memory>,t=<iterations>,p=<parallelism>$<salt_b64>$<derived_b64>.
+type Argon2idHasher struct {
+	options Argon2idOptions
+}
+
+var _ Hasher = (*Argon2idHasher)(nil)
+var _ Rehasher = (*Argon2idHasher)(nil)
+
+func DefaultArgon2idOptions() Argon2idOptions {
+	return Argon2idOptions{
+		Memory:      64 * 1024,
+		Iterations:  3,
+		Parallelism: 4,
+		SaltBytes:   16,
+		KeyBytes:    32,
+	}
+}
+
+func NewArgon2idHasher(options Argon2idOptions) *Argon2idHasher {
+	defaults := DefaultArgon2idOptions()
+
+	if options.Memory == 0 {
+		options.Memory = defaults.Memory
+	}
+	if options.Iterations == 0 {
+		options.Iterations = defaults.Iterations
+	}
+	if options.Parallelism == 0 {
+		options.Parallelism = defaults.Parallelism
+	}
+	if options.SaltBytes <= 0 {
+		options.SaltBytes = defaults.SaltBytes
+	}
+	if options.KeyBytes <= 0 {
+		options.KeyBytes = defaults.KeyBytes
+	}
+
+	return &Argon2idHasher{
+		options: options,
+	}
+}
+
+func (h *Argon2idHasher) Hash(password string) (string, error) {
+	if h == nil {
+		return "", ErrInvalidConfig
+	}
+	if password == "" {
+		return "", ErrInvalidConfig
+	}
+
+	salt := make([]byte, h.options.SaltBytes)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	derived := argon2.IDKey([]byte(password), salt, h.options.Iterations, h.options.Memory, h.options.Parallelism, uint32(h.options.KeyBytes))
+
+	return fmt.Sprintf(
+		"$%s$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idScheme,
+		argon2.Version,
+		h.options.Memory,
+		h.options.Iterations,
+		h.options.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(derived),
+	), nil
+}
+
+// Verify checks password against an Argon2id PHC string using the parameters
+// encoded in it, so hashes made with other options still verify.
+func (h *Argon2idHasher) Verify(password string, encodedHash string) (bool, error) {
+	if h == nil {
+		return false, ErrInvalidConfig
+	}
+	if password == "" {
+		return false, ErrInvalidConfig
+	}
+
+	params, salt, expected, err := parseArgon2idHash(encodedHash)
+	if err != nil {
+		return false, err
+	}
+
+	candidate := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(expected)))
+	return subtle.ConstantTimeCompare(candidate, expected) == 1, nil
+}
+
+func (h *Argon2idHasher) NeedsRehash(encodedHash string) bool {
+	if h == nil {
+		return false
+	}
+
+	params, _, _, err := parseArgon2idHash(encodedHash)
+	if err != nil {
+		return true
+	}
+	return params.Memory < h.options.Memory ||
+		params.Iterations < h.options.Iterations ||
+		params.Parallelism < h.options.Parallelism ||
+		params.SaltBytes < h.options.SaltBytes ||
+		params.KeyBytes < h.options.KeyBytes
+}
+
+func parseArgon2idHash(encodedHash string) (Argon2idOptions, []byte, []byte, error) {
+	parts := strings.Split(encodedHash, "$")
+	if len(parts) != 6 || parts[0] != "" || parts[1] != argon2idScheme {
+		return Argon2idOptions{}, nil, nil, ErrInvalidHash
+	}
+
+	if parts[2] != "v="+strconv.Itoa(argon2.Version) {
+		return Argon2idOptions{}, nil, nil, ErrInvalidHash
+	}
+
+	var params Argon2idOptions
+	for _, field := range strings.Split(parts[3], ",") {
+		key, raw, found := strings.Cut(field, "=")
+		if !found {
+			return Argon2idOptions{}, nil, nil, ErrInvalidHash
+		}
+		switch key {
+		case "m":
+			value, err := strconv.ParseUint(raw, 10, 32)
+			if err != nil || value == 0 {
+				return Argon2idOptions{}, nil, nil, ErrInvalidHash
+			}
+			params.Memory = uint32(value)
+		case "t":
+			value, err := strconv.ParseUint(raw, 10, 32)
+			if err != nil || value == 0 {
+				return Argon2idOptions{}, nil, nil, ErrInvalidHash
+			}
+			params.Iterations = uint32(value)
+		case "p":
+			value, err := strconv.ParseUint(raw, 10, 8)
+			if err != nil || value == 0 {
+				return Argon2idOptions{}, nil, nil, ErrInvalidHash
+			}
+			params.Parallelism = uint8(value)
+		default:
+			return Argon2idOptions{}, nil, nil, ErrInvalidHash
+		}
+	}
+	if params.Memory == 0 || params.Iterations == 0 || params.Parallelism == 0 {
+		return Argon2idOptions{}, nil, nil, ErrInvalidHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil || len(salt) == 0 {
+		return Argon2idOptions{}, nil, nil, ErrInvalidHash
+	}
+
+	derived, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(derived) == 0 {
+		return Argon2idOptions{}, nil, nil, ErrInvalidHash
+	}
+
+	params.SaltBytes = len(salt)
+	params.KeyBytes = len(derived)
+	return params, salt, derived, nil
+}
@@ -0,0 +1,66 @@
+package crypto
+
+import (
+	"strings"
+	"testing"
+)
+
+func testArgon2idHasher() *Argon2idHasher {
+	return NewArgon2idHasher(Argon2idOptions{
+		Memory:      1024,
+		Iterations:  1,
+		Parallelism: 1,
+		SaltBytes:   16,
+		KeyBytes:    32,
+	})
+}
+
+func TestArgon2idHashAndVerify(t *testing.T) {
+	hasher := testArgon2idHasher()
+
+	encoded, err := hasher.Hash("secret-pass")
+	if err != nil {
+		t.Fatalf("hash failed: %v", err)
+	}
+	if !strings.HasPrefix(encoded, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Fatalf("unexpected PHC encoding: %s", encoded)
+	}
+
+	ok, err := hasher.Verify("secret-pass", encoded)
+	if err != nil {
+		t.Fatalf("verify failed: %v", err)
+	}
+	if !ok {
+		t.Fatal("expected hash verification to succeed")
+	}
+
+	ok, err = hasher.Verify("wrong-pass", encoded)
+	if err != nil {
+		t.Fatalf("verify wrong password failed with error: %v", err)
+	}
+	if ok {
+		t.Fatal("expected hash verification to fail for wrong password")
+	}
+
+	ok, err = NewArgon2idHasher(DefaultArgon2idOptions()).Verify("secret-pass", encoded)
+	if err != nil || !ok {
+		t.Fatalf("verify with different options = (%v, %v), want encoded parameters to be used", ok, err)
+	}
+}
+
+func TestArgon2idVerifyInvalidHash(t *testing.T) {
+	hasher := testArgon2idHasher()
+
+	for _, encoded := range []string{
+		"invalid",
+		"$argon2i$v=19$m=1024,t=1,p=1$c2FsdHNhbHQ$aGFzaGhhc2g",
+		"$argon2id$v=16$m=1024,t=1,p=1$c2FsdHNhbHQ$aGFzaGhhc2g",
+		"$argon2id$v=19$m=1024,t=1$c2FsdHNhbHQ$aGFzaGhhc2g",
+		"$argon2id$v=19$m=1024,t=1,p=0$c2FsdHNhbHQ$aGFzaGhhc2g",
+	} {
+		ok, err := hasher.Verify("secret-pass", encoded)
+		if err == nil || ok {
+			t.Fatalf("Verify(%q) = (%v, %v), want invalid hash error", encoded, ok, err)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package crypto
+
+import (
+	"errors"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// BcryptPrefixes lists the modular crypt prefixes of bcrypt hashes accepted
+// by BcryptHasher. New hashes always use $2a$.
+var BcryptPrefixes = []string{"$2a$", "$2b$", "$2y$"}
+
+type BcryptOptions struct {
+	Cost int
+}
+
+// BcryptHasher hashes passwords with bcrypt. bcrypt only considers the first
+// 72 bytes of a password, so Hash rejects longer passwords.
+type BcryptHasher struct {
+	options BcryptOptions
+}
+
+var _ Hasher = (*BcryptHasher)(nil)
+var _ Rehasher = (*BcryptHasher)(nil)
+
+func DefaultBcryptOptions() BcryptOptions {
+	return BcryptOptions{
+		Cost: 12,
+	}
+}
+
+func NewBcryptHasher(options BcryptOptions) *BcryptHasher {
+	defaults := DefaultBcryptOptions()
+
+	if options.Cost <= 0 {
+		options.Cost = defaults.Cost
+	}
+	if options.Cost < bcrypt.MinCost {
+		options.Cost = bcrypt.MinCost
+	}
+	if options.Cost > bcrypt.MaxCost {
+		options.Cost = bcrypt.MaxCost
+	}
+
+	return &BcryptHasher{
+		options: options,
+	}
+}
+
+func (h *BcryptHasher) Hash(password string) (string, error) {
+	if h == nil {
+		return "", ErrInvalidConfig
+	}
+	if password == "" {
+		return "", ErrInvalidConfig
+	}
+
+	encoded, err := bcrypt.GenerateFromPassword([]byte(password), h.options.Cost)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
+
+func (h *BcryptHasher) Verify(password string, encodedHash string) (bool, error) {
+	if h == nil {
+		return false, ErrInvalidConfig
+	}
+	if password == "" {
+		return false, ErrInvalidConfig
+	}
+
+	err := bcrypt.CompareHashAndPassword([]byte(encodedHash), []byte(password))
+	if err == nil {
+		return true, nil
+	}
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	return false, ErrInvalidHash
+}
+
+func (h *BcryptHasher) NeedsRehash(encodedHash string) bool {
+	if h == nil {
+		return false
+	}
+
+	cost, err := bcrypt.Cost([]byte(encodedHash))
+	if err != nil {
+		return true
+	}
+	return cost < h.options.Cost
+}
@@ -0,0 +1,46 @@
+package crypto
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBcryptHashAndVerify(t *testing.T) {
+	hasher := NewBcryptHasher(BcryptOptions{Cost: 4})
+
+	encoded, err := hasher.Hash("secret-pass")
+	if err != nil {
+		t.Fatalf("hash failed: %v", err)
+	}
+	if !strings.HasPrefix(encoded, "$2a$04$") {
+		t.Fatalf("unexpected bcrypt encoding: %s", encoded)
+	}
+
+	ok, err := hasher.Verify("secret-pass", encoded)
+	if err != nil {
+		t.Fatalf("verify failed: %v", err)
+	}
+	if !ok {
+		t.Fatal("expected hash verification to succeed")
+	}
+
+	ok, err = hasher.Verify("wrong-pass", encoded)
+	if err != nil {
+		t.Fatalf("verify wrong password failed with error: %v", err)
+	}
+	if ok {
+		t.Fatal("expected hash verification to fail for wrong password")
+	}
+}
+
+func TestBcryptVerifyInvalidHash(t *testing.T) {
+	hasher := NewBcryptHasher(BcryptOptions{Cost: 4})
+
+	ok, err := hasher.Verify("secret-pass", "invalid")
+	if err == nil {
+		t.Fatal("expected invalid hash error")
+	}
+	if ok {
+		t.Fatal("expected verification to fail")
+	}
+}
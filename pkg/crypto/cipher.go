@@ -0,0 +1,128 @@
+package crypto
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+const (
+	aesGCMScheme = "aesgcm"
+	aesGCMInfo   = "openauth secret cipher"
+)
+
+// AESGCMPrefix starts every value produced by AESGCMCipher.
+const AESGCMPrefix = aesGCMScheme + "$"
+
+var (
+	ErrInvalidCiphertext    = errors.New("password: invalid ciphertext")
+	ErrCipherKeyUnavailable = errors.New("password: cipher key is unavailable")
+)
+
+// SecretCipher encrypts secrets that must be read back later, such as TOTP
+// seeds, and so cannot be hashed. associatedData binds a ciphertext to its
+// owner, for example the auth record ID, and must match on Decrypt.
+type SecretCipher interface {
+	Encrypt(plaintext []byte, associatedData []byte) (string, error)
+	Decrypt(encoded string, associatedData []byte) ([]byte, error)
+}
+
+// AESGCMCipher seals secrets with AES-256-GCM under a key derived by HKDF
+// from the current symmetric key of a keyring. Values are encoded as
+// aesgcm$<key_id>$<base64url nonce and ciphertext> so keys can be rotated.
+// Like PepperedHasher, it reads key sources through a PepperKeyring.
+type AESGCMCipher struct {
+	keys PepperKeys
+}
+
+var _ SecretCipher = (*AESGCMCipher)(nil)
+
+func NewAESGCMCipher(keys PepperKeys) (*AESGCMCipher, error) {
+	if keys == nil {
+		return nil, ErrInvalidConfig
+	}
+	return &AESGCMCipher{keys: persistentKeys(keys)}, nil
+}
+
+func (c *AESGCMCipher) Encrypt(plaintext []byte, associatedData []byte) (string, error) {
+	if c == nil || c.keys == nil {
+		return "", ErrInvalidConfig
+	}
+
+	key, err := c.keys.SigningKey(context.Background())
+	if err != nil {
+		return "", errors.Join(ErrCipherKeyUnavailable, err)
+	}
+	keyID, secret, err := symmetricSecret(key, ErrCipherKeyUnavailable)
+	if err != nil {
+		return "", err
+	}
+
+	aead, err := newAESGCM(secret)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, plaintext, associatedData)
+	return AESGCMPrefix + keyID + "$" + base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+func (c *AESGCMCipher) Decrypt(encoded string, associatedData []byte) ([]byte, error) {
+	if c == nil || c.keys == nil {
+		return nil, ErrInvalidConfig
+	}
+
+	parts := strings.SplitN(encoded, "$", 3)
+	if len(parts) != 3 || parts[0] != aesGCMScheme || parts[1] == "" || parts[2] == "" {
+		return nil, ErrInvalidCiphertext
+	}
+	sealed, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidCiphertext
+	}
+
+	key, err := c.keys.ResolveKey(context.Background(), parts[1])
+	if err != nil {
+		return nil, errors.Join(ErrCipherKeyUnavailable, err)
+	}
+	_, secret, err := symmetricSecret(key, ErrCipherKeyUnavailable)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newAESGCM(secret)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize()+aead.Overhead() {
+		return nil, ErrInvalidCiphertext
+	}
+
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], associatedData)
+	if err != nil {
+		return nil, ErrInvalidCiphertext
+	}
+	return plaintext, nil
+}
+
+func newAESGCM(secret []byte) (cipher.AEAD, error) {
+	derived, err := hkdf.Key(sha256.New, secret, nil, aesGCMInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(derived)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
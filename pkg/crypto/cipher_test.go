@@ -0,0 +1,61 @@
+package crypto
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestAESGCMCipherRoundTrip(t *testing.T) {
+	cipher, err := NewAESGCMCipher(newPepperKeyring(t, "cipher-1"))
+	if err != nil {
+		t.Fatalf("NewAESGCMCipher returned error: %v", err)
+	}
+
+	encoded, err := cipher.Encrypt([]byte("totp-seed"), []byte("auth-1"))
+	if err != nil {
+		t.Fatalf("Encrypt returned error: %v", err)
+	}
+	if !strings.HasPrefix(encoded, "aesgcm$cipher-1$") || strings.Contains(encoded, "totp-seed") {
+		t.Fatalf("unexpected ciphertext encoding: %s", encoded)
+	}
+
+	plaintext, err := cipher.Decrypt(encoded, []byte("auth-1"))
+	if err != nil || string(plaintext) != "totp-seed" {
+		t.Fatalf("Decrypt = (%q, %v), want original plaintext", plaintext, err)
+	}
+
+	if _, err := cipher.Decrypt(encoded, []byte("auth-2")); !errors.Is(err, ErrInvalidCiphertext) {
+		t.Fatalf("expected associated data mismatch to fail, got %v", err)
+	}
+	if _, err := cipher.Decrypt("aesgcm$cipher-1$AAAA", []byte("auth-1")); !errors.Is(err, ErrInvalidCiphertext) {
+		t.Fatalf("expected truncated ciphertext to fail, got %v", err)
+	}
+}
+
+func TestAESGCMCipherDecryptsWithRotatedKeys(t *testing.T) {
+	old, err := NewAESGCMCipher(newPepperKeyring(t, "cipher-1"))
+	if err != nil {
+		t.Fatalf("NewAESGCMCipher returned error: %v", err)
+	}
+	encoded, err := old.Encrypt([]byte("totp-seed"), nil)
+	if err != nil {
+		t.Fatalf("Encrypt returned error: %v", err)
+	}
+
+	rotated, err := NewAESGCMCipher(newPepperKeyring(t, "cipher-1", "cipher-2"))
+	if err != nil {
+		t.Fatalf("NewAESGCMCipher returned error: %v", err)
+	}
+	if plaintext, err := rotated.Decrypt(encoded, nil); err != nil || string(plaintext) != "totp-seed" {
+		t.Fatalf("Decrypt after rotation = (%q, %v), want original plaintext", plaintext, err)
+	}
+	reencoded, err := rotated.Encrypt([]byte("totp-seed"), nil)
+	if err != nil || !strings.HasPrefix(reencoded, "aesgcm$cipher-2$") {
+		t.Fatalf("Encrypt after rotation = (%s, %v), want current key", reencoded, err)
+	}
+
+	if _, err := old.Decrypt(reencoded, nil); !errors.Is(err, ErrCipherKeyUnavailable) {
+		t.Fatalf("expected unknown key to be unavailable, got %v", err)
+	}
+}
@@ -0,0 +1,100 @@
+package crypto
+
+import (
+	"sort"
+	"strings"
+)
+
+// PBKDF2Prefix starts every hash produced by PBKDF2Hasher.
+const PBKDF2Prefix = encodingScheme + "$"
+
+type prefixedHasher struct {
+	prefix string
+	hasher Hasher
+}
+
+// CompositeHasher hashes with a primary Hasher and verifies with whichever
+// registered Hasher owns the encoded prefix, so hashes imported from other
+// systems keep verifying alongside newly created ones.
+type CompositeHasher struct {
+	primary   Hasher
+	verifiers []prefixedHasher
+}
+
+var _ Hasher = (*CompositeHasher)(nil)
+var _ Rehasher = (*CompositeHasher)(nil)
+
+// NewCompositeHasher returns a CompositeHasher that hashes with primary and
+// dispatches Verify by the longest matching prefix in verifiers.
+func NewCompositeHasher(primary Hasher, verifiers map[string]Hasher) (*CompositeHasher, error) {
+	if primary == nil {
+		return nil, ErrInvalidConfig
+	}
+
+	routes := make([]prefixedHasher, 0, len(verifiers))
+	for prefix, hasher := range verifiers {
+		if prefix == "" || hasher == nil {
+			return nil, ErrInvalidConfig
+		}
+		routes = append(routes, prefixedHasher{prefix: prefix, hasher: hasher})
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		if len(routes[i].prefix) != len(routes[j].prefix) {
+			return len(routes[i].prefix) > len(routes[j].prefix)
+		}
+		return routes[i].prefix < routes[j].prefix
+	})
+
+	return &CompositeHasher{
+		primary:   primary,
+		verifiers: routes,
+	}, nil
+}
+
+// DefaultVerifiers maps the PBKDF2, Argon2id and bcrypt prefixes to hashers
+// with default options. Each one reads its cost parameters from the encoded
+// hash, so the defaults only matter for Hash.
+func DefaultVerifiers() map[string]Hasher {
+	verifiers := map[string]Hasher{
+		PBKDF2Prefix:   NewPBKDF2Hasher(DefaultPBKDF2Options()),
+		Argon2idPrefix: NewArgon2idHasher(DefaultArgon2idOptions()),
+	}
+	bcryptHasher := NewBcryptHasher(DefaultBcryptOptions())
+	for _, prefix := range BcryptPrefixes {
+		verifiers[prefix] = bcryptHasher
+	}
+	return verifiers
+}
+
+func (h *CompositeHasher) Hash(password string) (string, error) {
+	if h == nil || h.primary == nil {
+		return "", ErrInvalidConfig
+	}
+	return h.primary.Hash(password)
+}
+
+func (h *CompositeHasher) Verify(password string, encodedHash string) (bool, error) {
+	if h == nil || h.primary == nil {
+		return false, ErrInvalidConfig
+	}
+
+	for _, route := range h.verifiers {
+		if strings.HasPrefix(encodedHash, route.prefix) {
+			return route.hasher.Verify(password, encodedHash)
+		}
+	}
+	return false, ErrInvalidHash
+}
+
+// NeedsRehash defers to the primary hasher, so any hash in a format other
+// than the primary one is reported as outdated.
+func (h *CompositeHasher) NeedsRehash(encodedHash string) bool {
+	if h == nil || h.primary == nil {
+		return false
+	}
+	rehasher, ok := h.primary.(Rehasher)
+	if !ok {
+		return false
+	}
+	return rehasher.NeedsRehash(encodedHash)
+}
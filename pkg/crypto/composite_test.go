@@ -0,0 +1,96 @@
+package crypto
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCompositeHasherVerifiesEveryRegisteredFormat(t *testing.T) {
+	pbkdf2Hasher := NewPBKDF2Hasher(PBKDF2Options{Iterations: 1000})
+	argon2idHasher := testArgon2idHasher()
+	bcryptHasher := NewBcryptHasher(BcryptOptions{Cost: 4})
+
+	hasher, err := NewCompositeHasher(argon2idHasher, DefaultVerifiers())
+	if err != nil {
+		t.Fatalf("NewCompositeHasher returned error: %v", err)
+	}
+
+	for name, source := range map[string]Hasher{
+		"pbkdf2":   pbkdf2Hasher,
+		"argon2id": argon2idHasher,
+		"bcrypt":   bcryptHasher,
+		"primary":  hasher,
+	} {
+		encoded, err := source.Hash("secret-pass")
+		if err != nil {
+			t.Fatalf("%s hash failed: %v", name, err)
+		}
+
+		ok, err := hasher.Verify("secret-pass", encoded)
+		if err != nil || !ok {
+			t.Fatalf("%s Verify = (%v, %v), want match", name, ok, err)
+		}
+		ok, err = hasher.Verify("wrong-pass", encoded)
+		if err != nil || ok {
+			t.Fatalf("%s Verify wrong password = (%v, %v), want mismatch", name, ok, err)
+		}
+	}
+
+	encoded, err := hasher.Hash("secret-pass")
+	if err != nil {
+		t.Fatalf("hash failed: %v", err)
+	}
+	if _, _, _, err := parseArgon2idHash(encoded); err != nil {
+		t.Fatalf("expected primary hasher to produce argon2id, got %s", encoded)
+	}
+}
+
+func TestCompositeHasherRejectsUnknownPrefix(t *testing.T) {
+	hasher, err := NewCompositeHasher(NewPBKDF2Hasher(PBKDF2Options{Iterations: 1000}), map[string]Hasher{
+		PBKDF2Prefix: NewPBKDF2Hasher(PBKDF2Options{}),
+	})
+	if err != nil {
+		t.Fatalf("NewCompositeHasher returned error: %v", err)
+	}
+
+	ok, err := hasher.Verify("secret-pass", "$2a$04$abcdefghijklmnopqrstuu")
+	if !errors.Is(err, ErrInvalidHash) || ok {
+		t.Fatalf("Verify = (%v, %v), want ErrInvalidHash", ok, err)
+	}
+
+	if _, err := NewCompositeHasher(nil, nil); !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("NewCompositeHasher without primary error = %v, want ErrInvalidConfig", err)
+	}
+}
+
+func TestCompositeHasherNeedsRehashFollowsPrimary(t *testing.T) {
+	argon2idHasher := testArgon2idHasher()
+	hasher, err := NewCompositeHasher(argon2idHasher, DefaultVerifiers())
+	if err != nil {
+		t.Fatalf("NewCompositeHasher returned error: %v", err)
+	}
+
+	current, err := hasher.Hash("secret-pass")
+	if err != nil {
+		t.Fatalf("hash failed: %v", err)
+	}
+	if hasher.NeedsRehash(current) {
+		t.Fatal("expected primary hash to be up to date")
+	}
+
+	legacy, err := NewBcryptHasher(BcryptOptions{Cost: 4}).Hash("secret-pass")
+	if err != nil {
+		t.Fatalf("hash failed: %v", err)
+	}
+	if !hasher.NeedsRehash(legacy) {
+		t.Fatal("expected bcrypt hash to need rehash under an argon2id primary")
+	}
+
+	stronger := NewArgon2idHasher(Argon2idOptions{Memory: 2048, Iterations: 1, Parallelism: 1})
+	if !stronger.NeedsRehash(current) {
+		t.Fatal("expected argon2id hash with less memory to need rehash")
+	}
+	if NewBcryptHasher(BcryptOptions{Cost: 4}).NeedsRehash(legacy) {
+		t.Fatal("expected bcrypt hash with current cost to be up to date")
+	}
+}
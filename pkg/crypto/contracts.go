@@ -11,3 +11,10 @@ type Hasher interface {
 	Hash(password string) (string, error)
 	Verify(password string, encodedHash string) (bool, error)
 }
+
+// Rehasher is implemented by hashers that can tell when an encoded hash was
+// produced by another algorithm or with weaker parameters than they would use
+// today, so callers can replace it after a successful Verify.
+type Rehasher interface {
+	NeedsRehash(encodedHash string) bool
+}
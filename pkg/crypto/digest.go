@@ -0,0 +1,25 @@
+package crypto
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+)
+
+// SHA256DigestPrefix starts every digest produced by DigestSecret.
+const SHA256DigestPrefix = "sha256$"
+
+// DigestSecret returns an unsalted SHA-256 digest of secret. It is meant
+// for generated, high-entropy secrets such as API keys and client secrets,
+// which a slow password hash protects no better while costing every
+// request; never use it for passwords.
+func DigestSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return SHA256DigestPrefix + base64.RawStdEncoding.EncodeToString(sum[:])
+}
+
+// VerifySecretDigest reports whether secret matches a digest made by
+// DigestSecret. The comparison takes constant time.
+func VerifySecretDigest(secret string, digest string) bool {
+	return subtle.ConstantTimeCompare([]byte(DigestSecret(secret)), []byte(digest)) == 1
+}
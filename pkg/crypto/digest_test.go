@@ -0,0 +1,27 @@
+package crypto
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDigestSecretAndVerify(t *testing.T) {
+	digest := DigestSecret("high-entropy-secret")
+	if !strings.HasPrefix(digest, SHA256DigestPrefix) || strings.Contains(digest, "high-entropy-secret") {
+		t.Fatalf("unexpected digest %q", digest)
+	}
+	if DigestSecret("high-entropy-secret") != digest {
+		t.Fatalf("expected DigestSecret to be deterministic")
+	}
+	if !VerifySecretDigest("high-entropy-secret", digest) {
+		t.Fatalf("expected secret to verify against its digest")
+	}
+	for _, secret := range []string{"", "high-entropy-secreT", "high-entropy-secret "} {
+		if VerifySecretDigest(secret, digest) {
+			t.Fatalf("expected %q not to verify", secret)
+		}
+	}
+	if VerifySecretDigest("high-entropy-secret", strings.TrimPrefix(digest, SHA256DigestPrefix)) {
+		t.Fatalf("expected a digest without its prefix not to verify")
+	}
+}
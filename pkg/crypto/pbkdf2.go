@@ -27,6 +27,9 @@ type PBKDF2Hasher struct {
 	options PBKDF2Options
 }
 
+var _ Hasher = (*PBKDF2Hasher)(nil)
+var _ Rehasher = (*PBKDF2Hasher)(nil)
+
 func DefaultPBKDF2Options() PBKDF2Options {
 	return PBKDF2Options{
 		Iterations: 120000,
@@ -98,6 +101,18 @@ func (h *PBKDF2Hasher) Verify(password string, encodedHash string) (bool, error)
 	return subtle.ConstantTimeCompare(candidate, expected) == 1, nil
 }
 
+func (h *PBKDF2Hasher) NeedsRehash(encodedHash string) bool {
+	if h == nil {
+		return false
+	}
+
+	scheme, hashFn, iterations, salt, derived, err := parseEncodedHash(encodedHash)
+	if err != nil || scheme != encodingScheme || hashFn != hashFunction {
+		return true
+	}
+	return iterations < h.options.Iterations || len(salt) < h.options.SaltBytes || len(derived) < h.options.KeyBytes
+}
+
 func parseEncodedHash(encodedHash string) (string, string, int, []byte, []byte, error) {
 	parts := strings.Split(encodedHash, "$")
 	if len(parts) != 5 {
@@ -46,3 +46,23 @@ func TestPBKDF2VerifyInvalidHash(t *testing.T) {
 		t.Fatal("expected verification to fail")
 	}
 }
+
+func TestPBKDF2NeedsRehash(t *testing.T) {
+	weak := NewPBKDF2Hasher(PBKDF2Options{Iterations: 1000})
+	strong := NewPBKDF2Hasher(PBKDF2Options{Iterations: 2000})
+
+	encoded, err := weak.Hash("secret-pass")
+	if err != nil {
+		t.Fatalf("hash failed: %v", err)
+	}
+
+	if weak.NeedsRehash(encoded) {
+		t.Fatal("expected hash with current iterations to be up to date")
+	}
+	if !strong.NeedsRehash(encoded) {
+		t.Fatal("expected hash with fewer iterations to need rehash")
+	}
+	if !strong.NeedsRehash("$2a$04$abcdefghijklmnopqrstuu") {
+		t.Fatal("expected foreign hash format to need rehash")
+	}
+}
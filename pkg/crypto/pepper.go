@@ -0,0 +1,217 @@
+package crypto
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	"github.com/porthorian/openauth/pkg/session"
+)
+
+const pepperScheme = "pepper"
+
+// PepperPrefix starts every hash produced by PepperedHasher.
+const PepperPrefix = pepperScheme + "$"
+
+var ErrPepperKeyUnavailable = errors.New("password: pepper key is unavailable")
+
+// PepperKeys supplies pepper secrets. SigningKey returns the pepper applied
+// to new hashes and ResolveKey returns older peppers by key ID. A
+// *jwt.Keyring loaded with symmetric keys, for example from
+// pkg/keystore/file, satisfies it.
+type PepperKeys interface {
+	session.SigningKeyProvider
+	session.KeyResolver
+}
+
+// PepperKeySource lists every pepper key it holds, whatever its signing
+// window. *jwt.Keyring satisfies it.
+type PepperKeySource interface {
+	session.SigningKeyProvider
+	Keys() []session.Key
+}
+
+// PepperKeyring resolves pepper keys without retiring them by time. A
+// jwt.Keyring stops resolving a key once NotAfter plus MaxTokenTTL has
+// passed, which suits tokens but would strand every stored value made with
+// that key. PepperKeyring keeps the source's choice of current key and
+// resolves any key the source still lists, so a key only stops verifying
+// once it is removed.
+type PepperKeyring struct {
+	source PepperKeySource
+}
+
+var _ PepperKeys = (*PepperKeyring)(nil)
+
+func NewPepperKeyring(source PepperKeySource) (*PepperKeyring, error) {
+	if source == nil {
+		return nil, ErrInvalidConfig
+	}
+	return &PepperKeyring{source: source}, nil
+}
+
+func (k *PepperKeyring) SigningKey(ctx context.Context) (session.Key, error) {
+	if k == nil || k.source == nil {
+		return session.Key{}, ErrInvalidConfig
+	}
+	return k.source.SigningKey(ctx)
+}
+
+func (k *PepperKeyring) ResolveKey(ctx context.Context, keyID string) (session.Key, error) {
+	_ = ctx
+	if k == nil || k.source == nil {
+		return session.Key{}, ErrInvalidConfig
+	}
+
+	keyID = strings.TrimSpace(keyID)
+	for _, key := range k.source.Keys() {
+		if key.ID == keyID {
+			return key, nil
+		}
+	}
+	return session.Key{}, ErrPepperKeyUnavailable
+}
+
+// persistentKeys wraps keys that list their entries in a PepperKeyring so
+// values stored under a key keep verifying after its window closes.
+func persistentKeys(keys PepperKeys) PepperKeys {
+	if source, ok := keys.(PepperKeySource); ok {
+		return &PepperKeyring{source: source}
+	}
+	return keys
+}
+
+// PepperedHasher applies HMAC-SHA256 with a server-side key to the password
+// before handing it to an inner Hasher, so a database dump alone is not
+// enough to start offline cracking. Hashes are encoded as
+// pepper$<key_id>$<inner_hash> so peppers can be rotated. Key sources such as
+// *jwt.Keyring are read through a PepperKeyring, so a pepper keeps verifying
+// after its not_after until it is removed.
+type PepperedHasher struct {
+	inner Hasher
+	keys  PepperKeys
+}
+
+var _ Hasher = (*PepperedHasher)(nil)
+var _ Rehasher = (*PepperedHasher)(nil)
+
+func NewPepperedHasher(inner Hasher, keys PepperKeys) (*PepperedHasher, error) {
+	if inner == nil || keys == nil {
+		return nil, ErrInvalidConfig
+	}
+
+	return &PepperedHasher{
+		inner: inner,
+		keys:  persistentKeys(keys),
+	}, nil
+}
+
+func (h *PepperedHasher) Hash(password string) (string, error) {
+	if h == nil || h.inner == nil || h.keys == nil {
+		return "", ErrInvalidConfig
+	}
+	if password == "" {
+		return "", ErrInvalidConfig
+	}
+
+	key, err := h.keys.SigningKey(context.Background())
+	if err != nil {
+		return "", errors.Join(ErrPepperKeyUnavailable, err)
+	}
+	keyID, secret, err := symmetricSecret(key, ErrPepperKeyUnavailable)
+	if err != nil {
+		return "", err
+	}
+
+	encoded, err := h.inner.Hash(pepperPassword(secret, password))
+	if err != nil {
+		return "", err
+	}
+	return PepperPrefix + keyID + "$" + encoded, nil
+}
+
+// Verify resolves the pepper named in encodedHash. Hashes without the pepper
+// prefix are verified by the inner Hasher as-is so existing material keeps
+// working until it is rehashed.
+func (h *PepperedHasher) Verify(password string, encodedHash string) (bool, error) {
+	if h == nil || h.inner == nil || h.keys == nil {
+		return false, ErrInvalidConfig
+	}
+	if password == "" {
+		return false, ErrInvalidConfig
+	}
+
+	if !strings.HasPrefix(encodedHash, PepperPrefix) {
+		return h.inner.Verify(password, encodedHash)
+	}
+
+	keyID, innerHash, err := parsePepperedHash(encodedHash)
+	if err != nil {
+		return false, err
+	}
+
+	key, err := h.keys.ResolveKey(context.Background(), keyID)
+	if err != nil {
+		return false, errors.Join(ErrPepperKeyUnavailable, err)
+	}
+	_, secret, err := symmetricSecret(key, ErrPepperKeyUnavailable)
+	if err != nil {
+		return false, err
+	}
+
+	return h.inner.Verify(pepperPassword(secret, password), innerHash)
+}
+
+// NeedsRehash reports hashes without a pepper, hashes peppered with a key
+// other than the current one, and hashes the inner Hasher considers outdated.
+func (h *PepperedHasher) NeedsRehash(encodedHash string) bool {
+	if h == nil || h.inner == nil || h.keys == nil {
+		return false
+	}
+	if !strings.HasPrefix(encodedHash, PepperPrefix) {
+		return true
+	}
+
+	keyID, innerHash, err := parsePepperedHash(encodedHash)
+	if err != nil {
+		return true
+	}
+
+	current, err := h.keys.SigningKey(context.Background())
+	if err != nil {
+		return false
+	}
+	if strings.TrimSpace(current.ID) != keyID {
+		return true
+	}
+
+	rehasher, ok := h.inner.(Rehasher)
+	return ok && rehasher.NeedsRehash(innerHash)
+}
+
+func parsePepperedHash(encodedHash string) (string, string, error) {
+	parts := strings.SplitN(encodedHash, "$", 3)
+	if len(parts) != 3 || parts[0] != pepperScheme || parts[1] == "" || parts[2] == "" {
+		return "", "", ErrInvalidHash
+	}
+	return parts[1], parts[2], nil
+}
+
+// symmetricSecret returns the ID and material of a symmetric key, or
+// unavailable when key cannot be used for HMAC or encryption.
+func symmetricSecret(key session.Key, unavailable error) (string, []byte, error) {
+	keyID := strings.TrimSpace(key.ID)
+	if keyID == "" || strings.Contains(keyID, "$") || key.IsAsymmetric() || len(key.Material) == 0 {
+		return "", nil, unavailable
+	}
+	return keyID, key.Material, nil
+}
+
+func pepperPassword(secret []byte, password string) string {
+	mac := hmac.New(sha256.New, secret)
+	_, _ = mac.Write([]byte(password))
+	return base64.RawStdEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,167 @@
+package crypto
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/porthorian/openauth/pkg/session"
+	"github.com/porthorian/openauth/pkg/session/jwt"
+)
+
+func newPepperKeyring(t *testing.T, keyIDs ...string) *jwt.Keyring {
+	t.Helper()
+
+	entries := make([]jwt.KeyringEntry, 0, len(keyIDs))
+	for i, keyID := range keyIDs {
+		entries = append(entries, jwt.KeyringEntry{
+			Key:       session.Key{ID: keyID, Algorithm: "HS256", Material: []byte("pepper-secret-" + keyID)},
+			NotBefore: time.Now().Add(time.Duration(i-len(keyIDs)) * time.Minute),
+		})
+	}
+	keyring, err := jwt.NewKeyring(jwt.KeyringConfig{Keys: entries})
+	if err != nil {
+		t.Fatalf("NewKeyring returned error: %v", err)
+	}
+	return keyring
+}
+
+func TestPepperedHashAndVerify(t *testing.T) {
+	inner := NewPBKDF2Hasher(PBKDF2Options{Iterations: 1000})
+	hasher, err := NewPepperedHasher(inner, newPepperKeyring(t, "pepper-1"))
+	if err != nil {
+		t.Fatalf("NewPepperedHasher returned error: %v", err)
+	}
+
+	encoded, err := hasher.Hash("secret-pass")
+	if err != nil {
+		t.Fatalf("hash failed: %v", err)
+	}
+	if !strings.HasPrefix(encoded, "pepper$pepper-1$pbkdf2$") {
+		t.Fatalf("unexpected pepper encoding: %s", encoded)
+	}
+
+	ok, err := hasher.Verify("secret-pass", encoded)
+	if err != nil || !ok {
+		t.Fatalf("Verify = (%v, %v), want match", ok, err)
+	}
+	ok, err = hasher.Verify("wrong-pass", encoded)
+	if err != nil || ok {
+		t.Fatalf("Verify wrong password = (%v, %v), want mismatch", ok, err)
+	}
+
+	_, innerHash, err := parsePepperedHash(encoded)
+	if err != nil {
+		t.Fatalf("parsePepperedHash returned error: %v", err)
+	}
+	if ok, _ := inner.Verify("secret-pass", innerHash); ok {
+		t.Fatal("inner hash must not verify without the pepper")
+	}
+
+	if _, err := hasher.Verify("secret-pass", "pepper$unknown$"+innerHash); !errors.Is(err, ErrPepperKeyUnavailable) {
+		t.Fatalf("Verify with unknown pepper error = %v, want ErrPepperKeyUnavailable", err)
+	}
+}
+
+func TestPepperedHasherRotation(t *testing.T) {
+	inner := NewPBKDF2Hasher(PBKDF2Options{Iterations: 1000})
+
+	legacy, err := inner.Hash("secret-pass")
+	if err != nil {
+		t.Fatalf("hash failed: %v", err)
+	}
+
+	before, err := NewPepperedHasher(inner, newPepperKeyring(t, "pepper-1"))
+	if err != nil {
+		t.Fatalf("NewPepperedHasher returned error: %v", err)
+	}
+	old, err := before.Hash("secret-pass")
+	if err != nil {
+		t.Fatalf("hash failed: %v", err)
+	}
+
+	after, err := NewPepperedHasher(inner, newPepperKeyring(t, "pepper-1", "pepper-2"))
+	if err != nil {
+		t.Fatalf("NewPepperedHasher returned error: %v", err)
+	}
+
+	for name, encoded := range map[string]string{"legacy": legacy, "previous pepper": old} {
+		ok, err := after.Verify("secret-pass", encoded)
+		if err != nil || !ok {
+			t.Fatalf("%s Verify = (%v, %v), want match", name, ok, err)
+		}
+		if !after.NeedsRehash(encoded) {
+			t.Fatalf("expected %s hash to need rehash", name)
+		}
+	}
+
+	current, err := after.Hash("secret-pass")
+	if err != nil {
+		t.Fatalf("hash failed: %v", err)
+	}
+	if !strings.HasPrefix(current, "pepper$pepper-2$") {
+		t.Fatalf("expected new hashes to use the current pepper, got %s", current)
+	}
+	if after.NeedsRehash(current) {
+		t.Fatal("expected hash with current pepper to be up to date")
+	}
+}
+
+func TestPepperedHasherRequiresPepperKey(t *testing.T) {
+	keyring, err := jwt.NewKeyring(jwt.KeyringConfig{})
+	if err != nil {
+		t.Fatalf("NewKeyring returned error: %v", err)
+	}
+	hasher, err := NewPepperedHasher(NewPBKDF2Hasher(PBKDF2Options{Iterations: 1000}), keyring)
+	if err != nil {
+		t.Fatalf("NewPepperedHasher returned error: %v", err)
+	}
+
+	if _, err := hasher.Hash("secret-pass"); !errors.Is(err, ErrPepperKeyUnavailable) {
+		t.Fatalf("Hash without keys error = %v, want ErrPepperKeyUnavailable", err)
+	}
+	if _, err := NewPepperedHasher(nil, keyring); !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("NewPepperedHasher without inner error = %v, want ErrInvalidConfig", err)
+	}
+}
+
+func TestPepperedHasherVerifiesRetiredPeppers(t *testing.T) {
+	inner := NewPBKDF2Hasher(PBKDF2Options{Iterations: 1000})
+	keyring := newPepperKeyring(t, "pepper-1")
+	hasher, err := NewPepperedHasher(inner, keyring)
+	if err != nil {
+		t.Fatalf("NewPepperedHasher returned error: %v", err)
+	}
+	encoded, err := hasher.Hash("secret-pass")
+	if err != nil {
+		t.Fatalf("hash failed: %v", err)
+	}
+
+	retired := keyring.Entries()[0]
+	retired.NotBefore = time.Now().Add(-2 * jwt.DefaultMaxTokenTTL)
+	retired.NotAfter = time.Now().Add(-jwt.DefaultMaxTokenTTL - time.Hour)
+	if err := keyring.Replace([]jwt.KeyringEntry{retired, {
+		Key:       session.Key{ID: "pepper-2", Algorithm: "HS256", Material: []byte("pepper-secret-pepper-2")},
+		NotBefore: retired.NotAfter,
+	}}); err != nil {
+		t.Fatalf("Replace returned error: %v", err)
+	}
+	if _, err := keyring.ResolveKey(context.Background(), "pepper-1"); !errors.Is(err, jwt.ErrKeyRetired) {
+		t.Fatalf("keyring ResolveKey error = %v, want ErrKeyRetired", err)
+	}
+
+	ok, err := hasher.Verify("secret-pass", encoded)
+	if err != nil || !ok {
+		t.Fatalf("Verify with retired pepper = (%v, %v), want match", ok, err)
+	}
+	if !hasher.NeedsRehash(encoded) {
+		t.Fatal("expected hash with retired pepper to need rehash")
+	}
+
+	keyring.Remove("pepper-1")
+	if _, err := hasher.Verify("secret-pass", encoded); !errors.Is(err, ErrPepperKeyUnavailable) {
+		t.Fatalf("Verify with removed pepper error = %v, want ErrPepperKeyUnavailable", err)
+	}
+}
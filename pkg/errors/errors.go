@@ -2,6 +2,7 @@ package errors
 
 import (
 	"errors"
+	"time"
 )
 
 type Code string
@@ -15,6 +16,9 @@ const (
 	CodeNotFound           Code = "not_found"
 	CodeRole               Code = "role_error"
 	CodePermission         Code = "permission_error"
+	CodeAccountLocked      Code = "account_locked"
+	CodeMFARequired        Code = "mfa_required"
+	CodePolicyViolation    Code = "policy_violation"
 )
 
 const (
@@ -29,6 +33,19 @@ type Error struct {
 	Code    Code
 	Message string
 	Err     error
+	// RetryAfter tells callers how long to wait before retrying, for
+	// example while an account is locked. Zero means unknown.
+	RetryAfter time.Duration
+	// Violations lists the rules a rejected input broke, for example a
+	// password policy, so callers can explain every problem at once.
+	Violations []Violation
+}
+
+// Violation is one broken rule. Rule is a stable identifier such as
+// "min_length"; Message is a human readable explanation.
+type Violation struct {
+	Rule    string
+	Message string
 }
 
 func (e *Error) Error() string {
@@ -79,3 +96,35 @@ func IsCode(err error, code Code) bool {
 func IsInternalCode(err error) bool {
 	return IsCode(err, CodeUnknown) || IsCode(err, CodeStorageUnavailable) || IsCode(err, CodeNotImplemented)
 }
+
+// RetryAfter returns the RetryAfter hint of the first *Error in err's chain
+// that carries one.
+func RetryAfter(err error) (time.Duration, bool) {
+	for err != nil {
+		var typed *Error
+		if !errors.As(err, &typed) {
+			return 0, false
+		}
+		if typed.RetryAfter > 0 {
+			return typed.RetryAfter, true
+		}
+		err = typed.Err
+	}
+	return 0, false
+}
+
+// Violations returns the violations of the first *Error in err's chain that
+// carries any.
+func Violations(err error) ([]Violation, bool) {
+	for err != nil {
+		var typed *Error
+		if !errors.As(err, &typed) {
+			return nil, false
+		}
+		if len(typed.Violations) > 0 {
+			return typed.Violations, true
+		}
+		err = typed.Err
+	}
+	return nil, false
+}
@@ -0,0 +1,279 @@
+package file
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/porthorian/openauth/pkg/session"
+	"github.com/porthorian/openauth/pkg/session/jwt"
+)
+
+// PEM headers recognised on key blocks. They let a PEM file carry the same
+// metadata as a JSON bundle entry.
+const (
+	headerKeyID     = "Key-ID"
+	headerAlgorithm = "Algorithm"
+	headerNotBefore = "Not-Before"
+	headerNotAfter  = "Not-After"
+)
+
+type bundle struct {
+	Keys []bundleKey `json:"keys"`
+}
+
+// bundleKey is one entry of a JSON bundle. Asymmetric keys are PEM encoded;
+// HMAC secrets are base64 (standard or URL alphabet).
+type bundleKey struct {
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg,omitempty"`
+	NotBefore string `json:"not_before,omitempty"`
+	NotAfter  string `json:"not_after,omitempty"`
+	PEM       string `json:"pem,omitempty"`
+	Secret    string `json:"secret,omitempty"`
+}
+
+// loadPath reads a directory of *.pem/*.json files or a single bundle file.
+func loadPath(path string) ([]jwt.KeyringEntry, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrLoad, err)
+	}
+	if !info.IsDir() {
+		return loadFile(path)
+	}
+
+	files, err := keyFiles(path)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]jwt.KeyringEntry, 0, len(files))
+	for _, name := range files {
+		loaded, err := loadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, loaded...)
+	}
+	return entries, nil
+}
+
+func keyFiles(dir string) ([]string, error) {
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrLoad, err)
+	}
+
+	files := make([]string, 0, len(dirEntries))
+	for _, entry := range dirEntries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".pem", ".json":
+			files = append(files, filepath.Join(dir, entry.Name()))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func loadFile(name string) ([]jwt.KeyringEntry, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrLoad, err)
+	}
+
+	var entries []jwt.KeyringEntry
+	if strings.EqualFold(filepath.Ext(name), ".json") {
+		entries, err = parseBundle(data)
+	} else {
+		base := strings.TrimSuffix(filepath.Base(name), filepath.Ext(name))
+		entries, err = parsePEM(data, base)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return entries, nil
+}
+
+func parseBundle(data []byte) ([]jwt.KeyringEntry, error) {
+	var parsed bundle
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+	}
+
+	entries := make([]jwt.KeyringEntry, 0, len(parsed.Keys))
+	for _, raw := range parsed.Keys {
+		entry, err := raw.entry()
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func (b bundleKey) entry() (jwt.KeyringEntry, error) {
+	kid := strings.TrimSpace(b.KeyID)
+	if kid == "" {
+		return jwt.KeyringEntry{}, fmt.Errorf("%w: kid is required", ErrInvalidBundle)
+	}
+
+	key := session.Key{ID: kid, Algorithm: strings.TrimSpace(b.Algorithm)}
+	switch {
+	case b.PEM != "" && b.Secret != "":
+		return jwt.KeyringEntry{}, fmt.Errorf("%w: %s has both pem and secret", ErrInvalidBundle, kid)
+	case b.PEM != "":
+		block, _ := pem.Decode([]byte(b.PEM))
+		if block == nil {
+			return jwt.KeyringEntry{}, fmt.Errorf("%w: %s has no PEM block", ErrInvalidBundle, kid)
+		}
+		if err := applyPEMKey(&key, block); err != nil {
+			return jwt.KeyringEntry{}, fmt.Errorf("%s: %w", kid, err)
+		}
+	case b.Secret != "":
+		secret, err := decodeSecret(b.Secret)
+		if err != nil {
+			return jwt.KeyringEntry{}, fmt.Errorf("%w: %s secret: %v", ErrInvalidBundle, kid, err)
+		}
+		key.Material = secret
+	default:
+		return jwt.KeyringEntry{}, fmt.Errorf("%w: %s has no key material", ErrInvalidBundle, kid)
+	}
+
+	notBefore, err := parseWindow(b.NotBefore)
+	if err != nil {
+		return jwt.KeyringEntry{}, fmt.Errorf("%w: %s not_before: %v", ErrInvalidBundle, kid, err)
+	}
+	notAfter, err := parseWindow(b.NotAfter)
+	if err != nil {
+		return jwt.KeyringEntry{}, fmt.Errorf("%w: %s not_after: %v", ErrInvalidBundle, kid, err)
+	}
+
+	return jwt.KeyringEntry{Key: key, NotBefore: notBefore, NotAfter: notAfter}, nil
+}
+
+// parsePEM reads every key block in data. A lone block without a Key-ID
+// header takes its key ID from the file name.
+func parsePEM(data []byte, defaultKeyID string) ([]jwt.KeyringEntry, error) {
+	var blocks []*pem.Block
+	for rest := data; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		blocks = append(blocks, block)
+	}
+	if len(blocks) == 0 {
+		return nil, fmt.Errorf("%w: no PEM blocks found", ErrInvalidBundle)
+	}
+
+	entries := make([]jwt.KeyringEntry, 0, len(blocks))
+	for _, block := range blocks {
+		kid := strings.TrimSpace(block.Headers[headerKeyID])
+		if kid == "" {
+			if len(blocks) > 1 {
+				return nil, fmt.Errorf("%w: %s header is required when a file holds several keys", ErrInvalidBundle, headerKeyID)
+			}
+			kid = defaultKeyID
+		}
+
+		key := session.Key{ID: kid, Algorithm: strings.TrimSpace(block.Headers[headerAlgorithm])}
+		if err := applyPEMKey(&key, block); err != nil {
+			return nil, fmt.Errorf("%s: %w", kid, err)
+		}
+
+		notBefore, err := parseWindow(block.Headers[headerNotBefore])
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s %s: %v", ErrInvalidBundle, kid, headerNotBefore, err)
+		}
+		notAfter, err := parseWindow(block.Headers[headerNotAfter])
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s %s: %v", ErrInvalidBundle, kid, headerNotAfter, err)
+		}
+
+		entries = append(entries, jwt.KeyringEntry{Key: key, NotBefore: notBefore, NotAfter: notAfter})
+	}
+	return entries, nil
+}
+
+func applyPEMKey(key *session.Key, block *pem.Block) error {
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+		}
+		signer, err := asSigner(parsed)
+		if err != nil {
+			return err
+		}
+		key.PrivateKey = signer
+	case "RSA PRIVATE KEY":
+		parsed, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+		}
+		key.PrivateKey = parsed
+	case "EC PRIVATE KEY":
+		parsed, err := x509.ParseECPrivateKey(block.Bytes)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+		}
+		key.PrivateKey = parsed
+	case "PUBLIC KEY":
+		parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+		}
+		key.PublicKey = parsed
+	default:
+		return fmt.Errorf("%w: unsupported PEM block %q", ErrInvalidBundle, block.Type)
+	}
+	return nil
+}
+
+func asSigner(parsed any) (crypto.Signer, error) {
+	switch typed := parsed.(type) {
+	case *rsa.PrivateKey:
+		return typed, nil
+	case *ecdsa.PrivateKey:
+		return typed, nil
+	case ed25519.PrivateKey:
+		return typed, nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported private key type %T", ErrInvalidBundle, parsed)
+	}
+}
+
+func decodeSecret(value string) ([]byte, error) {
+	value = strings.TrimSpace(value)
+	for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if decoded, err := encoding.DecodeString(value); err == nil {
+			return decoded, nil
+		}
+	}
+	return nil, fmt.Errorf("secret is not valid base64")
+}
+
+func parseWindow(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
@@ -0,0 +1,268 @@
+package file
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/porthorian/openauth/pkg/session/jwt"
+)
+
+const defaultPollInterval = 30 * time.Second
+
+var (
+	ErrMissingPath   = errors.New("keystore/file: path is required")
+	ErrLoad          = errors.New("keystore/file: failed to read key material")
+	ErrInvalidBundle = errors.New("keystore/file: invalid key bundle")
+	ErrNoKeys        = errors.New("keystore/file: no keys found")
+	ErrClosed        = errors.New("keystore/file: store is closed")
+)
+
+// Config points the store at a directory of *.pem/*.json files or a single
+// bundle file.
+type Config struct {
+	Path string
+	// Keyring receives the loaded keys. A new keyring is created when nil.
+	Keyring *jwt.Keyring
+	// MaxTokenTTL and PublishAhead configure the keyring created when
+	// Keyring is nil. A zero MaxTokenTTL uses jwt.DefaultMaxTokenTTL.
+	MaxTokenTTL  time.Duration
+	PublishAhead time.Duration
+	// PollInterval is how often Watch checks the files for changes. Zero
+	// uses the default; a negative value disables polling.
+	PollInterval time.Duration
+	// ReloadSignals trigger a reload while watching. Defaults to SIGHUP.
+	ReloadSignals []os.Signal
+	Logger        logr.Logger
+}
+
+// Store loads signing keys from disk into a jwt.Keyring. A failed reload
+// keeps the previously loaded keys in place.
+type Store struct {
+	path          string
+	keyring       *jwt.Keyring
+	pollInterval  time.Duration
+	reloadSignals []os.Signal
+	logger        logr.Logger
+
+	mu          sync.Mutex
+	fingerprint []byte
+	watching    bool
+	closed      bool
+	stop        chan struct{}
+	done        chan struct{}
+}
+
+// New loads the keys once. Call Watch to keep them in sync with the files.
+func New(config Config) (*Store, error) {
+	if config.Path == "" {
+		return nil, ErrMissingPath
+	}
+
+	logger := config.Logger
+	if logger.GetSink() == nil {
+		logger = logr.Discard()
+	}
+
+	keyring := config.Keyring
+	if keyring == nil {
+		created, err := jwt.NewKeyring(jwt.KeyringConfig{
+			MaxTokenTTL:  config.MaxTokenTTL,
+			PublishAhead: config.PublishAhead,
+		})
+		if err != nil {
+			return nil, err
+		}
+		keyring = created
+	}
+
+	pollInterval := config.PollInterval
+	if pollInterval == 0 {
+		pollInterval = defaultPollInterval
+	}
+
+	reloadSignals := config.ReloadSignals
+	if reloadSignals == nil {
+		reloadSignals = []os.Signal{syscall.SIGHUP}
+	}
+
+	store := &Store{
+		path:          config.Path,
+		keyring:       keyring,
+		pollInterval:  pollInterval,
+		reloadSignals: reloadSignals,
+		logger:        logger,
+	}
+	if err := store.Reload(); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// Keyring returns the keyring the store loads into. Use it as
+// jwt.Config.KeyResolver.
+func (s *Store) Keyring() *jwt.Keyring {
+	return s.keyring
+}
+
+// Reload re-reads the files and swaps the keyring contents atomically.
+func (s *Store) Reload() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+	return s.reloadLocked()
+}
+
+func (s *Store) reloadLocked() error {
+	fingerprint, err := fingerprintPath(s.path)
+	if err != nil {
+		return err
+	}
+
+	entries, err := loadPath(s.path)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("%w: %s", ErrNoKeys, s.path)
+	}
+	if err := s.keyring.Replace(entries); err != nil {
+		return err
+	}
+
+	s.fingerprint = fingerprint
+	s.logger.V(1).Info("loaded signing keys", "path", s.path, "keys", len(entries))
+	return nil
+}
+
+// Watch reloads the keys on ReloadSignals and whenever the files change. It
+// returns immediately; Close stops the watcher.
+func (s *Store) Watch() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+	if s.watching {
+		return nil
+	}
+
+	signals := make(chan os.Signal, 1)
+	if len(s.reloadSignals) > 0 {
+		signal.Notify(signals, s.reloadSignals...)
+	}
+
+	var ticks <-chan time.Time
+	var ticker *time.Ticker
+	if s.pollInterval > 0 {
+		ticker = time.NewTicker(s.pollInterval)
+		ticks = ticker.C
+	}
+
+	s.watching = true
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+
+	go func(stop <-chan struct{}, done chan<- struct{}) {
+		defer close(done)
+		defer signal.Stop(signals)
+		if ticker != nil {
+			defer ticker.Stop()
+		}
+
+		for {
+			select {
+			case <-stop:
+				return
+			case sig := <-signals:
+				s.logger.V(1).Info("reloading signing keys", "path", s.path, "signal", sig.String())
+				if err := s.Reload(); err != nil && !errors.Is(err, ErrClosed) {
+					s.logger.Error(err, "failed to reload signing keys; keeping previous keys", "path", s.path)
+				}
+			case <-ticks:
+				if err := s.reloadIfChanged(); err != nil && !errors.Is(err, ErrClosed) {
+					s.logger.Error(err, "failed to reload signing keys; keeping previous keys", "path", s.path)
+				}
+			}
+		}
+	}(s.stop, s.done)
+
+	return nil
+}
+
+func (s *Store) reloadIfChanged() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+
+	fingerprint, err := fingerprintPath(s.path)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(fingerprint, s.fingerprint) {
+		return nil
+	}
+
+	s.logger.V(1).Info("signing key files changed", "path", s.path)
+	return s.reloadLocked()
+}
+
+// Close stops the watcher. The keyring keeps serving the last loaded keys.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	stop, done := s.stop, s.done
+	s.mu.Unlock()
+
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+	return nil
+}
+
+// fingerprintPath hashes the names and contents of the key files so polling
+// notices edits, additions and removals regardless of modification times.
+func fingerprintPath(path string) ([]byte, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrLoad, err)
+	}
+
+	files := []string{path}
+	if info.IsDir() {
+		files, err = keyFiles(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	hasher := sha256.New()
+	for _, name := range files {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrLoad, err)
+		}
+		_, _ = fmt.Fprintf(hasher, "%s\x00%d\x00", name, len(data))
+		_, _ = hasher.Write(data)
+	}
+	return hasher.Sum(nil), nil
+}
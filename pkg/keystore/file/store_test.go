@@ -0,0 +1,286 @@
+package file
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/porthorian/openauth/pkg/session"
+	"github.com/porthorian/openauth/pkg/session/jwt"
+)
+
+func TestNewLoadsDirectoryAndSignsTokens(t *testing.T) {
+	dir := t.TempDir()
+	writePEM(t, filepath.Join(dir, "ed-1.pem"), pkcs8Block(t, mustEd25519Key(t), nil))
+	writePEM(t, filepath.Join(dir, "ignored.txt"), pkcs8Block(t, mustEd25519Key(t), nil))
+
+	store, err := New(Config{Path: dir, PollInterval: -1})
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+
+	entries := store.Keyring().Entries()
+	if len(entries) != 1 || entries[0].Key.ID != "ed-1" {
+		t.Fatalf("entries = %+v, want single key ed-1", entries)
+	}
+
+	manager, err := jwt.NewManager(jwt.Config{Issuer: "openauth", KeyResolver: store.Keyring()})
+	if err != nil {
+		t.Fatalf("NewManager returned error: %v", err)
+	}
+
+	ctx := context.Background()
+	token, err := manager.IssueToken(ctx, "user-1", session.Claims{}, time.Minute)
+	if err != nil {
+		t.Fatalf("IssueToken returned error: %v", err)
+	}
+	if _, err := manager.ValidateToken(ctx, token); err != nil {
+		t.Fatalf("ValidateToken returned error: %v", err)
+	}
+}
+
+func TestNewLoadsPEMBundleWithHeaders(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey returned error: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey returned error: %v", err)
+	}
+	ecDER, err := x509.MarshalECPrivateKey(ecKey)
+	if err != nil {
+		t.Fatalf("MarshalECPrivateKey returned error: %v", err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(ecKey.Public())
+	if err != nil {
+		t.Fatalf("MarshalPKIXPublicKey returned error: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "keys.pem")
+	writePEM(t, path,
+		&pem.Block{Type: "RSA PRIVATE KEY", Headers: map[string]string{
+			headerKeyID:     "old",
+			headerAlgorithm: "PS256",
+			headerNotAfter:  now.Add(-time.Minute).Format(time.RFC3339),
+		}, Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)},
+		&pem.Block{Type: "EC PRIVATE KEY", Headers: map[string]string{
+			headerKeyID:     "current",
+			headerNotBefore: now.Add(-time.Hour).Format(time.RFC3339),
+		}, Bytes: ecDER},
+		&pem.Block{Type: "PUBLIC KEY", Headers: map[string]string{
+			headerKeyID: "partner",
+		}, Bytes: publicDER},
+	)
+
+	store, err := New(Config{Path: path, MaxTokenTTL: time.Hour, PollInterval: -1})
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+
+	keyring := store.Keyring()
+	if state, _ := keyring.State("old"); state != jwt.KeyStateRetiring {
+		t.Fatalf("old state = %q, want retiring", state)
+	}
+
+	signing, err := keyring.SigningKey(context.Background())
+	if err != nil {
+		t.Fatalf("SigningKey returned error: %v", err)
+	}
+	if signing.ID != "current" || signing.Algorithm != "ES256" {
+		t.Fatalf("signing key = %s/%s, want current/ES256", signing.ID, signing.Algorithm)
+	}
+
+	partner, err := keyring.ResolveKey(context.Background(), "partner")
+	if err != nil {
+		t.Fatalf("ResolveKey returned error: %v", err)
+	}
+	if partner.PrivateKey != nil || partner.PublicKey == nil {
+		t.Fatalf("partner key should be verification-only")
+	}
+}
+
+func TestNewLoadsJSONBundle(t *testing.T) {
+	edKey := mustEd25519Key(t)
+	der, err := x509.MarshalPKCS8PrivateKey(edKey)
+	if err != nil {
+		t.Fatalf("MarshalPKCS8PrivateKey returned error: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "keys.json")
+	writeJSON(t, path, bundle{Keys: []bundleKey{
+		{KeyID: "ed", PEM: string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))},
+		{KeyID: "hmac", Algorithm: "HS384", Secret: base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))},
+	}})
+
+	store, err := New(Config{Path: path, PollInterval: -1})
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+
+	hmacKey, err := store.Keyring().ResolveKey(context.Background(), "hmac")
+	if err != nil {
+		t.Fatalf("ResolveKey returned error: %v", err)
+	}
+	if hmacKey.Algorithm != "HS384" || string(hmacKey.Material) != "0123456789abcdef0123456789abcdef" {
+		t.Fatalf("hmac key = %+v, want decoded HS384 secret", hmacKey)
+	}
+	if _, err := store.Keyring().ResolveKey(context.Background(), "ed"); err != nil {
+		t.Fatalf("ResolveKey(ed) returned error: %v", err)
+	}
+}
+
+func TestNewRejectsInvalidMaterial(t *testing.T) {
+	dir := t.TempDir()
+
+	if _, err := New(Config{}); !errors.Is(err, ErrMissingPath) {
+		t.Fatalf("New without path error = %v, want ErrMissingPath", err)
+	}
+	if _, err := New(Config{Path: dir}); !errors.Is(err, ErrNoKeys) {
+		t.Fatalf("New on empty dir error = %v, want ErrNoKeys", err)
+	}
+
+	bad := filepath.Join(dir, "bad.json")
+	writeJSON(t, bad, bundle{Keys: []bundleKey{{KeyID: "k"}}})
+	if _, err := New(Config{Path: bad}); !errors.Is(err, ErrInvalidBundle) {
+		t.Fatalf("New on key without material error = %v, want ErrInvalidBundle", err)
+	}
+
+	multi := filepath.Join(dir, "multi.pem")
+	writePEM(t, multi, pkcs8Block(t, mustEd25519Key(t), nil), pkcs8Block(t, mustEd25519Key(t), nil))
+	if _, err := New(Config{Path: multi}); !errors.Is(err, ErrInvalidBundle) {
+		t.Fatalf("New on multi-key PEM without Key-ID error = %v, want ErrInvalidBundle", err)
+	}
+}
+
+func TestReloadKeepsPreviousKeysOnError(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.pem")
+	writePEM(t, path, pkcs8Block(t, mustEd25519Key(t), nil))
+
+	store, err := New(Config{Path: dir, PollInterval: -1})
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+
+	if err := os.WriteFile(path, []byte("not a key"), 0o600); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+	if err := store.Reload(); !errors.Is(err, ErrInvalidBundle) {
+		t.Fatalf("Reload error = %v, want ErrInvalidBundle", err)
+	}
+	if entries := store.Keyring().Entries(); len(entries) != 1 || entries[0].Key.ID != "a" {
+		t.Fatalf("entries = %+v, want previous key a", entries)
+	}
+}
+
+func TestWatchReloadsOnFileChange(t *testing.T) {
+	dir := t.TempDir()
+	writePEM(t, filepath.Join(dir, "a.pem"), pkcs8Block(t, mustEd25519Key(t), nil))
+
+	store, err := New(Config{Path: dir, PollInterval: 10 * time.Millisecond, ReloadSignals: []os.Signal{}})
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	if err := store.Watch(); err != nil {
+		t.Fatalf("Watch returned error: %v", err)
+	}
+
+	writePEM(t, filepath.Join(dir, "b.pem"), pkcs8Block(t, mustEd25519Key(t), nil))
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(store.Keyring().Entries()) != 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("watcher did not pick up new key file")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	if err := store.Reload(); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Reload after Close error = %v, want ErrClosed", err)
+	}
+}
+
+func TestNewLoadsIntoProvidedKeyring(t *testing.T) {
+	keyring, err := jwt.NewKeyring(jwt.KeyringConfig{})
+	if err != nil {
+		t.Fatalf("NewKeyring returned error: %v", err)
+	}
+
+	dir := t.TempDir()
+	writePEM(t, filepath.Join(dir, "a.pem"), pkcs8Block(t, mustEd25519Key(t), map[string]string{headerKeyID: "custom"}))
+
+	store, err := New(Config{Path: dir, Keyring: keyring, PollInterval: -1})
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+
+	if store.Keyring() != keyring {
+		t.Fatalf("expected store to load into the provided keyring")
+	}
+	if _, ok := keyring.State("custom"); !ok {
+		t.Fatalf("expected Key-ID header to override file name")
+	}
+}
+
+func mustEd25519Key(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey returned error: %v", err)
+	}
+	return key
+}
+
+func pkcs8Block(t *testing.T, key any, headers map[string]string) *pem.Block {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalPKCS8PrivateKey returned error: %v", err)
+	}
+	return &pem.Block{Type: "PRIVATE KEY", Headers: headers, Bytes: der}
+}
+
+func writePEM(t *testing.T, path string, blocks ...*pem.Block) {
+	t.Helper()
+	var data []byte
+	for _, block := range blocks {
+		data = append(data, pem.EncodeToMemory(block)...)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+}
+
+func writeJSON(t *testing.T, path string, value any) {
+	t.Helper()
+	data, err := json.Marshal(value)
+	if err != nil {
+		t.Fatalf("Marshal returned error: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+}
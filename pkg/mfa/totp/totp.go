@@ -0,0 +1,186 @@
+// Package totp implements RFC 6238 time-based one-time passwords using
+// HMAC-SHA1, the variant supported by common authenticator apps.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// SecretBytes is the secret length recommended by RFC 4226 for HMAC-SHA1.
+	SecretBytes = 20
+
+	defaultDigits = 6
+	defaultPeriod = 30 * time.Second
+	defaultSkew   = 1
+)
+
+var (
+	ErrInvalidSecret  = errors.New("totp: invalid secret")
+	ErrInvalidOptions = errors.New("totp: invalid options")
+)
+
+var secretEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// Options control code generation. Skew is the number of periods accepted
+// on either side of the current one to absorb clock drift.
+type Options struct {
+	Digits int
+	Period time.Duration
+	Skew   int
+}
+
+func DefaultOptions() Options {
+	return Options{
+		Digits: defaultDigits,
+		Period: defaultPeriod,
+		Skew:   defaultSkew,
+	}
+}
+
+func (o Options) normalize() (Options, error) {
+	if o.Digits == 0 {
+		o.Digits = defaultDigits
+	}
+	if o.Period == 0 {
+		o.Period = defaultPeriod
+	}
+	if o.Digits < 6 || o.Digits > 8 || o.Period < time.Second || o.Skew < 0 {
+		return Options{}, ErrInvalidOptions
+	}
+	return o, nil
+}
+
+// GenerateSecret returns SecretBytes random bytes.
+func GenerateSecret() ([]byte, error) {
+	secret := make([]byte, SecretBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+// EncodeSecret returns the unpadded base32 form users type into
+// authenticator apps.
+func EncodeSecret(secret []byte) string {
+	return secretEncoding.EncodeToString(secret)
+}
+
+func DecodeSecret(encoded string) ([]byte, error) {
+	normalized := strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(encoded), " ", ""))
+	secret, err := secretEncoding.DecodeString(strings.TrimRight(normalized, "="))
+	if err != nil || len(secret) == 0 {
+		return nil, ErrInvalidSecret
+	}
+	return secret, nil
+}
+
+// Step returns the time step counter for at.
+func Step(at time.Time, options Options) (int64, error) {
+	options, err := options.normalize()
+	if err != nil {
+		return 0, err
+	}
+	return at.Unix() / int64(options.Period/time.Second), nil
+}
+
+// Code returns the code for the time step containing at.
+func Code(secret []byte, at time.Time, options Options) (string, error) {
+	options, err := options.normalize()
+	if err != nil {
+		return "", err
+	}
+	if len(secret) == 0 {
+		return "", ErrInvalidSecret
+	}
+	step, _ := Step(at, options)
+	return codeAt(secret, step, options.Digits), nil
+}
+
+// Verify checks code against the steps within options.Skew of at and returns
+// the matching step. Callers should reject steps at or before the last step
+// they accepted so a code cannot be replayed.
+func Verify(secret []byte, code string, at time.Time, options Options) (int64, bool, error) {
+	options, err := options.normalize()
+	if err != nil {
+		return 0, false, err
+	}
+	if len(secret) == 0 {
+		return 0, false, ErrInvalidSecret
+	}
+
+	code = strings.TrimSpace(code)
+	if len(code) != options.Digits {
+		return 0, false, nil
+	}
+
+	current, _ := Step(at, options)
+	var matched int64
+	found := 0
+	for offset := -int64(options.Skew); offset <= int64(options.Skew); offset++ {
+		step := current + offset
+		// Compare every candidate so timing does not reveal which step matched.
+		if subtle.ConstantTimeCompare([]byte(codeAt(secret, step, options.Digits)), []byte(code)) == 1 && found == 0 {
+			matched = step
+			found = 1
+		}
+	}
+	return matched, found == 1, nil
+}
+
+// KeyURI returns an otpauth:// URI for QR code enrolment.
+func KeyURI(issuer string, account string, secret []byte, options Options) (string, error) {
+	options, err := options.normalize()
+	if err != nil {
+		return "", err
+	}
+	if len(secret) == 0 {
+		return "", ErrInvalidSecret
+	}
+
+	label := url.PathEscape(account)
+	if issuer != "" {
+		label = url.PathEscape(issuer) + ":" + label
+	}
+
+	query := url.Values{}
+	query.Set("secret", EncodeSecret(secret))
+	if issuer != "" {
+		query.Set("issuer", issuer)
+	}
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", strconv.Itoa(options.Digits))
+	query.Set("period", strconv.Itoa(int(options.Period/time.Second)))
+
+	return "otpauth://totp/" + label + "?" + query.Encode(), nil
+}
+
+func codeAt(secret []byte, step int64, digits int) string {
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+
+	mac := hmac.New(sha1.New, secret)
+	_, _ = mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	modulus := uint32(1)
+	for i := 0; i < digits; i++ {
+		modulus *= 10
+	}
+
+	code := strconv.FormatUint(uint64(value%modulus), 10)
+	return strings.Repeat("0", digits-len(code)) + code
+}
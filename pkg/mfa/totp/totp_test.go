@@ -0,0 +1,83 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCodeMatchesRFC6238Vectors(t *testing.T) {
+	secret := []byte("12345678901234567890")
+	options := Options{Digits: 8, Period: 30 * time.Second}
+
+	for unix, want := range map[int64]string{
+		59:          "94287082",
+		1111111109:  "07081804",
+		1111111111:  "14050471",
+		1234567890:  "89005924",
+		2000000000:  "69279037",
+		20000000000: "65353130",
+	} {
+		got, err := Code(secret, time.Unix(unix, 0), options)
+		if err != nil {
+			t.Fatalf("Code returned error: %v", err)
+		}
+		if got != want {
+			t.Fatalf("Code(%d) = %s, want %s", unix, got, want)
+		}
+	}
+}
+
+func TestVerifyAcceptsSkewAndReportsStep(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret returned error: %v", err)
+	}
+	now := time.Unix(1700000000, 0)
+	options := DefaultOptions()
+
+	previous, err := Code(secret, now.Add(-30*time.Second), options)
+	if err != nil {
+		t.Fatalf("Code returned error: %v", err)
+	}
+	step, ok, err := Verify(secret, previous, now, options)
+	if err != nil || !ok {
+		t.Fatalf("Verify previous step = (%v, %v), want match", ok, err)
+	}
+	current, _ := Step(now, options)
+	if step != current-1 {
+		t.Fatalf("matched step = %d, want %d", step, current-1)
+	}
+
+	stale, _ := Code(secret, now.Add(-90*time.Second), options)
+	if _, ok, _ := Verify(secret, stale, now, options); ok {
+		t.Fatalf("expected code outside skew to be rejected")
+	}
+	if _, ok, _ := Verify(secret, "12345", now, options); ok {
+		t.Fatalf("expected short code to be rejected")
+	}
+}
+
+func TestSecretEncodingAndKeyURI(t *testing.T) {
+	secret := []byte("12345678901234567890")
+	encoded := EncodeSecret(secret)
+	if encoded != "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ" {
+		t.Fatalf("EncodeSecret = %s", encoded)
+	}
+	decoded, err := DecodeSecret(strings.ToLower(encoded[:4]) + " " + encoded[4:])
+	if err != nil || string(decoded) != string(secret) {
+		t.Fatalf("DecodeSecret = (%q, %v), want original secret", decoded, err)
+	}
+
+	uri, err := KeyURI("Example Co", "user@example.com", secret, DefaultOptions())
+	if err != nil {
+		t.Fatalf("KeyURI returned error: %v", err)
+	}
+	if !strings.HasPrefix(uri, "otpauth://totp/Example%20Co:user@example.com?") || !strings.Contains(uri, "secret="+encoded) {
+		t.Fatalf("unexpected key URI: %s", uri)
+	}
+
+	if _, err := Code(secret, time.Now(), Options{Digits: 4}); err != ErrInvalidOptions {
+		t.Fatalf("expected invalid options, got %v", err)
+	}
+}
@@ -0,0 +1,124 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/asn1"
+	"slices"
+)
+
+type AttestationType string
+
+const (
+	AttestationTypeNone  AttestationType = "none"
+	AttestationTypeSelf  AttestationType = "self"
+	AttestationTypeBasic AttestationType = "basic"
+)
+
+const (
+	AttestationFormatNone   = "none"
+	AttestationFormatPacked = "packed"
+)
+
+// oidFIDOAAGUID is id-fido-gen-ce-aaguid; when present in a packed
+// attestation certificate it must match the authenticator data AAGUID.
+var oidFIDOAAGUID = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 45724, 1, 1, 4}
+
+// Attestation describes how the authenticator vouched for a new credential.
+// For AttestationTypeBasic the signature was checked against Certificates[0]
+// but the chain is not; relying parties that restrict authenticator models
+// must validate Certificates against their own roots.
+type Attestation struct {
+	Format       string
+	Type         AttestationType
+	Certificates []*x509.Certificate
+}
+
+func verifyAttestation(format string, statement map[any]any, authData []byte, clientDataHash []byte, credentialKey PublicKey, aaguid []byte) (Attestation, error) {
+	switch format {
+	case AttestationFormatNone:
+		if len(statement) != 0 {
+			return Attestation{}, ErrInvalidAttestation
+		}
+		return Attestation{Format: format, Type: AttestationTypeNone}, nil
+	case AttestationFormatPacked:
+		return verifyPackedAttestation(statement, authData, clientDataHash, credentialKey, aaguid)
+	}
+	return Attestation{}, ErrUnsupportedAttestation
+}
+
+// verifyPackedAttestation implements the packed statement format for self
+// attestation and x5c basic attestation. ECDAA is not supported.
+func verifyPackedAttestation(statement map[any]any, authData []byte, clientDataHash []byte, credentialKey PublicKey, aaguid []byte) (Attestation, error) {
+	algorithm, ok := statement["alg"].(int64)
+	signature, _ := statement["sig"].([]byte)
+	if !ok || len(signature) == 0 {
+		return Attestation{}, ErrInvalidAttestation
+	}
+	if _, ok := statement["ecdaaKeyId"]; ok {
+		return Attestation{}, ErrUnsupportedAttestation
+	}
+	signed := append(append([]byte(nil), authData...), clientDataHash...)
+
+	rawChain, hasChain := statement["x5c"]
+	if !hasChain {
+		if COSEAlgorithm(algorithm) != credentialKey.Algorithm {
+			return Attestation{}, ErrInvalidAttestation
+		}
+		if err := credentialKey.Verify(signed, signature); err != nil {
+			return Attestation{}, ErrInvalidAttestation
+		}
+		return Attestation{Format: AttestationFormatPacked, Type: AttestationTypeSelf}, nil
+	}
+
+	chain, ok := rawChain.([]any)
+	if !ok || len(chain) == 0 {
+		return Attestation{}, ErrInvalidAttestation
+	}
+	certificates := make([]*x509.Certificate, 0, len(chain))
+	for _, entry := range chain {
+		der, ok := entry.([]byte)
+		if !ok {
+			return Attestation{}, ErrInvalidAttestation
+		}
+		certificate, err := x509.ParseCertificate(der)
+		if err != nil {
+			return Attestation{}, ErrInvalidAttestation
+		}
+		certificates = append(certificates, certificate)
+	}
+
+	leaf := certificates[0]
+	if err := verifySignature(COSEAlgorithm(algorithm), leaf.PublicKey, signed, signature); err != nil {
+		return Attestation{}, ErrInvalidAttestation
+	}
+	if err := checkPackedCertificate(leaf, aaguid); err != nil {
+		return Attestation{}, err
+	}
+	return Attestation{Format: AttestationFormatPacked, Type: AttestationTypeBasic, Certificates: certificates}, nil
+}
+
+// checkPackedCertificate applies the packed attestation certificate
+// requirements from WebAuthn Level 2 section 8.2.1.
+func checkPackedCertificate(certificate *x509.Certificate, aaguid []byte) error {
+	subject := certificate.Subject
+	if certificate.Version != 3 || certificate.IsCA ||
+		len(subject.Country) == 0 || len(subject.Organization) == 0 || subject.CommonName == "" ||
+		!slices.Contains(subject.OrganizationalUnit, "Authenticator Attestation") {
+		return ErrInvalidAttestation
+	}
+
+	for _, extension := range certificate.Extensions {
+		if !extension.Id.Equal(oidFIDOAAGUID) {
+			continue
+		}
+		var value []byte
+		if rest, err := asn1.Unmarshal(extension.Value, &value); err != nil || len(rest) != 0 || extension.Critical {
+			return ErrInvalidAttestation
+		}
+		if !bytes.Equal(value, aaguid) {
+			return ErrInvalidAttestation
+		}
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package webauthn
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// maxCBORDepth bounds nesting so hostile attestation objects cannot exhaust
+// the stack. Real authenticators nest at most three levels.
+const maxCBORDepth = 16
+
+var errCBOR = errors.New("webauthn: malformed cbor")
+
+// decodeCBOR decodes the first CBOR data item in data and returns it with
+// the remaining bytes. It supports the definite-length subset CTAP2 emits:
+// integers as int64, byte strings as []byte, text as string, arrays as
+// []any, maps as map[any]any keyed by int64 or string, and simple values.
+func decodeCBOR(data []byte) (any, []byte, error) {
+	return decodeCBORItem(data, 0)
+}
+
+func decodeCBORItem(data []byte, depth int) (any, []byte, error) {
+	if depth > maxCBORDepth || len(data) == 0 {
+		return nil, nil, errCBOR
+	}
+
+	major := data[0] >> 5
+	info := data[0] & 0x1f
+	argument, rest, err := cborArgument(info, data[1:])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch major {
+	case 0:
+		if argument > math.MaxInt64 {
+			return nil, nil, errCBOR
+		}
+		return int64(argument), rest, nil
+	case 1:
+		if argument > math.MaxInt64 {
+			return nil, nil, errCBOR
+		}
+		return -1 - int64(argument), rest, nil
+	case 2, 3:
+		if argument > uint64(len(rest)) {
+			return nil, nil, errCBOR
+		}
+		value := rest[:argument]
+		if major == 3 {
+			return string(value), rest[argument:], nil
+		}
+		return append([]byte(nil), value...), rest[argument:], nil
+	case 4:
+		if argument > uint64(len(rest)) {
+			return nil, nil, errCBOR
+		}
+		items := make([]any, 0, argument)
+		for i := uint64(0); i < argument; i++ {
+			var item any
+			item, rest, err = decodeCBORItem(rest, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			items = append(items, item)
+		}
+		return items, rest, nil
+	case 5:
+		if argument > uint64(len(rest)) {
+			return nil, nil, errCBOR
+		}
+		entries := make(map[any]any, argument)
+		for i := uint64(0); i < argument; i++ {
+			var key, value any
+			key, rest, err = decodeCBORItem(rest, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			switch key.(type) {
+			case int64, string:
+			default:
+				return nil, nil, errCBOR
+			}
+			if _, duplicate := entries[key]; duplicate {
+				return nil, nil, errCBOR
+			}
+			value, rest, err = decodeCBORItem(rest, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			entries[key] = value
+		}
+		return entries, rest, nil
+	case 7:
+		switch info {
+		case 20:
+			return false, rest, nil
+		case 21:
+			return true, rest, nil
+		case 22:
+			return nil, rest, nil
+		}
+	}
+	return nil, nil, errCBOR
+}
+
+// cborArgument reads the argument encoded by the additional information
+// bits. Indefinite lengths are rejected.
+func cborArgument(info byte, data []byte) (uint64, []byte, error) {
+	switch {
+	case info < 24:
+		return uint64(info), data, nil
+	case info == 24 && len(data) >= 1:
+		return uint64(data[0]), data[1:], nil
+	case info == 25 && len(data) >= 2:
+		return uint64(binary.BigEndian.Uint16(data)), data[2:], nil
+	case info == 26 && len(data) >= 4:
+		return uint64(binary.BigEndian.Uint32(data)), data[4:], nil
+	case info == 27 && len(data) >= 8:
+		return binary.BigEndian.Uint64(data), data[8:], nil
+	}
+	return 0, nil, errCBOR
+}
+
+// decodeCBORMap decodes data as exactly one CBOR map.
+func decodeCBORMap(data []byte) (map[any]any, error) {
+	value, rest, err := decodeCBOR(data)
+	if err != nil {
+		return nil, err
+	}
+	entries, ok := value.(map[any]any)
+	if !ok || len(rest) != 0 {
+		return nil, errCBOR
+	}
+	return entries, nil
+}
@@ -0,0 +1,143 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"math/big"
+)
+
+// COSEAlgorithm identifies a signature algorithm in the IANA COSE
+// Algorithms registry.
+type COSEAlgorithm int64
+
+const (
+	AlgorithmES256 COSEAlgorithm = -7
+	AlgorithmEdDSA COSEAlgorithm = -8
+	AlgorithmRS256 COSEAlgorithm = -257
+)
+
+// SupportedAlgorithms lists the algorithms offered at registration, in order
+// of preference.
+func SupportedAlgorithms() []COSEAlgorithm {
+	return []COSEAlgorithm{AlgorithmES256, AlgorithmEdDSA, AlgorithmRS256}
+}
+
+const (
+	coseKeyType      = 1
+	coseKeyAlgorithm = 3
+	coseKeyCurve     = -1 // also the RSA modulus
+	coseKeyX         = -2 // also the RSA exponent
+	coseKeyY         = -3
+
+	coseKeyTypeOKP = 1
+	coseKeyTypeEC2 = 2
+	coseKeyTypeRSA = 3
+
+	coseCurveP256    = 1
+	coseCurveEd25519 = 6
+
+	minRSAKeyBits = 2048
+)
+
+// PublicKey is a credential public key decoded from its COSE_Key form.
+type PublicKey struct {
+	Algorithm COSEAlgorithm
+	Key       crypto.PublicKey
+}
+
+// ParsePublicKey decodes a COSE_Key as found in attested credential data.
+func ParsePublicKey(data []byte) (PublicKey, error) {
+	entries, err := decodeCBORMap(data)
+	if err != nil {
+		return PublicKey{}, ErrInvalidPublicKey
+	}
+
+	keyType, _ := entries[int64(coseKeyType)].(int64)
+	algorithm, ok := entries[int64(coseKeyAlgorithm)].(int64)
+	if !ok {
+		return PublicKey{}, ErrInvalidPublicKey
+	}
+
+	switch COSEAlgorithm(algorithm) {
+	case AlgorithmES256:
+		curve, _ := entries[int64(coseKeyCurve)].(int64)
+		x, _ := entries[int64(coseKeyX)].([]byte)
+		y, _ := entries[int64(coseKeyY)].([]byte)
+		if keyType != coseKeyTypeEC2 || curve != coseCurveP256 || len(x) != 32 || len(y) != 32 {
+			return PublicKey{}, ErrInvalidPublicKey
+		}
+		key, err := ecdsa.ParseUncompressedPublicKey(elliptic.P256(), append(append([]byte{4}, x...), y...))
+		if err != nil {
+			return PublicKey{}, ErrInvalidPublicKey
+		}
+		return PublicKey{Algorithm: AlgorithmES256, Key: key}, nil
+	case AlgorithmEdDSA:
+		curve, _ := entries[int64(coseKeyCurve)].(int64)
+		x, _ := entries[int64(coseKeyX)].([]byte)
+		if keyType != coseKeyTypeOKP || curve != coseCurveEd25519 || len(x) != ed25519.PublicKeySize {
+			return PublicKey{}, ErrInvalidPublicKey
+		}
+		return PublicKey{Algorithm: AlgorithmEdDSA, Key: ed25519.PublicKey(x)}, nil
+	case AlgorithmRS256:
+		modulus, _ := entries[int64(coseKeyCurve)].([]byte)
+		exponent, _ := entries[int64(coseKeyX)].([]byte)
+		if keyType != coseKeyTypeRSA || len(exponent) == 0 || len(exponent) > 4 {
+			return PublicKey{}, ErrInvalidPublicKey
+		}
+		e := 0
+		for _, b := range exponent {
+			e = e<<8 | int(b)
+		}
+		key := &rsa.PublicKey{N: new(big.Int).SetBytes(modulus), E: e}
+		if key.N.BitLen() < minRSAKeyBits || e < 3 || e%2 == 0 {
+			return PublicKey{}, ErrInvalidPublicKey
+		}
+		return PublicKey{Algorithm: AlgorithmRS256, Key: key}, nil
+	}
+	return PublicKey{}, ErrUnsupportedAlgorithm
+}
+
+// Verify checks signature over message as produced by an authenticator.
+// ECDSA signatures are ASN.1 DER encoded, as WebAuthn requires.
+func (k PublicKey) Verify(message []byte, signature []byte) error {
+	return verifySignature(k.Algorithm, k.Key, message, signature)
+}
+
+func verifySignature(algorithm COSEAlgorithm, key crypto.PublicKey, message []byte, signature []byte) error {
+	switch algorithm {
+	case AlgorithmES256:
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		if !ok || ecKey.Curve != elliptic.P256() {
+			return ErrUnsupportedAlgorithm
+		}
+		digest := sha256.Sum256(message)
+		if !ecdsa.VerifyASN1(ecKey, digest[:], signature) {
+			return ErrInvalidSignature
+		}
+		return nil
+	case AlgorithmEdDSA:
+		edKey, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return ErrUnsupportedAlgorithm
+		}
+		if !ed25519.Verify(edKey, message, signature) {
+			return ErrInvalidSignature
+		}
+		return nil
+	case AlgorithmRS256:
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return ErrUnsupportedAlgorithm
+		}
+		digest := sha256.Sum256(message)
+		if err := rsa.VerifyPKCS1v15(rsaKey, crypto.SHA256, digest[:], signature); err != nil {
+			return ErrInvalidSignature
+		}
+		return nil
+	}
+	return ErrUnsupportedAlgorithm
+}
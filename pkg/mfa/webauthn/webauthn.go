@@ -0,0 +1,474 @@
+// Package webauthn implements the relying party side of WebAuthn
+// registration and authentication ceremonies: challenges, "none" and
+// "packed" attestation, assertion signatures, and signature counters.
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+)
+
+const (
+	// ChallengeBytes is the challenge length; WebAuthn asks for at least 16.
+	ChallengeBytes = 32
+
+	CredentialTypePublicKey = "public-key"
+
+	ceremonyCreate = "webauthn.create"
+	ceremonyGet    = "webauthn.get"
+
+	flagUserPresent   = 0x01
+	flagUserVerified  = 0x04
+	flagAttestedData  = 0x40
+	flagExtensionData = 0x80
+
+	authDataMinLength = 37
+	aaguidLength      = 16
+)
+
+var (
+	ErrInvalidResponse          = errors.New("webauthn: malformed credential response")
+	ErrInvalidClientData        = errors.New("webauthn: invalid client data")
+	ErrChallengeMismatch        = errors.New("webauthn: challenge mismatch")
+	ErrOriginNotAllowed         = errors.New("webauthn: origin not allowed")
+	ErrInvalidAuthenticatorData = errors.New("webauthn: invalid authenticator data")
+	ErrRPIDMismatch             = errors.New("webauthn: rp id hash mismatch")
+	ErrUserNotPresent           = errors.New("webauthn: user presence not asserted")
+	ErrUserNotVerified          = errors.New("webauthn: user verification required")
+	ErrCredentialMismatch       = errors.New("webauthn: credential id mismatch")
+	ErrInvalidAttestation       = errors.New("webauthn: invalid attestation")
+	ErrUnsupportedAttestation   = errors.New("webauthn: unsupported attestation format")
+	ErrInvalidPublicKey         = errors.New("webauthn: invalid credential public key")
+	ErrUnsupportedAlgorithm     = errors.New("webauthn: unsupported public key algorithm")
+	ErrInvalidSignature         = errors.New("webauthn: invalid signature")
+	ErrSignCountRegression      = errors.New("webauthn: signature counter did not increase")
+)
+
+// Bytes marshals as unpadded base64url, the encoding WebAuthn JSON uses for
+// binary fields. Padded input is accepted when unmarshalling.
+type Bytes []byte
+
+func (b Bytes) MarshalJSON() ([]byte, error) {
+	return json.Marshal(base64.RawURLEncoding.EncodeToString(b))
+}
+
+func (b *Bytes) UnmarshalJSON(data []byte) error {
+	var encoded string
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return err
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(encoded, "="))
+	if err != nil {
+		return err
+	}
+	*b = decoded
+	return nil
+}
+
+type UserVerification string
+
+const (
+	UserVerificationRequired    UserVerification = "required"
+	UserVerificationPreferred   UserVerification = "preferred"
+	UserVerificationDiscouraged UserVerification = "discouraged"
+)
+
+// RelyingParty describes the site credentials are scoped to.
+type RelyingParty struct {
+	ID      string   // ID is the RP ID, a registrable domain such as "example.com".
+	Name    string   // Name is shown by authenticators during registration.
+	Origins []string // Origins lists accepted client origins such as "https://example.com".
+	// UserVerification is requested from authenticators and, when required,
+	// enforced on every response. Empty means preferred.
+	UserVerification UserVerification
+}
+
+func (rp RelyingParty) userVerification() UserVerification {
+	if rp.UserVerification == "" {
+		return UserVerificationPreferred
+	}
+	return rp.UserVerification
+}
+
+type RelyingPartyEntity struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type UserEntity struct {
+	ID          Bytes  `json:"id"` // ID is the opaque user handle; it must not contain personal data.
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+type CredentialParameter struct {
+	Type      string        `json:"type"`
+	Algorithm COSEAlgorithm `json:"alg"`
+}
+
+type CredentialDescriptor struct {
+	Type string `json:"type"`
+	ID   Bytes  `json:"id"`
+}
+
+type AuthenticatorSelection struct {
+	ResidentKey      string           `json:"residentKey,omitempty"`
+	UserVerification UserVerification `json:"userVerification,omitempty"`
+}
+
+// CreationOptions is the JSON form of PublicKeyCredentialCreationOptions,
+// ready for PublicKeyCredential.parseCreationOptionsFromJSON.
+type CreationOptions struct {
+	RP                     RelyingPartyEntity     `json:"rp"`
+	User                   UserEntity             `json:"user"`
+	Challenge              Bytes                  `json:"challenge"`
+	PubKeyCredParams       []CredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout,omitempty"` // Timeout is in milliseconds.
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials,omitempty"`
+	AuthenticatorSelection AuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation,omitempty"`
+}
+
+// RequestOptions is the JSON form of PublicKeyCredentialRequestOptions,
+// ready for PublicKeyCredential.parseRequestOptionsFromJSON.
+type RequestOptions struct {
+	Challenge        Bytes                  `json:"challenge"`
+	Timeout          int64                  `json:"timeout,omitempty"` // Timeout is in milliseconds.
+	RPID             string                 `json:"rpId"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials,omitempty"`
+	UserVerification UserVerification       `json:"userVerification,omitempty"`
+}
+
+// NewChallenge returns ChallengeBytes random bytes.
+func NewChallenge() ([]byte, error) {
+	challenge := make([]byte, ChallengeBytes)
+	if _, err := rand.Read(challenge); err != nil {
+		return nil, err
+	}
+	return challenge, nil
+}
+
+// CreationOptions builds registration options. exclude lists credential IDs
+// the user already registered so authenticators do not create duplicates.
+func (rp RelyingParty) CreationOptions(user UserEntity, challenge []byte, exclude [][]byte) CreationOptions {
+	params := make([]CredentialParameter, 0, len(SupportedAlgorithms()))
+	for _, algorithm := range SupportedAlgorithms() {
+		params = append(params, CredentialParameter{Type: CredentialTypePublicKey, Algorithm: algorithm})
+	}
+
+	return CreationOptions{
+		RP:                 RelyingPartyEntity{ID: rp.ID, Name: rp.Name},
+		User:               user,
+		Challenge:          challenge,
+		PubKeyCredParams:   params,
+		ExcludeCredentials: descriptors(exclude),
+		AuthenticatorSelection: AuthenticatorSelection{
+			ResidentKey:      "preferred",
+			UserVerification: rp.userVerification(),
+		},
+		Attestation: "none",
+	}
+}
+
+// RequestOptions builds authentication options limited to allow.
+func (rp RelyingParty) RequestOptions(challenge []byte, allow [][]byte) RequestOptions {
+	return RequestOptions{
+		Challenge:        challenge,
+		RPID:             rp.ID,
+		AllowCredentials: descriptors(allow),
+		UserVerification: rp.userVerification(),
+	}
+}
+
+func descriptors(ids [][]byte) []CredentialDescriptor {
+	if len(ids) == 0 {
+		return nil
+	}
+	out := make([]CredentialDescriptor, 0, len(ids))
+	for _, id := range ids {
+		out = append(out, CredentialDescriptor{Type: CredentialTypePublicKey, ID: id})
+	}
+	return out
+}
+
+// RegistrationResponse is the JSON form of the PublicKeyCredential returned
+// by navigator.credentials.create.
+type RegistrationResponse struct {
+	ID       string `json:"id"`
+	RawID    Bytes  `json:"rawId"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    Bytes `json:"clientDataJSON"`
+		AttestationObject Bytes `json:"attestationObject"`
+	} `json:"response"`
+}
+
+// AuthenticationResponse is the JSON form of the PublicKeyCredential
+// returned by navigator.credentials.get.
+type AuthenticationResponse struct {
+	ID       string `json:"id"`
+	RawID    Bytes  `json:"rawId"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    Bytes `json:"clientDataJSON"`
+		AuthenticatorData Bytes `json:"authenticatorData"`
+		Signature         Bytes `json:"signature"`
+		UserHandle        Bytes `json:"userHandle,omitempty"`
+	} `json:"response"`
+}
+
+func ParseRegistrationResponse(data []byte) (RegistrationResponse, error) {
+	var response RegistrationResponse
+	if err := json.Unmarshal(data, &response); err != nil {
+		return RegistrationResponse{}, ErrInvalidResponse
+	}
+	if !validCredentialID(response.ID, response.RawID, response.Type) ||
+		len(response.Response.ClientDataJSON) == 0 || len(response.Response.AttestationObject) == 0 {
+		return RegistrationResponse{}, ErrInvalidResponse
+	}
+	return response, nil
+}
+
+func ParseAuthenticationResponse(data []byte) (AuthenticationResponse, error) {
+	var response AuthenticationResponse
+	if err := json.Unmarshal(data, &response); err != nil {
+		return AuthenticationResponse{}, ErrInvalidResponse
+	}
+	if !validCredentialID(response.ID, response.RawID, response.Type) || len(response.Response.ClientDataJSON) == 0 ||
+		len(response.Response.AuthenticatorData) == 0 || len(response.Response.Signature) == 0 {
+		return AuthenticationResponse{}, ErrInvalidResponse
+	}
+	return response, nil
+}
+
+func validCredentialID(id string, rawID []byte, credentialType string) bool {
+	return credentialType == CredentialTypePublicKey && len(rawID) > 0 && id == base64.RawURLEncoding.EncodeToString(rawID)
+}
+
+// Credential is a verified registration. Store ID, PublicKey, and SignCount
+// to verify later assertions.
+type Credential struct {
+	ID          []byte
+	PublicKey   []byte // PublicKey is the COSE_Key from the attested credential data.
+	Algorithm   COSEAlgorithm
+	SignCount   uint32
+	AAGUID      []byte
+	Attestation Attestation
+	// UserVerified reports whether the authenticator verified the user,
+	// such as by PIN or biometric, during the ceremony.
+	UserVerified bool
+}
+
+// Assertion is a verified authentication.
+type Assertion struct {
+	SignCount    uint32
+	UserVerified bool
+	UserHandle   []byte
+}
+
+// VerifyRegistration checks a registration response against the challenge
+// issued for it and returns the new credential.
+func (rp RelyingParty) VerifyRegistration(challenge []byte, response RegistrationResponse) (Credential, error) {
+	if err := rp.verifyClientData(response.Response.ClientDataJSON, ceremonyCreate, challenge); err != nil {
+		return Credential{}, err
+	}
+
+	object, err := decodeCBORMap(response.Response.AttestationObject)
+	if err != nil {
+		return Credential{}, ErrInvalidAttestation
+	}
+	format, _ := object["fmt"].(string)
+	statement, _ := object["attStmt"].(map[any]any)
+	rawAuthData, _ := object["authData"].([]byte)
+	if format == "" || statement == nil || rawAuthData == nil {
+		return Credential{}, ErrInvalidAttestation
+	}
+
+	authData, err := rp.verifyAuthenticatorData(rawAuthData)
+	if err != nil {
+		return Credential{}, err
+	}
+	if authData.flags&flagAttestedData == 0 {
+		return Credential{}, ErrInvalidAuthenticatorData
+	}
+	if !bytes.Equal(authData.credentialID, response.RawID) {
+		return Credential{}, ErrCredentialMismatch
+	}
+	publicKey, err := ParsePublicKey(authData.credentialKey)
+	if err != nil {
+		return Credential{}, err
+	}
+
+	clientDataHash := sha256.Sum256(response.Response.ClientDataJSON)
+	attestation, err := verifyAttestation(format, statement, rawAuthData, clientDataHash[:], publicKey, authData.aaguid)
+	if err != nil {
+		return Credential{}, err
+	}
+
+	return Credential{
+		ID:           authData.credentialID,
+		PublicKey:    authData.credentialKey,
+		Algorithm:    publicKey.Algorithm,
+		SignCount:    authData.signCount,
+		AAGUID:       authData.aaguid,
+		Attestation:  attestation,
+		UserVerified: authData.flags&flagUserVerified != 0,
+	}, nil
+}
+
+// VerifyAssertion checks an authentication response for credential against
+// the challenge issued for it. A signature counter that does not increase
+// signals a cloned authenticator and fails with ErrSignCountRegression;
+// authenticators that always report zero are accepted.
+func (rp RelyingParty) VerifyAssertion(challenge []byte, credential Credential, response AuthenticationResponse) (Assertion, error) {
+	if !bytes.Equal(response.RawID, credential.ID) {
+		return Assertion{}, ErrCredentialMismatch
+	}
+	if err := rp.verifyClientData(response.Response.ClientDataJSON, ceremonyGet, challenge); err != nil {
+		return Assertion{}, err
+	}
+
+	authData, err := rp.verifyAuthenticatorData(response.Response.AuthenticatorData)
+	if err != nil {
+		return Assertion{}, err
+	}
+
+	publicKey, err := ParsePublicKey(credential.PublicKey)
+	if err != nil {
+		return Assertion{}, err
+	}
+	clientDataHash := sha256.Sum256(response.Response.ClientDataJSON)
+	signed := append(append([]byte(nil), response.Response.AuthenticatorData...), clientDataHash[:]...)
+	if err := publicKey.Verify(signed, response.Response.Signature); err != nil {
+		return Assertion{}, err
+	}
+
+	if err := CheckSignCount(credential.SignCount, authData.signCount); err != nil {
+		return Assertion{}, err
+	}
+
+	return Assertion{
+		SignCount:    authData.signCount,
+		UserVerified: authData.flags&flagUserVerified != 0,
+		UserHandle:   response.Response.UserHandle,
+	}, nil
+}
+
+// CheckSignCount reports ErrSignCountRegression when received does not
+// exceed stored. Both being zero means the authenticator has no counter.
+func CheckSignCount(stored uint32, received uint32) error {
+	if (stored != 0 || received != 0) && received <= stored {
+		return ErrSignCountRegression
+	}
+	return nil
+}
+
+type clientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+func (rp RelyingParty) verifyClientData(raw []byte, ceremony string, challenge []byte) error {
+	var data clientData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return ErrInvalidClientData
+	}
+	if data.Type != ceremony || data.CrossOrigin {
+		return ErrInvalidClientData
+	}
+
+	received, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(data.Challenge, "="))
+	if err != nil || len(challenge) == 0 || subtle.ConstantTimeCompare(received, challenge) != 1 {
+		return ErrChallengeMismatch
+	}
+	if !slices.Contains(rp.Origins, data.Origin) {
+		return ErrOriginNotAllowed
+	}
+	return nil
+}
+
+type authenticatorData struct {
+	rpIDHash      []byte
+	flags         byte
+	signCount     uint32
+	aaguid        []byte
+	credentialID  []byte
+	credentialKey []byte
+}
+
+func (rp RelyingParty) verifyAuthenticatorData(raw []byte) (authenticatorData, error) {
+	data, err := parseAuthenticatorData(raw)
+	if err != nil {
+		return authenticatorData{}, err
+	}
+
+	rpIDHash := sha256.Sum256([]byte(rp.ID))
+	if subtle.ConstantTimeCompare(data.rpIDHash, rpIDHash[:]) != 1 {
+		return authenticatorData{}, ErrRPIDMismatch
+	}
+	if data.flags&flagUserPresent == 0 {
+		return authenticatorData{}, ErrUserNotPresent
+	}
+	if rp.userVerification() == UserVerificationRequired && data.flags&flagUserVerified == 0 {
+		return authenticatorData{}, ErrUserNotVerified
+	}
+	return data, nil
+}
+
+// parseAuthenticatorData splits the authenticator data layout:
+// rpIdHash(32) flags(1) signCount(4) [attestedCredentialData] [extensions].
+func parseAuthenticatorData(raw []byte) (authenticatorData, error) {
+	if len(raw) < authDataMinLength {
+		return authenticatorData{}, ErrInvalidAuthenticatorData
+	}
+
+	data := authenticatorData{
+		rpIDHash:  raw[:32],
+		flags:     raw[32],
+		signCount: binary.BigEndian.Uint32(raw[33:37]),
+	}
+	rest := raw[authDataMinLength:]
+
+	if data.flags&flagAttestedData != 0 {
+		if len(rest) < aaguidLength+2 {
+			return authenticatorData{}, ErrInvalidAuthenticatorData
+		}
+		data.aaguid = rest[:aaguidLength]
+		idLength := int(binary.BigEndian.Uint16(rest[aaguidLength:]))
+		rest = rest[aaguidLength+2:]
+		if idLength == 0 || len(rest) < idLength {
+			return authenticatorData{}, ErrInvalidAuthenticatorData
+		}
+		data.credentialID = rest[:idLength]
+		rest = rest[idLength:]
+
+		_, remaining, err := decodeCBOR(rest)
+		if err != nil {
+			return authenticatorData{}, ErrInvalidAuthenticatorData
+		}
+		data.credentialKey = rest[:len(rest)-len(remaining)]
+		rest = remaining
+	}
+
+	if data.flags&flagExtensionData != 0 {
+		extensions, remaining, err := decodeCBOR(rest)
+		if _, ok := extensions.(map[any]any); err != nil || !ok {
+			return authenticatorData{}, ErrInvalidAuthenticatorData
+		}
+		rest = remaining
+	}
+	if len(rest) != 0 {
+		return authenticatorData{}, ErrInvalidAuthenticatorData
+	}
+	return data, nil
+}
@@ -0,0 +1,169 @@
+package webauthn
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/porthorian/openauth/pkg/mfa/webauthn/webauthntest"
+)
+
+func testRelyingParty() RelyingParty {
+	return RelyingParty{ID: "example.com", Name: "Example", Origins: []string{"https://example.com"}}
+}
+
+func register(t *testing.T, rp RelyingParty, authenticator *webauthntest.Authenticator) Credential {
+	t.Helper()
+
+	challenge, err := NewChallenge()
+	if err != nil {
+		t.Fatalf("NewChallenge returned error: %v", err)
+	}
+	raw, err := authenticator.Register(challenge, []byte("user-handle"))
+	if err != nil {
+		t.Fatalf("Register returned error: %v", err)
+	}
+	response, err := ParseRegistrationResponse(raw)
+	if err != nil {
+		t.Fatalf("ParseRegistrationResponse returned error: %v", err)
+	}
+	credential, err := rp.VerifyRegistration(challenge, response)
+	if err != nil {
+		t.Fatalf("VerifyRegistration returned error: %v", err)
+	}
+	return credential
+}
+
+func assert(rp RelyingParty, authenticator *webauthntest.Authenticator, credential Credential) (Assertion, error) {
+	challenge, err := NewChallenge()
+	if err != nil {
+		return Assertion{}, err
+	}
+	raw, err := authenticator.Login(challenge, credential.ID)
+	if err != nil {
+		return Assertion{}, err
+	}
+	response, err := ParseAuthenticationResponse(raw)
+	if err != nil {
+		return Assertion{}, err
+	}
+	return rp.VerifyAssertion(challenge, credential, response)
+}
+
+func TestRegistrationAndAssertionByAttestationFormat(t *testing.T) {
+	rp := testRelyingParty()
+
+	for _, tc := range []struct {
+		attestation webauthntest.Attestation
+		format      string
+		kind        AttestationType
+	}{
+		{webauthntest.AttestationNone, AttestationFormatNone, AttestationTypeNone},
+		{webauthntest.AttestationPackedSelf, AttestationFormatPacked, AttestationTypeSelf},
+		{webauthntest.AttestationPackedBasic, AttestationFormatPacked, AttestationTypeBasic},
+	} {
+		authenticator := webauthntest.New(rp.ID, rp.Origins[0])
+		authenticator.Attestation = tc.attestation
+
+		credential := register(t, rp, authenticator)
+		if credential.Attestation.Format != tc.format || credential.Attestation.Type != tc.kind {
+			t.Fatalf("%s: attestation = %+v", tc.attestation, credential.Attestation)
+		}
+		if credential.Algorithm != AlgorithmES256 || credential.SignCount != 1 || !credential.UserVerified {
+			t.Fatalf("%s: credential = %+v", tc.attestation, credential)
+		}
+		if _, err := ParsePublicKey(credential.PublicKey); err != nil {
+			t.Fatalf("%s: stored public key does not parse: %v", tc.attestation, err)
+		}
+
+		assertion, err := assert(rp, authenticator, credential)
+		if err != nil {
+			t.Fatalf("%s: VerifyAssertion returned error: %v", tc.attestation, err)
+		}
+		if assertion.SignCount != 2 || string(assertion.UserHandle) != "user-handle" {
+			t.Fatalf("%s: assertion = %+v", tc.attestation, assertion)
+		}
+	}
+}
+
+func TestVerifyRegistrationRejectsForeignCeremonies(t *testing.T) {
+	rp := testRelyingParty()
+	challenge, _ := NewChallenge()
+
+	for name, tc := range map[string]struct {
+		authenticator *webauthntest.Authenticator
+		challenge     []byte
+		want          error
+	}{
+		"challenge": {webauthntest.New(rp.ID, rp.Origins[0]), []byte("another challenge value"), ErrChallengeMismatch},
+		"origin":    {webauthntest.New(rp.ID, "https://evil.example"), challenge, ErrOriginNotAllowed},
+		"rp id":     {webauthntest.New("evil.example", rp.Origins[0]), challenge, ErrRPIDMismatch},
+	} {
+		raw, err := tc.authenticator.Register(tc.challenge, []byte("user-handle"))
+		if err != nil {
+			t.Fatalf("%s: Register returned error: %v", name, err)
+		}
+		response, err := ParseRegistrationResponse(raw)
+		if err != nil {
+			t.Fatalf("%s: ParseRegistrationResponse returned error: %v", name, err)
+		}
+		if _, err := rp.VerifyRegistration(challenge, response); !errors.Is(err, tc.want) {
+			t.Fatalf("%s: VerifyRegistration error = %v, want %v", name, err, tc.want)
+		}
+	}
+}
+
+func TestVerifyAssertionChecksSignCount(t *testing.T) {
+	rp := testRelyingParty()
+	authenticator := webauthntest.New(rp.ID, rp.Origins[0])
+	credential := register(t, rp, authenticator)
+	credential.SignCount = 5
+
+	if err := authenticator.SetSignCount(credential.ID, 4); err != nil {
+		t.Fatalf("SetSignCount returned error: %v", err)
+	}
+	if _, err := assert(rp, authenticator, credential); !errors.Is(err, ErrSignCountRegression) {
+		t.Fatalf("cloned authenticator error = %v, want sign count regression", err)
+	}
+
+	counterless := webauthntest.New(rp.ID, rp.Origins[0])
+	counterless.Counterless = true
+	credential = register(t, rp, counterless)
+	for i := 0; i < 2; i++ {
+		if _, err := assert(rp, counterless, credential); err != nil {
+			t.Fatalf("counterless assertion returned error: %v", err)
+		}
+	}
+}
+
+func TestVerifyAssertionEnforcesUserVerificationAndSignature(t *testing.T) {
+	rp := testRelyingParty()
+	authenticator := webauthntest.New(rp.ID, rp.Origins[0])
+	credential := register(t, rp, authenticator)
+
+	other := webauthntest.New(rp.ID, rp.Origins[0])
+	forged := register(t, rp, other)
+	forged.ID = credential.ID
+	if _, err := assert(rp, authenticator, forged); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("assertion against another key error = %v, want invalid signature", err)
+	}
+
+	rp.UserVerification = UserVerificationRequired
+	authenticator.UserVerified = false
+	if _, err := assert(rp, authenticator, credential); !errors.Is(err, ErrUserNotVerified) {
+		t.Fatalf("unverified assertion error = %v, want user verification required", err)
+	}
+}
+
+func TestDecodeCBORRejectsMalformedInput(t *testing.T) {
+	for name, data := range map[string][]byte{
+		"indefinite map": {0xbf, 0xff},
+		"short bytes":    {0x45, 0x01},
+		"trailing data":  {0xa0, 0x00},
+		"duplicate key":  {0xa2, 0x01, 0x01, 0x01, 0x02},
+		"float key":      {0xa1, 0xf9, 0x00, 0x00, 0x01},
+	} {
+		if _, err := decodeCBORMap(data); err == nil {
+			t.Fatalf("%s: expected decode error", name)
+		}
+	}
+}
@@ -0,0 +1,273 @@
+// Package webauthntest provides a software authenticator that produces
+// WebAuthn registration and authentication responses for tests.
+package webauthntest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"time"
+)
+
+// Attestation selects the statement format returned by Register.
+type Attestation string
+
+const (
+	AttestationNone        Attestation = "none"
+	AttestationPackedSelf  Attestation = "packed-self"
+	AttestationPackedBasic Attestation = "packed-basic"
+)
+
+const (
+	flagUserPresent  = 0x01
+	flagUserVerified = 0x04
+	flagAttestedData = 0x40
+
+	algorithmES256 = -7
+)
+
+var ErrUnknownCredential = errors.New("webauthntest: unknown credential")
+
+// Authenticator holds ES256 credentials in memory. The zero value is not
+// usable; create one with New.
+type Authenticator struct {
+	RPID   string
+	Origin string
+	// Attestation defaults to AttestationNone.
+	Attestation Attestation
+	// UserVerified sets the UV flag on responses.
+	UserVerified bool
+	// Counterless keeps every signature counter at zero, as some platform
+	// authenticators do.
+	Counterless bool
+	AAGUID      [16]byte
+
+	credentials map[string]*credential
+}
+
+type credential struct {
+	id         []byte
+	key        *ecdsa.PrivateKey
+	userHandle []byte
+	signCount  uint32
+}
+
+func New(rpID string, origin string) *Authenticator {
+	return &Authenticator{
+		RPID:         rpID,
+		Origin:       origin,
+		Attestation:  AttestationNone,
+		UserVerified: true,
+		AAGUID:       [16]byte{0x0a, 0x0b, 0x0c, 0x0d},
+		credentials:  map[string]*credential{},
+	}
+}
+
+// Register creates a credential for userHandle and returns the JSON
+// PublicKeyCredential a browser would hand to the relying party.
+func (a *Authenticator) Register(challenge []byte, userHandle []byte) ([]byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	cred := &credential{id: id, key: key, userHandle: append([]byte(nil), userHandle...)}
+	if !a.Counterless {
+		cred.signCount = 1
+	}
+
+	clientDataJSON, err := a.clientData("webauthn.create", challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey, err := key.PublicKey.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	coseKey := encodeCBOR(cborMap{
+		{int64(1), int64(2)},
+		{int64(3), int64(algorithmES256)},
+		{int64(-1), int64(1)},
+		{int64(-2), publicKey[1:33]},
+		{int64(-3), publicKey[33:]},
+	})
+
+	authData := a.authenticatorData(flagAttestedData, cred.signCount)
+	authData = append(authData, a.AAGUID[:]...)
+	authData = binary.BigEndian.AppendUint16(authData, uint16(len(id)))
+	authData = append(authData, id...)
+	authData = append(authData, coseKey...)
+
+	format, statement, err := a.attestationStatement(authData, clientDataJSON, key)
+	if err != nil {
+		return nil, err
+	}
+	attestationObject := encodeCBOR(cborMap{
+		{"fmt", format},
+		{"attStmt", statement},
+		{"authData", authData},
+	})
+
+	a.credentials[string(id)] = cred
+	return json.Marshal(map[string]any{
+		"id":    base64.RawURLEncoding.EncodeToString(id),
+		"rawId": base64.RawURLEncoding.EncodeToString(id),
+		"type":  "public-key",
+		"response": map[string]string{
+			"clientDataJSON":    base64.RawURLEncoding.EncodeToString(clientDataJSON),
+			"attestationObject": base64.RawURLEncoding.EncodeToString(attestationObject),
+		},
+	})
+}
+
+// Login signs challenge with the credential identified by credentialID and
+// returns the JSON PublicKeyCredential a browser would hand to the relying
+// party.
+func (a *Authenticator) Login(challenge []byte, credentialID []byte) ([]byte, error) {
+	cred, ok := a.credentials[string(credentialID)]
+	if !ok {
+		return nil, ErrUnknownCredential
+	}
+	if !a.Counterless {
+		cred.signCount++
+	}
+
+	clientDataJSON, err := a.clientData("webauthn.get", challenge)
+	if err != nil {
+		return nil, err
+	}
+	authData := a.authenticatorData(0, cred.signCount)
+	signature, err := sign(cred.key, authData, clientDataJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(map[string]any{
+		"id":    base64.RawURLEncoding.EncodeToString(cred.id),
+		"rawId": base64.RawURLEncoding.EncodeToString(cred.id),
+		"type":  "public-key",
+		"response": map[string]string{
+			"clientDataJSON":    base64.RawURLEncoding.EncodeToString(clientDataJSON),
+			"authenticatorData": base64.RawURLEncoding.EncodeToString(authData),
+			"signature":         base64.RawURLEncoding.EncodeToString(signature),
+			"userHandle":        base64.RawURLEncoding.EncodeToString(cred.userHandle),
+		},
+	})
+}
+
+// Credentials returns the IDs of registered credentials.
+func (a *Authenticator) Credentials() [][]byte {
+	ids := make([][]byte, 0, len(a.credentials))
+	for _, cred := range a.credentials {
+		ids = append(ids, cred.id)
+	}
+	return ids
+}
+
+// SetSignCount overwrites a credential's counter, for example to simulate a
+// cloned authenticator.
+func (a *Authenticator) SetSignCount(credentialID []byte, count uint32) error {
+	cred, ok := a.credentials[string(credentialID)]
+	if !ok {
+		return ErrUnknownCredential
+	}
+	cred.signCount = count
+	return nil
+}
+
+func (a *Authenticator) clientData(ceremony string, challenge []byte) ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"type":        ceremony,
+		"challenge":   base64.RawURLEncoding.EncodeToString(challenge),
+		"origin":      a.Origin,
+		"crossOrigin": false,
+	})
+}
+
+func (a *Authenticator) authenticatorData(flags byte, signCount uint32) []byte {
+	rpIDHash := sha256.Sum256([]byte(a.RPID))
+	flags |= flagUserPresent
+	if a.UserVerified {
+		flags |= flagUserVerified
+	}
+	data := append(rpIDHash[:], flags)
+	return binary.BigEndian.AppendUint32(data, signCount)
+}
+
+func (a *Authenticator) attestationStatement(authData []byte, clientDataJSON []byte, key *ecdsa.PrivateKey) (string, cborMap, error) {
+	switch a.Attestation {
+	case "", AttestationNone:
+		return "none", cborMap{}, nil
+	case AttestationPackedSelf:
+		signature, err := sign(key, authData, clientDataJSON)
+		if err != nil {
+			return "", nil, err
+		}
+		return "packed", cborMap{{"alg", int64(algorithmES256)}, {"sig", signature}}, nil
+	case AttestationPackedBasic:
+		attestationKey, certificate, err := a.attestationCertificate()
+		if err != nil {
+			return "", nil, err
+		}
+		signature, err := sign(attestationKey, authData, clientDataJSON)
+		if err != nil {
+			return "", nil, err
+		}
+		return "packed", cborMap{{"alg", int64(algorithmES256)}, {"sig", signature}, {"x5c", []any{certificate}}}, nil
+	}
+	return "", nil, errors.New("webauthntest: unknown attestation")
+}
+
+// attestationCertificate issues a self-signed certificate that meets the
+// packed attestation certificate requirements.
+func (a *Authenticator) attestationCertificate() (*ecdsa.PrivateKey, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	aaguid, err := asn1.Marshal(a.AAGUID[:])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject: pkix.Name{
+			Country:            []string{"US"},
+			Organization:       []string{"openauth test"},
+			OrganizationalUnit: []string{"Authenticator Attestation"},
+			CommonName:         "webauthntest",
+		},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		BasicConstraintsValid: true,
+		ExtraExtensions: []pkix.Extension{{
+			Id:    asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 45724, 1, 1, 4},
+			Value: aaguid,
+		}},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return key, der, nil
+}
+
+func sign(key *ecdsa.PrivateKey, authData []byte, clientDataJSON []byte) ([]byte, error) {
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(append(append([]byte(nil), authData...), clientDataHash[:]...))
+	return ecdsa.SignASN1(rand.Reader, key, digest[:])
+}
@@ -0,0 +1,56 @@
+package webauthntest
+
+import "encoding/binary"
+
+// cborMap keeps entry order so encoded maps are deterministic.
+type cborMap []cborPair
+
+type cborPair struct {
+	key   any
+	value any
+}
+
+// encodeCBOR encodes the subset of CBOR the authenticator emits: int64,
+// []byte, string, []any, and cborMap.
+func encodeCBOR(value any) []byte {
+	switch v := value.(type) {
+	case int64:
+		if v < 0 {
+			return cborHeader(1, uint64(-1-v))
+		}
+		return cborHeader(0, uint64(v))
+	case []byte:
+		return append(cborHeader(2, uint64(len(v))), v...)
+	case string:
+		return append(cborHeader(3, uint64(len(v))), v...)
+	case []any:
+		out := cborHeader(4, uint64(len(v)))
+		for _, item := range v {
+			out = append(out, encodeCBOR(item)...)
+		}
+		return out
+	case cborMap:
+		out := cborHeader(5, uint64(len(v)))
+		for _, pair := range v {
+			out = append(out, encodeCBOR(pair.key)...)
+			out = append(out, encodeCBOR(pair.value)...)
+		}
+		return out
+	}
+	panic("webauthntest: unsupported cbor value")
+}
+
+func cborHeader(major byte, argument uint64) []byte {
+	prefix := major << 5
+	switch {
+	case argument < 24:
+		return []byte{prefix | byte(argument)}
+	case argument <= 0xff:
+		return []byte{prefix | 24, byte(argument)}
+	case argument <= 0xffff:
+		return binary.BigEndian.AppendUint16([]byte{prefix | 25}, uint16(argument))
+	case argument <= 0xffffffff:
+		return binary.BigEndian.AppendUint32([]byte{prefix | 26}, uint32(argument))
+	}
+	return binary.BigEndian.AppendUint64([]byte{prefix | 27}, argument)
+}
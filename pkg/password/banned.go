@@ -0,0 +1,79 @@
+package password
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+var ErrInvalidBannedList = errors.New("password: invalid banned list")
+
+// BannedList is a case-insensitive set of rejected passwords. A nil list
+// contains nothing.
+type BannedList struct {
+	entries map[string]struct{}
+}
+
+func NewBannedList(passwords []string) *BannedList {
+	list := &BannedList{entries: make(map[string]struct{}, len(passwords))}
+	for _, password := range passwords {
+		list.add(password)
+	}
+	return list
+}
+
+// LoadBannedList reads one password per line from path. Blank lines and
+// lines starting with '#' are skipped.
+func LoadBannedList(path string) (*BannedList, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ReadBannedList(file)
+}
+
+func ReadBannedList(reader io.Reader) (*BannedList, error) {
+	list := &BannedList{entries: map[string]struct{}{}}
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		list.add(line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBannedList, err)
+	}
+	return list, nil
+}
+
+func (l *BannedList) Contains(password string) bool {
+	if l == nil {
+		return false
+	}
+	_, found := l.entries[normalizeBanned(password)]
+	return found
+}
+
+func (l *BannedList) Len() int {
+	if l == nil {
+		return 0
+	}
+	return len(l.entries)
+}
+
+func (l *BannedList) add(password string) {
+	if normalized := normalizeBanned(password); normalized != "" {
+		l.entries[normalized] = struct{}{}
+	}
+}
+
+func normalizeBanned(password string) string {
+	return strings.ToLower(strings.TrimSpace(password))
+}
@@ -0,0 +1,191 @@
+// Package password checks candidate passwords against a policy before they
+// are hashed and stored.
+package password
+
+import (
+	"fmt"
+	"math/bits"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	oerrors "github.com/porthorian/openauth/pkg/errors"
+)
+
+const (
+	RuleMinLength         = "min_length"
+	RuleMaxLength         = "max_length"
+	RuleCharacterClass    = "character_class"
+	RuleMinClasses        = "min_character_classes"
+	RuleBanned            = "banned"
+	RuleSubjectSimilarity = "subject_similarity"
+	// RuleHistory is reported by the auth service, which holds the hashes
+	// of previous passwords.
+	RuleHistory = "history"
+
+	defaultMinLength         = 12
+	defaultMaxLength         = 128
+	defaultSubjectSimilarity = 0.7
+
+	// minSubjectLength skips the containment check for very short subjects,
+	// which would otherwise reject passwords by accident.
+	minSubjectLength = 3
+)
+
+// Policy reports every rule password breaks for subject. An empty result
+// means the password is acceptable.
+type Policy interface {
+	Check(password string, subject string) []oerrors.Violation
+}
+
+// CharacterClass is a bit mask of character classes.
+type CharacterClass uint8
+
+const (
+	ClassLower CharacterClass = 1 << iota
+	ClassUpper
+	ClassDigit
+	ClassSymbol
+)
+
+var classNames = []struct {
+	class CharacterClass
+	name  string
+}{
+	{ClassLower, "lowercase letter"},
+	{ClassUpper, "uppercase letter"},
+	{ClassDigit, "digit"},
+	{ClassSymbol, "symbol"},
+}
+
+// Rules is the built-in Policy. Lengths count runes. Zero values disable a
+// rule.
+type Rules struct {
+	MinLength int
+	MaxLength int
+	// RequiredClasses must each appear at least once.
+	RequiredClasses CharacterClass
+	// MinClasses is the number of distinct classes that must appear, for
+	// rules such as "three of four".
+	MinClasses int
+	// Banned rejects common or breached passwords.
+	Banned *BannedList
+	// MaxSubjectSimilarity rejects passwords whose similarity to the subject,
+	// or to the local part of an email subject, reaches this ratio in (0, 1].
+	// Passwords containing the subject are always rejected when it is set.
+	MaxSubjectSimilarity float64
+}
+
+var _ Policy = Rules{}
+
+// DefaultRules follows NIST SP 800-63B: length over composition, no banned
+// list until one is loaded, and no passwords derived from the subject.
+func DefaultRules() Rules {
+	return Rules{
+		MinLength:            defaultMinLength,
+		MaxLength:            defaultMaxLength,
+		MaxSubjectSimilarity: defaultSubjectSimilarity,
+	}
+}
+
+func (r Rules) Check(password string, subject string) []oerrors.Violation {
+	var violations []oerrors.Violation
+	add := func(rule string, format string, args ...any) {
+		violations = append(violations, oerrors.Violation{Rule: rule, Message: fmt.Sprintf(format, args...)})
+	}
+
+	length := utf8.RuneCountInString(password)
+	if r.MinLength > 0 && length < r.MinLength {
+		add(RuleMinLength, "password must be at least %d characters", r.MinLength)
+	}
+	if r.MaxLength > 0 && length > r.MaxLength {
+		add(RuleMaxLength, "password must be at most %d characters", r.MaxLength)
+	}
+
+	present := classesOf(password)
+	for _, class := range classNames {
+		if r.RequiredClasses&class.class != 0 && present&class.class == 0 {
+			add(RuleCharacterClass, "password must contain a %s", class.name)
+		}
+	}
+	if r.MinClasses > 0 && bits.OnesCount8(uint8(present)) < r.MinClasses {
+		add(RuleMinClasses, "password must mix at least %d of lowercase, uppercase, digits, and symbols", r.MinClasses)
+	}
+
+	if r.Banned.Contains(password) {
+		add(RuleBanned, "password is too common")
+	}
+	if r.MaxSubjectSimilarity > 0 && similarToSubject(password, subject, r.MaxSubjectSimilarity) {
+		add(RuleSubjectSimilarity, "password is too similar to the user id")
+	}
+	return violations
+}
+
+func classesOf(password string) CharacterClass {
+	var present CharacterClass
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			present |= ClassLower
+		case unicode.IsUpper(r):
+			present |= ClassUpper
+		case unicode.IsDigit(r):
+			present |= ClassDigit
+		case !unicode.IsSpace(r):
+			present |= ClassSymbol
+		}
+	}
+	return present
+}
+
+func similarToSubject(password string, subject string, threshold float64) bool {
+	password = strings.ToLower(password)
+	candidates := []string{strings.ToLower(strings.TrimSpace(subject))}
+	if local, _, found := strings.Cut(candidates[0], "@"); found {
+		candidates = append(candidates, local)
+	}
+
+	for _, candidate := range candidates {
+		if utf8.RuneCountInString(candidate) < minSubjectLength {
+			continue
+		}
+		if strings.Contains(password, candidate) || strings.Contains(candidate, password) {
+			return true
+		}
+		if similarity(password, candidate) >= threshold {
+			return true
+		}
+	}
+	return false
+}
+
+// similarity is one minus the Levenshtein distance divided by the longer
+// length, so identical strings score 1.
+func similarity(a string, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := max(len(ra), len(rb))
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+func levenshtein(a []rune, b []rune) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
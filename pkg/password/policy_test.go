@@ -0,0 +1,81 @@
+package password
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	oerrors "github.com/porthorian/openauth/pkg/errors"
+)
+
+func rules(violations []oerrors.Violation) []string {
+	out := make([]string, 0, len(violations))
+	for _, violation := range violations {
+		out = append(out, violation.Rule)
+	}
+	return out
+}
+
+func TestRulesReportEveryViolation(t *testing.T) {
+	policy := Rules{
+		MinLength:            10,
+		MaxLength:            16,
+		RequiredClasses:      ClassUpper | ClassDigit,
+		MinClasses:           3,
+		Banned:               NewBannedList([]string{"Password"}),
+		MaxSubjectSimilarity: 0.7,
+	}
+
+	for _, tc := range []struct {
+		password string
+		subject  string
+		want     []string
+	}{
+		{"Correct-Horse-9", "user-1", nil},
+		{"short", "user-1", []string{RuleMinLength, RuleCharacterClass, RuleCharacterClass, RuleMinClasses}},
+		{"Aa1-this-is-far-too-long", "user-1", []string{RuleMaxLength}},
+		{"PASSWORD", "user-1", []string{RuleMinLength, RuleCharacterClass, RuleMinClasses, RuleBanned}},
+		{"Alice.Smith9", "alice.smith@example.com", []string{RuleSubjectSimilarity}},
+		{"Alise.Smyth9", "alice.smith", []string{RuleSubjectSimilarity}},
+		{"Unrelated-9x", "alice.smith", nil},
+		{"Über-Straße-7", "user-1", nil},
+	} {
+		got := rules(policy.Check(tc.password, tc.subject))
+		if !slices.Equal(got, tc.want) {
+			t.Fatalf("Check(%q, %q) = %v, want %v", tc.password, tc.subject, got, tc.want)
+		}
+	}
+}
+
+func TestDefaultRulesFavourLength(t *testing.T) {
+	if violations := DefaultRules().Check("correct horse battery staple", "user-1"); len(violations) != 0 {
+		t.Fatalf("expected a long passphrase to pass, got %v", violations)
+	}
+	if got := rules(DefaultRules().Check("user-1-password", "user-1")); !slices.Equal(got, []string{RuleSubjectSimilarity}) {
+		t.Fatalf("expected subject in password to be rejected, got %v", got)
+	}
+}
+
+func TestLoadBannedList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "banned.txt")
+	if err := os.WriteFile(path, []byte("# top passwords\n123456\n\n  Qwerty123  \n"), 0o600); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+
+	list, err := LoadBannedList(path)
+	if err != nil {
+		t.Fatalf("LoadBannedList returned error: %v", err)
+	}
+	if list.Len() != 2 || !list.Contains("qwerty123") || list.Contains("# top passwords") {
+		t.Fatalf("unexpected banned list contents: %v", list.entries)
+	}
+	if _, err := LoadBannedList(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Fatalf("expected missing banned list to fail")
+	}
+
+	var empty *BannedList
+	if empty.Contains("123456") {
+		t.Fatalf("nil banned list must not contain anything")
+	}
+}
@@ -5,6 +5,13 @@ import (
 	"time"
 )
 
+// Grant types from RFC 6749.
+const (
+	GrantTypeAuthorizationCode = "authorization_code"
+	GrantTypeClientCredentials = "client_credentials"
+	GrantTypeRefreshToken      = "refresh_token"
+)
+
 type IntrospectionResponse struct {
 	Active    bool
 	Subject   string
@@ -0,0 +1,298 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"hash"
+	"math/big"
+	"strings"
+
+	"github.com/porthorian/openauth/pkg/session"
+)
+
+const (
+	algorithmRS256 = "RS256"
+	algorithmRS384 = "RS384"
+	algorithmRS512 = "RS512"
+	algorithmPS256 = "PS256"
+	algorithmPS384 = "PS384"
+	algorithmPS512 = "PS512"
+	algorithmES256 = "ES256"
+	algorithmES384 = "ES384"
+	algorithmES512 = "ES512"
+	algorithmEdDSA = "EdDSA"
+
+	minRSAKeyBits = 2048
+)
+
+var ErrInvalidKeyMaterial = errors.New("session/jwt: key material does not match algorithm")
+
+type algorithmFamily int
+
+const (
+	familyHMAC algorithmFamily = iota
+	familyRSA
+	familyRSAPSS
+	familyECDSA
+	familyEdDSA
+)
+
+type algorithmSpec struct {
+	family algorithmFamily
+	hash   crypto.Hash
+	curve  elliptic.Curve
+}
+
+var algorithmSpecs = map[string]algorithmSpec{
+	algorithmHS256: {family: familyHMAC, hash: crypto.SHA256},
+	algorithmHS384: {family: familyHMAC, hash: crypto.SHA384},
+	algorithmHS512: {family: familyHMAC, hash: crypto.SHA512},
+	algorithmRS256: {family: familyRSA, hash: crypto.SHA256},
+	algorithmRS384: {family: familyRSA, hash: crypto.SHA384},
+	algorithmRS512: {family: familyRSA, hash: crypto.SHA512},
+	algorithmPS256: {family: familyRSAPSS, hash: crypto.SHA256},
+	algorithmPS384: {family: familyRSAPSS, hash: crypto.SHA384},
+	algorithmPS512: {family: familyRSAPSS, hash: crypto.SHA512},
+	algorithmES256: {family: familyECDSA, hash: crypto.SHA256, curve: elliptic.P256()},
+	algorithmES384: {family: familyECDSA, hash: crypto.SHA384, curve: elliptic.P384()},
+	algorithmES512: {family: familyECDSA, hash: crypto.SHA512, curve: elliptic.P521()},
+	algorithmEdDSA: {family: familyEdDSA},
+}
+
+// canonicalAlgorithm maps a case-insensitive algorithm name onto its JOSE
+// spelling so "eddsa" and "EdDSA" resolve to the same spec.
+func canonicalAlgorithm(algorithm string) string {
+	trimmed := strings.TrimSpace(algorithm)
+	if strings.EqualFold(trimmed, algorithmEdDSA) {
+		return algorithmEdDSA
+	}
+	return strings.ToUpper(trimmed)
+}
+
+func defaultAlgorithm(key session.Key) string {
+	if key.IsAsymmetric() {
+		return inferAlgorithm(key)
+	}
+	return algorithmHS256
+}
+
+// inferAlgorithm picks the default algorithm for an asymmetric key that was
+// configured without one.
+func inferAlgorithm(key session.Key) string {
+	switch public := verificationPublicKey(key).(type) {
+	case *rsa.PublicKey:
+		return algorithmRS256
+	case *ecdsa.PublicKey:
+		switch public.Curve {
+		case elliptic.P256():
+			return algorithmES256
+		case elliptic.P384():
+			return algorithmES384
+		case elliptic.P521():
+			return algorithmES512
+		}
+	case ed25519.PublicKey:
+		return algorithmEdDSA
+	}
+	return ""
+}
+
+func verificationPublicKey(key session.Key) crypto.PublicKey {
+	if key.PublicKey != nil {
+		return key.PublicKey
+	}
+	if key.PrivateKey != nil {
+		return key.PrivateKey.Public()
+	}
+	return nil
+}
+
+func hasSigningMaterial(key session.Key) bool {
+	return len(key.Material) > 0 || key.PrivateKey != nil
+}
+
+func hasVerificationMaterial(key session.Key) bool {
+	return len(key.Material) > 0 || verificationPublicKey(key) != nil
+}
+
+// validateKeyForAlgorithm rejects key material that cannot be used with the
+// algorithm, which also guards against alg-confusion between HMAC secrets and
+// public keys.
+func validateKeyForAlgorithm(key session.Key, algorithm string, signing bool) error {
+	spec, ok := algorithmSpecs[algorithm]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, algorithm)
+	}
+
+	if spec.family == familyHMAC {
+		if len(key.Material) == 0 || key.IsAsymmetric() {
+			return fmt.Errorf("%w: %s requires a symmetric secret", ErrInvalidKeyMaterial, algorithm)
+		}
+		return nil
+	}
+
+	if len(key.Material) > 0 {
+		return fmt.Errorf("%w: %s does not accept a symmetric secret", ErrInvalidKeyMaterial, algorithm)
+	}
+	if signing && key.PrivateKey == nil {
+		return fmt.Errorf("%w: %s requires a private key for signing", ErrInvalidKeyMaterial, algorithm)
+	}
+
+	switch public := verificationPublicKey(key).(type) {
+	case *rsa.PublicKey:
+		if spec.family != familyRSA && spec.family != familyRSAPSS {
+			break
+		}
+		if public.N.BitLen() < minRSAKeyBits {
+			return fmt.Errorf("%w: RSA keys must be at least %d bits", ErrInvalidKeyMaterial, minRSAKeyBits)
+		}
+		return nil
+	case *ecdsa.PublicKey:
+		if spec.family != familyECDSA {
+			break
+		}
+		if public.Curve != spec.curve {
+			return fmt.Errorf("%w: %s requires curve %s", ErrInvalidKeyMaterial, algorithm, spec.curve.Params().Name)
+		}
+		return nil
+	case ed25519.PublicKey:
+		if spec.family == familyEdDSA {
+			return nil
+		}
+	case nil:
+		return fmt.Errorf("%w: %s requires a public or private key", ErrInvalidKeyMaterial, algorithm)
+	}
+
+	return fmt.Errorf("%w: %s cannot use %T", ErrInvalidKeyMaterial, algorithm, verificationPublicKey(key))
+}
+
+func signSignature(signingInput string, key session.Key) ([]byte, error) {
+	spec, ok := algorithmSpecs[key.Algorithm]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, key.Algorithm)
+	}
+	if !hasSigningMaterial(key) {
+		return nil, ErrMissingSigningKey
+	}
+	if err := validateKeyForAlgorithm(key, key.Algorithm, true); err != nil {
+		return nil, err
+	}
+
+	switch spec.family {
+	case familyHMAC:
+		mac := hmac.New(hashConstructor(spec.hash), key.Material)
+		_, _ = mac.Write([]byte(signingInput))
+		return mac.Sum(nil), nil
+	case familyRSA:
+		return key.PrivateKey.Sign(rand.Reader, digest(spec.hash, signingInput), spec.hash)
+	case familyRSAPSS:
+		return key.PrivateKey.Sign(rand.Reader, digest(spec.hash, signingInput), &rsa.PSSOptions{
+			SaltLength: rsa.PSSSaltLengthEqualsHash,
+			Hash:       spec.hash,
+		})
+	case familyECDSA:
+		der, err := key.PrivateKey.Sign(rand.Reader, digest(spec.hash, signingInput), spec.hash)
+		if err != nil {
+			return nil, err
+		}
+		return ecdsaDERToJOSE(der, spec.curve)
+	case familyEdDSA:
+		return key.PrivateKey.Sign(rand.Reader, []byte(signingInput), crypto.Hash(0))
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, key.Algorithm)
+	}
+}
+
+func verifySignature(signingInput string, signature []byte, key session.Key) error {
+	spec, ok := algorithmSpecs[key.Algorithm]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, key.Algorithm)
+	}
+	if !hasVerificationMaterial(key) {
+		return ErrMissingValidationKey
+	}
+	if err := validateKeyForAlgorithm(key, key.Algorithm, false); err != nil {
+		return err
+	}
+
+	valid := false
+	switch spec.family {
+	case familyHMAC:
+		mac := hmac.New(hashConstructor(spec.hash), key.Material)
+		_, _ = mac.Write([]byte(signingInput))
+		valid = hmac.Equal(mac.Sum(nil), signature)
+	case familyRSA:
+		public := verificationPublicKey(key).(*rsa.PublicKey)
+		valid = rsa.VerifyPKCS1v15(public, spec.hash, digest(spec.hash, signingInput), signature) == nil
+	case familyRSAPSS:
+		public := verificationPublicKey(key).(*rsa.PublicKey)
+		valid = rsa.VerifyPSS(public, spec.hash, digest(spec.hash, signingInput), signature, &rsa.PSSOptions{
+			SaltLength: rsa.PSSSaltLengthAuto,
+			Hash:       spec.hash,
+		}) == nil
+	case familyECDSA:
+		public := verificationPublicKey(key).(*ecdsa.PublicKey)
+		size := curveByteSize(spec.curve)
+		if len(signature) == 2*size {
+			r := new(big.Int).SetBytes(signature[:size])
+			s := new(big.Int).SetBytes(signature[size:])
+			valid = ecdsa.Verify(public, digest(spec.hash, signingInput), r, s)
+		}
+	case familyEdDSA:
+		public := verificationPublicKey(key).(ed25519.PublicKey)
+		valid = ed25519.Verify(public, []byte(signingInput), signature)
+	}
+
+	if !valid {
+		return fmt.Errorf("%w: signature mismatch", ErrInvalidToken)
+	}
+	return nil
+}
+
+func hashConstructor(h crypto.Hash) func() hash.Hash {
+	switch h {
+	case crypto.SHA384:
+		return sha512.New384
+	case crypto.SHA512:
+		return sha512.New
+	default:
+		return sha256.New
+	}
+}
+
+func digest(h crypto.Hash, signingInput string) []byte {
+	hasher := hashConstructor(h)()
+	_, _ = hasher.Write([]byte(signingInput))
+	return hasher.Sum(nil)
+}
+
+func curveByteSize(curve elliptic.Curve) int {
+	return (curve.Params().BitSize + 7) / 8
+}
+
+// ecdsaDERToJOSE converts the ASN.1 signature produced by crypto.Signer into
+// the fixed-width R||S encoding required by RFC 7518.
+func ecdsaDERToJOSE(der []byte, curve elliptic.Curve) ([]byte, error) {
+	var parsed struct {
+		R, S *big.Int
+	}
+	if _, err := asn1.Unmarshal(der, &parsed); err != nil {
+		return nil, fmt.Errorf("session/jwt: invalid ECDSA signature: %w", err)
+	}
+
+	size := curveByteSize(curve)
+	signature := make([]byte, 2*size)
+	parsed.R.FillBytes(signature[:size])
+	parsed.S.FillBytes(signature[size:])
+	return signature, nil
+}
@@ -0,0 +1,197 @@
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/porthorian/openauth/pkg/session"
+)
+
+func TestAsymmetricAlgorithmsRoundTrip(t *testing.T) {
+	rsaKey := mustRSAKey(t, 2048)
+	p256 := mustECDSAKey(t, elliptic.P256())
+	p384 := mustECDSAKey(t, elliptic.P384())
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("ed25519.GenerateKey returned error: %v", err)
+	}
+
+	tests := []struct {
+		algorithm string
+		signer    crypto.Signer
+	}{
+		{algorithm: algorithmRS256, signer: rsaKey},
+		{algorithm: algorithmRS512, signer: rsaKey},
+		{algorithm: algorithmPS256, signer: rsaKey},
+		{algorithm: algorithmES256, signer: p256},
+		{algorithm: algorithmES384, signer: p384},
+		{algorithm: algorithmEdDSA, signer: edKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.algorithm, func(t *testing.T) {
+			now := time.Date(2026, 3, 4, 12, 0, 0, 0, time.UTC)
+			signingKey := session.Key{
+				ID:         "key-1",
+				Algorithm:  tt.algorithm,
+				PrivateKey: tt.signer,
+			}
+			signer := newTestManager(t, Config{
+				SigningKey: signingKey,
+				Now:        func() time.Time { return now },
+			})
+
+			token, err := signer.IssueToken(context.Background(), "user-1", nil, time.Minute)
+			if err != nil {
+				t.Fatalf("IssueToken returned error: %v", err)
+			}
+			if header := decodeHeader(t, token); header["alg"] != tt.algorithm {
+				t.Fatalf("expected alg %s, got %v", tt.algorithm, header["alg"])
+			}
+
+			validator := newTestManager(t, Config{
+				KeyResolver: staticResolver{
+					keys: map[string]session.Key{"key-1": signingKey.Public()},
+				},
+				Now: func() time.Time { return now },
+			})
+			if _, err := validator.ValidateToken(context.Background(), token); err != nil {
+				t.Fatalf("ValidateToken returned error: %v", err)
+			}
+
+			parts := strings.Split(token, ".")
+			parts[1] = tamperSegment(parts[1])
+			_, err = validator.ValidateToken(context.Background(), strings.Join(parts, "."))
+			if !errors.Is(err, ErrInvalidToken) {
+				t.Fatalf("expected ErrInvalidToken for tampered token, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestNewManagerInfersAsymmetricAlgorithm(t *testing.T) {
+	manager := newTestManager(t, Config{
+		SigningKey: session.Key{ID: "key-1", PrivateKey: mustECDSAKey(t, elliptic.P384())},
+	})
+	if manager.signingKey.Algorithm != algorithmES384 {
+		t.Fatalf("expected ES384, got %s", manager.signingKey.Algorithm)
+	}
+}
+
+func TestNewManagerRejectsMismatchedKeyMaterial(t *testing.T) {
+	tests := []struct {
+		name string
+		key  session.Key
+	}{
+		{name: "curve mismatch", key: session.Key{Algorithm: algorithmES256, PrivateKey: mustECDSAKey(t, elliptic.P384())}},
+		{name: "weak rsa", key: session.Key{Algorithm: algorithmRS256, PrivateKey: mustRSAKey(t, 1024)}},
+		{name: "rsa key for ecdsa", key: session.Key{Algorithm: algorithmES256, PrivateKey: mustRSAKey(t, 2048)}},
+		{name: "secret for rsa", key: session.Key{Algorithm: algorithmRS256, Material: []byte("secret")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewManager(Config{SigningKey: tt.key})
+			if !errors.Is(err, ErrInvalidKeyMaterial) {
+				t.Fatalf("expected ErrInvalidKeyMaterial, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateTokenRejectsAlgorithmConfusion(t *testing.T) {
+	rsaKey := mustRSAKey(t, 2048)
+	public := session.Key{ID: "key-1", PublicKey: rsaKey.Public()}
+
+	// An attacker signs an HS256 token using the public key bytes as secret.
+	forger := newTestManager(t, Config{
+		SigningKey: session.Key{
+			ID:        "key-1",
+			Algorithm: algorithmHS256,
+			Material:  rsaKey.N.Bytes(),
+		},
+	})
+	token, err := forger.IssueToken(context.Background(), "user-1", nil, time.Minute)
+	if err != nil {
+		t.Fatalf("IssueToken returned error: %v", err)
+	}
+
+	validator := newTestManager(t, Config{
+		KeyResolver: staticResolver{keys: map[string]session.Key{"key-1": public}},
+	})
+	_, err = validator.ValidateToken(context.Background(), token)
+	if !errors.Is(err, ErrInvalidKeyMaterial) {
+		t.Fatalf("expected ErrInvalidKeyMaterial, got: %v", err)
+	}
+}
+
+func TestPublicKeyManagerIsVerifyOnly(t *testing.T) {
+	edPublic, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("ed25519.GenerateKey returned error: %v", err)
+	}
+
+	key := session.Key{ID: "key-1", Algorithm: algorithmEdDSA, PrivateKey: edKey}
+	if public := key.Public(); public.PrivateKey != nil || !edPublic.Equal(public.PublicKey) {
+		t.Fatalf("expected verification-only key, got %+v", public)
+	}
+
+	manager := newTestManager(t, Config{SigningKey: key})
+	token, err := manager.IssueToken(context.Background(), "user-1", nil, time.Minute)
+	if err != nil {
+		t.Fatalf("IssueToken returned error: %v", err)
+	}
+
+	verifier := newTestManager(t, Config{SigningKey: key.Public()})
+	if _, err := verifier.ValidateToken(context.Background(), token); err != nil {
+		t.Fatalf("ValidateToken returned error: %v", err)
+	}
+	if _, err := verifier.IssueToken(context.Background(), "user-1", nil, time.Minute); !errors.Is(err, ErrMissingSigningKey) {
+		t.Fatalf("expected verify-only manager to refuse issuance, got: %v", err)
+	}
+}
+
+func mustRSAKey(t *testing.T, bits int) *rsa.PrivateKey {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey returned error: %v", err)
+	}
+	return key
+}
+
+func mustECDSAKey(t *testing.T, curve elliptic.Curve) *ecdsa.PrivateKey {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		t.Fatalf("ecdsa.GenerateKey returned error: %v", err)
+	}
+	return key
+}
+
+func decodeHeader(t *testing.T, token string) map[string]any {
+	t.Helper()
+
+	raw, err := base64.RawURLEncoding.DecodeString(strings.Split(token, ".")[0])
+	if err != nil {
+		t.Fatalf("decode header: %v", err)
+	}
+	header := map[string]any{}
+	if err := json.Unmarshal(raw, &header); err != nil {
+		t.Fatalf("unmarshal header: %v", err)
+	}
+	return header
+}
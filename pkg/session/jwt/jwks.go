@@ -0,0 +1,347 @@
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/porthorian/openauth/pkg/session"
+)
+
+const (
+	jwkUseSignature = "sig"
+
+	defaultJWKSMaxAge = 5 * time.Minute
+)
+
+var (
+	ErrNilKeySet       = errors.New("session/jwt: key set is nil")
+	ErrInvalidJWK      = errors.New("session/jwt: invalid JSON web key")
+	ErrUnsupportedJWK  = errors.New("session/jwt: unsupported JSON web key type")
+	ErrMissingJWKKeyID = errors.New("session/jwt: JSON web key is missing kid")
+)
+
+// JSONWebKey is the public RFC 7517 representation of a verification key.
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg,omitempty"`
+	Use       string `json:"use,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	Y         string `json:"y,omitempty"`
+}
+
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+type JWKSHandlerConfig struct {
+	MaxAge time.Duration
+}
+
+var _ session.KeySet = (*Manager)(nil)
+
+// VerificationKeys returns the public form of the manager's signing key plus
+// every key exposed by the resolver when it implements session.KeySet.
+func (m *Manager) VerificationKeys(ctx context.Context) ([]session.Key, error) {
+	if m == nil {
+		return nil, fmt.Errorf("%w: manager is nil", ErrInvalidConfig)
+	}
+
+	keys := make([]session.Key, 0, 1)
+	seen := map[string]struct{}{}
+	if hasVerificationMaterial(m.signingKey) {
+		keys = append(keys, m.signingKey.Public())
+		seen[m.signingKey.ID] = struct{}{}
+	}
+
+	keySet, ok := m.keyResolver.(session.KeySet)
+	if !ok {
+		return keys, nil
+	}
+
+	resolved, err := keySet.VerificationKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range resolved {
+		key = normalizeKey(key).Public()
+		if _, exists := seen[key.ID]; exists {
+			continue
+		}
+		seen[key.ID] = struct{}{}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// BuildJWKS converts verification keys into a JWKS document. Symmetric keys
+// are skipped because publishing them would leak the signing secret.
+func BuildJWKS(keys []session.Key) (JSONWebKeySet, error) {
+	set := JSONWebKeySet{Keys: make([]JSONWebKey, 0, len(keys))}
+	for _, key := range keys {
+		key = normalizeKey(key)
+		if !key.IsAsymmetric() {
+			continue
+		}
+
+		jwk, err := newJSONWebKey(key)
+		if err != nil {
+			return JSONWebKeySet{}, err
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set, nil
+}
+
+// ParseJWKS decodes a JWKS document into verification-only keys, suitable for
+// serving from a session.KeyResolver in downstream services.
+func ParseJWKS(data []byte) ([]session.Key, error) {
+	var set JSONWebKeySet
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidJWK, err)
+	}
+
+	keys := make([]session.Key, 0, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != jwkUseSignature {
+			continue
+		}
+
+		key, err := jwk.sessionKey()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// NewJWKSHandler serves the key set as application/jwk-set+json. The document
+// is rebuilt on every request so rotations show up without a restart, while
+// Cache-Control and ETag let clients and proxies avoid refetching.
+func NewJWKSHandler(keySet session.KeySet, config JWKSHandlerConfig) http.Handler {
+	maxAge := config.MaxAge
+	if maxAge <= 0 {
+		maxAge = defaultJWKSMaxAge
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		if keySet == nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		keys, err := keySet.VerificationKeys(r.Context())
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+			return
+		}
+
+		set, err := BuildJWKS(keys)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		body, err := json.Marshal(set)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		sum := sha256.Sum256(body)
+		etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+		w.Header().Set("Content-Type", "application/jwk-set+json")
+		w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(int(maxAge/time.Second)))
+		w.Header().Set("ETag", etag)
+
+		if match := r.Header.Get("If-None-Match"); match != "" && strings.Contains(match, etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(body)
+		}
+	})
+}
+
+func newJSONWebKey(key session.Key) (JSONWebKey, error) {
+	if key.ID == "" {
+		return JSONWebKey{}, ErrMissingJWKKeyID
+	}
+
+	algorithm := key.Algorithm
+	if algorithm == "" {
+		algorithm = inferAlgorithm(key)
+	}
+
+	jwk := JSONWebKey{
+		KeyID:     key.ID,
+		Algorithm: algorithm,
+		Use:       jwkUseSignature,
+	}
+
+	switch public := verificationPublicKey(key).(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = encodeSegment(public.N.Bytes())
+		jwk.E = encodeSegment(big.NewInt(int64(public.E)).Bytes())
+	case *ecdsa.PublicKey:
+		curveName, ok := jwkCurveName(public.Curve)
+		if !ok {
+			return JSONWebKey{}, fmt.Errorf("%w: curve %s", ErrUnsupportedJWK, public.Curve.Params().Name)
+		}
+		ecdhKey, err := public.ECDH()
+		if err != nil {
+			return JSONWebKey{}, fmt.Errorf("%w: %v", ErrInvalidJWK, err)
+		}
+		// Uncompressed point: 0x04 || X || Y, each coordinate fixed width.
+		point := ecdhKey.Bytes()
+		size := curveByteSize(public.Curve)
+		jwk.KeyType = "EC"
+		jwk.Curve = curveName
+		jwk.X = encodeSegment(point[1 : 1+size])
+		jwk.Y = encodeSegment(point[1+size:])
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = encodeSegment(public)
+	default:
+		return JSONWebKey{}, fmt.Errorf("%w: %T", ErrUnsupportedJWK, public)
+	}
+
+	return jwk, nil
+}
+
+func (jwk JSONWebKey) sessionKey() (session.Key, error) {
+	kid := strings.TrimSpace(jwk.KeyID)
+	if kid == "" {
+		return session.Key{}, ErrMissingJWKKeyID
+	}
+
+	var public crypto.PublicKey
+	switch jwk.KeyType {
+	case "RSA":
+		n, err := decodeSegment(jwk.N)
+		if err != nil {
+			return session.Key{}, err
+		}
+		e, err := decodeSegment(jwk.E)
+		if err != nil {
+			return session.Key{}, err
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() > int64(^uint32(0)>>1) {
+			return session.Key{}, fmt.Errorf("%w: RSA exponent out of range", ErrInvalidJWK)
+		}
+		public = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}
+	case "EC":
+		curve, ok := jwkCurve(jwk.Curve)
+		if !ok {
+			return session.Key{}, fmt.Errorf("%w: curve %s", ErrUnsupportedJWK, jwk.Curve)
+		}
+		x, err := decodeSegment(jwk.X)
+		if err != nil {
+			return session.Key{}, err
+		}
+		y, err := decodeSegment(jwk.Y)
+		if err != nil {
+			return session.Key{}, err
+		}
+		size := curveByteSize(curve)
+		if len(x) != size || len(y) != size {
+			return session.Key{}, fmt.Errorf("%w: EC coordinates have the wrong length", ErrInvalidJWK)
+		}
+		point := append([]byte{4}, append(x, y...)...)
+		ecdsaKey, err := ecdsa.ParseUncompressedPublicKey(curve, point)
+		if err != nil {
+			return session.Key{}, fmt.Errorf("%w: %v", ErrInvalidJWK, err)
+		}
+		public = ecdsaKey
+	case "OKP":
+		if jwk.Curve != "Ed25519" {
+			return session.Key{}, fmt.Errorf("%w: curve %s", ErrUnsupportedJWK, jwk.Curve)
+		}
+		x, err := decodeSegment(jwk.X)
+		if err != nil {
+			return session.Key{}, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return session.Key{}, fmt.Errorf("%w: Ed25519 key has the wrong length", ErrInvalidJWK)
+		}
+		public = ed25519.PublicKey(x)
+	default:
+		return session.Key{}, fmt.Errorf("%w: %s", ErrUnsupportedJWK, jwk.KeyType)
+	}
+
+	return normalizeKey(session.Key{
+		ID:        kid,
+		Algorithm: jwk.Algorithm,
+		PublicKey: public,
+	}), nil
+}
+
+func jwkCurveName(curve elliptic.Curve) (string, bool) {
+	switch curve {
+	case elliptic.P256():
+		return "P-256", true
+	case elliptic.P384():
+		return "P-384", true
+	case elliptic.P521():
+		return "P-521", true
+	default:
+		return "", false
+	}
+}
+
+func jwkCurve(name string) (elliptic.Curve, bool) {
+	switch name {
+	case "P-256":
+		return elliptic.P256(), true
+	case "P-384":
+		return elliptic.P384(), true
+	case "P-521":
+		return elliptic.P521(), true
+	default:
+		return nil, false
+	}
+}
+
+func encodeSegment(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeSegment(value string) ([]byte, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidJWK, err)
+	}
+	return decoded, nil
+}
@@ -0,0 +1,171 @@
+package jwt
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/porthorian/openauth/pkg/session"
+)
+
+func TestBuildJWKSRoundTrip(t *testing.T) {
+	now := time.Date(2026, 3, 4, 12, 0, 0, 0, time.UTC)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("ed25519.GenerateKey returned error: %v", err)
+	}
+
+	signingKeys := []session.Key{
+		{ID: "rsa-1", Algorithm: algorithmPS256, PrivateKey: mustRSAKey(t, 2048)},
+		{ID: "ec-1", Algorithm: algorithmES384, PrivateKey: mustECDSAKey(t, elliptic.P384())},
+		{ID: "ed-1", Algorithm: algorithmEdDSA, PrivateKey: edKey},
+	}
+
+	public := make([]session.Key, 0, len(signingKeys))
+	for _, key := range signingKeys {
+		public = append(public, key.Public())
+	}
+	public = append(public, session.Key{ID: "hmac-1", Algorithm: algorithmHS256, Material: []byte("secret")})
+
+	set, err := BuildJWKS(public)
+	if err != nil {
+		t.Fatalf("BuildJWKS returned error: %v", err)
+	}
+	if len(set.Keys) != 3 {
+		t.Fatalf("expected symmetric key to be skipped, got %d keys", len(set.Keys))
+	}
+	for _, jwk := range set.Keys {
+		if jwk.Use != "sig" || jwk.Algorithm == "" || jwk.KeyID == "" {
+			t.Fatalf("incomplete jwk: %+v", jwk)
+		}
+	}
+
+	raw, err := json.Marshal(set)
+	if err != nil {
+		t.Fatalf("json.Marshal returned error: %v", err)
+	}
+	parsed, err := ParseJWKS(raw)
+	if err != nil {
+		t.Fatalf("ParseJWKS returned error: %v", err)
+	}
+
+	resolver := staticResolver{keys: map[string]session.Key{}}
+	for _, key := range parsed {
+		if key.PrivateKey != nil {
+			t.Fatalf("expected parsed keys to be verification-only")
+		}
+		resolver.keys[key.ID] = key
+	}
+
+	validator := newTestManager(t, Config{KeyResolver: resolver, Now: func() time.Time { return now }})
+	for _, key := range signingKeys {
+		signer := newTestManager(t, Config{SigningKey: key, Now: func() time.Time { return now }})
+		token, err := signer.IssueToken(context.Background(), "user-1", nil, time.Minute)
+		if err != nil {
+			t.Fatalf("IssueToken returned error: %v", err)
+		}
+		if _, err := validator.ValidateToken(context.Background(), token); err != nil {
+			t.Fatalf("ValidateToken with %s returned error: %v", key.ID, err)
+		}
+	}
+}
+
+func TestJWKSHandlerServesRotatingKeySet(t *testing.T) {
+	keySet := &mutableKeySet{keys: []session.Key{
+		{ID: "ec-1", Algorithm: algorithmES256, PrivateKey: mustECDSAKey(t, elliptic.P256())},
+	}}
+	manager := newTestManager(t, Config{KeyResolver: keySet})
+	handler := NewJWKSHandler(manager, JWKSHandlerConfig{MaxAge: time.Minute})
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", recorder.Code)
+	}
+	if got := recorder.Header().Get("Cache-Control"); got != "public, max-age=60" {
+		t.Fatalf("unexpected Cache-Control: %q", got)
+	}
+	if got := recorder.Header().Get("Content-Type"); got != "application/jwk-set+json" {
+		t.Fatalf("unexpected Content-Type: %q", got)
+	}
+	etag := recorder.Header().Get("ETag")
+	if etag == "" {
+		t.Fatalf("expected ETag header")
+	}
+
+	var set JSONWebKeySet
+	if err := json.Unmarshal(recorder.Body.Bytes(), &set); err != nil {
+		t.Fatalf("unmarshal JWKS: %v", err)
+	}
+	if len(set.Keys) != 1 || set.Keys[0].KeyID != "ec-1" || set.Keys[0].Curve != "P-256" {
+		t.Fatalf("unexpected JWKS: %+v", set)
+	}
+
+	request := httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+	request.Header.Set("If-None-Match", etag)
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusNotModified {
+		t.Fatalf("expected 304, got %d", recorder.Code)
+	}
+
+	keySet.add(session.Key{ID: "ec-2", Algorithm: algorithmES256, PrivateKey: mustECDSAKey(t, elliptic.P256())})
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected rotated key set to invalidate ETag, got %d", recorder.Code)
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &set); err != nil {
+		t.Fatalf("unmarshal JWKS: %v", err)
+	}
+	if len(set.Keys) != 2 {
+		t.Fatalf("expected rotated key to be published, got %+v", set)
+	}
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/.well-known/jwks.json", nil))
+	if recorder.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405, got %d", recorder.Code)
+	}
+}
+
+type mutableKeySet struct {
+	mu   sync.Mutex
+	keys []session.Key
+}
+
+func (s *mutableKeySet) add(key session.Key) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = append(s.keys, key)
+}
+
+func (s *mutableKeySet) ResolveKey(ctx context.Context, keyID string) (session.Key, error) {
+	_ = ctx
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, key := range s.keys {
+		if key.ID == keyID {
+			return key.Public(), nil
+		}
+	}
+	return session.Key{}, ErrUnknownKeyID
+}
+
+func (s *mutableKeySet) VerificationKeys(ctx context.Context) ([]session.Key, error) {
+	_ = ctx
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := make([]session.Key, 0, len(s.keys))
+	for _, key := range s.keys {
+		keys = append(keys, key.Public())
+	}
+	return keys, nil
+}
@@ -0,0 +1,315 @@
+package jwt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/porthorian/openauth/pkg/session"
+)
+
+var (
+	ErrNoActiveSigningKey = errors.New("session/jwt: keyring has no active signing key")
+	ErrKeyNotYetValid     = errors.New("session/jwt: key is not valid yet")
+	ErrKeyRetired         = errors.New("session/jwt: key has been retired")
+	ErrDuplicateKeyID     = errors.New("session/jwt: duplicate key ID")
+	ErrInvalidKeyWindow   = errors.New("session/jwt: key not-after must be later than not-before")
+)
+
+// KeyringEntry is a key plus its signing window. The key signs new tokens
+// between NotBefore and NotAfter (open-ended when zero) and is accepted for
+// verification from publication until NotAfter plus the keyring's
+// MaxTokenTTL, so tokens it signed last can still be validated.
+type KeyringEntry struct {
+	Key       session.Key
+	NotBefore time.Time
+	NotAfter  time.Time
+}
+
+// DefaultMaxTokenTTL is how long retired keys keep verifying when
+// KeyringConfig.MaxTokenTTL is zero. Set MaxTokenTTL explicitly when tokens
+// live longer.
+const DefaultMaxTokenTTL = 24 * time.Hour
+
+type KeyringConfig struct {
+	Keys []KeyringEntry
+	// MaxTokenTTL is the longest lifetime of any token signed by the
+	// keyring; retired keys verify for this long after NotAfter. Zero uses
+	// DefaultMaxTokenTTL.
+	MaxTokenTTL time.Duration
+	// PublishAhead limits how early upcoming keys are exposed through
+	// VerificationKeys and ResolveKey. Zero publishes them immediately.
+	PublishAhead time.Duration
+	Now          func() time.Time
+}
+
+type KeyState string
+
+const (
+	KeyStateUpcoming KeyState = "upcoming"
+	KeyStateActive   KeyState = "active"
+	KeyStateRetiring KeyState = "retiring"
+	KeyStateExpired  KeyState = "expired"
+)
+
+// Keyring is a rotating session.KeyResolver. It also implements
+// session.SigningKeyProvider and session.KeySet so a Manager configured with
+// it as KeyResolver signs with the current key and publishes the rest.
+type Keyring struct {
+	maxTokenTTL  time.Duration
+	publishAhead time.Duration
+	now          func() time.Time
+
+	mu      sync.RWMutex
+	entries []KeyringEntry
+}
+
+var _ session.KeyResolver = (*Keyring)(nil)
+var _ session.SigningKeyProvider = (*Keyring)(nil)
+var _ session.KeySet = (*Keyring)(nil)
+
+func NewKeyring(config KeyringConfig) (*Keyring, error) {
+	if config.MaxTokenTTL < 0 {
+		return nil, fmt.Errorf("%w: max token ttl cannot be negative", ErrInvalidConfig)
+	}
+	if config.PublishAhead < 0 {
+		return nil, fmt.Errorf("%w: publish ahead cannot be negative", ErrInvalidConfig)
+	}
+
+	if config.MaxTokenTTL == 0 {
+		config.MaxTokenTTL = DefaultMaxTokenTTL
+	}
+	nowFn := config.Now
+	if nowFn == nil {
+		nowFn = time.Now
+	}
+
+	keyring := &Keyring{
+		maxTokenTTL:  config.MaxTokenTTL,
+		publishAhead: config.PublishAhead,
+		now:          nowFn,
+	}
+	if err := keyring.Replace(config.Keys); err != nil {
+		return nil, err
+	}
+	return keyring, nil
+}
+
+// Replace swaps the whole key set atomically, which is how external key
+// sources reload the keyring.
+func (k *Keyring) Replace(entries []KeyringEntry) error {
+	normalized, err := normalizeKeyringEntries(entries)
+	if err != nil {
+		return err
+	}
+
+	k.mu.Lock()
+	k.entries = normalized
+	k.mu.Unlock()
+	return nil
+}
+
+func (k *Keyring) Add(entry KeyringEntry) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	entries := append(append([]KeyringEntry(nil), k.entries...), entry)
+	normalized, err := normalizeKeyringEntries(entries)
+	if err != nil {
+		return err
+	}
+	k.entries = normalized
+	return nil
+}
+
+func (k *Keyring) Remove(keyID string) bool {
+	keyID = strings.TrimSpace(keyID)
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	for i, entry := range k.entries {
+		if entry.Key.ID == keyID {
+			k.entries = append(k.entries[:i:i], k.entries[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (k *Keyring) Entries() []KeyringEntry {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return append([]KeyringEntry(nil), k.entries...)
+}
+
+// Keys lists every held key with its material, whatever its window. Callers
+// that must keep verifying stored values after a key retires, such as
+// pepper and field encryption keyrings, read keys through it.
+func (k *Keyring) Keys() []session.Key {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	keys := make([]session.Key, 0, len(k.entries))
+	for _, entry := range k.entries {
+		keys = append(keys, entry.Key)
+	}
+	return keys
+}
+
+func (k *Keyring) State(keyID string) (KeyState, bool) {
+	now := k.now().UTC()
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	for _, entry := range k.entries {
+		if entry.Key.ID == strings.TrimSpace(keyID) {
+			return k.stateAt(entry, now), true
+		}
+	}
+	return "", false
+}
+
+// SigningKey returns the active key with the most recent NotBefore, so an
+// upcoming key takes over as soon as its window opens.
+func (k *Keyring) SigningKey(ctx context.Context) (session.Key, error) {
+	_ = ctx
+	now := k.now().UTC()
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	for i := len(k.entries) - 1; i >= 0; i-- {
+		entry := k.entries[i]
+		if k.stateAt(entry, now) != KeyStateActive || !hasSigningMaterial(entry.Key) {
+			continue
+		}
+		return entry.Key, nil
+	}
+	return session.Key{}, ErrNoActiveSigningKey
+}
+
+// ResolveKey returns the verification-only form of the key. An empty key ID
+// resolves to the current signing key.
+func (k *Keyring) ResolveKey(ctx context.Context, keyID string) (session.Key, error) {
+	keyID = strings.TrimSpace(keyID)
+	if keyID == "" {
+		key, err := k.SigningKey(ctx)
+		if err != nil {
+			return session.Key{}, err
+		}
+		return key.Public(), nil
+	}
+
+	now := k.now().UTC()
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	for _, entry := range k.entries {
+		if entry.Key.ID != keyID {
+			continue
+		}
+
+		switch k.stateAt(entry, now) {
+		case KeyStateExpired:
+			return session.Key{}, fmt.Errorf("%w: %s", ErrKeyRetired, keyID)
+		case KeyStateUpcoming:
+			if !k.published(entry, now) {
+				return session.Key{}, fmt.Errorf("%w: %s", ErrKeyNotYetValid, keyID)
+			}
+		}
+		return entry.Key.Public(), nil
+	}
+	return session.Key{}, fmt.Errorf("%w: %s", ErrUnknownKeyID, keyID)
+}
+
+// VerificationKeys lists published upcoming, active and retiring keys.
+func (k *Keyring) VerificationKeys(ctx context.Context) ([]session.Key, error) {
+	_ = ctx
+	now := k.now().UTC()
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	keys := make([]session.Key, 0, len(k.entries))
+	for _, entry := range k.entries {
+		switch k.stateAt(entry, now) {
+		case KeyStateExpired:
+			continue
+		case KeyStateUpcoming:
+			if !k.published(entry, now) {
+				continue
+			}
+		}
+		keys = append(keys, entry.Key.Public())
+	}
+	return keys, nil
+}
+
+func (k *Keyring) stateAt(entry KeyringEntry, now time.Time) KeyState {
+	if !entry.NotBefore.IsZero() && now.Before(entry.NotBefore) {
+		return KeyStateUpcoming
+	}
+	if entry.NotAfter.IsZero() || now.Before(entry.NotAfter) {
+		return KeyStateActive
+	}
+	if now.Before(entry.NotAfter.Add(k.maxTokenTTL)) {
+		return KeyStateRetiring
+	}
+	return KeyStateExpired
+}
+
+func (k *Keyring) published(entry KeyringEntry, now time.Time) bool {
+	return k.publishAhead == 0 || !now.Add(k.publishAhead).Before(entry.NotBefore)
+}
+
+// normalizeKeyringEntries validates entries and orders them by NotBefore so
+// the newest active key is found by scanning from the end.
+func normalizeKeyringEntries(entries []KeyringEntry) ([]KeyringEntry, error) {
+	normalized := make([]KeyringEntry, 0, len(entries))
+	seen := map[string]struct{}{}
+
+	for _, entry := range entries {
+		entry.Key = normalizeKey(entry.Key)
+		if entry.Key.ID == "" {
+			return nil, fmt.Errorf("%w: keyring entries require a key ID", ErrInvalidConfig)
+		}
+		if _, exists := seen[entry.Key.ID]; exists {
+			return nil, fmt.Errorf("%w: %s", ErrDuplicateKeyID, entry.Key.ID)
+		}
+		seen[entry.Key.ID] = struct{}{}
+
+		if !hasVerificationMaterial(entry.Key) {
+			return nil, fmt.Errorf("%w: %s", ErrMissingValidationKey, entry.Key.ID)
+		}
+		if entry.Key.Algorithm == "" {
+			entry.Key.Algorithm = defaultAlgorithm(entry.Key)
+		}
+		if err := validateKeyForAlgorithm(entry.Key, entry.Key.Algorithm, hasSigningMaterial(entry.Key)); err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Key.ID, err)
+		}
+
+		if !entry.NotBefore.IsZero() {
+			entry.NotBefore = entry.NotBefore.UTC()
+		}
+		if !entry.NotAfter.IsZero() {
+			entry.NotAfter = entry.NotAfter.UTC()
+			if !entry.NotBefore.IsZero() && !entry.NotAfter.After(entry.NotBefore) {
+				return nil, fmt.Errorf("%w: %s", ErrInvalidKeyWindow, entry.Key.ID)
+			}
+		}
+
+		normalized = append(normalized, entry)
+	}
+
+	sort.SliceStable(normalized, func(i, j int) bool {
+		return normalized[i].NotBefore.Before(normalized[j].NotBefore)
+	})
+	return normalized, nil
+}
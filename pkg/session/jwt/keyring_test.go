@@ -0,0 +1,196 @@
+package jwt
+
+import (
+	"context"
+	"crypto/elliptic"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/porthorian/openauth/pkg/session"
+)
+
+func TestKeyringRotatesSigningKeyWithOverlap(t *testing.T) {
+	start := time.Date(2026, 3, 4, 12, 0, 0, 0, time.UTC)
+	now := start
+
+	keyring, err := NewKeyring(KeyringConfig{
+		Keys: []KeyringEntry{
+			{
+				Key:      session.Key{ID: "key-1", PrivateKey: mustECDSAKey(t, elliptic.P256())},
+				NotAfter: start.Add(time.Hour),
+			},
+			{
+				Key:       session.Key{ID: "key-2", PrivateKey: mustECDSAKey(t, elliptic.P256())},
+				NotBefore: start.Add(time.Hour),
+			},
+		},
+		MaxTokenTTL: 15 * time.Minute,
+		Now:         func() time.Time { return now },
+	})
+	if err != nil {
+		t.Fatalf("NewKeyring returned error: %v", err)
+	}
+
+	manager := newTestManager(t, Config{
+		KeyResolver: keyring,
+		ClockSkew:   time.Nanosecond,
+		Now:         func() time.Time { return now },
+	})
+
+	oldToken, err := manager.IssueToken(context.Background(), "user-1", nil, 15*time.Minute)
+	if err != nil {
+		t.Fatalf("IssueToken returned error: %v", err)
+	}
+	if kid := decodeHeader(t, oldToken)["kid"]; kid != "key-1" {
+		t.Fatalf("expected key-1 to sign, got %v", kid)
+	}
+
+	published, err := manager.VerificationKeys(context.Background())
+	if err != nil {
+		t.Fatalf("VerificationKeys returned error: %v", err)
+	}
+	if len(published) != 2 {
+		t.Fatalf("expected current and upcoming keys to be published, got %d", len(published))
+	}
+
+	now = start.Add(time.Hour - time.Minute)
+	lastOldToken, err := manager.IssueToken(context.Background(), "user-1", nil, 15*time.Minute)
+	if err != nil {
+		t.Fatalf("IssueToken returned error: %v", err)
+	}
+
+	now = start.Add(time.Hour)
+	newToken, err := manager.IssueToken(context.Background(), "user-1", nil, 15*time.Minute)
+	if err != nil {
+		t.Fatalf("IssueToken returned error: %v", err)
+	}
+	if kid := decodeHeader(t, newToken)["kid"]; kid != "key-2" {
+		t.Fatalf("expected signing kid to switch to key-2, got %v", kid)
+	}
+	if state, _ := keyring.State("key-1"); state != KeyStateRetiring {
+		t.Fatalf("expected key-1 to be retiring, got %s", state)
+	}
+
+	now = start.Add(time.Hour + 10*time.Minute)
+	if _, err := manager.ValidateToken(context.Background(), lastOldToken); err != nil {
+		t.Fatalf("expected retiring key to keep validating, got: %v", err)
+	}
+
+	now = start.Add(time.Hour + 15*time.Minute)
+	if _, err := manager.ValidateToken(context.Background(), oldToken); err == nil {
+		t.Fatalf("expected token to fail after retirement window")
+	}
+	if _, err := keyring.ResolveKey(context.Background(), "key-1"); !errors.Is(err, ErrKeyRetired) {
+		t.Fatalf("expected ErrKeyRetired, got: %v", err)
+	}
+	if _, err := manager.ValidateToken(context.Background(), newToken); err != nil {
+		t.Fatalf("ValidateToken returned error: %v", err)
+	}
+
+	published, err = keyring.VerificationKeys(context.Background())
+	if err != nil {
+		t.Fatalf("VerificationKeys returned error: %v", err)
+	}
+	if len(published) != 1 || published[0].ID != "key-2" || published[0].PrivateKey != nil {
+		t.Fatalf("expected only public key-2 to remain published, got %+v", published)
+	}
+}
+
+func TestKeyringDefaultsMaxTokenTTL(t *testing.T) {
+	now := time.Date(2026, 3, 4, 12, 0, 0, 0, time.UTC)
+	keyring, err := NewKeyring(KeyringConfig{
+		Keys: []KeyringEntry{
+			{Key: session.Key{ID: "key-1", Algorithm: algorithmHS256, Material: []byte("secret-1")}, NotAfter: now},
+			{Key: session.Key{ID: "key-2", Algorithm: algorithmHS256, Material: []byte("secret-2")}, NotBefore: now},
+		},
+		Now: func() time.Time { return now },
+	})
+	if err != nil {
+		t.Fatalf("NewKeyring returned error: %v", err)
+	}
+
+	now = now.Add(DefaultMaxTokenTTL - time.Minute)
+	if _, err := keyring.ResolveKey(context.Background(), "key-1"); err != nil {
+		t.Fatalf("expected retired key-1 to verify within DefaultMaxTokenTTL, got: %v", err)
+	}
+	now = now.Add(time.Minute)
+	if _, err := keyring.ResolveKey(context.Background(), "key-1"); !errors.Is(err, ErrKeyRetired) {
+		t.Fatalf("expected ErrKeyRetired after DefaultMaxTokenTTL, got: %v", err)
+	}
+	if keys := keyring.Keys(); len(keys) != 2 || keys[0].ID != "key-1" || string(keys[0].Material) != "secret-1" {
+		t.Fatalf("expected Keys to keep the expired key with its material, got %+v", keys)
+	}
+}
+
+func TestKeyringPublishAheadHidesDistantKeys(t *testing.T) {
+	now := time.Date(2026, 3, 4, 12, 0, 0, 0, time.UTC)
+	keyring, err := NewKeyring(KeyringConfig{
+		Keys: []KeyringEntry{
+			{Key: session.Key{ID: "key-1", Algorithm: algorithmHS256, Material: []byte("secret-1")}},
+			{Key: session.Key{ID: "key-2", Algorithm: algorithmHS256, Material: []byte("secret-2")}, NotBefore: now.Add(48 * time.Hour)},
+		},
+		PublishAhead: 24 * time.Hour,
+		Now:          func() time.Time { return now },
+	})
+	if err != nil {
+		t.Fatalf("NewKeyring returned error: %v", err)
+	}
+
+	if _, err := keyring.ResolveKey(context.Background(), "key-2"); !errors.Is(err, ErrKeyNotYetValid) {
+		t.Fatalf("expected ErrKeyNotYetValid, got: %v", err)
+	}
+
+	now = now.Add(25 * time.Hour)
+	if _, err := keyring.ResolveKey(context.Background(), "key-2"); err != nil {
+		t.Fatalf("expected key-2 to be published, got: %v", err)
+	}
+	signing, err := keyring.SigningKey(context.Background())
+	if err != nil {
+		t.Fatalf("SigningKey returned error: %v", err)
+	}
+	if signing.ID != "key-1" {
+		t.Fatalf("expected key-1 to keep signing, got %s", signing.ID)
+	}
+}
+
+func TestKeyringRejectsInvalidEntries(t *testing.T) {
+	now := time.Date(2026, 3, 4, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		entries []KeyringEntry
+		want    error
+	}{
+		{
+			name: "duplicate kid",
+			entries: []KeyringEntry{
+				{Key: session.Key{ID: "key-1", Material: []byte("a")}},
+				{Key: session.Key{ID: "key-1", Material: []byte("b")}},
+			},
+			want: ErrDuplicateKeyID,
+		},
+		{
+			name: "inverted window",
+			entries: []KeyringEntry{
+				{Key: session.Key{ID: "key-1", Material: []byte("a")}, NotBefore: now, NotAfter: now.Add(-time.Minute)},
+			},
+			want: ErrInvalidKeyWindow,
+		},
+		{
+			name: "missing material",
+			entries: []KeyringEntry{
+				{Key: session.Key{ID: "key-1"}},
+			},
+			want: ErrMissingValidationKey,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewKeyring(KeyringConfig{Keys: tt.entries})
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got: %v", tt.want, err)
+			}
+		})
+	}
+}
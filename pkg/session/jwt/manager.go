@@ -2,16 +2,12 @@ package jwt
 
 import (
 	"context"
-	"crypto/hmac"
-	"crypto/sha256"
-	"crypto/sha512"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -47,6 +43,7 @@ var (
 	ErrInvalidAudience      = errors.New("session/jwt: audience claim does not match configuration")
 
 	ErrInvalidSessionToken = errors.New("session/jwt: token is not a session token")
+	ErrTokenRevoked        = errors.New("session/jwt: token has been revoked")
 )
 
 type Config struct {
@@ -57,6 +54,10 @@ type Config struct {
 	ClockSkew    time.Duration
 	Now          func() time.Time
 	SessionClaim string
+	// RevocationStore holds revoked jti values. Defaults to a process-local
+	// MemoryRevocationStore; use a storage or cache backed store to share
+	// revocations across replicas and restarts.
+	RevocationStore session.RevocationStore
 }
 
 type Manager struct {
@@ -67,9 +68,7 @@ type Manager struct {
 	clockSkew    time.Duration
 	now          func() time.Time
 	sessionClaim string
-
-	revokedMu sync.RWMutex
-	revoked   map[string]time.Time
+	revocations  session.RevocationStore
 }
 
 var _ session.TokenIssuer = (*Manager)(nil)
@@ -78,19 +77,23 @@ var _ session.SessionManager = (*Manager)(nil)
 
 func NewManager(config Config) (*Manager, error) {
 	signingKey := normalizeKey(config.SigningKey)
-	if len(signingKey.Material) > 0 {
+	// A SigningKey holding only a public key yields a verify-only manager.
+	if hasVerificationMaterial(signingKey) {
 		if signingKey.ID == "" {
 			signingKey.ID = defaultSigningKeyID
 		}
 		if signingKey.Algorithm == "" {
-			signingKey.Algorithm = algorithmHS256
+			signingKey.Algorithm = defaultAlgorithm(signingKey)
 		}
 		if !isSupportedAlgorithm(signingKey.Algorithm) {
 			return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, signingKey.Algorithm)
 		}
+		if err := validateKeyForAlgorithm(signingKey, signingKey.Algorithm, hasSigningMaterial(signingKey)); err != nil {
+			return nil, err
+		}
 	}
 
-	if len(signingKey.Material) == 0 && config.KeyResolver == nil {
+	if !hasVerificationMaterial(signingKey) && config.KeyResolver == nil {
 		return nil, fmt.Errorf("%w: either signing key or key resolver must be configured", ErrInvalidConfig)
 	}
 
@@ -112,6 +115,11 @@ func NewManager(config Config) (*Manager, error) {
 		sessionClaim = defaultSessionClaim
 	}
 
+	revocations := config.RevocationStore
+	if revocations == nil {
+		revocations = NewMemoryRevocationStore(nowFn)
+	}
+
 	return &Manager{
 		signingKey:   signingKey,
 		keyResolver:  config.KeyResolver,
@@ -120,18 +128,18 @@ func NewManager(config Config) (*Manager, error) {
 		clockSkew:    clockSkew,
 		now:          nowFn,
 		sessionClaim: sessionClaim,
-		revoked:      map[string]time.Time{},
+		revocations:  revocations,
 	}, nil
 }
 
 func (m *Manager) IssueToken(ctx context.Context, subject string, claims session.Claims, ttl time.Duration) (string, error) {
-	_ = ctx
-
 	if m == nil {
 		return "", fmt.Errorf("%w: manager is nil", ErrInvalidConfig)
 	}
-	if len(m.signingKey.Material) == 0 {
-		return "", ErrMissingSigningKey
+
+	signingKey, err := m.currentSigningKey(ctx)
+	if err != nil {
+		return "", err
 	}
 	if ttl <= 0 {
 		return "", ErrInvalidTTL
@@ -164,16 +172,40 @@ func (m *Manager) IssueToken(ctx context.Context, subject string, claims session
 
 	header := map[string]any{
 		"typ": "JWT",
-		"alg": m.signingKey.Algorithm,
+		"alg": signingKey.Algorithm,
 	}
-	if m.signingKey.ID != "" {
-		header["kid"] = m.signingKey.ID
+	if signingKey.ID != "" {
+		header["kid"] = signingKey.ID
 	}
 
-	return encodeAndSignToken(header, payload, m.signingKey)
+	return encodeAndSignToken(header, payload, signingKey)
 }
 
+// ValidateToken verifies the signature and registered claims, then rejects
+// tokens whose jti has been revoked with ErrTokenRevoked.
 func (m *Manager) ValidateToken(ctx context.Context, token string) (session.Claims, error) {
+	claims, err := m.verifyToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	jti, ok := claimString(claims, "jti")
+	if !ok || strings.TrimSpace(jti) == "" {
+		return claims, nil
+	}
+
+	revoked, err := m.revocations.IsTokenRevoked(ctx, strings.TrimSpace(jti))
+	if err != nil {
+		return nil, fmt.Errorf("session/jwt: revocation lookup failed: %w", err)
+	}
+	if revoked {
+		return nil, ErrTokenRevoked
+	}
+
+	return claims, nil
+}
+
+func (m *Manager) verifyToken(ctx context.Context, token string) (session.Claims, error) {
 	if m == nil {
 		return nil, fmt.Errorf("%w: manager is nil", ErrInvalidConfig)
 	}
@@ -187,7 +219,7 @@ func (m *Manager) ValidateToken(ctx context.Context, token string) (session.Clai
 	if err != nil || strings.TrimSpace(alg) == "" {
 		return nil, fmt.Errorf("%w: missing alg header", ErrInvalidToken)
 	}
-	alg = strings.ToUpper(strings.TrimSpace(alg))
+	alg = canonicalAlgorithm(alg)
 	if !isSupportedAlgorithm(alg) {
 		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, alg)
 	}
@@ -226,51 +258,93 @@ func (m *Manager) IssueSession(ctx context.Context, subject string, ttl time.Dur
 }
 
 func (m *Manager) ValidateSession(ctx context.Context, sessionID string) (bool, error) {
-	claims, err := m.ValidateToken(ctx, sessionID)
+	claims, err := m.verifyToken(ctx, sessionID)
 	if err != nil {
 		return false, err
 	}
 
-	isSession, ok := claimBool(claims, m.sessionClaim)
-	if !ok || !isSession {
-		return false, ErrInvalidSessionToken
+	jti, err := m.sessionTokenID(claims)
+	if err != nil {
+		return false, err
 	}
 
-	jti, ok := claimString(claims, "jti")
-	if !ok || strings.TrimSpace(jti) == "" {
-		return false, ErrInvalidSessionToken
+	revoked, err := m.revocations.IsTokenRevoked(ctx, jti)
+	if err != nil {
+		return false, fmt.Errorf("session/jwt: revocation lookup failed: %w", err)
 	}
-
-	if m.isRevoked(jti) {
+	if revoked {
 		return false, nil
 	}
 
 	return true, nil
 }
 
+// RevokeSession records the session jti in the RevocationStore until the
+// token's exp. Revoking an already revoked session is a no-op.
 func (m *Manager) RevokeSession(ctx context.Context, sessionID string) error {
-	claims, err := m.ValidateToken(ctx, sessionID)
+	claims, err := m.verifyToken(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+
+	jti, err := m.sessionTokenID(claims)
+	if err != nil {
+		return err
+	}
+
+	expiresAt, err := readTimeClaim(claims, "exp", true)
 	if err != nil {
 		return err
 	}
 
+	if err := m.revocations.RevokeToken(ctx, jti, expiresAt); err != nil {
+		return fmt.Errorf("session/jwt: revocation write failed: %w", err)
+	}
+	return nil
+}
+
+func (m *Manager) sessionTokenID(claims session.Claims) (string, error) {
 	isSession, ok := claimBool(claims, m.sessionClaim)
 	if !ok || !isSession {
-		return ErrInvalidSessionToken
+		return "", ErrInvalidSessionToken
 	}
 
 	jti, ok := claimString(claims, "jti")
 	if !ok || strings.TrimSpace(jti) == "" {
-		return ErrInvalidSessionToken
+		return "", ErrInvalidSessionToken
 	}
+	return strings.TrimSpace(jti), nil
+}
 
-	expiresAt, err := readTimeClaim(claims, "exp", true)
+// currentSigningKey prefers the statically configured key and otherwise asks
+// a KeyResolver that also implements session.SigningKeyProvider, so rotating
+// keyrings switch the signing kid without rebuilding the manager.
+func (m *Manager) currentSigningKey(ctx context.Context) (session.Key, error) {
+	if hasSigningMaterial(m.signingKey) {
+		return m.signingKey, nil
+	}
+
+	provider, ok := m.keyResolver.(session.SigningKeyProvider)
+	if !ok {
+		return session.Key{}, ErrMissingSigningKey
+	}
+
+	key, err := provider.SigningKey(ctx)
 	if err != nil {
-		return err
+		return session.Key{}, fmt.Errorf("%w: %v", ErrMissingSigningKey, err)
 	}
 
-	m.markRevoked(jti, expiresAt)
-	return nil
+	key = normalizeKey(key)
+	if !hasSigningMaterial(key) {
+		return session.Key{}, ErrMissingSigningKey
+	}
+	if key.Algorithm == "" {
+		key.Algorithm = defaultAlgorithm(key)
+	}
+	if err := validateKeyForAlgorithm(key, key.Algorithm, true); err != nil {
+		return session.Key{}, err
+	}
+	return key, nil
 }
 
 func (m *Manager) resolveValidationKey(ctx context.Context, keyID string, algorithm string) (session.Key, error) {
@@ -280,8 +354,8 @@ func (m *Manager) resolveValidationKey(ctx context.Context, keyID string, algori
 			return session.Key{}, fmt.Errorf("%w: %v", ErrMissingValidationKey, err)
 		}
 
-		key = normalizeKey(key)
-		if len(key.Material) == 0 {
+		key = normalizeKey(key).Public()
+		if !hasVerificationMaterial(key) {
 			return session.Key{}, ErrMissingValidationKey
 		}
 
@@ -295,8 +369,8 @@ func (m *Manager) resolveValidationKey(ctx context.Context, keyID string, algori
 		return key, nil
 	}
 
-	key := normalizeKey(m.signingKey)
-	if len(key.Material) == 0 {
+	key := normalizeKey(m.signingKey).Public()
+	if !hasVerificationMaterial(key) {
 		return session.Key{}, ErrMissingValidationKey
 	}
 
@@ -363,57 +437,6 @@ func (m *Manager) validateRegisteredClaims(claims session.Claims) error {
 	return nil
 }
 
-func (m *Manager) markRevoked(jti string, expiresAt time.Time) {
-	jti = strings.TrimSpace(jti)
-	if jti == "" {
-		return
-	}
-
-	now := m.now().UTC()
-	if !expiresAt.IsZero() && now.After(expiresAt) {
-		return
-	}
-
-	m.revokedMu.Lock()
-	defer m.revokedMu.Unlock()
-
-	m.cleanupRevokedLocked(now)
-	m.revoked[jti] = expiresAt
-}
-
-func (m *Manager) isRevoked(jti string) bool {
-	jti = strings.TrimSpace(jti)
-	if jti == "" {
-		return false
-	}
-
-	now := m.now().UTC()
-
-	m.revokedMu.Lock()
-	defer m.revokedMu.Unlock()
-
-	expiresAt, found := m.revoked[jti]
-	if !found {
-		m.cleanupRevokedLocked(now)
-		return false
-	}
-
-	if !expiresAt.IsZero() && now.After(expiresAt) {
-		delete(m.revoked, jti)
-		return false
-	}
-
-	return true
-}
-
-func (m *Manager) cleanupRevokedLocked(now time.Time) {
-	for jti, expiresAt := range m.revoked {
-		if !expiresAt.IsZero() && now.After(expiresAt) {
-			delete(m.revoked, jti)
-		}
-	}
-}
-
 type parsedToken struct {
 	header       map[string]any
 	claims       session.Claims
@@ -484,46 +507,6 @@ func encodeAndSignToken(header map[string]any, claims session.Claims, key sessio
 	return signingInput + "." + encodedSignature, nil
 }
 
-func signSignature(signingInput string, key session.Key) ([]byte, error) {
-	if len(key.Material) == 0 {
-		return nil, ErrMissingSigningKey
-	}
-
-	switch key.Algorithm {
-	case algorithmHS256:
-		mac := hmac.New(sha256.New, key.Material)
-		_, _ = mac.Write([]byte(signingInput))
-		return mac.Sum(nil), nil
-	case algorithmHS384:
-		mac := hmac.New(sha512.New384, key.Material)
-		_, _ = mac.Write([]byte(signingInput))
-		return mac.Sum(nil), nil
-	case algorithmHS512:
-		mac := hmac.New(sha512.New, key.Material)
-		_, _ = mac.Write([]byte(signingInput))
-		return mac.Sum(nil), nil
-	default:
-		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, key.Algorithm)
-	}
-}
-
-func verifySignature(signingInput string, signature []byte, key session.Key) error {
-	if len(key.Material) == 0 {
-		return ErrMissingValidationKey
-	}
-
-	expected, err := signSignature(signingInput, key)
-	if err != nil {
-		return err
-	}
-
-	if !hmac.Equal(expected, signature) {
-		return fmt.Errorf("%w: signature mismatch", ErrInvalidToken)
-	}
-
-	return nil
-}
-
 func validateCustomClaims(claims session.Claims) error {
 	for key := range claims {
 		switch key {
@@ -711,17 +694,13 @@ func normalizeAudience(audience []string) []string {
 func normalizeKey(key session.Key) session.Key {
 	normalized := key
 	normalized.ID = strings.TrimSpace(normalized.ID)
-	normalized.Algorithm = strings.ToUpper(strings.TrimSpace(normalized.Algorithm))
+	normalized.Algorithm = canonicalAlgorithm(normalized.Algorithm)
 	return normalized
 }
 
 func isSupportedAlgorithm(algorithm string) bool {
-	switch strings.ToUpper(strings.TrimSpace(algorithm)) {
-	case algorithmHS256, algorithmHS384, algorithmHS512:
-		return true
-	default:
-		return false
-	}
+	_, ok := algorithmSpecs[canonicalAlgorithm(algorithm)]
+	return ok
 }
 
 func cloneClaims(claims session.Claims) session.Claims {
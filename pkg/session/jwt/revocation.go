@@ -0,0 +1,86 @@
+package jwt
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/porthorian/openauth/pkg/session"
+)
+
+// MemoryRevocationStore is the process-local session.RevocationStore used
+// when a Manager is configured without one. Revocations are neither shared
+// across replicas nor kept across restarts.
+type MemoryRevocationStore struct {
+	now func() time.Time
+
+	mu      sync.Mutex
+	revoked map[string]time.Time
+}
+
+var _ session.RevocationStore = (*MemoryRevocationStore)(nil)
+
+func NewMemoryRevocationStore(now func() time.Time) *MemoryRevocationStore {
+	if now == nil {
+		now = time.Now
+	}
+	return &MemoryRevocationStore{
+		now:     now,
+		revoked: map[string]time.Time{},
+	}
+}
+
+func (s *MemoryRevocationStore) RevokeToken(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	_ = ctx
+	tokenID = strings.TrimSpace(tokenID)
+	if tokenID == "" {
+		return nil
+	}
+
+	now := s.now().UTC()
+	if !expiresAt.IsZero() && now.After(expiresAt) {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cleanupLocked(now)
+	s.revoked[tokenID] = expiresAt
+	return nil
+}
+
+func (s *MemoryRevocationStore) IsTokenRevoked(ctx context.Context, tokenID string) (bool, error) {
+	_ = ctx
+	tokenID = strings.TrimSpace(tokenID)
+	if tokenID == "" {
+		return false, nil
+	}
+
+	now := s.now().UTC()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expiresAt, found := s.revoked[tokenID]
+	if !found {
+		s.cleanupLocked(now)
+		return false, nil
+	}
+
+	if !expiresAt.IsZero() && now.After(expiresAt) {
+		delete(s.revoked, tokenID)
+		return false, nil
+	}
+
+	return true, nil
+}
+
+func (s *MemoryRevocationStore) cleanupLocked(now time.Time) {
+	for tokenID, expiresAt := range s.revoked {
+		if !expiresAt.IsZero() && now.After(expiresAt) {
+			delete(s.revoked, tokenID)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package jwt
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/porthorian/openauth/pkg/session"
+)
+
+func TestRevocationIsSharedAcrossManagers(t *testing.T) {
+	now := time.Date(2026, 3, 4, 12, 0, 0, 0, time.UTC)
+	store := NewMemoryRevocationStore(func() time.Time { return now })
+	config := Config{
+		SigningKey: session.Key{
+			ID:        "key-1",
+			Algorithm: algorithmHS256,
+			Material:  []byte("test-secret-signing-key"),
+		},
+		ClockSkew:       0,
+		Now:             func() time.Time { return now },
+		RevocationStore: store,
+	}
+	issuer := newTestManager(t, config)
+	replica := newTestManager(t, config)
+
+	ctx := context.Background()
+	sessionToken, err := issuer.IssueSession(ctx, "user-1", 5*time.Minute)
+	if err != nil {
+		t.Fatalf("IssueSession returned error: %v", err)
+	}
+
+	if err := issuer.RevokeSession(ctx, sessionToken); err != nil {
+		t.Fatalf("RevokeSession returned error: %v", err)
+	}
+	if err := issuer.RevokeSession(ctx, sessionToken); err != nil {
+		t.Fatalf("RevokeSession on revoked session returned error: %v", err)
+	}
+
+	ok, err := replica.ValidateSession(ctx, sessionToken)
+	if err != nil || ok {
+		t.Fatalf("replica ValidateSession = (%v, %v), want revoked", ok, err)
+	}
+	if _, err := replica.ValidateToken(ctx, sessionToken); !errors.Is(err, ErrTokenRevoked) {
+		t.Fatalf("replica ValidateToken error = %v, want ErrTokenRevoked", err)
+	}
+
+	jti := mustSessionID(t, issuer, sessionToken)
+	now = now.Add(10 * time.Minute)
+	if revoked, err := store.IsTokenRevoked(ctx, jti); err != nil || revoked {
+		t.Fatalf("IsTokenRevoked after exp = (%v, %v), want expired entry", revoked, err)
+	}
+}
+
+func TestValidateTokenFailsClosedOnRevocationStoreError(t *testing.T) {
+	storeErr := errors.New("store down")
+	manager := newTestManager(t, Config{
+		SigningKey: session.Key{
+			ID:        "key-1",
+			Algorithm: algorithmHS256,
+			Material:  []byte("test-secret-signing-key"),
+		},
+		RevocationStore: failingRevocationStore{err: storeErr},
+	})
+
+	ctx := context.Background()
+	sessionToken, err := manager.IssueSession(ctx, "user-1", 5*time.Minute)
+	if err != nil {
+		t.Fatalf("IssueSession returned error: %v", err)
+	}
+
+	if _, err := manager.ValidateToken(ctx, sessionToken); !errors.Is(err, storeErr) {
+		t.Fatalf("ValidateToken error = %v, want store error", err)
+	}
+	if ok, err := manager.ValidateSession(ctx, sessionToken); !errors.Is(err, storeErr) || ok {
+		t.Fatalf("ValidateSession = (%v, %v), want store error", ok, err)
+	}
+	if err := manager.RevokeSession(ctx, sessionToken); !errors.Is(err, storeErr) {
+		t.Fatalf("RevokeSession error = %v, want store error", err)
+	}
+
+	token, err := manager.IssueToken(ctx, "user-1", nil, 5*time.Minute)
+	if err != nil {
+		t.Fatalf("IssueToken returned error: %v", err)
+	}
+	if _, err := manager.ValidateToken(ctx, token); err != nil {
+		t.Fatalf("ValidateToken without jti returned error: %v", err)
+	}
+}
+
+func mustSessionID(t *testing.T, manager *Manager, token string) string {
+	t.Helper()
+
+	claims, err := manager.verifyToken(context.Background(), token)
+	if err != nil {
+		t.Fatalf("verifyToken returned error: %v", err)
+	}
+	return mustString(t, claims["jti"])
+}
+
+type failingRevocationStore struct {
+	err error
+}
+
+func (s failingRevocationStore) RevokeToken(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	return s.err
+}
+
+func (s failingRevocationStore) IsTokenRevoked(ctx context.Context, tokenID string) (bool, error) {
+	return false, s.err
+}
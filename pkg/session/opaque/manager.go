@@ -0,0 +1,215 @@
+// Package opaque implements session.SessionManager with server-side session
+// records. Clients hold a random session ID; the store only ever sees its
+// SHA-256 digest, so a leaked session table cannot be replayed.
+package opaque
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/porthorian/openauth/pkg/session"
+	"github.com/porthorian/openauth/pkg/storage"
+)
+
+const sessionIDBytes = 32
+
+var (
+	ErrMissingStore     = errors.New("session/opaque: session store is required")
+	ErrInvalidSubject   = errors.New("session/opaque: invalid subject")
+	ErrInvalidTTL       = errors.New("session/opaque: ttl must be greater than zero")
+	ErrInvalidSessionID = errors.New("session/opaque: invalid session ID")
+	ErrSessionNotFound  = errors.New("session/opaque: session not found")
+)
+
+type Config struct {
+	Store storage.SessionStore
+	Now   func() time.Time
+}
+
+// IssueInput describes a session to create. AuthID links the session to the
+// auth material that established it; storage adapters delete the session
+// when that material is deleted.
+type IssueInput struct {
+	Subject  string
+	AuthID   string
+	Tenant   string
+	Metadata map[string]string
+	TTL      time.Duration
+}
+
+// Session is a validated session as seen by callers. ID is the client-held
+// session ID, not the stored digest.
+type Session struct {
+	ID        string
+	AuthID    string
+	Subject   string
+	Tenant    string
+	ExpiresAt time.Time
+	Metadata  map[string]string
+}
+
+type Manager struct {
+	store storage.SessionStore
+	now   func() time.Time
+}
+
+var _ session.SessionManager = (*Manager)(nil)
+
+func NewManager(config Config) (*Manager, error) {
+	if config.Store == nil {
+		return nil, ErrMissingStore
+	}
+
+	now := config.Now
+	if now == nil {
+		now = time.Now
+	}
+
+	return &Manager{
+		store: config.Store,
+		now:   now,
+	}, nil
+}
+
+func (m *Manager) IssueSession(ctx context.Context, subject string, ttl time.Duration) (string, error) {
+	issued, err := m.Issue(ctx, IssueInput{Subject: subject, TTL: ttl})
+	if err != nil {
+		return "", err
+	}
+	return issued.ID, nil
+}
+
+// Issue creates a session record and returns it with the session ID the
+// client should present.
+func (m *Manager) Issue(ctx context.Context, input IssueInput) (Session, error) {
+	subject := strings.TrimSpace(input.Subject)
+	if subject == "" {
+		return Session{}, ErrInvalidSubject
+	}
+	if input.TTL <= 0 {
+		return Session{}, ErrInvalidTTL
+	}
+
+	sessionID, err := newSessionID()
+	if err != nil {
+		return Session{}, err
+	}
+
+	issued := Session{
+		ID:        sessionID,
+		AuthID:    strings.TrimSpace(input.AuthID),
+		Subject:   subject,
+		Tenant:    strings.TrimSpace(input.Tenant),
+		ExpiresAt: m.now().UTC().Add(input.TTL),
+		Metadata:  cloneMetadata(input.Metadata),
+	}
+
+	record := storage.SessionRecord{
+		ID:        digestSessionID(sessionID),
+		AuthID:    issued.AuthID,
+		Subject:   issued.Subject,
+		Tenant:    issued.Tenant,
+		ExpiresAt: issued.ExpiresAt,
+		Metadata:  cloneMetadata(issued.Metadata),
+	}
+	if err := m.store.PutSession(ctx, record); err != nil {
+		return Session{}, fmt.Errorf("session/opaque: session write failed: %w", err)
+	}
+
+	return issued, nil
+}
+
+func (m *Manager) ValidateSession(ctx context.Context, sessionID string) (bool, error) {
+	_, err := m.Lookup(ctx, sessionID)
+	if errors.Is(err, ErrSessionNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Lookup returns the live session for sessionID. Unknown and expired
+// sessions both yield ErrSessionNotFound; expired records are deleted.
+func (m *Manager) Lookup(ctx context.Context, sessionID string) (Session, error) {
+	sessionID = strings.TrimSpace(sessionID)
+	if !validSessionID(sessionID) {
+		return Session{}, ErrInvalidSessionID
+	}
+
+	digest := digestSessionID(sessionID)
+	record, err := m.store.GetSession(ctx, digest)
+	if errors.Is(err, storage.ErrNotFound) {
+		return Session{}, ErrSessionNotFound
+	}
+	if err != nil {
+		return Session{}, fmt.Errorf("session/opaque: session lookup failed: %w", err)
+	}
+
+	if !m.now().UTC().Before(record.ExpiresAt) {
+		if err := m.store.DeleteSession(ctx, digest); err != nil {
+			return Session{}, fmt.Errorf("session/opaque: expired session delete failed: %w", err)
+		}
+		return Session{}, ErrSessionNotFound
+	}
+
+	return Session{
+		ID:        sessionID,
+		AuthID:    record.AuthID,
+		Subject:   record.Subject,
+		Tenant:    record.Tenant,
+		ExpiresAt: record.ExpiresAt,
+		Metadata:  cloneMetadata(record.Metadata),
+	}, nil
+}
+
+// RevokeSession deletes the session record. Revoking an unknown or already
+// revoked session is not an error.
+func (m *Manager) RevokeSession(ctx context.Context, sessionID string) error {
+	sessionID = strings.TrimSpace(sessionID)
+	if !validSessionID(sessionID) {
+		return ErrInvalidSessionID
+	}
+
+	if err := m.store.DeleteSession(ctx, digestSessionID(sessionID)); err != nil {
+		return fmt.Errorf("session/opaque: session delete failed: %w", err)
+	}
+	return nil
+}
+
+func newSessionID() (string, error) {
+	raw := make([]byte, sessionIDBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("session/opaque: generate session ID: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+func validSessionID(sessionID string) bool {
+	raw, err := base64.RawURLEncoding.DecodeString(sessionID)
+	return err == nil && len(raw) == sessionIDBytes
+}
+
+func digestSessionID(sessionID string) string {
+	sum := sha256.Sum256([]byte(sessionID))
+	return hex.EncodeToString(sum[:])
+}
+
+func cloneMetadata(metadata map[string]string) map[string]string {
+	if len(metadata) == 0 {
+		return map[string]string{}
+	}
+	cloned := make(map[string]string, len(metadata))
+	for key, value := range metadata {
+		cloned[key] = value
+	}
+	return cloned
+}
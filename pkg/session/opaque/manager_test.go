@@ -0,0 +1,169 @@
+package opaque
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/porthorian/openauth/pkg/storage"
+)
+
+func TestIssueStoresDigestAndLooksUpSession(t *testing.T) {
+	now := time.Date(2026, 3, 4, 12, 0, 0, 0, time.UTC)
+	store := newMemorySessionStore()
+	manager := newTestManager(t, store, func() time.Time { return now })
+
+	ctx := context.Background()
+	issued, err := manager.Issue(ctx, IssueInput{
+		Subject:  " user-1 ",
+		AuthID:   "auth-1",
+		Tenant:   "tenant-a",
+		Metadata: map[string]string{"ip": "127.0.0.1"},
+		TTL:      time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("Issue returned error: %v", err)
+	}
+
+	if _, found := store.records[issued.ID]; found {
+		t.Fatalf("store should not hold the raw session ID")
+	}
+	record, found := store.records[digestSessionID(issued.ID)]
+	if !found {
+		t.Fatalf("store has no record for session digest")
+	}
+	if record.Subject != "user-1" || record.AuthID != "auth-1" || record.Tenant != "tenant-a" {
+		t.Fatalf("record = %+v, want trimmed subject and auth/tenant", record)
+	}
+
+	got, err := manager.Lookup(ctx, issued.ID)
+	if err != nil {
+		t.Fatalf("Lookup returned error: %v", err)
+	}
+	if got.ID != issued.ID || got.Metadata["ip"] != "127.0.0.1" || !got.ExpiresAt.Equal(now.Add(time.Hour)) {
+		t.Fatalf("Lookup = %+v, want issued session", got)
+	}
+}
+
+func TestValidateSessionLifecycle(t *testing.T) {
+	now := time.Date(2026, 3, 4, 12, 0, 0, 0, time.UTC)
+	store := newMemorySessionStore()
+	manager := newTestManager(t, store, func() time.Time { return now })
+
+	ctx := context.Background()
+	revoked, err := manager.IssueSession(ctx, "user-1", time.Minute)
+	if err != nil {
+		t.Fatalf("IssueSession returned error: %v", err)
+	}
+	expiring, err := manager.IssueSession(ctx, "user-1", time.Minute)
+	if err != nil {
+		t.Fatalf("IssueSession returned error: %v", err)
+	}
+
+	if ok, err := manager.ValidateSession(ctx, revoked); err != nil || !ok {
+		t.Fatalf("ValidateSession = (%v, %v), want valid", ok, err)
+	}
+
+	if err := manager.RevokeSession(ctx, revoked); err != nil {
+		t.Fatalf("RevokeSession returned error: %v", err)
+	}
+	if err := manager.RevokeSession(ctx, revoked); err != nil {
+		t.Fatalf("RevokeSession on revoked session returned error: %v", err)
+	}
+	if ok, err := manager.ValidateSession(ctx, revoked); err != nil || ok {
+		t.Fatalf("ValidateSession after revoke = (%v, %v), want invalid", ok, err)
+	}
+
+	now = now.Add(time.Minute)
+	if ok, err := manager.ValidateSession(ctx, expiring); err != nil || ok {
+		t.Fatalf("ValidateSession after expiry = (%v, %v), want invalid", ok, err)
+	}
+	if len(store.records) != 0 {
+		t.Fatalf("expired session should be deleted, store has %d records", len(store.records))
+	}
+}
+
+func TestManagerRejectsInvalidInput(t *testing.T) {
+	if _, err := NewManager(Config{}); !errors.Is(err, ErrMissingStore) {
+		t.Fatalf("NewManager error = %v, want ErrMissingStore", err)
+	}
+
+	manager := newTestManager(t, newMemorySessionStore(), nil)
+	ctx := context.Background()
+
+	if _, err := manager.IssueSession(ctx, " ", time.Minute); !errors.Is(err, ErrInvalidSubject) {
+		t.Fatalf("IssueSession error = %v, want ErrInvalidSubject", err)
+	}
+	if _, err := manager.IssueSession(ctx, "user-1", 0); !errors.Is(err, ErrInvalidTTL) {
+		t.Fatalf("IssueSession error = %v, want ErrInvalidTTL", err)
+	}
+	if _, err := manager.ValidateSession(ctx, "not-a-session"); !errors.Is(err, ErrInvalidSessionID) {
+		t.Fatalf("ValidateSession error = %v, want ErrInvalidSessionID", err)
+	}
+	if err := manager.RevokeSession(ctx, ""); !errors.Is(err, ErrInvalidSessionID) {
+		t.Fatalf("RevokeSession error = %v, want ErrInvalidSessionID", err)
+	}
+}
+
+func TestValidateSessionSurfacesStoreErrors(t *testing.T) {
+	storeErr := errors.New("store down")
+	store := newMemorySessionStore()
+	manager := newTestManager(t, store, nil)
+
+	ctx := context.Background()
+	sessionID, err := manager.IssueSession(ctx, "user-1", time.Minute)
+	if err != nil {
+		t.Fatalf("IssueSession returned error: %v", err)
+	}
+
+	store.err = storeErr
+	if ok, err := manager.ValidateSession(ctx, sessionID); !errors.Is(err, storeErr) || ok {
+		t.Fatalf("ValidateSession = (%v, %v), want store error", ok, err)
+	}
+}
+
+func newTestManager(t *testing.T, store storage.SessionStore, now func() time.Time) *Manager {
+	t.Helper()
+	manager, err := NewManager(Config{Store: store, Now: now})
+	if err != nil {
+		t.Fatalf("NewManager returned error: %v", err)
+	}
+	return manager
+}
+
+type memorySessionStore struct {
+	records map[string]storage.SessionRecord
+	err     error
+}
+
+func newMemorySessionStore() *memorySessionStore {
+	return &memorySessionStore{records: map[string]storage.SessionRecord{}}
+}
+
+func (s *memorySessionStore) PutSession(ctx context.Context, record storage.SessionRecord) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.records[record.ID] = record
+	return nil
+}
+
+func (s *memorySessionStore) GetSession(ctx context.Context, id string) (storage.SessionRecord, error) {
+	if s.err != nil {
+		return storage.SessionRecord{}, s.err
+	}
+	record, found := s.records[id]
+	if !found {
+		return storage.SessionRecord{}, storage.ErrNotFound
+	}
+	return record, nil
+}
+
+func (s *memorySessionStore) DeleteSession(ctx context.Context, id string) error {
+	if s.err != nil {
+		return s.err
+	}
+	delete(s.records, id)
+	return nil
+}
@@ -2,21 +2,63 @@ package session
 
 import (
 	"context"
+	"crypto"
 	"time"
 )
 
 type Claims map[string]any
 
+// Key carries either a symmetric secret (Material) for HMAC algorithms or an
+// asymmetric key pair for RSA, RSA-PSS, ECDSA and Ed25519 algorithms.
+// PublicKey is derived from PrivateKey when unset.
 type Key struct {
-	ID        string
-	Algorithm string
-	Material  []byte
+	ID         string
+	Algorithm  string
+	Material   []byte
+	PrivateKey crypto.Signer
+	PublicKey  crypto.PublicKey
 }
 
+// Public returns the verification-only form of the key. Symmetric keys are
+// returned unchanged because the secret is also the verification key.
+func (k Key) Public() Key {
+	if k.PrivateKey == nil {
+		return k
+	}
+
+	public := k
+	if public.PublicKey == nil {
+		public.PublicKey = k.PrivateKey.Public()
+	}
+	public.PrivateKey = nil
+	return public
+}
+
+// IsAsymmetric reports whether the key holds public/private key material
+// rather than a shared secret.
+func (k Key) IsAsymmetric() bool {
+	return k.PrivateKey != nil || k.PublicKey != nil
+}
+
+// KeyResolver looks up verification keys by key ID. Resolvers should return
+// the Public form of asymmetric keys so private material never leaves the
+// signer.
 type KeyResolver interface {
 	ResolveKey(ctx context.Context, keyID string) (Key, error)
 }
 
+// SigningKeyProvider supplies the key new tokens are signed with, letting a
+// rotating key source switch the signing kid without a restart.
+type SigningKeyProvider interface {
+	SigningKey(ctx context.Context) (Key, error)
+}
+
+// KeySet enumerates the verification keys currently accepted, including keys
+// that are being retired, so they can be published as a JWKS document.
+type KeySet interface {
+	VerificationKeys(ctx context.Context) ([]Key, error)
+}
+
 type TokenValidator interface {
 	ValidateToken(ctx context.Context, token string) (Claims, error)
 }
@@ -30,3 +72,11 @@ type SessionManager interface {
 	ValidateSession(ctx context.Context, sessionID string) (bool, error)
 	RevokeSession(ctx context.Context, sessionID string) error
 }
+
+// RevocationStore records revoked token IDs (the jti claim) until the token
+// itself expires. Implementations stop reporting an ID as revoked once
+// expiresAt has passed, so entries never outlive the token they block.
+type RevocationStore interface {
+	RevokeToken(ctx context.Context, tokenID string, expiresAt time.Time) error
+	IsTokenRevoked(ctx context.Context, tokenID string) (bool, error)
+}
@@ -2,9 +2,14 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"time"
 )
 
+// ErrNotFound is returned by single-record getters such as GetAuth,
+// GetSession and GetOAuthClient when no record has the requested ID.
+var ErrNotFound = errors.New("storage: record not found")
+
 type AuthMaterialType string
 
 const (
@@ -13,6 +18,13 @@ const (
 	AuthMaterialTypeRefreshToken AuthMaterialType = "refresh_token"
 	AuthMaterialTypeAPIKey       AuthMaterialType = "api_key"
 	AuthMaterialTypeClientSecret AuthMaterialType = "client_secret"
+	AuthMaterialTypeTOTP         AuthMaterialType = "totp"
+	AuthMaterialTypeMFAChallenge AuthMaterialType = "mfa_challenge"
+	AuthMaterialTypeRecoveryCode AuthMaterialType = "recovery_code"
+	// AuthMaterialTypeWebAuthn records hold a credential's COSE public key,
+	// not a hash; only the private key on the authenticator is secret.
+	AuthMaterialTypeWebAuthn          AuthMaterialType = "webauthn"
+	AuthMaterialTypeWebAuthnChallenge AuthMaterialType = "webauthn_challenge"
 )
 
 type AuthStatus string
@@ -53,6 +65,20 @@ type SessionRecord struct {
 	Metadata  map[string]string
 }
 
+// OAuthClientRecord registers an OAuth client. The client authenticates as
+// the subject ID; its secrets are AuthMaterialTypeClientSecret records
+// linked to that subject.
+type OAuthClientRecord struct {
+	ID           string
+	Name         string
+	Tenant       string
+	GrantTypes   []string
+	Scopes       []string
+	RedirectURIs []string
+	DateAdded    time.Time
+	DateModified *time.Time
+}
+
 type SubjectRoleRecord struct {
 	Subject string
 	Tenant  string
@@ -83,6 +109,10 @@ const (
 	AuthLogEventDeleted   AuthLogEvent = "deleted"
 	AuthLogEventExpired   AuthLogEvent = "expired"
 	AuthLogEventRevoked   AuthLogEvent = "revoked"
+	AuthLogEventUnlocked  AuthLogEvent = "unlocked"
+	// AuthLogEventDeactivated marks material retired without suspicion,
+	// such as a password replaced by rotation.
+	AuthLogEventDeactivated AuthLogEvent = "deactivated"
 )
 
 type AuthLogRecord struct {
@@ -100,6 +130,15 @@ type AuthStore interface {
 	GetAuth(ctx context.Context, id string) (AuthRecord, error)
 	GetAuths(ctx context.Context, ids []string) ([]AuthRecord, error)
 	DeleteAuth(ctx context.Context, id string) error
+	// SwapAuthStatus sets the status of id to to only while it is still from,
+	// and reports whether it did. It is a single conditional update, so of
+	// several concurrent callers at most one sees true.
+	SwapAuthStatus(ctx context.Context, id string, from AuthStatus, to AuthStatus, modifiedAt time.Time) (bool, error)
+	// SwapAuth writes record, metadata included, only while the stored
+	// record's DateModified still equals lastModified, and reports whether it
+	// did. Read-modify-write callers pass the DateModified they read so that
+	// of several concurrent writers at most one succeeds.
+	SwapAuth(ctx context.Context, record AuthRecord, lastModified *time.Time) (bool, error)
 }
 
 type SubjectAuthStore interface {
@@ -115,6 +154,12 @@ type SessionStore interface {
 	DeleteSession(ctx context.Context, id string) error
 }
 
+type OAuthClientStore interface {
+	PutOAuthClient(ctx context.Context, record OAuthClientRecord) error
+	GetOAuthClient(ctx context.Context, id string) (OAuthClientRecord, error)
+	DeleteOAuthClient(ctx context.Context, id string) error
+}
+
 type RoleStore interface {
 	ReplaceSubjectRoles(ctx context.Context, subject string, tenant string, roleKeys []string) error
 	ListSubjectRoles(ctx context.Context, subject string, tenant string) ([]SubjectRoleRecord, error)
@@ -129,6 +174,9 @@ type AuthLogStore interface {
 	PutAuthLog(ctx context.Context, record AuthLogRecord) error
 	ListAuthLogsByAuthID(ctx context.Context, authID string) ([]AuthLogRecord, error)
 	ListAuthLogsBySubject(ctx context.Context, subject string) ([]AuthLogRecord, error)
+	// ListAuthLogsBySubjectSince lists the subject's events that occurred at
+	// or after since, oldest first.
+	ListAuthLogsBySubjectSince(ctx context.Context, subject string, since time.Time) ([]AuthLogRecord, error)
 }
 
 type AuthMaterial struct {
@@ -43,6 +43,7 @@ type PersistencePolicy struct {
 	CacheRole              CacheRole
 	PersistInSourceOfTruth bool
 	AllowNonExpiring       bool
+	DefaultTTL             time.Duration // DefaultTTL expires material created without an expiry when AllowNonExpiring is false; zero rejects it instead.
 	MaxCacheTTL            time.Duration
 	FailureMode            FailureMode
 }
@@ -75,6 +76,7 @@ func DefaultPersistencePolicies() map[AuthProfile]PersistencePolicy {
 			CacheRole:              CacheRoleNone,
 			PersistInSourceOfTruth: true,
 			AllowNonExpiring:       false,
+			DefaultTTL:             365 * 24 * time.Hour,
 			FailureMode:            FailureModeClosed,
 		},
 		AuthProfileRefreshRotating: {
@@ -135,6 +137,7 @@ func DefaultPersistencePolicies() map[AuthProfile]PersistencePolicy {
 			CacheRole:              CacheRoleNone,
 			PersistInSourceOfTruth: true,
 			AllowNonExpiring:       false,
+			DefaultTTL:             90 * 24 * time.Hour,
 			FailureMode:            FailureModeClosed,
 		},
 	}
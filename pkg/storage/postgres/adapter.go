@@ -17,9 +17,11 @@ type Adapter struct {
 }
 
 type preparedStatements struct {
-	putAuth    *sql.Stmt
-	getAuth    *sql.Stmt
-	deleteAuth *sql.Stmt
+	putAuth        *sql.Stmt
+	getAuth        *sql.Stmt
+	deleteAuth     *sql.Stmt
+	swapAuthStatus *sql.Stmt
+	swapAuth       *sql.Stmt
 
 	deleteAuthMetadata *sql.Stmt
 	putAuthMetadata    *sql.Stmt
@@ -30,9 +32,10 @@ type preparedStatements struct {
 	listSubjectAuthByAuthID  *sql.Stmt
 	deleteSubjectAuthByID    *sql.Stmt
 
-	putAuthLog           *sql.Stmt
-	listAuthLogByAuthID  *sql.Stmt
-	listAuthLogBySubject *sql.Stmt
+	putAuthLog                *sql.Stmt
+	listAuthLogByAuthID       *sql.Stmt
+	listAuthLogBySubject      *sql.Stmt
+	listAuthLogBySubjectSince *sql.Stmt
 
 	deleteSubjectRoles               *sql.Stmt
 	putSubjectRole                   *sql.Stmt
@@ -41,6 +44,19 @@ type preparedStatements struct {
 	putSubjectPermissionOverride     *sql.Stmt
 	listSubjectPermissionOverrides   *sql.Stmt
 
+	revokeToken              *sql.Stmt
+	isTokenRevoked           *sql.Stmt
+	deleteExpiredRevocations *sql.Stmt
+
+	putSession            *sql.Stmt
+	getSession            *sql.Stmt
+	deleteSession         *sql.Stmt
+	deleteExpiredSessions *sql.Stmt
+
+	putOAuthClient    *sql.Stmt
+	getOAuthClient    *sql.Stmt
+	deleteOAuthClient *sql.Stmt
+
 	getAuthsMu     sync.Mutex
 	getAuthsBySize map[int]*sql.Stmt
 }
@@ -73,6 +89,20 @@ var fixedPrepareStatementSpecs = []prepareStatementSpec{
 			ps.deleteAuth = stmt
 		},
 	},
+	{
+		label: "swap auth status",
+		query: swapAuthStatusQuery,
+		assign: func(ps *preparedStatements, stmt *sql.Stmt) {
+			ps.swapAuthStatus = stmt
+		},
+	},
+	{
+		label: "swap auth",
+		query: swapAuthQuery,
+		assign: func(ps *preparedStatements, stmt *sql.Stmt) {
+			ps.swapAuth = stmt
+		},
+	},
 	{
 		label: "delete auth metadata",
 		query: deleteAuthMetadataQuery,
@@ -143,6 +173,13 @@ var fixedPrepareStatementSpecs = []prepareStatementSpec{
 			ps.listAuthLogBySubject = stmt
 		},
 	},
+	{
+		label: "list auth log by subject since",
+		query: listAuthLogBySubjectSinceQuery,
+		assign: func(ps *preparedStatements, stmt *sql.Stmt) {
+			ps.listAuthLogBySubjectSince = stmt
+		},
+	},
 	{
 		label: "delete subject roles",
 		query: deleteSubjectRolesQuery,
@@ -185,6 +222,76 @@ var fixedPrepareStatementSpecs = []prepareStatementSpec{
 			ps.listSubjectPermissionOverrides = stmt
 		},
 	},
+	{
+		label: "revoke token",
+		query: revokeTokenQuery,
+		assign: func(ps *preparedStatements, stmt *sql.Stmt) {
+			ps.revokeToken = stmt
+		},
+	},
+	{
+		label: "is token revoked",
+		query: isTokenRevokedQuery,
+		assign: func(ps *preparedStatements, stmt *sql.Stmt) {
+			ps.isTokenRevoked = stmt
+		},
+	},
+	{
+		label: "delete expired revocations",
+		query: deleteExpiredRevocationsQuery,
+		assign: func(ps *preparedStatements, stmt *sql.Stmt) {
+			ps.deleteExpiredRevocations = stmt
+		},
+	},
+	{
+		label: "put session",
+		query: putSessionQuery,
+		assign: func(ps *preparedStatements, stmt *sql.Stmt) {
+			ps.putSession = stmt
+		},
+	},
+	{
+		label: "get session",
+		query: getSessionQuery,
+		assign: func(ps *preparedStatements, stmt *sql.Stmt) {
+			ps.getSession = stmt
+		},
+	},
+	{
+		label: "delete session",
+		query: deleteSessionQuery,
+		assign: func(ps *preparedStatements, stmt *sql.Stmt) {
+			ps.deleteSession = stmt
+		},
+	},
+	{
+		label: "delete expired sessions",
+		query: deleteExpiredSessionsQuery,
+		assign: func(ps *preparedStatements, stmt *sql.Stmt) {
+			ps.deleteExpiredSessions = stmt
+		},
+	},
+	{
+		label: "put oauth client",
+		query: putOAuthClientQuery,
+		assign: func(ps *preparedStatements, stmt *sql.Stmt) {
+			ps.putOAuthClient = stmt
+		},
+	},
+	{
+		label: "get oauth client",
+		query: getOAuthClientQuery,
+		assign: func(ps *preparedStatements, stmt *sql.Stmt) {
+			ps.getOAuthClient = stmt
+		},
+	},
+	{
+		label: "delete oauth client",
+		query: deleteOAuthClientQuery,
+		assign: func(ps *preparedStatements, stmt *sql.Stmt) {
+			ps.deleteOAuthClient = stmt
+		},
+	},
 }
 
 var (
@@ -233,6 +340,8 @@ func (a *Adapter) Close() error {
 		a.stmts.putAuth,
 		a.stmts.getAuth,
 		a.stmts.deleteAuth,
+		a.stmts.swapAuthStatus,
+		a.stmts.swapAuth,
 		a.stmts.deleteAuthMetadata,
 		a.stmts.putAuthMetadata,
 		a.stmts.getAuthMetadata,
@@ -243,12 +352,23 @@ func (a *Adapter) Close() error {
 		a.stmts.putAuthLog,
 		a.stmts.listAuthLogByAuthID,
 		a.stmts.listAuthLogBySubject,
+		a.stmts.listAuthLogBySubjectSince,
 		a.stmts.deleteSubjectRoles,
 		a.stmts.putSubjectRole,
 		a.stmts.listSubjectRoles,
 		a.stmts.deleteSubjectPermissionOverrides,
 		a.stmts.putSubjectPermissionOverride,
 		a.stmts.listSubjectPermissionOverrides,
+		a.stmts.revokeToken,
+		a.stmts.isTokenRevoked,
+		a.stmts.deleteExpiredRevocations,
+		a.stmts.putSession,
+		a.stmts.getSession,
+		a.stmts.deleteSession,
+		a.stmts.deleteExpiredSessions,
+		a.stmts.putOAuthClient,
+		a.stmts.getOAuthClient,
+		a.stmts.deleteOAuthClient,
 	); err != nil {
 		errs = append(errs, err)
 	}
@@ -301,7 +421,7 @@ func (a *Adapter) requirePreparedStatements() error {
 		return ErrAdapterNotInitialized
 	}
 
-	if a.stmts.putAuth == nil || a.stmts.getAuth == nil || a.stmts.deleteAuth == nil {
+	if a.stmts.putAuth == nil || a.stmts.getAuth == nil || a.stmts.deleteAuth == nil || a.stmts.swapAuthStatus == nil || a.stmts.swapAuth == nil {
 		return ErrAdapterNotInitialized
 	}
 	if a.stmts.deleteAuthMetadata == nil || a.stmts.putAuthMetadata == nil || a.stmts.getAuthMetadata == nil {
@@ -310,7 +430,7 @@ func (a *Adapter) requirePreparedStatements() error {
 	if a.stmts.putSubjectAuth == nil || a.stmts.listSubjectAuthBySubject == nil || a.stmts.listSubjectAuthByAuthID == nil || a.stmts.deleteSubjectAuthByID == nil {
 		return ErrAdapterNotInitialized
 	}
-	if a.stmts.putAuthLog == nil || a.stmts.listAuthLogByAuthID == nil || a.stmts.listAuthLogBySubject == nil {
+	if a.stmts.putAuthLog == nil || a.stmts.listAuthLogByAuthID == nil || a.stmts.listAuthLogBySubject == nil || a.stmts.listAuthLogBySubjectSince == nil {
 		return ErrAdapterNotInitialized
 	}
 	if a.stmts.deleteSubjectRoles == nil || a.stmts.putSubjectRole == nil || a.stmts.listSubjectRoles == nil {
@@ -319,6 +439,15 @@ func (a *Adapter) requirePreparedStatements() error {
 	if a.stmts.deleteSubjectPermissionOverrides == nil || a.stmts.putSubjectPermissionOverride == nil || a.stmts.listSubjectPermissionOverrides == nil {
 		return ErrAdapterNotInitialized
 	}
+	if a.stmts.revokeToken == nil || a.stmts.isTokenRevoked == nil || a.stmts.deleteExpiredRevocations == nil {
+		return ErrAdapterNotInitialized
+	}
+	if a.stmts.putSession == nil || a.stmts.getSession == nil || a.stmts.deleteSession == nil || a.stmts.deleteExpiredSessions == nil {
+		return ErrAdapterNotInitialized
+	}
+	if a.stmts.putOAuthClient == nil || a.stmts.getOAuthClient == nil || a.stmts.deleteOAuthClient == nil {
+		return ErrAdapterNotInitialized
+	}
 
 	return nil
 }
@@ -334,6 +463,14 @@ type scanner interface {
 	Scan(dest ...any) error
 }
 
+// notFound maps sql.ErrNoRows from a single-row scan to storage.ErrNotFound.
+func notFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.ErrNotFound
+	}
+	return err
+}
+
 func closeStatements(stmts ...*sql.Stmt) error {
 	var errs []error
 	for _, stmt := range stmts {
@@ -29,6 +29,14 @@ SELECT
 FROM openauth.auth_log
 WHERE subject = $1
 ORDER BY date_added ASC
+`
+
+	listAuthLogBySubjectSinceQuery = `
+SELECT
+  id::text, date_added, auth_id::text, subject, event, occurred_at, metadata
+FROM openauth.auth_log
+WHERE subject = $1 AND occurred_at >= $2
+ORDER BY occurred_at ASC
 `
 )
 
@@ -124,6 +132,32 @@ func (a *Adapter) ListAuthLogsBySubject(ctx context.Context, subject string) ([]
 	return records, nil
 }
 
+func (a *Adapter) ListAuthLogsBySubjectSince(ctx context.Context, subject string, since time.Time) ([]storage.AuthLogRecord, error) {
+	if err := a.requirePreparedStatements(); err != nil {
+		return nil, err
+	}
+
+	rows, err := a.stmts.listAuthLogBySubjectSince.QueryContext(ctx, subject, since.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := []storage.AuthLogRecord{}
+	for rows.Next() {
+		record, scanErr := scanAuthLog(rows)
+		if scanErr != nil {
+			return nil, scanErr
+		}
+		records = append(records, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
 func scanAuthLog(s scanner) (storage.AuthLogRecord, error) {
 	var (
 		record      storage.AuthLogRecord
@@ -35,6 +35,24 @@ WHERE id = $1
 
 	deleteAuthQuery = `DELETE FROM openauth.auth WHERE id = $1`
 
+	swapAuthStatusQuery = `
+UPDATE openauth.auth
+SET status = $1, date_modified = $2
+WHERE id = $3 AND status = $4
+`
+
+	swapAuthQuery = `
+UPDATE openauth.auth
+SET
+  status = $1,
+  date_modified = $2,
+  material_type = $3,
+  material_hash = $4,
+  expires_at = $5,
+  revoked_at = $6
+WHERE id = $7 AND date_modified IS NOT DISTINCT FROM $8
+`
+
 	deleteAuthMetadataQuery = `
 DELETE FROM openauth.auth_metadata
 WHERE auth_id = $1
@@ -115,6 +133,12 @@ func (a *Adapter) putAuthInTx(ctx context.Context, tx *sql.Tx, record storage.Au
 		return err
 	}
 
+	return a.replaceAuthMetadataInTx(ctx, tx, record)
+}
+
+// replaceAuthMetadataInTx swaps the stored metadata of record.ID for
+// record.Metadata.
+func (a *Adapter) replaceAuthMetadataInTx(ctx context.Context, tx *sql.Tx, record storage.AuthRecord) error {
 	deleteMetadataStmt := tx.StmtContext(ctx, a.stmts.deleteAuthMetadata)
 	if _, err := deleteMetadataStmt.ExecContext(ctx, record.ID); err != nil {
 		_ = deleteMetadataStmt.Close()
@@ -145,7 +169,7 @@ func (a *Adapter) GetAuth(ctx context.Context, id string) (storage.AuthRecord, e
 	row := a.stmts.getAuth.QueryRowContext(ctx, id)
 	record, err := scanAuth(row)
 	if err != nil {
-		return storage.AuthRecord{}, err
+		return storage.AuthRecord{}, notFound(err)
 	}
 
 	metadata, err := a.getMetadataByAuthID(ctx, id)
@@ -226,6 +250,92 @@ func (a *Adapter) DeleteAuth(ctx context.Context, id string) error {
 	return err
 }
 
+func (a *Adapter) SwapAuthStatus(ctx context.Context, id string, from storage.AuthStatus, to storage.AuthStatus, modifiedAt time.Time) (bool, error) {
+	if err := a.requirePreparedStatements(); err != nil {
+		return false, err
+	}
+
+	stmt := a.stmts.swapAuthStatus
+	if a.tx != nil {
+		stmt = a.tx.StmtContext(ctx, a.stmts.swapAuthStatus)
+		defer stmt.Close()
+	}
+
+	result, err := stmt.ExecContext(ctx, string(to), modifiedAt.UTC(), id, string(from))
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected == 1, err
+}
+
+func (a *Adapter) SwapAuth(ctx context.Context, record storage.AuthRecord, lastModified *time.Time) (bool, error) {
+	if err := a.requirePreparedStatements(); err != nil {
+		return false, err
+	}
+
+	if a.tx != nil {
+		return a.swapAuthInTx(ctx, a.tx, record, lastModified)
+	}
+
+	db, err := a.requireDB()
+	if err != nil {
+		return false, err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	swapped, err := a.swapAuthInTx(ctx, tx, record, lastModified)
+	if err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+
+	return swapped, nil
+}
+
+func (a *Adapter) swapAuthInTx(ctx context.Context, tx *sql.Tx, record storage.AuthRecord, lastModified *time.Time) (bool, error) {
+	dateModified := time.Now().UTC()
+	if record.DateModified != nil {
+		dateModified = record.DateModified.UTC()
+	}
+
+	stmt := tx.StmtContext(ctx, a.stmts.swapAuth)
+	result, err := stmt.ExecContext(
+		ctx,
+		string(record.Status),
+		dateModified,
+		string(record.MaterialType),
+		record.MaterialHash,
+		record.ExpiresAt,
+		record.RevokedAt,
+		record.ID,
+		lastModified,
+	)
+	_ = stmt.Close()
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil || affected != 1 {
+		return false, err
+	}
+
+	if err := a.replaceAuthMetadataInTx(ctx, tx, record); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 func (a *Adapter) getAuthsPrepared(size int) (*sql.Stmt, error) {
 	if size <= 0 {
 		return nil, nil
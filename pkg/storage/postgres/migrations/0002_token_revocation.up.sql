@@ -0,0 +1,11 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS openauth.token_revocation (
+  token_id TEXT NOT NULL PRIMARY KEY,
+  expires_at TIMESTAMPTZ NOT NULL,
+  date_added TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_token_revocation_expires_at ON openauth.token_revocation (expires_at);
+
+COMMIT;
@@ -0,0 +1,22 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS openauth.session (
+  id TEXT NOT NULL PRIMARY KEY,
+  auth_id UUID NULL,
+  subject TEXT NOT NULL,
+  tenant TEXT NOT NULL DEFAULT '',
+  date_added TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  expires_at TIMESTAMPTZ NOT NULL,
+  metadata JSONB NULL,
+
+  CONSTRAINT fk_session_auth_id
+    FOREIGN KEY (auth_id)
+    REFERENCES openauth.auth (id)
+    ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_session_subject ON openauth.session (subject);
+CREATE INDEX IF NOT EXISTS idx_session_auth_id ON openauth.session (auth_id);
+CREATE INDEX IF NOT EXISTS idx_session_expires_at ON openauth.session (expires_at);
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+ALTER TYPE openauth.material_type_enum ADD VALUE IF NOT EXISTS 'totp';
+ALTER TYPE openauth.material_type_enum ADD VALUE IF NOT EXISTS 'mfa_challenge';
+
+COMMIT;
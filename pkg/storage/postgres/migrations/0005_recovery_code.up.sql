@@ -0,0 +1,5 @@
+BEGIN;
+
+ALTER TYPE openauth.material_type_enum ADD VALUE IF NOT EXISTS 'recovery_code';
+
+COMMIT;
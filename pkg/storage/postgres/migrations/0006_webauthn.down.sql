@@ -0,0 +1,13 @@
+BEGIN;
+
+-- Enum values cannot be dropped, so the type is recreated without them.
+DELETE FROM openauth.auth WHERE material_type::text IN ('webauthn', 'webauthn_challenge');
+
+ALTER TYPE openauth.material_type_enum RENAME TO material_type_enum_old;
+CREATE TYPE openauth.material_type_enum AS ENUM ('password', 'access_token', 'refresh_token', 'api_key', 'client_secret', 'totp', 'mfa_challenge', 'recovery_code');
+ALTER TABLE openauth.auth
+  ALTER COLUMN material_type TYPE openauth.material_type_enum
+  USING material_type::text::openauth.material_type_enum;
+DROP TYPE openauth.material_type_enum_old;
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+ALTER TYPE openauth.material_type_enum ADD VALUE IF NOT EXISTS 'webauthn';
+ALTER TYPE openauth.material_type_enum ADD VALUE IF NOT EXISTS 'webauthn_challenge';
+
+COMMIT;
@@ -0,0 +1,16 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS openauth.oauth_client (
+  id TEXT NOT NULL PRIMARY KEY,
+  name TEXT NOT NULL DEFAULT '',
+  tenant TEXT NOT NULL DEFAULT '',
+  grant_types JSONB NOT NULL DEFAULT '[]'::jsonb,
+  scopes JSONB NOT NULL DEFAULT '[]'::jsonb,
+  redirect_uris JSONB NOT NULL DEFAULT '[]'::jsonb,
+  date_added TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  date_modified TIMESTAMPTZ NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_oauth_client_tenant ON openauth.oauth_client (tenant);
+
+COMMIT;
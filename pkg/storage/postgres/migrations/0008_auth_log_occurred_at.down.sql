@@ -0,0 +1,5 @@
+BEGIN;
+
+DROP INDEX IF EXISTS openauth.idx_auth_log_subject_occurred_at;
+
+COMMIT;
@@ -0,0 +1,5 @@
+BEGIN;
+
+CREATE INDEX IF NOT EXISTS idx_auth_log_subject_occurred_at ON openauth.auth_log (subject, occurred_at);
+
+COMMIT;
@@ -0,0 +1,146 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/porthorian/openauth/pkg/storage"
+)
+
+const (
+	putOAuthClientQuery = `
+INSERT INTO openauth.oauth_client (
+  id, name, tenant, grant_types, scopes, redirect_uris, date_added, date_modified
+) VALUES ($1, $2, $3, $4, $5, $6, $7, NULL)
+ON CONFLICT (id) DO UPDATE
+SET
+  name = EXCLUDED.name,
+  tenant = EXCLUDED.tenant,
+  grant_types = EXCLUDED.grant_types,
+  scopes = EXCLUDED.scopes,
+  redirect_uris = EXCLUDED.redirect_uris,
+  date_modified = EXCLUDED.date_added
+`
+
+	getOAuthClientQuery = `
+SELECT
+  id, name, tenant, grant_types, scopes, redirect_uris, date_added, date_modified
+FROM openauth.oauth_client
+WHERE id = $1
+`
+
+	deleteOAuthClientQuery = `DELETE FROM openauth.oauth_client WHERE id = $1`
+)
+
+var ErrInvalidOAuthClient = errors.New("postgres adapter: oauth client id is required")
+
+var _ storage.OAuthClientStore = (*Adapter)(nil)
+
+// PutOAuthClient inserts or replaces a client registration. Replacing keeps
+// the original DateAdded and sets DateModified.
+func (a *Adapter) PutOAuthClient(ctx context.Context, record storage.OAuthClientRecord) error {
+	if err := a.requirePreparedStatements(); err != nil {
+		return err
+	}
+
+	record.ID = strings.TrimSpace(record.ID)
+	if record.ID == "" {
+		return ErrInvalidOAuthClient
+	}
+
+	grantTypes, err := marshalStringList(record.GrantTypes)
+	if err != nil {
+		return err
+	}
+	scopes, err := marshalStringList(record.Scopes)
+	if err != nil {
+		return err
+	}
+	redirectURIs, err := marshalStringList(record.RedirectURIs)
+	if err != nil {
+		return err
+	}
+
+	_, err = a.stmts.putOAuthClient.ExecContext(
+		ctx,
+		record.ID,
+		strings.TrimSpace(record.Name),
+		strings.TrimSpace(record.Tenant),
+		grantTypes,
+		scopes,
+		redirectURIs,
+		time.Now().UTC(),
+	)
+	return err
+}
+
+// GetOAuthClient returns storage.ErrNotFound when the client is not registered.
+func (a *Adapter) GetOAuthClient(ctx context.Context, id string) (storage.OAuthClientRecord, error) {
+	if err := a.requirePreparedStatements(); err != nil {
+		return storage.OAuthClientRecord{}, err
+	}
+
+	record, err := scanOAuthClient(a.stmts.getOAuthClient.QueryRowContext(ctx, strings.TrimSpace(id)))
+	return record, notFound(err)
+}
+
+func (a *Adapter) DeleteOAuthClient(ctx context.Context, id string) error {
+	if err := a.requirePreparedStatements(); err != nil {
+		return err
+	}
+
+	_, err := a.stmts.deleteOAuthClient.ExecContext(ctx, strings.TrimSpace(id))
+	return err
+}
+
+func scanOAuthClient(s scanner) (storage.OAuthClientRecord, error) {
+	var (
+		record       storage.OAuthClientRecord
+		grantTypes   []byte
+		scopes       []byte
+		redirectURIs []byte
+		dateModified sql.NullTime
+	)
+
+	if err := s.Scan(
+		&record.ID,
+		&record.Name,
+		&record.Tenant,
+		&grantTypes,
+		&scopes,
+		&redirectURIs,
+		&record.DateAdded,
+		&dateModified,
+	); err != nil {
+		return storage.OAuthClientRecord{}, err
+	}
+
+	record.DateAdded = record.DateAdded.UTC()
+	if dateModified.Valid {
+		modified := dateModified.Time.UTC()
+		record.DateModified = &modified
+	}
+	if err := json.Unmarshal(grantTypes, &record.GrantTypes); err != nil {
+		return storage.OAuthClientRecord{}, err
+	}
+	if err := json.Unmarshal(scopes, &record.Scopes); err != nil {
+		return storage.OAuthClientRecord{}, err
+	}
+	if err := json.Unmarshal(redirectURIs, &record.RedirectURIs); err != nil {
+		return storage.OAuthClientRecord{}, err
+	}
+	return record, nil
+}
+
+// marshalStringList stores nil as an empty JSON array so reads never see
+// null.
+func marshalStringList(values []string) ([]byte, error) {
+	if values == nil {
+		values = []string{}
+	}
+	return json.Marshal(values)
+}
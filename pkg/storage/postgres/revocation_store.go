@@ -0,0 +1,100 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/porthorian/openauth/pkg/session"
+)
+
+const (
+	revokeTokenQuery = `
+INSERT INTO openauth.token_revocation (
+  token_id, expires_at, date_added
+) VALUES ($1, $2, $3)
+ON CONFLICT (token_id) DO UPDATE
+SET
+  expires_at = GREATEST(openauth.token_revocation.expires_at, EXCLUDED.expires_at)
+`
+
+	isTokenRevokedQuery = `
+SELECT EXISTS (
+  SELECT 1
+  FROM openauth.token_revocation
+  WHERE token_id = $1 AND expires_at > $2
+)
+`
+
+	deleteExpiredRevocationsQuery = `
+DELETE FROM openauth.token_revocation
+WHERE expires_at <= $1
+`
+)
+
+var (
+	ErrMissingTokenID        = errors.New("postgres adapter: token id is required")
+	ErrMissingTokenExpiresAt = errors.New("postgres adapter: token expiry is required")
+)
+
+var _ session.RevocationStore = (*Adapter)(nil)
+
+// RevokeToken records tokenID as revoked until expiresAt. Rows past their
+// expiry are pruned on every write so the table tracks live tokens only.
+func (a *Adapter) RevokeToken(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	if err := a.requirePreparedStatements(); err != nil {
+		return err
+	}
+
+	tokenID = strings.TrimSpace(tokenID)
+	if tokenID == "" {
+		return ErrMissingTokenID
+	}
+	if expiresAt.IsZero() {
+		return ErrMissingTokenExpiresAt
+	}
+
+	now := time.Now().UTC()
+	if !expiresAt.After(now) {
+		return nil
+	}
+
+	if _, err := a.stmts.revokeToken.ExecContext(ctx, tokenID, expiresAt.UTC(), now); err != nil {
+		return err
+	}
+
+	_, err := a.stmts.deleteExpiredRevocations.ExecContext(ctx, now)
+	return err
+}
+
+func (a *Adapter) IsTokenRevoked(ctx context.Context, tokenID string) (bool, error) {
+	if err := a.requirePreparedStatements(); err != nil {
+		return false, err
+	}
+
+	tokenID = strings.TrimSpace(tokenID)
+	if tokenID == "" {
+		return false, nil
+	}
+
+	var revoked bool
+	if err := a.stmts.isTokenRevoked.QueryRowContext(ctx, tokenID, time.Now().UTC()).Scan(&revoked); err != nil {
+		return false, err
+	}
+	return revoked, nil
+}
+
+// DeleteExpiredRevocations removes revocations whose token expired at or
+// before the given time and reports how many were removed.
+func (a *Adapter) DeleteExpiredRevocations(ctx context.Context, before time.Time) (int64, error) {
+	if err := a.requirePreparedStatements(); err != nil {
+		return 0, err
+	}
+
+	result, err := a.stmts.deleteExpiredRevocations.ExecContext(ctx, before.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
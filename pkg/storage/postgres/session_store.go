@@ -0,0 +1,148 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/porthorian/openauth/pkg/storage"
+)
+
+const (
+	putSessionQuery = `
+INSERT INTO openauth.session (
+  id, auth_id, subject, tenant, date_added, expires_at, metadata
+) VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (id) DO UPDATE
+SET
+  auth_id = EXCLUDED.auth_id,
+  subject = EXCLUDED.subject,
+  tenant = EXCLUDED.tenant,
+  expires_at = EXCLUDED.expires_at,
+  metadata = EXCLUDED.metadata
+`
+
+	getSessionQuery = `
+SELECT
+  id, auth_id::text, subject, tenant, expires_at, metadata
+FROM openauth.session
+WHERE id = $1
+`
+
+	deleteSessionQuery = `DELETE FROM openauth.session WHERE id = $1`
+
+	deleteExpiredSessionsQuery = `
+DELETE FROM openauth.session
+WHERE expires_at <= $1
+`
+)
+
+var ErrInvalidSession = errors.New("postgres adapter: session id, subject and expiry are required")
+
+var _ storage.SessionStore = (*Adapter)(nil)
+
+func (a *Adapter) PutSession(ctx context.Context, record storage.SessionRecord) error {
+	if err := a.requirePreparedStatements(); err != nil {
+		return err
+	}
+
+	record.ID = strings.TrimSpace(record.ID)
+	record.Subject = strings.TrimSpace(record.Subject)
+	if record.ID == "" || record.Subject == "" || record.ExpiresAt.IsZero() {
+		return ErrInvalidSession
+	}
+
+	metadata := cloneStringMap(record.Metadata)
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+	metadataRaw, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+
+	var authID any
+	if trimmed := strings.TrimSpace(record.AuthID); trimmed != "" {
+		authID = trimmed
+	}
+
+	_, err = a.stmts.putSession.ExecContext(
+		ctx,
+		record.ID,
+		authID,
+		record.Subject,
+		strings.TrimSpace(record.Tenant),
+		time.Now().UTC(),
+		record.ExpiresAt.UTC(),
+		metadataRaw,
+	)
+	return err
+}
+
+// GetSession returns storage.ErrNotFound when the session does not exist. Expired
+// sessions are returned as stored; callers compare ExpiresAt.
+func (a *Adapter) GetSession(ctx context.Context, id string) (storage.SessionRecord, error) {
+	if err := a.requirePreparedStatements(); err != nil {
+		return storage.SessionRecord{}, err
+	}
+
+	record, err := scanSession(a.stmts.getSession.QueryRowContext(ctx, strings.TrimSpace(id)))
+	return record, notFound(err)
+}
+
+func (a *Adapter) DeleteSession(ctx context.Context, id string) error {
+	if err := a.requirePreparedStatements(); err != nil {
+		return err
+	}
+
+	_, err := a.stmts.deleteSession.ExecContext(ctx, strings.TrimSpace(id))
+	return err
+}
+
+// DeleteExpiredSessions removes sessions that expired at or before the given
+// time and reports how many were removed.
+func (a *Adapter) DeleteExpiredSessions(ctx context.Context, before time.Time) (int64, error) {
+	if err := a.requirePreparedStatements(); err != nil {
+		return 0, err
+	}
+
+	result, err := a.stmts.deleteExpiredSessions.ExecContext(ctx, before.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func scanSession(s scanner) (storage.SessionRecord, error) {
+	var (
+		record      storage.SessionRecord
+		authID      sql.NullString
+		expiresAt   time.Time
+		metadataRaw []byte
+	)
+
+	if err := s.Scan(
+		&record.ID,
+		&authID,
+		&record.Subject,
+		&record.Tenant,
+		&expiresAt,
+		&metadataRaw,
+	); err != nil {
+		return storage.SessionRecord{}, err
+	}
+
+	record.AuthID = authID.String
+	record.ExpiresAt = expiresAt.UTC()
+	record.Metadata = map[string]string{}
+	if len(metadataRaw) == 0 {
+		return record, nil
+	}
+	if err := json.Unmarshal(metadataRaw, &record.Metadata); err != nil {
+		return storage.SessionRecord{}, err
+	}
+	return record, nil
+}
@@ -1,7 +1,597 @@
 package sqlite
 
-type Adapter struct{}
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
 
-func NewAdapter() *Adapter {
-	return &Adapter{}
+	"github.com/porthorian/openauth/pkg/storage"
+)
+
+// timeLayout is fixed-width so that lexical ordering of stored TEXT
+// timestamps matches chronological ordering.
+const timeLayout = "2006-01-02T15:04:05.000000000Z"
+
+type Adapter struct {
+	db *sql.DB
+	tx *sql.Tx
+
+	stmts *preparedStatements
+}
+
+type preparedStatements struct {
+	putAuth        *sql.Stmt
+	getAuth        *sql.Stmt
+	deleteAuth     *sql.Stmt
+	swapAuthStatus *sql.Stmt
+	swapAuth       *sql.Stmt
+
+	deleteAuthMetadata *sql.Stmt
+	putAuthMetadata    *sql.Stmt
+	getAuthMetadata    *sql.Stmt
+
+	putSubjectAuth            *sql.Stmt
+	listSubjectAuthBySubject  *sql.Stmt
+	listSubjectAuthByAuthID   *sql.Stmt
+	deleteSubjectAuthByID     *sql.Stmt
+	deleteSubjectAuthByAuthID *sql.Stmt
+
+	putAuthLog                *sql.Stmt
+	listAuthLogByAuthID       *sql.Stmt
+	listAuthLogBySubject      *sql.Stmt
+	listAuthLogBySubjectSince *sql.Stmt
+	deleteAuthLogByAuthID     *sql.Stmt
+
+	deleteSubjectRoles               *sql.Stmt
+	putSubjectRole                   *sql.Stmt
+	listSubjectRoles                 *sql.Stmt
+	deleteSubjectPermissionOverrides *sql.Stmt
+	putSubjectPermissionOverride     *sql.Stmt
+	listSubjectPermissionOverrides   *sql.Stmt
+
+	revokeToken              *sql.Stmt
+	isTokenRevoked           *sql.Stmt
+	deleteExpiredRevocations *sql.Stmt
+
+	putSession             *sql.Stmt
+	getSession             *sql.Stmt
+	deleteSession          *sql.Stmt
+	deleteSessionsByAuthID *sql.Stmt
+	deleteExpiredSessions  *sql.Stmt
+
+	putOAuthClient    *sql.Stmt
+	getOAuthClient    *sql.Stmt
+	deleteOAuthClient *sql.Stmt
+
+	getAuthsMu     sync.Mutex
+	getAuthsBySize map[int]*sql.Stmt
+}
+
+type prepareStatementSpec struct {
+	label  string
+	query  string
+	assign func(*preparedStatements, *sql.Stmt)
+}
+
+var fixedPrepareStatementSpecs = []prepareStatementSpec{
+	{
+		label: "put auth",
+		query: putAuthQuery,
+		assign: func(ps *preparedStatements, stmt *sql.Stmt) {
+			ps.putAuth = stmt
+		},
+	},
+	{
+		label: "get auth",
+		query: getAuthQuery,
+		assign: func(ps *preparedStatements, stmt *sql.Stmt) {
+			ps.getAuth = stmt
+		},
+	},
+	{
+		label: "delete auth",
+		query: deleteAuthQuery,
+		assign: func(ps *preparedStatements, stmt *sql.Stmt) {
+			ps.deleteAuth = stmt
+		},
+	},
+	{
+		label: "swap auth status",
+		query: swapAuthStatusQuery,
+		assign: func(ps *preparedStatements, stmt *sql.Stmt) {
+			ps.swapAuthStatus = stmt
+		},
+	},
+	{
+		label: "swap auth",
+		query: swapAuthQuery,
+		assign: func(ps *preparedStatements, stmt *sql.Stmt) {
+			ps.swapAuth = stmt
+		},
+	},
+	{
+		label: "delete auth metadata",
+		query: deleteAuthMetadataQuery,
+		assign: func(ps *preparedStatements, stmt *sql.Stmt) {
+			ps.deleteAuthMetadata = stmt
+		},
+	},
+	{
+		label: "put auth metadata",
+		query: putAuthMetadataQuery,
+		assign: func(ps *preparedStatements, stmt *sql.Stmt) {
+			ps.putAuthMetadata = stmt
+		},
+	},
+	{
+		label: "get auth metadata",
+		query: getAuthMetadataQuery,
+		assign: func(ps *preparedStatements, stmt *sql.Stmt) {
+			ps.getAuthMetadata = stmt
+		},
+	},
+	{
+		label: "put subject auth",
+		query: putSubjectAuthQuery,
+		assign: func(ps *preparedStatements, stmt *sql.Stmt) {
+			ps.putSubjectAuth = stmt
+		},
+	},
+	{
+		label: "list subject auth by subject",
+		query: listSubjectAuthBySubjectQuery,
+		assign: func(ps *preparedStatements, stmt *sql.Stmt) {
+			ps.listSubjectAuthBySubject = stmt
+		},
+	},
+	{
+		label: "list subject auth by auth_id",
+		query: listSubjectAuthByAuthIDQuery,
+		assign: func(ps *preparedStatements, stmt *sql.Stmt) {
+			ps.listSubjectAuthByAuthID = stmt
+		},
+	},
+	{
+		label: "delete subject auth by id",
+		query: deleteSubjectAuthQuery,
+		assign: func(ps *preparedStatements, stmt *sql.Stmt) {
+			ps.deleteSubjectAuthByID = stmt
+		},
+	},
+	{
+		label: "delete subject auth by auth_id",
+		query: deleteSubjectAuthByAuthIDQuery,
+		assign: func(ps *preparedStatements, stmt *sql.Stmt) {
+			ps.deleteSubjectAuthByAuthID = stmt
+		},
+	},
+	{
+		label: "put auth log",
+		query: putAuthLogQuery,
+		assign: func(ps *preparedStatements, stmt *sql.Stmt) {
+			ps.putAuthLog = stmt
+		},
+	},
+	{
+		label: "list auth log by auth_id",
+		query: listAuthLogByAuthIDQuery,
+		assign: func(ps *preparedStatements, stmt *sql.Stmt) {
+			ps.listAuthLogByAuthID = stmt
+		},
+	},
+	{
+		label: "list auth log by subject",
+		query: listAuthLogBySubjectQuery,
+		assign: func(ps *preparedStatements, stmt *sql.Stmt) {
+			ps.listAuthLogBySubject = stmt
+		},
+	},
+	{
+		label: "list auth log by subject since",
+		query: listAuthLogBySubjectSinceQuery,
+		assign: func(ps *preparedStatements, stmt *sql.Stmt) {
+			ps.listAuthLogBySubjectSince = stmt
+		},
+	},
+	{
+		label: "delete auth log by auth_id",
+		query: deleteAuthLogByAuthIDQuery,
+		assign: func(ps *preparedStatements, stmt *sql.Stmt) {
+			ps.deleteAuthLogByAuthID = stmt
+		},
+	},
+	{
+		label: "delete subject roles",
+		query: deleteSubjectRolesQuery,
+		assign: func(ps *preparedStatements, stmt *sql.Stmt) {
+			ps.deleteSubjectRoles = stmt
+		},
+	},
+	{
+		label: "put subject role",
+		query: putSubjectRoleQuery,
+		assign: func(ps *preparedStatements, stmt *sql.Stmt) {
+			ps.putSubjectRole = stmt
+		},
+	},
+	{
+		label: "list subject roles",
+		query: listSubjectRolesQuery,
+		assign: func(ps *preparedStatements, stmt *sql.Stmt) {
+			ps.listSubjectRoles = stmt
+		},
+	},
+	{
+		label: "delete subject permission overrides",
+		query: deleteSubjectPermissionOverridesQuery,
+		assign: func(ps *preparedStatements, stmt *sql.Stmt) {
+			ps.deleteSubjectPermissionOverrides = stmt
+		},
+	},
+	{
+		label: "put subject permission override",
+		query: putSubjectPermissionOverrideQuery,
+		assign: func(ps *preparedStatements, stmt *sql.Stmt) {
+			ps.putSubjectPermissionOverride = stmt
+		},
+	},
+	{
+		label: "list subject permission overrides",
+		query: listSubjectPermissionOverridesQuery,
+		assign: func(ps *preparedStatements, stmt *sql.Stmt) {
+			ps.listSubjectPermissionOverrides = stmt
+		},
+	},
+	{
+		label: "revoke token",
+		query: revokeTokenQuery,
+		assign: func(ps *preparedStatements, stmt *sql.Stmt) {
+			ps.revokeToken = stmt
+		},
+	},
+	{
+		label: "is token revoked",
+		query: isTokenRevokedQuery,
+		assign: func(ps *preparedStatements, stmt *sql.Stmt) {
+			ps.isTokenRevoked = stmt
+		},
+	},
+	{
+		label: "delete expired revocations",
+		query: deleteExpiredRevocationsQuery,
+		assign: func(ps *preparedStatements, stmt *sql.Stmt) {
+			ps.deleteExpiredRevocations = stmt
+		},
+	},
+	{
+		label: "put session",
+		query: putSessionQuery,
+		assign: func(ps *preparedStatements, stmt *sql.Stmt) {
+			ps.putSession = stmt
+		},
+	},
+	{
+		label: "get session",
+		query: getSessionQuery,
+		assign: func(ps *preparedStatements, stmt *sql.Stmt) {
+			ps.getSession = stmt
+		},
+	},
+	{
+		label: "delete session",
+		query: deleteSessionQuery,
+		assign: func(ps *preparedStatements, stmt *sql.Stmt) {
+			ps.deleteSession = stmt
+		},
+	},
+	{
+		label: "delete sessions by auth_id",
+		query: deleteSessionsByAuthIDQuery,
+		assign: func(ps *preparedStatements, stmt *sql.Stmt) {
+			ps.deleteSessionsByAuthID = stmt
+		},
+	},
+	{
+		label: "delete expired sessions",
+		query: deleteExpiredSessionsQuery,
+		assign: func(ps *preparedStatements, stmt *sql.Stmt) {
+			ps.deleteExpiredSessions = stmt
+		},
+	},
+	{
+		label: "put oauth client",
+		query: putOAuthClientQuery,
+		assign: func(ps *preparedStatements, stmt *sql.Stmt) {
+			ps.putOAuthClient = stmt
+		},
+	},
+	{
+		label: "get oauth client",
+		query: getOAuthClientQuery,
+		assign: func(ps *preparedStatements, stmt *sql.Stmt) {
+			ps.getOAuthClient = stmt
+		},
+	},
+	{
+		label: "delete oauth client",
+		query: deleteOAuthClientQuery,
+		assign: func(ps *preparedStatements, stmt *sql.Stmt) {
+			ps.deleteOAuthClient = stmt
+		},
+	},
+}
+
+var (
+	ErrNilDB                 = errors.New("sqlite adapter: db is nil")
+	ErrAdapterNotInitialized = errors.New("sqlite adapter: adapter not initialized")
+	ErrNotImplemented        = errors.New("sqlite adapter: method not implemented")
+)
+
+var _ storage.AuthStore = (*Adapter)(nil)
+var _ storage.SubjectAuthStore = (*Adapter)(nil)
+var _ storage.AuthLogStore = (*Adapter)(nil)
+var _ storage.RoleStore = (*Adapter)(nil)
+var _ storage.PermissionStore = (*Adapter)(nil)
+var _ storage.AuthMaterialTransactor = (*Adapter)(nil)
+
+func NewAdapter(db *sql.DB) (*Adapter, error) {
+	adapter := &Adapter{
+		db: db,
+		stmts: &preparedStatements{
+			getAuthsBySize: map[int]*sql.Stmt{},
+		},
+	}
+
+	if err := adapter.prepareStatements(); err != nil {
+		_ = adapter.Close()
+		return nil, err
+	}
+
+	return adapter, nil
+}
+
+func (a *Adapter) Close() error {
+	if a == nil {
+		return nil
+	}
+	if a.tx != nil {
+		return nil
+	}
+	if a.stmts == nil {
+		return nil
+	}
+
+	var errs []error
+
+	if err := closeStatements(
+		a.stmts.putAuth,
+		a.stmts.getAuth,
+		a.stmts.deleteAuth,
+		a.stmts.swapAuthStatus,
+		a.stmts.swapAuth,
+		a.stmts.deleteAuthMetadata,
+		a.stmts.putAuthMetadata,
+		a.stmts.getAuthMetadata,
+		a.stmts.putSubjectAuth,
+		a.stmts.listSubjectAuthBySubject,
+		a.stmts.listSubjectAuthByAuthID,
+		a.stmts.deleteSubjectAuthByID,
+		a.stmts.deleteSubjectAuthByAuthID,
+		a.stmts.putAuthLog,
+		a.stmts.listAuthLogByAuthID,
+		a.stmts.listAuthLogBySubject,
+		a.stmts.listAuthLogBySubjectSince,
+		a.stmts.deleteAuthLogByAuthID,
+		a.stmts.deleteSubjectRoles,
+		a.stmts.putSubjectRole,
+		a.stmts.listSubjectRoles,
+		a.stmts.deleteSubjectPermissionOverrides,
+		a.stmts.putSubjectPermissionOverride,
+		a.stmts.listSubjectPermissionOverrides,
+		a.stmts.revokeToken,
+		a.stmts.isTokenRevoked,
+		a.stmts.deleteExpiredRevocations,
+		a.stmts.putSession,
+		a.stmts.getSession,
+		a.stmts.deleteSession,
+		a.stmts.deleteSessionsByAuthID,
+		a.stmts.deleteExpiredSessions,
+		a.stmts.putOAuthClient,
+		a.stmts.getOAuthClient,
+		a.stmts.deleteOAuthClient,
+	); err != nil {
+		errs = append(errs, err)
+	}
+
+	a.stmts.getAuthsMu.Lock()
+	dynamicStmts := make([]*sql.Stmt, 0, len(a.stmts.getAuthsBySize))
+	for _, stmt := range a.stmts.getAuthsBySize {
+		dynamicStmts = append(dynamicStmts, stmt)
+	}
+	a.stmts.getAuthsBySize = map[int]*sql.Stmt{}
+	a.stmts.getAuthsMu.Unlock()
+
+	if err := closeStatements(dynamicStmts...); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+func (a *Adapter) prepareStatements() (err error) {
+	db, err := a.requireDB()
+	if err != nil {
+		return err
+	}
+
+	prepared := make([]*sql.Stmt, 0, len(fixedPrepareStatementSpecs))
+	defer func() {
+		if err != nil {
+			_ = closeStatements(prepared...)
+		}
+	}()
+
+	for _, spec := range fixedPrepareStatementSpecs {
+		stmt, prepErr := db.Prepare(spec.query)
+		if prepErr != nil {
+			err = fmt.Errorf("sqlite adapter: prepare %s statement: %w", spec.label, prepErr)
+			return err
+		}
+		prepared = append(prepared, stmt)
+		spec.assign(a.stmts, stmt)
+	}
+	return nil
+}
+
+func (a *Adapter) requirePreparedStatements() error {
+	if _, err := a.requireDB(); err != nil {
+		return err
+	}
+	if a.stmts == nil {
+		return ErrAdapterNotInitialized
+	}
+
+	if a.stmts.putAuth == nil || a.stmts.getAuth == nil || a.stmts.deleteAuth == nil || a.stmts.swapAuthStatus == nil || a.stmts.swapAuth == nil {
+		return ErrAdapterNotInitialized
+	}
+	if a.stmts.deleteAuthMetadata == nil || a.stmts.putAuthMetadata == nil || a.stmts.getAuthMetadata == nil {
+		return ErrAdapterNotInitialized
+	}
+	if a.stmts.putSubjectAuth == nil || a.stmts.listSubjectAuthBySubject == nil || a.stmts.listSubjectAuthByAuthID == nil || a.stmts.deleteSubjectAuthByID == nil || a.stmts.deleteSubjectAuthByAuthID == nil {
+		return ErrAdapterNotInitialized
+	}
+	if a.stmts.putAuthLog == nil || a.stmts.listAuthLogByAuthID == nil || a.stmts.listAuthLogBySubject == nil || a.stmts.listAuthLogBySubjectSince == nil || a.stmts.deleteAuthLogByAuthID == nil {
+		return ErrAdapterNotInitialized
+	}
+	if a.stmts.deleteSubjectRoles == nil || a.stmts.putSubjectRole == nil || a.stmts.listSubjectRoles == nil {
+		return ErrAdapterNotInitialized
+	}
+	if a.stmts.deleteSubjectPermissionOverrides == nil || a.stmts.putSubjectPermissionOverride == nil || a.stmts.listSubjectPermissionOverrides == nil {
+		return ErrAdapterNotInitialized
+	}
+	if a.stmts.revokeToken == nil || a.stmts.isTokenRevoked == nil || a.stmts.deleteExpiredRevocations == nil {
+		return ErrAdapterNotInitialized
+	}
+	if a.stmts.putSession == nil || a.stmts.getSession == nil || a.stmts.deleteSession == nil || a.stmts.deleteSessionsByAuthID == nil || a.stmts.deleteExpiredSessions == nil {
+		return ErrAdapterNotInitialized
+	}
+	if a.stmts.putOAuthClient == nil || a.stmts.getOAuthClient == nil || a.stmts.deleteOAuthClient == nil {
+		return ErrAdapterNotInitialized
+	}
+
+	return nil
+}
+
+func (a *Adapter) requireDB() (*sql.DB, error) {
+	if a == nil || a.db == nil {
+		return nil, ErrNilDB
+	}
+	return a.db, nil
+}
+
+// bind returns stmt scoped to the adapter's transaction when one is active.
+// SQLite serialises writers, so every statement issued inside
+// WithAuthMaterialTx must run on the transaction's connection to avoid
+// blocking on the lock it already holds.
+func (a *Adapter) bind(ctx context.Context, stmt *sql.Stmt) (*sql.Stmt, func()) {
+	if a.tx != nil {
+		txStmt := a.tx.StmtContext(ctx, stmt)
+		return txStmt, func() { _ = txStmt.Close() }
+	}
+	return stmt, func() {}
+}
+
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+func (a *Adapter) queryer() (queryer, error) {
+	if a.tx != nil {
+		return a.tx, nil
+	}
+	return a.requireDB()
+}
+
+// withTx runs fn inside the adapter's active transaction, or opens a new one.
+func (a *Adapter) withTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	if a.tx != nil {
+		return fn(a.tx)
+	}
+
+	db, err := a.requireDB()
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// notFound maps sql.ErrNoRows from a single-row scan to storage.ErrNotFound.
+func notFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.ErrNotFound
+	}
+	return err
+}
+
+func closeStatements(stmts ...*sql.Stmt) error {
+	var errs []error
+	for _, stmt := range stmts {
+		if stmt == nil {
+			continue
+		}
+		if err := stmt.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timeLayout)
+}
+
+func formatNullableTime(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return formatTime(*t)
+}
+
+func parseTime(value string) (time.Time, error) {
+	parsed, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("sqlite adapter: parse timestamp %q: %w", value, err)
+	}
+	return parsed.UTC(), nil
+}
+
+func parseNullableTime(value sql.NullString) (*time.Time, error) {
+	if !value.Valid || value.String == "" {
+		return nil, nil
+	}
+	parsed, err := parseTime(value.String)
+	if err != nil {
+		return nil, err
+	}
+	return &parsed, nil
 }
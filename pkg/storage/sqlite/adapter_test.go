@@ -0,0 +1,489 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/porthorian/openauth/pkg/storage"
+	_ "modernc.org/sqlite"
+)
+
+func newTestAdapter(t *testing.T) *Adapter {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("sql.Open returned error: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	migrations, err := filepath.Glob("migrations/*.up.sql")
+	if err != nil {
+		t.Fatalf("list migrations: %v", err)
+	}
+	sort.Strings(migrations)
+	for _, name := range migrations {
+		migration, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatalf("read migration %s: %v", name, err)
+		}
+		if _, err := db.Exec(string(migration)); err != nil {
+			t.Fatalf("apply migration %s: %v", name, err)
+		}
+	}
+
+	adapter, err := NewAdapter(db)
+	if err != nil {
+		t.Fatalf("NewAdapter returned error: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = adapter.Close()
+	})
+	return adapter
+}
+
+func TestAuthAcceptsMFAMaterialTypes(t *testing.T) {
+	ctx := context.Background()
+	adapter := newTestAdapter(t)
+
+	for i, materialType := range []storage.AuthMaterialType{storage.AuthMaterialTypeTOTP, storage.AuthMaterialTypeMFAChallenge, storage.AuthMaterialTypeRecoveryCode, storage.AuthMaterialTypeWebAuthn, storage.AuthMaterialTypeWebAuthnChallenge} {
+		id := "mfa-" + string(rune('a'+i))
+		if err := adapter.PutAuth(ctx, storage.AuthRecord{
+			ID:           id,
+			Status:       storage.StatusActive,
+			MaterialType: materialType,
+			MaterialHash: "sealed",
+		}); err != nil {
+			t.Fatalf("PutAuth(%s) returned error: %v", materialType, err)
+		}
+		got, err := adapter.GetAuth(ctx, id)
+		if err != nil || got.MaterialType != materialType {
+			t.Fatalf("GetAuth(%s) = (%+v, %v)", materialType, got, err)
+		}
+	}
+
+	if err := adapter.PutAuth(ctx, storage.AuthRecord{
+		ID:           "bogus",
+		Status:       storage.StatusActive,
+		MaterialType: "bogus",
+		MaterialHash: "hash",
+	}); err == nil {
+		t.Fatalf("expected unknown material type to be rejected")
+	}
+}
+
+func TestAuthRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	adapter := newTestAdapter(t)
+
+	expiresAt := time.Now().UTC().Add(time.Hour).Truncate(time.Microsecond)
+	record := storage.AuthRecord{
+		ID:           "auth-1",
+		Status:       storage.StatusActive,
+		DateAdded:    time.Now().UTC(),
+		MaterialType: storage.AuthMaterialTypePassword,
+		MaterialHash: "hash-1",
+		ExpiresAt:    &expiresAt,
+		Metadata:     map[string]string{"source": "test"},
+	}
+	if err := adapter.PutAuth(ctx, record); err != nil {
+		t.Fatalf("PutAuth returned error: %v", err)
+	}
+	if err := adapter.PutAuth(ctx, storage.AuthRecord{
+		ID:           "auth-2",
+		Status:       storage.StatusInActive,
+		MaterialType: storage.AuthMaterialTypeAPIKey,
+		MaterialHash: "hash-2",
+	}); err != nil {
+		t.Fatalf("PutAuth returned error: %v", err)
+	}
+
+	got, err := adapter.GetAuth(ctx, "auth-1")
+	if err != nil {
+		t.Fatalf("GetAuth returned error: %v", err)
+	}
+	if got.MaterialHash != "hash-1" || got.Status != storage.StatusActive {
+		t.Fatalf("unexpected record: %+v", got)
+	}
+	if got.ExpiresAt == nil || !got.ExpiresAt.Equal(expiresAt) {
+		t.Fatalf("expected expires_at %v, got %v", expiresAt, got.ExpiresAt)
+	}
+	if got.Metadata["source"] != "test" {
+		t.Fatalf("expected metadata to round trip, got %v", got.Metadata)
+	}
+
+	records, err := adapter.GetAuths(ctx, []string{"auth-1", "auth-2", "missing"})
+	if err != nil {
+		t.Fatalf("GetAuths returned error: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(records))
+	}
+
+	record.Status = storage.StatusRevoked
+	record.Metadata = nil
+	if err := adapter.PutAuth(ctx, record); err != nil {
+		t.Fatalf("PutAuth update returned error: %v", err)
+	}
+	got, err = adapter.GetAuth(ctx, "auth-1")
+	if err != nil {
+		t.Fatalf("GetAuth returned error: %v", err)
+	}
+	if got.Status != storage.StatusRevoked || len(got.Metadata) != 0 || got.DateModified == nil {
+		t.Fatalf("expected updated record, got %+v", got)
+	}
+
+	if _, err := adapter.GetAuth(ctx, "missing"); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected storage.ErrNotFound, got %v", err)
+	}
+
+	swapped, err := adapter.SwapAuthStatus(ctx, "auth-2", storage.StatusInActive, storage.StatusActive, time.Now())
+	if err != nil || !swapped {
+		t.Fatalf("SwapAuthStatus = %v, %v; want swapped", swapped, err)
+	}
+	swapped, err = adapter.SwapAuthStatus(ctx, "auth-2", storage.StatusInActive, storage.StatusRevoked, time.Now())
+	if err != nil || swapped {
+		t.Fatalf("SwapAuthStatus from a stale status = %v, %v; want no change", swapped, err)
+	}
+	if got, _ := adapter.GetAuth(ctx, "auth-2"); got.Status != storage.StatusActive {
+		t.Fatalf("status after swaps = %s, want active", got.Status)
+	}
+
+	read, err := adapter.GetAuth(ctx, "auth-2")
+	if err != nil {
+		t.Fatalf("GetAuth returned error: %v", err)
+	}
+	modified := read.DateModified.Add(time.Second)
+	update := read
+	update.DateModified = &modified
+	update.Metadata = map[string]string{"sign_count": "7"}
+	swapped, err = adapter.SwapAuth(ctx, update, read.DateModified)
+	if err != nil || !swapped {
+		t.Fatalf("SwapAuth = %v, %v; want swapped", swapped, err)
+	}
+	swapped, err = adapter.SwapAuth(ctx, read, read.DateModified)
+	if err != nil || swapped {
+		t.Fatalf("SwapAuth from a stale read = %v, %v; want no change", swapped, err)
+	}
+	if got, _ := adapter.GetAuth(ctx, "auth-2"); got.Metadata["sign_count"] != "7" {
+		t.Fatalf("metadata after swaps = %v, want the first write", got.Metadata)
+	}
+}
+
+func TestDeleteAuthRemovesDependents(t *testing.T) {
+	ctx := context.Background()
+	adapter := newTestAdapter(t)
+
+	if err := adapter.PutAuth(ctx, storage.AuthRecord{
+		ID:           "auth-1",
+		Status:       storage.StatusActive,
+		MaterialType: storage.AuthMaterialTypePassword,
+		MaterialHash: "hash",
+		Metadata:     map[string]string{"k": "v"},
+	}); err != nil {
+		t.Fatalf("PutAuth returned error: %v", err)
+	}
+	if err := adapter.PutSubjectAuth(ctx, storage.SubjectAuthRecord{ID: "link-1", Subject: "user-1", AuthID: "auth-1"}); err != nil {
+		t.Fatalf("PutSubjectAuth returned error: %v", err)
+	}
+	if err := adapter.PutAuthLog(ctx, storage.AuthLogRecord{ID: "log-1", AuthID: "auth-1", Subject: "user-1", Event: storage.AuthLogEventCreated}); err != nil {
+		t.Fatalf("PutAuthLog returned error: %v", err)
+	}
+	if err := adapter.PutSession(ctx, storage.SessionRecord{ID: "session-1", AuthID: "auth-1", Subject: "user-1", ExpiresAt: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatalf("PutSession returned error: %v", err)
+	}
+
+	if err := adapter.DeleteAuth(ctx, "auth-1"); err != nil {
+		t.Fatalf("DeleteAuth returned error: %v", err)
+	}
+
+	links, err := adapter.ListSubjectAuthBySubject(ctx, "user-1")
+	if err != nil {
+		t.Fatalf("ListSubjectAuthBySubject returned error: %v", err)
+	}
+	if len(links) != 0 {
+		t.Fatalf("expected subject links to be removed, got %d", len(links))
+	}
+	logs, err := adapter.ListAuthLogsBySubject(ctx, "user-1")
+	if err != nil {
+		t.Fatalf("ListAuthLogsBySubject returned error: %v", err)
+	}
+	if len(logs) != 0 {
+		t.Fatalf("expected auth logs to be removed, got %d", len(logs))
+	}
+	if _, err := adapter.GetSession(ctx, "session-1"); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("GetSession after DeleteAuth error = %v, want storage.ErrNotFound", err)
+	}
+}
+
+func TestListAuthLogsBySubjectSince(t *testing.T) {
+	ctx := context.Background()
+	adapter := newTestAdapter(t)
+
+	if err := adapter.PutAuth(ctx, storage.AuthRecord{ID: "auth-1", Status: storage.StatusActive, MaterialType: storage.AuthMaterialTypePassword, MaterialHash: "hash"}); err != nil {
+		t.Fatalf("PutAuth returned error: %v", err)
+	}
+	now := time.Now().UTC()
+	for id, age := range map[string]time.Duration{"log-old": 2 * time.Hour, "log-recent": 30 * time.Minute, "log-latest": time.Minute} {
+		record := storage.AuthLogRecord{ID: id, AuthID: "auth-1", Subject: "user-1", Event: storage.AuthLogEventFailed, OccurredAt: now.Add(-age)}
+		if err := adapter.PutAuthLog(ctx, record); err != nil {
+			t.Fatalf("PutAuthLog returned error: %v", err)
+		}
+	}
+
+	logs, err := adapter.ListAuthLogsBySubjectSince(ctx, "user-1", now.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("ListAuthLogsBySubjectSince returned error: %v", err)
+	}
+	if len(logs) != 2 || logs[0].ID != "log-recent" || logs[1].ID != "log-latest" {
+		t.Fatalf("logs = %+v, want the two events inside the last hour, oldest first", logs)
+	}
+}
+
+func TestWithAuthMaterialTxCommitsAndRollsBack(t *testing.T) {
+	ctx := context.Background()
+	adapter := newTestAdapter(t)
+
+	rollbackErr := errors.New("rollback")
+	err := adapter.WithAuthMaterialTx(ctx, func(material storage.AuthMaterial) error {
+		if err := material.Auth.PutAuth(ctx, storage.AuthRecord{
+			ID:           "auth-rollback",
+			Status:       storage.StatusActive,
+			MaterialType: storage.AuthMaterialTypePassword,
+			MaterialHash: "hash",
+		}); err != nil {
+			return err
+		}
+		return rollbackErr
+	})
+	if !errors.Is(err, rollbackErr) {
+		t.Fatalf("expected rollback error, got %v", err)
+	}
+	if _, err := adapter.GetAuth(ctx, "auth-rollback"); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected rolled back record to be missing, got %v", err)
+	}
+
+	err = adapter.WithAuthMaterialTx(ctx, func(material storage.AuthMaterial) error {
+		if err := material.Auth.PutAuth(ctx, storage.AuthRecord{
+			ID:           "auth-commit",
+			Status:       storage.StatusActive,
+			MaterialType: storage.AuthMaterialTypePassword,
+			MaterialHash: "hash",
+		}); err != nil {
+			return err
+		}
+		if err := material.SubjectAuth.PutSubjectAuth(ctx, storage.SubjectAuthRecord{ID: "link-commit", Subject: "user-1", AuthID: "auth-commit"}); err != nil {
+			return err
+		}
+		records, err := material.Auth.GetAuths(ctx, []string{"auth-commit"})
+		if err != nil {
+			return err
+		}
+		if len(records) != 1 {
+			return errors.New("expected record to be visible inside transaction")
+		}
+		return material.AuthLog.PutAuthLog(ctx, storage.AuthLogRecord{ID: "log-commit", AuthID: "auth-commit", Subject: "user-1", Event: storage.AuthLogEventCreated})
+	})
+	if err != nil {
+		t.Fatalf("WithAuthMaterialTx returned error: %v", err)
+	}
+
+	links, err := adapter.ListSubjectAuthByAuthID(ctx, "auth-commit")
+	if err != nil {
+		t.Fatalf("ListSubjectAuthByAuthID returned error: %v", err)
+	}
+	if len(links) != 1 || links[0].Subject != "user-1" {
+		t.Fatalf("expected committed subject link, got %+v", links)
+	}
+}
+
+func TestReplaceSubjectAuthorization(t *testing.T) {
+	ctx := context.Background()
+	adapter := newTestAdapter(t)
+
+	if err := adapter.ReplaceSubjectRoles(ctx, "user-1", "tenant-a", []string{"viewer", " editor ", "viewer"}); err != nil {
+		t.Fatalf("ReplaceSubjectRoles returned error: %v", err)
+	}
+	if err := adapter.ReplaceSubjectRoles(ctx, "user-1", "tenant-a", []string{"editor"}); err != nil {
+		t.Fatalf("ReplaceSubjectRoles returned error: %v", err)
+	}
+	roles, err := adapter.ListSubjectRoles(ctx, "user-1", "tenant-a")
+	if err != nil {
+		t.Fatalf("ListSubjectRoles returned error: %v", err)
+	}
+	if len(roles) != 1 || roles[0].RoleKey != "editor" {
+		t.Fatalf("expected only editor role, got %+v", roles)
+	}
+
+	if err := adapter.ReplaceSubjectPermissionOverrides(ctx, "user-1", "tenant-a", []storage.SubjectPermissionOverrideRecord{
+		{PermissionKey: "read", Effect: storage.PermissionEffectGrant},
+		{PermissionKey: "write", Effect: storage.PermissionEffectDeny},
+		{PermissionKey: "read", Effect: storage.PermissionEffectDeny},
+	}); err != nil {
+		t.Fatalf("ReplaceSubjectPermissionOverrides returned error: %v", err)
+	}
+	overrides, err := adapter.ListSubjectPermissionOverrides(ctx, "user-1", "tenant-a")
+	if err != nil {
+		t.Fatalf("ListSubjectPermissionOverrides returned error: %v", err)
+	}
+	if len(overrides) != 2 || overrides[0].PermissionKey != "read" || overrides[0].Effect != storage.PermissionEffectDeny {
+		t.Fatalf("unexpected overrides: %+v", overrides)
+	}
+}
+
+func TestTokenRevocationExpiresWithToken(t *testing.T) {
+	ctx := context.Background()
+	adapter := newTestAdapter(t)
+
+	if err := adapter.RevokeToken(ctx, "jti-live", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("RevokeToken returned error: %v", err)
+	}
+	revoked, err := adapter.IsTokenRevoked(ctx, "jti-live")
+	if err != nil || !revoked {
+		t.Fatalf("IsTokenRevoked(jti-live) = (%v, %v), want revoked", revoked, err)
+	}
+
+	if err := adapter.RevokeToken(ctx, "jti-expired", time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("RevokeToken returned error: %v", err)
+	}
+	if revoked, err := adapter.IsTokenRevoked(ctx, "jti-expired"); err != nil || revoked {
+		t.Fatalf("IsTokenRevoked(jti-expired) = (%v, %v), want not revoked", revoked, err)
+	}
+	if revoked, err := adapter.IsTokenRevoked(ctx, "jti-unknown"); err != nil || revoked {
+		t.Fatalf("IsTokenRevoked(jti-unknown) = (%v, %v), want not revoked", revoked, err)
+	}
+
+	if err := adapter.RevokeToken(ctx, " ", time.Now().Add(time.Hour)); !errors.Is(err, ErrMissingTokenID) {
+		t.Fatalf("RevokeToken with empty ID error = %v, want ErrMissingTokenID", err)
+	}
+
+	removed, err := adapter.DeleteExpiredRevocations(ctx, time.Now().Add(2*time.Hour))
+	if err != nil {
+		t.Fatalf("DeleteExpiredRevocations returned error: %v", err)
+	}
+	if removed != 1 {
+		t.Fatalf("DeleteExpiredRevocations removed %d rows, want 1", removed)
+	}
+	if revoked, err := adapter.IsTokenRevoked(ctx, "jti-live"); err != nil || revoked {
+		t.Fatalf("IsTokenRevoked after prune = (%v, %v), want not revoked", revoked, err)
+	}
+}
+
+func TestSessionRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	adapter := newTestAdapter(t)
+
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Microsecond)
+	if err := adapter.PutSession(ctx, storage.SessionRecord{
+		ID:        "session-1",
+		Subject:   "user-1",
+		Tenant:    "tenant-a",
+		ExpiresAt: expiresAt,
+		Metadata:  map[string]string{"ip": "127.0.0.1"},
+	}); err != nil {
+		t.Fatalf("PutSession returned error: %v", err)
+	}
+	if err := adapter.PutSession(ctx, storage.SessionRecord{ID: "session-2", Subject: "user-1", ExpiresAt: time.Now().Add(-time.Minute)}); err != nil {
+		t.Fatalf("PutSession returned error: %v", err)
+	}
+
+	record, err := adapter.GetSession(ctx, "session-1")
+	if err != nil {
+		t.Fatalf("GetSession returned error: %v", err)
+	}
+	if record.AuthID != "" || record.Tenant != "tenant-a" || record.Metadata["ip"] != "127.0.0.1" || !record.ExpiresAt.Equal(expiresAt) {
+		t.Fatalf("GetSession = %+v, want stored session", record)
+	}
+
+	if err := adapter.PutSession(ctx, storage.SessionRecord{ID: "session-3", Subject: "user-1"}); !errors.Is(err, ErrInvalidSession) {
+		t.Fatalf("PutSession without expiry error = %v, want ErrInvalidSession", err)
+	}
+
+	removed, err := adapter.DeleteExpiredSessions(ctx, time.Now())
+	if err != nil {
+		t.Fatalf("DeleteExpiredSessions returned error: %v", err)
+	}
+	if removed != 1 {
+		t.Fatalf("DeleteExpiredSessions removed %d rows, want 1", removed)
+	}
+
+	if err := adapter.DeleteSession(ctx, "session-1"); err != nil {
+		t.Fatalf("DeleteSession returned error: %v", err)
+	}
+	if _, err := adapter.GetSession(ctx, "session-1"); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("GetSession after delete error = %v, want storage.ErrNotFound", err)
+	}
+}
+
+func TestOAuthClientRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	adapter := newTestAdapter(t)
+
+	if err := adapter.PutOAuthClient(ctx, storage.OAuthClientRecord{
+		ID:         "client-1",
+		Name:       "billing",
+		Tenant:     "tenant-a",
+		GrantTypes: []string{"client_credentials"},
+		Scopes:     []string{"invoices:read", "invoices:write"},
+	}); err != nil {
+		t.Fatalf("PutOAuthClient returned error: %v", err)
+	}
+
+	record, err := adapter.GetOAuthClient(ctx, "client-1")
+	if err != nil {
+		t.Fatalf("GetOAuthClient returned error: %v", err)
+	}
+	if record.Name != "billing" || record.Tenant != "tenant-a" || len(record.Scopes) != 2 || record.RedirectURIs == nil || len(record.RedirectURIs) != 0 {
+		t.Fatalf("GetOAuthClient = %+v, want stored client", record)
+	}
+	if record.DateAdded.IsZero() || record.DateModified != nil {
+		t.Fatalf("expected new client to carry DateAdded only, got %+v", record)
+	}
+
+	record.Scopes = []string{"invoices:read"}
+	if err := adapter.PutOAuthClient(ctx, record); err != nil {
+		t.Fatalf("PutOAuthClient update returned error: %v", err)
+	}
+	updated, err := adapter.GetOAuthClient(ctx, "client-1")
+	if err != nil {
+		t.Fatalf("GetOAuthClient returned error: %v", err)
+	}
+	if len(updated.Scopes) != 1 || updated.DateModified == nil || !updated.DateAdded.Equal(record.DateAdded) {
+		t.Fatalf("updated client = %+v, want narrowed scopes and DateModified", updated)
+	}
+
+	if err := adapter.PutOAuthClient(ctx, storage.OAuthClientRecord{}); !errors.Is(err, ErrInvalidOAuthClient) {
+		t.Fatalf("PutOAuthClient without id error = %v, want ErrInvalidOAuthClient", err)
+	}
+	if err := adapter.DeleteOAuthClient(ctx, "client-1"); err != nil {
+		t.Fatalf("DeleteOAuthClient returned error: %v", err)
+	}
+	if _, err := adapter.GetOAuthClient(ctx, "client-1"); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("GetOAuthClient after delete error = %v, want storage.ErrNotFound", err)
+	}
+}
+
+func TestColumnDefaultsMatchTimeLayout(t *testing.T) {
+	ctx := context.Background()
+	adapter := newTestAdapter(t)
+
+	if _, err := adapter.db.ExecContext(ctx, `INSERT INTO auth (id, status, material_type, material_hash) VALUES ('auth-raw', 'active', 'password', 'hash')`); err != nil {
+		t.Fatalf("insert with default date_added: %v", err)
+	}
+	var dateAdded string
+	if err := adapter.db.QueryRowContext(ctx, `SELECT date_added FROM auth WHERE id = 'auth-raw'`).Scan(&dateAdded); err != nil {
+		t.Fatalf("select date_added: %v", err)
+	}
+	if _, err := time.Parse(timeLayout, dateAdded); err != nil || len(dateAdded) != len(timeLayout) {
+		t.Fatalf("default date_added = %q, want timeLayout so it sorts with adapter-written rows", dateAdded)
+	}
+}
@@ -0,0 +1,186 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/porthorian/openauth/pkg/storage"
+)
+
+const (
+	putAuthLogQuery = `
+INSERT INTO auth_log (
+  id, auth_id, subject, event, occurred_at, date_added, metadata
+) VALUES (?, ?, ?, ?, ?, ?, ?)
+`
+
+	listAuthLogByAuthIDQuery = `
+SELECT
+  id, date_added, auth_id, subject, event, occurred_at, metadata
+FROM auth_log
+WHERE auth_id = ?
+ORDER BY date_added ASC
+`
+
+	listAuthLogBySubjectQuery = `
+SELECT
+  id, date_added, auth_id, subject, event, occurred_at, metadata
+FROM auth_log
+WHERE subject = ?
+ORDER BY date_added ASC
+`
+
+	listAuthLogBySubjectSinceQuery = `
+SELECT
+  id, date_added, auth_id, subject, event, occurred_at, metadata
+FROM auth_log
+WHERE subject = ? AND occurred_at >= ?
+ORDER BY occurred_at ASC
+`
+
+	deleteAuthLogByAuthIDQuery = `DELETE FROM auth_log WHERE auth_id = ?`
+)
+
+func (a *Adapter) PutAuthLog(ctx context.Context, record storage.AuthLogRecord) error {
+	if err := a.requirePreparedStatements(); err != nil {
+		return err
+	}
+
+	dateAdded := record.DateAdded
+	if dateAdded.IsZero() {
+		dateAdded = time.Now().UTC()
+	}
+
+	occurredAt := record.OccurredAt
+	if occurredAt.IsZero() {
+		occurredAt = dateAdded
+	}
+
+	event := string(record.Event)
+	if event == "" {
+		event = string(storage.AuthLogEventUsed)
+	}
+
+	metadata := cloneStringMap(record.Metadata)
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+	metadataRaw, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+
+	stmt, release := a.bind(ctx, a.stmts.putAuthLog)
+	defer release()
+
+	_, err = stmt.ExecContext(ctx, record.ID, record.AuthID, record.Subject, event, formatTime(occurredAt), formatTime(dateAdded), string(metadataRaw))
+	return err
+}
+
+func (a *Adapter) ListAuthLogsByAuthID(ctx context.Context, authID string) ([]storage.AuthLogRecord, error) {
+	if err := a.requirePreparedStatements(); err != nil {
+		return nil, err
+	}
+
+	return a.listAuthLogs(ctx, a.stmts.listAuthLogByAuthID, authID)
+}
+
+func (a *Adapter) ListAuthLogsBySubject(ctx context.Context, subject string) ([]storage.AuthLogRecord, error) {
+	if err := a.requirePreparedStatements(); err != nil {
+		return nil, err
+	}
+
+	return a.listAuthLogs(ctx, a.stmts.listAuthLogBySubject, subject)
+}
+
+// ListAuthLogsBySubjectSince lists the subject's events that occurred at or
+// after since. occurred_at is stored in a fixed-width layout, so the text
+// comparison orders like the timestamps.
+func (a *Adapter) ListAuthLogsBySubjectSince(ctx context.Context, subject string, since time.Time) ([]storage.AuthLogRecord, error) {
+	if err := a.requirePreparedStatements(); err != nil {
+		return nil, err
+	}
+
+	return a.listAuthLogs(ctx, a.stmts.listAuthLogBySubjectSince, subject, formatTime(since))
+}
+
+func (a *Adapter) listAuthLogs(ctx context.Context, prepared *sql.Stmt, args ...any) ([]storage.AuthLogRecord, error) {
+	stmt, release := a.bind(ctx, prepared)
+	defer release()
+
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := []storage.AuthLogRecord{}
+	for rows.Next() {
+		record, scanErr := scanAuthLog(rows)
+		if scanErr != nil {
+			return nil, scanErr
+		}
+		records = append(records, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+func scanAuthLog(s scanner) (storage.AuthLogRecord, error) {
+	var (
+		record      storage.AuthLogRecord
+		dateAdded   string
+		event       string
+		occurredAt  string
+		metadataRaw sql.NullString
+	)
+
+	if err := s.Scan(
+		&record.ID,
+		&dateAdded,
+		&record.AuthID,
+		&record.Subject,
+		&event,
+		&occurredAt,
+		&metadataRaw,
+	); err != nil {
+		return storage.AuthLogRecord{}, err
+	}
+
+	var err error
+	if record.DateAdded, err = parseTime(dateAdded); err != nil {
+		return storage.AuthLogRecord{}, err
+	}
+	if record.OccurredAt, err = parseTime(occurredAt); err != nil {
+		return storage.AuthLogRecord{}, err
+	}
+	record.Event = storage.AuthLogEvent(event)
+	record.Metadata = map[string]string{}
+	if !metadataRaw.Valid || metadataRaw.String == "" {
+		return record, nil
+	}
+
+	decoded := map[string]string{}
+	if err := json.Unmarshal([]byte(metadataRaw.String), &decoded); err != nil {
+		return storage.AuthLogRecord{}, err
+	}
+	record.Metadata = decoded
+	return record, nil
+}
+
+func cloneStringMap(input map[string]string) map[string]string {
+	if len(input) == 0 {
+		return nil
+	}
+
+	cloned := make(map[string]string, len(input))
+	for key, value := range input {
+		cloned[key] = value
+	}
+	return cloned
+}
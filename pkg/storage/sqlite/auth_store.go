@@ -0,0 +1,487 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/porthorian/openauth/pkg/storage"
+)
+
+const (
+	putAuthQuery = `
+INSERT INTO auth (
+  id, status, date_added, date_modified, material_type, material_hash, expires_at, revoked_at
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT (id) DO UPDATE
+SET
+  status = excluded.status,
+  date_modified = excluded.date_modified,
+  material_type = excluded.material_type,
+  material_hash = excluded.material_hash,
+  expires_at = excluded.expires_at,
+  revoked_at = excluded.revoked_at
+`
+
+	getAuthQuery = `
+SELECT
+  id, status, date_added, date_modified, material_type, material_hash, expires_at, revoked_at
+FROM auth
+WHERE id = ?
+`
+
+	deleteAuthQuery = `DELETE FROM auth WHERE id = ?`
+
+	swapAuthStatusQuery = `
+UPDATE auth
+SET status = ?, date_modified = ?
+WHERE id = ? AND status = ?
+`
+
+	swapAuthQuery = `
+UPDATE auth
+SET
+  status = ?,
+  date_modified = ?,
+  material_type = ?,
+  material_hash = ?,
+  expires_at = ?,
+  revoked_at = ?
+WHERE id = ? AND date_modified IS ?
+`
+
+	deleteAuthMetadataQuery = `
+DELETE FROM auth_metadata
+WHERE auth_id = ?
+`
+
+	putAuthMetadataQuery = `
+INSERT INTO auth_metadata (
+  id, auth_id, date_added, key, value
+) VALUES (?, ?, ?, ?, ?)
+`
+
+	getAuthMetadataQuery = `
+SELECT
+  key, value
+FROM auth_metadata
+WHERE auth_id = ?
+`
+)
+
+func (a *Adapter) PutAuth(ctx context.Context, record storage.AuthRecord) error {
+	if err := a.requirePreparedStatements(); err != nil {
+		return err
+	}
+
+	return a.withTx(ctx, func(tx *sql.Tx) error {
+		return a.putAuthInTx(ctx, tx, record)
+	})
+}
+
+func (a *Adapter) putAuthInTx(ctx context.Context, tx *sql.Tx, record storage.AuthRecord) error {
+	dateAdded := record.DateAdded
+	if dateAdded.IsZero() {
+		dateAdded = time.Now().UTC()
+	}
+
+	dateModified := time.Now().UTC()
+	if record.DateModified != nil {
+		dateModified = record.DateModified.UTC()
+	}
+
+	putAuthStmt := tx.StmtContext(ctx, a.stmts.putAuth)
+	_, err := putAuthStmt.ExecContext(
+		ctx,
+		record.ID,
+		string(record.Status),
+		formatTime(dateAdded),
+		formatTime(dateModified),
+		string(record.MaterialType),
+		record.MaterialHash,
+		formatNullableTime(record.ExpiresAt),
+		formatNullableTime(record.RevokedAt),
+	)
+	_ = putAuthStmt.Close()
+	if err != nil {
+		return err
+	}
+
+	return a.replaceAuthMetadataInTx(ctx, tx, record)
+}
+
+// replaceAuthMetadataInTx swaps the stored metadata of record.ID for
+// record.Metadata.
+func (a *Adapter) replaceAuthMetadataInTx(ctx context.Context, tx *sql.Tx, record storage.AuthRecord) error {
+	deleteMetadataStmt := tx.StmtContext(ctx, a.stmts.deleteAuthMetadata)
+	if _, err := deleteMetadataStmt.ExecContext(ctx, record.ID); err != nil {
+		_ = deleteMetadataStmt.Close()
+		return err
+	}
+	_ = deleteMetadataStmt.Close()
+
+	keys := sortedMetadataKeys(record.Metadata)
+	if len(keys) > 0 {
+		putMetadataStmt := tx.StmtContext(ctx, a.stmts.putAuthMetadata)
+		for _, key := range keys {
+			if _, err := putMetadataStmt.ExecContext(ctx, uuid.NewString(), record.ID, formatTime(time.Now()), key, record.Metadata[key]); err != nil {
+				_ = putMetadataStmt.Close()
+				return err
+			}
+		}
+		_ = putMetadataStmt.Close()
+	}
+
+	return nil
+}
+
+func (a *Adapter) GetAuth(ctx context.Context, id string) (storage.AuthRecord, error) {
+	if err := a.requirePreparedStatements(); err != nil {
+		return storage.AuthRecord{}, err
+	}
+
+	stmt, release := a.bind(ctx, a.stmts.getAuth)
+	defer release()
+
+	row := stmt.QueryRowContext(ctx, id)
+	record, err := scanAuth(row)
+	if err != nil {
+		return storage.AuthRecord{}, notFound(err)
+	}
+
+	metadata, err := a.getMetadataByAuthID(ctx, id)
+	if err != nil {
+		return storage.AuthRecord{}, err
+	}
+	record.Metadata = metadata
+
+	return record, nil
+}
+
+func (a *Adapter) GetAuths(ctx context.Context, ids []string) ([]storage.AuthRecord, error) {
+	if len(ids) == 0 {
+		return []storage.AuthRecord{}, nil
+	}
+
+	args := make([]any, len(ids))
+	for i := range ids {
+		args[i] = ids[i]
+	}
+
+	rows, err := a.queryAuths(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := make([]storage.AuthRecord, 0, len(ids))
+	authIDs := make([]string, 0, len(ids))
+	for rows.Next() {
+		record, scanErr := scanAuth(rows)
+		if scanErr != nil {
+			return nil, scanErr
+		}
+		records = append(records, record)
+		authIDs = append(authIDs, record.ID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	_ = rows.Close()
+
+	metadataByAuthID, err := a.getMetadataByAuthIDs(ctx, authIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range records {
+		metadata, ok := metadataByAuthID[records[i].ID]
+		if !ok {
+			records[i].Metadata = map[string]string{}
+			continue
+		}
+		records[i].Metadata = metadata
+	}
+
+	return records, nil
+}
+
+func (a *Adapter) SwapAuthStatus(ctx context.Context, id string, from storage.AuthStatus, to storage.AuthStatus, modifiedAt time.Time) (bool, error) {
+	if err := a.requirePreparedStatements(); err != nil {
+		return false, err
+	}
+
+	stmt, release := a.bind(ctx, a.stmts.swapAuthStatus)
+	defer release()
+
+	result, err := stmt.ExecContext(ctx, string(to), formatTime(modifiedAt), id, string(from))
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected == 1, err
+}
+
+func (a *Adapter) SwapAuth(ctx context.Context, record storage.AuthRecord, lastModified *time.Time) (bool, error) {
+	if err := a.requirePreparedStatements(); err != nil {
+		return false, err
+	}
+
+	dateModified := time.Now().UTC()
+	if record.DateModified != nil {
+		dateModified = record.DateModified.UTC()
+	}
+
+	swapped := false
+	err := a.withTx(ctx, func(tx *sql.Tx) error {
+		stmt := tx.StmtContext(ctx, a.stmts.swapAuth)
+		result, err := stmt.ExecContext(
+			ctx,
+			string(record.Status),
+			formatTime(dateModified),
+			string(record.MaterialType),
+			record.MaterialHash,
+			formatNullableTime(record.ExpiresAt),
+			formatNullableTime(record.RevokedAt),
+			record.ID,
+			formatNullableTime(lastModified),
+		)
+		_ = stmt.Close()
+		if err != nil {
+			return err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil || affected != 1 {
+			return err
+		}
+
+		swapped = true
+		return a.replaceAuthMetadataInTx(ctx, tx, record)
+	})
+	if err != nil {
+		return false, err
+	}
+	return swapped, nil
+}
+
+// DeleteAuth removes the auth record and every row that references it.
+// Dependents are deleted explicitly because SQLite only honours
+// ON DELETE CASCADE when PRAGMA foreign_keys is enabled on the connection.
+func (a *Adapter) DeleteAuth(ctx context.Context, id string) error {
+	if err := a.requirePreparedStatements(); err != nil {
+		return err
+	}
+
+	return a.withTx(ctx, func(tx *sql.Tx) error {
+		for _, stmt := range []*sql.Stmt{
+			a.stmts.deleteAuthMetadata,
+			a.stmts.deleteSubjectAuthByAuthID,
+			a.stmts.deleteAuthLogByAuthID,
+			a.stmts.deleteSessionsByAuthID,
+			a.stmts.deleteAuth,
+		} {
+			txStmt := tx.StmtContext(ctx, stmt)
+			_, err := txStmt.ExecContext(ctx, id)
+			_ = txStmt.Close()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// queryAuths runs the IN (...) lookup. Inside a transaction the query is run
+// directly on the transaction because preparing on the pool would wait for
+// the connection the transaction already holds.
+func (a *Adapter) queryAuths(ctx context.Context, args []any) (*sql.Rows, error) {
+	if a.tx != nil {
+		return a.tx.QueryContext(ctx, getAuthsQuery(len(args)), args...)
+	}
+
+	stmt, err := a.getAuthsPrepared(len(args))
+	if err != nil {
+		return nil, err
+	}
+	return stmt.QueryContext(ctx, args...)
+}
+
+func (a *Adapter) getAuthsPrepared(size int) (*sql.Stmt, error) {
+	if size <= 0 {
+		return nil, nil
+	}
+
+	db, err := a.requireDB()
+	if err != nil {
+		return nil, err
+	}
+
+	a.stmts.getAuthsMu.Lock()
+	defer a.stmts.getAuthsMu.Unlock()
+
+	if stmt, ok := a.stmts.getAuthsBySize[size]; ok {
+		return stmt, nil
+	}
+
+	stmt, err := db.Prepare(getAuthsQuery(size))
+	if err != nil {
+		return nil, err
+	}
+
+	a.stmts.getAuthsBySize[size] = stmt
+	return stmt, nil
+}
+
+func getAuthsQuery(size int) string {
+	return fmt.Sprintf(`
+SELECT
+  id, status, date_added, date_modified, material_type, material_hash, expires_at, revoked_at
+FROM auth
+WHERE id IN (%s)
+`, placeholders(size))
+}
+
+func scanAuth(s scanner) (storage.AuthRecord, error) {
+	var (
+		record       storage.AuthRecord
+		status       string
+		materialType string
+		dateAdded    string
+		dateModified sql.NullString
+		expiresAt    sql.NullString
+		revokedAt    sql.NullString
+	)
+
+	if err := s.Scan(
+		&record.ID,
+		&status,
+		&dateAdded,
+		&dateModified,
+		&materialType,
+		&record.MaterialHash,
+		&expiresAt,
+		&revokedAt,
+	); err != nil {
+		return storage.AuthRecord{}, err
+	}
+
+	var err error
+	record.Status = storage.AuthStatus(status)
+	record.MaterialType = storage.AuthMaterialType(materialType)
+	if record.DateAdded, err = parseTime(dateAdded); err != nil {
+		return storage.AuthRecord{}, err
+	}
+	if record.DateModified, err = parseNullableTime(dateModified); err != nil {
+		return storage.AuthRecord{}, err
+	}
+	if record.ExpiresAt, err = parseNullableTime(expiresAt); err != nil {
+		return storage.AuthRecord{}, err
+	}
+	if record.RevokedAt, err = parseNullableTime(revokedAt); err != nil {
+		return storage.AuthRecord{}, err
+	}
+	record.Metadata = map[string]string{}
+
+	return record, nil
+}
+
+func sortedMetadataKeys(metadata map[string]string) []string {
+	if len(metadata) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(metadata))
+	for key := range metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func placeholders(size int) string {
+	if size <= 0 {
+		return ""
+	}
+	return strings.TrimSuffix(strings.Repeat("?, ", size), ", ")
+}
+
+func (a *Adapter) getMetadataByAuthID(ctx context.Context, authID string) (map[string]string, error) {
+	stmt, release := a.bind(ctx, a.stmts.getAuthMetadata)
+	defer release()
+
+	rows, err := stmt.QueryContext(ctx, authID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	metadata := map[string]string{}
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, err
+		}
+		metadata[key] = value
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return metadata, nil
+}
+
+func (a *Adapter) getMetadataByAuthIDs(ctx context.Context, authIDs []string) (map[string]map[string]string, error) {
+	if len(authIDs) == 0 {
+		return map[string]map[string]string{}, nil
+	}
+
+	q, err := a.queryer()
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]any, len(authIDs))
+	for i := range authIDs {
+		args[i] = authIDs[i]
+	}
+
+	query := fmt.Sprintf(`
+SELECT
+  auth_id, key, value
+FROM auth_metadata
+WHERE auth_id IN (%s)
+`, placeholders(len(authIDs)))
+
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	metadataByAuthID := map[string]map[string]string{}
+	for rows.Next() {
+		var authID, key, value string
+		if err := rows.Scan(&authID, &key, &value); err != nil {
+			return nil, err
+		}
+
+		metadata, ok := metadataByAuthID[authID]
+		if !ok {
+			metadata = map[string]string{}
+			metadataByAuthID[authID] = metadata
+		}
+		metadata[key] = value
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return metadataByAuthID, nil
+}
@@ -0,0 +1,246 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/porthorian/openauth/pkg/storage"
+)
+
+const (
+	deleteSubjectRolesQuery = `
+DELETE FROM subject_role
+WHERE subject = ? AND tenant = ?
+`
+
+	putSubjectRoleQuery = `
+INSERT INTO subject_role (
+  subject, tenant, role_key, date_added
+) VALUES (?, ?, ?, ?)
+ON CONFLICT (subject, tenant, role_key) DO NOTHING
+`
+
+	listSubjectRolesQuery = `
+SELECT
+  subject, tenant, role_key
+FROM subject_role
+WHERE subject = ? AND tenant = ?
+ORDER BY role_key ASC
+`
+
+	deleteSubjectPermissionOverridesQuery = `
+DELETE FROM subject_permission_override
+WHERE subject = ? AND tenant = ?
+`
+
+	putSubjectPermissionOverrideQuery = `
+INSERT INTO subject_permission_override (
+  subject, tenant, permission_key, effect, date_added
+) VALUES (?, ?, ?, ?, ?)
+ON CONFLICT (subject, tenant, permission_key) DO UPDATE
+SET
+  effect = excluded.effect,
+  date_added = excluded.date_added
+`
+
+	listSubjectPermissionOverridesQuery = `
+SELECT
+  subject, tenant, permission_key, effect
+FROM subject_permission_override
+WHERE subject = ? AND tenant = ?
+ORDER BY permission_key ASC
+`
+)
+
+func (a *Adapter) ReplaceSubjectRoles(ctx context.Context, subject string, tenant string, roleKeys []string) error {
+	if err := a.requirePreparedStatements(); err != nil {
+		return err
+	}
+
+	normalizedSubject := strings.TrimSpace(subject)
+	normalizedTenant := strings.TrimSpace(tenant)
+	normalizedKeys := normalizeRoleKeys(roleKeys)
+
+	return a.withTx(ctx, func(tx *sql.Tx) error {
+		return a.replaceSubjectRolesInTx(ctx, tx, normalizedSubject, normalizedTenant, normalizedKeys)
+	})
+}
+
+func (a *Adapter) ListSubjectRoles(ctx context.Context, subject string, tenant string) ([]storage.SubjectRoleRecord, error) {
+	if err := a.requirePreparedStatements(); err != nil {
+		return nil, err
+	}
+
+	stmt, release := a.bind(ctx, a.stmts.listSubjectRoles)
+	defer release()
+
+	rows, err := stmt.QueryContext(ctx, strings.TrimSpace(subject), strings.TrimSpace(tenant))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := []storage.SubjectRoleRecord{}
+	for rows.Next() {
+		var record storage.SubjectRoleRecord
+		if err := rows.Scan(&record.Subject, &record.Tenant, &record.RoleKey); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+func (a *Adapter) ReplaceSubjectPermissionOverrides(ctx context.Context, subject string, tenant string, overrides []storage.SubjectPermissionOverrideRecord) error {
+	if err := a.requirePreparedStatements(); err != nil {
+		return err
+	}
+
+	normalizedSubject := strings.TrimSpace(subject)
+	normalizedTenant := strings.TrimSpace(tenant)
+	normalizedOverrides := normalizePermissionOverrides(overrides, normalizedSubject, normalizedTenant)
+
+	return a.withTx(ctx, func(tx *sql.Tx) error {
+		return a.replaceSubjectPermissionOverridesInTx(ctx, tx, normalizedSubject, normalizedTenant, normalizedOverrides)
+	})
+}
+
+func (a *Adapter) ListSubjectPermissionOverrides(ctx context.Context, subject string, tenant string) ([]storage.SubjectPermissionOverrideRecord, error) {
+	if err := a.requirePreparedStatements(); err != nil {
+		return nil, err
+	}
+
+	stmt, release := a.bind(ctx, a.stmts.listSubjectPermissionOverrides)
+	defer release()
+
+	rows, err := stmt.QueryContext(ctx, strings.TrimSpace(subject), strings.TrimSpace(tenant))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := []storage.SubjectPermissionOverrideRecord{}
+	for rows.Next() {
+		var (
+			record storage.SubjectPermissionOverrideRecord
+			effect string
+		)
+		if err := rows.Scan(&record.Subject, &record.Tenant, &record.PermissionKey, &effect); err != nil {
+			return nil, err
+		}
+		record.Effect = storage.PermissionEffect(effect)
+		records = append(records, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+func (a *Adapter) replaceSubjectRolesInTx(ctx context.Context, tx *sql.Tx, subject string, tenant string, roleKeys []string) error {
+	deleteStmt := tx.StmtContext(ctx, a.stmts.deleteSubjectRoles)
+	if _, err := deleteStmt.ExecContext(ctx, subject, tenant); err != nil {
+		_ = deleteStmt.Close()
+		return err
+	}
+	_ = deleteStmt.Close()
+
+	if len(roleKeys) == 0 {
+		return nil
+	}
+
+	insertStmt := tx.StmtContext(ctx, a.stmts.putSubjectRole)
+	defer insertStmt.Close()
+
+	now := formatTime(time.Now())
+	for _, roleKey := range roleKeys {
+		if _, err := insertStmt.ExecContext(ctx, subject, tenant, roleKey, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *Adapter) replaceSubjectPermissionOverridesInTx(ctx context.Context, tx *sql.Tx, subject string, tenant string, overrides []storage.SubjectPermissionOverrideRecord) error {
+	deleteStmt := tx.StmtContext(ctx, a.stmts.deleteSubjectPermissionOverrides)
+	if _, err := deleteStmt.ExecContext(ctx, subject, tenant); err != nil {
+		_ = deleteStmt.Close()
+		return err
+	}
+	_ = deleteStmt.Close()
+
+	if len(overrides) == 0 {
+		return nil
+	}
+
+	insertStmt := tx.StmtContext(ctx, a.stmts.putSubjectPermissionOverride)
+	defer insertStmt.Close()
+
+	now := formatTime(time.Now())
+	for _, override := range overrides {
+		if _, err := insertStmt.ExecContext(ctx, subject, tenant, override.PermissionKey, string(override.Effect), now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func normalizeRoleKeys(roleKeys []string) []string {
+	if len(roleKeys) == 0 {
+		return nil
+	}
+
+	dedup := make(map[string]struct{}, len(roleKeys))
+	normalized := make([]string, 0, len(roleKeys))
+	for _, roleKey := range roleKeys {
+		trimmed := strings.TrimSpace(roleKey)
+		if trimmed == "" {
+			continue
+		}
+		if _, exists := dedup[trimmed]; exists {
+			continue
+		}
+		dedup[trimmed] = struct{}{}
+		normalized = append(normalized, trimmed)
+	}
+	return normalized
+}
+
+func normalizePermissionOverrides(overrides []storage.SubjectPermissionOverrideRecord, subject string, tenant string) []storage.SubjectPermissionOverrideRecord {
+	if len(overrides) == 0 {
+		return nil
+	}
+
+	normalized := make([]storage.SubjectPermissionOverrideRecord, 0, len(overrides))
+	seen := make(map[string]int, len(overrides))
+	for _, override := range overrides {
+		permissionKey := strings.TrimSpace(override.PermissionKey)
+		if permissionKey == "" {
+			continue
+		}
+		record := storage.SubjectPermissionOverrideRecord{
+			Subject:       subject,
+			Tenant:        tenant,
+			PermissionKey: permissionKey,
+			Effect:        override.Effect,
+		}
+		if record.Effect != storage.PermissionEffectGrant && record.Effect != storage.PermissionEffectDeny {
+			continue
+		}
+
+		if index, exists := seen[permissionKey]; exists {
+			normalized[index] = record
+			continue
+		}
+		seen[permissionKey] = len(normalized)
+		normalized = append(normalized, record)
+	}
+	return normalized
+}
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS subject_permission_override;
+DROP TABLE IF EXISTS subject_role;
+DROP TABLE IF EXISTS auth_log;
+DROP TABLE IF EXISTS subject_auth;
+DROP TABLE IF EXISTS auth_metadata;
+DROP TABLE IF EXISTS auth;
@@ -0,0 +1,83 @@
+CREATE TABLE IF NOT EXISTS auth (
+  id TEXT NOT NULL PRIMARY KEY,
+  status TEXT NOT NULL CHECK (status IN ('active', 'inactive', 'revoked', 'expired')),
+  date_added TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%f', 'now') || '000000Z'),
+  date_modified TEXT NULL,
+  material_type TEXT NOT NULL CHECK (material_type IN ('password', 'access_token', 'refresh_token', 'api_key', 'client_secret')),
+  material_hash TEXT NOT NULL,
+  expires_at TEXT NULL,
+  revoked_at TEXT NULL
+);
+
+CREATE TABLE IF NOT EXISTS auth_metadata (
+  id TEXT NOT NULL PRIMARY KEY,
+  auth_id TEXT NOT NULL,
+  date_added TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%f', 'now') || '000000Z'),
+  key TEXT NOT NULL,
+  value TEXT NOT NULL,
+
+  CONSTRAINT fk_auth_metadata_auth_id
+    FOREIGN KEY (auth_id)
+    REFERENCES auth (id)
+    ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_auth_metadata_auth_id ON auth_metadata (auth_id);
+
+CREATE TABLE IF NOT EXISTS subject_auth (
+  id TEXT NOT NULL PRIMARY KEY,
+  auth_id TEXT NOT NULL UNIQUE,
+  subject TEXT NOT NULL,
+  date_added TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%f', 'now') || '000000Z'),
+  date_modified TEXT NULL,
+
+  CONSTRAINT fk_subject_auth_auth_id
+    FOREIGN KEY (auth_id)
+    REFERENCES auth (id)
+    ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_subject_auth_subject ON subject_auth (subject);
+CREATE INDEX IF NOT EXISTS idx_subject_auth_auth_id ON subject_auth (auth_id);
+
+CREATE TABLE IF NOT EXISTS auth_log (
+  id TEXT NOT NULL PRIMARY KEY,
+  auth_id TEXT NOT NULL,
+  subject TEXT NOT NULL,
+  event TEXT NOT NULL,
+  occurred_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%f', 'now') || '000000Z'),
+  date_added TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%f', 'now') || '000000Z'),
+  metadata TEXT NULL,
+
+  CONSTRAINT fk_auth_log_auth_id
+    FOREIGN KEY (auth_id)
+    REFERENCES auth (id)
+    ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_auth_log_auth_id ON auth_log (auth_id);
+CREATE INDEX IF NOT EXISTS idx_auth_log_subject ON auth_log (subject);
+
+CREATE TABLE IF NOT EXISTS subject_role (
+  subject TEXT NOT NULL,
+  tenant TEXT NOT NULL,
+  role_key TEXT NOT NULL,
+  date_added TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%f', 'now') || '000000Z'),
+
+  CONSTRAINT pk_subject_role PRIMARY KEY (subject, tenant, role_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_subject_role_subject_tenant ON subject_role (subject, tenant);
+
+CREATE TABLE IF NOT EXISTS subject_permission_override (
+  subject TEXT NOT NULL,
+  tenant TEXT NOT NULL,
+  permission_key TEXT NOT NULL,
+  effect TEXT NOT NULL CHECK (effect IN ('grant', 'deny')),
+  date_added TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%f', 'now') || '000000Z'),
+
+  CONSTRAINT pk_subject_permission_override PRIMARY KEY (subject, tenant, permission_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_subject_permission_override_subject_tenant
+  ON subject_permission_override (subject, tenant);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_token_revocation_expires_at;
+DROP TABLE IF EXISTS token_revocation;
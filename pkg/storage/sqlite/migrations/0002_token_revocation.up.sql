@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS token_revocation (
+  token_id TEXT NOT NULL PRIMARY KEY,
+  expires_at TEXT NOT NULL,
+  date_added TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%f', 'now') || '000000Z')
+);
+
+CREATE INDEX IF NOT EXISTS idx_token_revocation_expires_at ON token_revocation (expires_at);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_session_expires_at;
+DROP INDEX IF EXISTS idx_session_auth_id;
+DROP INDEX IF EXISTS idx_session_subject;
+DROP TABLE IF EXISTS session;
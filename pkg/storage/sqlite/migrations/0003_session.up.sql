@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS session (
+  id TEXT NOT NULL PRIMARY KEY,
+  auth_id TEXT NULL,
+  subject TEXT NOT NULL,
+  tenant TEXT NOT NULL DEFAULT '',
+  date_added TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%f', 'now') || '000000Z'),
+  expires_at TEXT NOT NULL,
+  metadata TEXT NULL,
+
+  CONSTRAINT fk_session_auth_id
+    FOREIGN KEY (auth_id)
+    REFERENCES auth (id)
+    ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_session_subject ON session (subject);
+CREATE INDEX IF NOT EXISTS idx_session_auth_id ON session (auth_id);
+CREATE INDEX IF NOT EXISTS idx_session_expires_at ON session (expires_at);
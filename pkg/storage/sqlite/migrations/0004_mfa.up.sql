@@ -0,0 +1,21 @@
+-- SQLite cannot alter a CHECK constraint, so auth is rebuilt with the new
+-- material types. Run with PRAGMA foreign_keys off (the default) so dropping
+-- the old table does not cascade into dependent rows.
+CREATE TABLE auth_next (
+  id TEXT NOT NULL PRIMARY KEY,
+  status TEXT NOT NULL CHECK (status IN ('active', 'inactive', 'revoked', 'expired')),
+  date_added TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%f', 'now') || '000000Z'),
+  date_modified TEXT NULL,
+  material_type TEXT NOT NULL CHECK (material_type IN ('password', 'access_token', 'refresh_token', 'api_key', 'client_secret', 'totp', 'mfa_challenge')),
+  material_hash TEXT NOT NULL,
+  expires_at TEXT NULL,
+  revoked_at TEXT NULL
+);
+
+INSERT INTO auth_next (id, status, date_added, date_modified, material_type, material_hash, expires_at, revoked_at)
+SELECT id, status, date_added, date_modified, material_type, material_hash, expires_at, revoked_at
+FROM auth;
+
+DROP TABLE auth;
+
+ALTER TABLE auth_next RENAME TO auth;
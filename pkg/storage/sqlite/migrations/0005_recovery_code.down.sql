@@ -0,0 +1,24 @@
+DELETE FROM auth_metadata WHERE auth_id IN (SELECT id FROM auth WHERE material_type = 'recovery_code');
+DELETE FROM subject_auth WHERE auth_id IN (SELECT id FROM auth WHERE material_type = 'recovery_code');
+DELETE FROM auth_log WHERE auth_id IN (SELECT id FROM auth WHERE material_type = 'recovery_code');
+DELETE FROM session WHERE auth_id IN (SELECT id FROM auth WHERE material_type = 'recovery_code');
+DELETE FROM auth WHERE material_type = 'recovery_code';
+
+CREATE TABLE auth_prev (
+  id TEXT NOT NULL PRIMARY KEY,
+  status TEXT NOT NULL CHECK (status IN ('active', 'inactive', 'revoked', 'expired')),
+  date_added TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%f', 'now') || '000000Z'),
+  date_modified TEXT NULL,
+  material_type TEXT NOT NULL CHECK (material_type IN ('password', 'access_token', 'refresh_token', 'api_key', 'client_secret', 'totp', 'mfa_challenge')),
+  material_hash TEXT NOT NULL,
+  expires_at TEXT NULL,
+  revoked_at TEXT NULL
+);
+
+INSERT INTO auth_prev (id, status, date_added, date_modified, material_type, material_hash, expires_at, revoked_at)
+SELECT id, status, date_added, date_modified, material_type, material_hash, expires_at, revoked_at
+FROM auth;
+
+DROP TABLE auth;
+
+ALTER TABLE auth_prev RENAME TO auth;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_oauth_client_tenant;
+DROP TABLE IF EXISTS oauth_client;
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS oauth_client (
+  id TEXT NOT NULL PRIMARY KEY,
+  name TEXT NOT NULL DEFAULT '',
+  tenant TEXT NOT NULL DEFAULT '',
+  grant_types TEXT NOT NULL DEFAULT '[]',
+  scopes TEXT NOT NULL DEFAULT '[]',
+  redirect_uris TEXT NOT NULL DEFAULT '[]',
+  date_added TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%f', 'now') || '000000Z'),
+  date_modified TEXT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_oauth_client_tenant ON oauth_client (tenant);
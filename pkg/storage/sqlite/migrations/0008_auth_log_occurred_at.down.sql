@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_auth_log_subject_occurred_at;
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS idx_auth_log_subject_occurred_at ON auth_log (subject, occurred_at);
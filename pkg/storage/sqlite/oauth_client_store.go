@@ -0,0 +1,162 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/porthorian/openauth/pkg/storage"
+)
+
+const (
+	putOAuthClientQuery = `
+INSERT INTO oauth_client (
+  id, name, tenant, grant_types, scopes, redirect_uris, date_added, date_modified
+) VALUES (?, ?, ?, ?, ?, ?, ?, NULL)
+ON CONFLICT (id) DO UPDATE
+SET
+  name = excluded.name,
+  tenant = excluded.tenant,
+  grant_types = excluded.grant_types,
+  scopes = excluded.scopes,
+  redirect_uris = excluded.redirect_uris,
+  date_modified = excluded.date_added
+`
+
+	getOAuthClientQuery = `
+SELECT
+  id, name, tenant, grant_types, scopes, redirect_uris, date_added, date_modified
+FROM oauth_client
+WHERE id = ?
+`
+
+	deleteOAuthClientQuery = `DELETE FROM oauth_client WHERE id = ?`
+)
+
+var ErrInvalidOAuthClient = errors.New("sqlite adapter: oauth client id is required")
+
+var _ storage.OAuthClientStore = (*Adapter)(nil)
+
+// PutOAuthClient inserts or replaces a client registration. Replacing keeps
+// the original DateAdded and sets DateModified.
+func (a *Adapter) PutOAuthClient(ctx context.Context, record storage.OAuthClientRecord) error {
+	if err := a.requirePreparedStatements(); err != nil {
+		return err
+	}
+
+	record.ID = strings.TrimSpace(record.ID)
+	if record.ID == "" {
+		return ErrInvalidOAuthClient
+	}
+
+	grantTypes, err := marshalStringList(record.GrantTypes)
+	if err != nil {
+		return err
+	}
+	scopes, err := marshalStringList(record.Scopes)
+	if err != nil {
+		return err
+	}
+	redirectURIs, err := marshalStringList(record.RedirectURIs)
+	if err != nil {
+		return err
+	}
+
+	stmt, release := a.bind(ctx, a.stmts.putOAuthClient)
+	defer release()
+
+	_, err = stmt.ExecContext(
+		ctx,
+		record.ID,
+		strings.TrimSpace(record.Name),
+		strings.TrimSpace(record.Tenant),
+		grantTypes,
+		scopes,
+		redirectURIs,
+		formatTime(time.Now()),
+	)
+	return err
+}
+
+// GetOAuthClient returns storage.ErrNotFound when the client is not registered.
+func (a *Adapter) GetOAuthClient(ctx context.Context, id string) (storage.OAuthClientRecord, error) {
+	if err := a.requirePreparedStatements(); err != nil {
+		return storage.OAuthClientRecord{}, err
+	}
+
+	stmt, release := a.bind(ctx, a.stmts.getOAuthClient)
+	defer release()
+
+	record, err := scanOAuthClient(stmt.QueryRowContext(ctx, strings.TrimSpace(id)))
+	return record, notFound(err)
+}
+
+func (a *Adapter) DeleteOAuthClient(ctx context.Context, id string) error {
+	if err := a.requirePreparedStatements(); err != nil {
+		return err
+	}
+
+	stmt, release := a.bind(ctx, a.stmts.deleteOAuthClient)
+	defer release()
+
+	_, err := stmt.ExecContext(ctx, strings.TrimSpace(id))
+	return err
+}
+
+func scanOAuthClient(s scanner) (storage.OAuthClientRecord, error) {
+	var (
+		record       storage.OAuthClientRecord
+		grantTypes   string
+		scopes       string
+		redirectURIs string
+		dateAdded    string
+		dateModified sql.NullString
+	)
+
+	if err := s.Scan(
+		&record.ID,
+		&record.Name,
+		&record.Tenant,
+		&grantTypes,
+		&scopes,
+		&redirectURIs,
+		&dateAdded,
+		&dateModified,
+	); err != nil {
+		return storage.OAuthClientRecord{}, err
+	}
+
+	var err error
+	if record.DateAdded, err = parseTime(dateAdded); err != nil {
+		return storage.OAuthClientRecord{}, err
+	}
+	if record.DateModified, err = parseNullableTime(dateModified); err != nil {
+		return storage.OAuthClientRecord{}, err
+	}
+	if err := json.Unmarshal([]byte(grantTypes), &record.GrantTypes); err != nil {
+		return storage.OAuthClientRecord{}, err
+	}
+	if err := json.Unmarshal([]byte(scopes), &record.Scopes); err != nil {
+		return storage.OAuthClientRecord{}, err
+	}
+	if err := json.Unmarshal([]byte(redirectURIs), &record.RedirectURIs); err != nil {
+		return storage.OAuthClientRecord{}, err
+	}
+	return record, nil
+}
+
+// marshalStringList stores nil as an empty JSON array so reads never see
+// null.
+func marshalStringList(values []string) (string, error) {
+	if values == nil {
+		values = []string{}
+	}
+	raw, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+	return string(raw), nil
+}
@@ -0,0 +1,111 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/porthorian/openauth/pkg/session"
+)
+
+const (
+	revokeTokenQuery = `
+INSERT INTO token_revocation (
+  token_id, expires_at, date_added
+) VALUES (?, ?, ?)
+ON CONFLICT (token_id) DO UPDATE
+SET
+  expires_at = MAX(token_revocation.expires_at, excluded.expires_at)
+`
+
+	isTokenRevokedQuery = `
+SELECT EXISTS (
+  SELECT 1
+  FROM token_revocation
+  WHERE token_id = ? AND expires_at > ?
+)
+`
+
+	deleteExpiredRevocationsQuery = `
+DELETE FROM token_revocation
+WHERE expires_at <= ?
+`
+)
+
+var (
+	ErrMissingTokenID        = errors.New("sqlite adapter: token id is required")
+	ErrMissingTokenExpiresAt = errors.New("sqlite adapter: token expiry is required")
+)
+
+var _ session.RevocationStore = (*Adapter)(nil)
+
+// RevokeToken records tokenID as revoked until expiresAt. Rows past their
+// expiry are pruned on every write so the table tracks live tokens only.
+// Expiry comparisons rely on the fixed-width timeLayout ordering lexically.
+func (a *Adapter) RevokeToken(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	if err := a.requirePreparedStatements(); err != nil {
+		return err
+	}
+
+	tokenID = strings.TrimSpace(tokenID)
+	if tokenID == "" {
+		return ErrMissingTokenID
+	}
+	if expiresAt.IsZero() {
+		return ErrMissingTokenExpiresAt
+	}
+
+	now := time.Now().UTC()
+	if !expiresAt.After(now) {
+		return nil
+	}
+
+	revokeStmt, releaseRevoke := a.bind(ctx, a.stmts.revokeToken)
+	defer releaseRevoke()
+	if _, err := revokeStmt.ExecContext(ctx, tokenID, formatTime(expiresAt), formatTime(now)); err != nil {
+		return err
+	}
+
+	pruneStmt, releasePrune := a.bind(ctx, a.stmts.deleteExpiredRevocations)
+	defer releasePrune()
+	_, err := pruneStmt.ExecContext(ctx, formatTime(now))
+	return err
+}
+
+func (a *Adapter) IsTokenRevoked(ctx context.Context, tokenID string) (bool, error) {
+	if err := a.requirePreparedStatements(); err != nil {
+		return false, err
+	}
+
+	tokenID = strings.TrimSpace(tokenID)
+	if tokenID == "" {
+		return false, nil
+	}
+
+	stmt, release := a.bind(ctx, a.stmts.isTokenRevoked)
+	defer release()
+
+	var revoked bool
+	if err := stmt.QueryRowContext(ctx, tokenID, formatTime(time.Now())).Scan(&revoked); err != nil {
+		return false, err
+	}
+	return revoked, nil
+}
+
+// DeleteExpiredRevocations removes revocations whose token expired at or
+// before the given time and reports how many were removed.
+func (a *Adapter) DeleteExpiredRevocations(ctx context.Context, before time.Time) (int64, error) {
+	if err := a.requirePreparedStatements(); err != nil {
+		return 0, err
+	}
+
+	stmt, release := a.bind(ctx, a.stmts.deleteExpiredRevocations)
+	defer release()
+
+	result, err := stmt.ExecContext(ctx, formatTime(before))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,166 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/porthorian/openauth/pkg/storage"
+)
+
+const (
+	putSessionQuery = `
+INSERT INTO session (
+  id, auth_id, subject, tenant, date_added, expires_at, metadata
+) VALUES (?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT (id) DO UPDATE
+SET
+  auth_id = excluded.auth_id,
+  subject = excluded.subject,
+  tenant = excluded.tenant,
+  expires_at = excluded.expires_at,
+  metadata = excluded.metadata
+`
+
+	getSessionQuery = `
+SELECT
+  id, auth_id, subject, tenant, expires_at, metadata
+FROM session
+WHERE id = ?
+`
+
+	deleteSessionQuery = `DELETE FROM session WHERE id = ?`
+
+	deleteSessionsByAuthIDQuery = `DELETE FROM session WHERE auth_id = ?`
+
+	deleteExpiredSessionsQuery = `
+DELETE FROM session
+WHERE expires_at <= ?
+`
+)
+
+var ErrInvalidSession = errors.New("sqlite adapter: session id, subject and expiry are required")
+
+var _ storage.SessionStore = (*Adapter)(nil)
+
+func (a *Adapter) PutSession(ctx context.Context, record storage.SessionRecord) error {
+	if err := a.requirePreparedStatements(); err != nil {
+		return err
+	}
+
+	record.ID = strings.TrimSpace(record.ID)
+	record.Subject = strings.TrimSpace(record.Subject)
+	if record.ID == "" || record.Subject == "" || record.ExpiresAt.IsZero() {
+		return ErrInvalidSession
+	}
+
+	metadata := cloneStringMap(record.Metadata)
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+	metadataRaw, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+
+	var authID any
+	if trimmed := strings.TrimSpace(record.AuthID); trimmed != "" {
+		authID = trimmed
+	}
+
+	stmt, release := a.bind(ctx, a.stmts.putSession)
+	defer release()
+
+	_, err = stmt.ExecContext(
+		ctx,
+		record.ID,
+		authID,
+		record.Subject,
+		strings.TrimSpace(record.Tenant),
+		formatTime(time.Now()),
+		formatTime(record.ExpiresAt),
+		string(metadataRaw),
+	)
+	return err
+}
+
+// GetSession returns storage.ErrNotFound when the session does not exist. Expired
+// sessions are returned as stored; callers compare ExpiresAt.
+func (a *Adapter) GetSession(ctx context.Context, id string) (storage.SessionRecord, error) {
+	if err := a.requirePreparedStatements(); err != nil {
+		return storage.SessionRecord{}, err
+	}
+
+	stmt, release := a.bind(ctx, a.stmts.getSession)
+	defer release()
+
+	record, err := scanSession(stmt.QueryRowContext(ctx, strings.TrimSpace(id)))
+	return record, notFound(err)
+}
+
+func (a *Adapter) DeleteSession(ctx context.Context, id string) error {
+	if err := a.requirePreparedStatements(); err != nil {
+		return err
+	}
+
+	stmt, release := a.bind(ctx, a.stmts.deleteSession)
+	defer release()
+
+	_, err := stmt.ExecContext(ctx, strings.TrimSpace(id))
+	return err
+}
+
+// DeleteExpiredSessions removes sessions that expired at or before the given
+// time and reports how many were removed.
+func (a *Adapter) DeleteExpiredSessions(ctx context.Context, before time.Time) (int64, error) {
+	if err := a.requirePreparedStatements(); err != nil {
+		return 0, err
+	}
+
+	stmt, release := a.bind(ctx, a.stmts.deleteExpiredSessions)
+	defer release()
+
+	result, err := stmt.ExecContext(ctx, formatTime(before))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func scanSession(s scanner) (storage.SessionRecord, error) {
+	var (
+		record      storage.SessionRecord
+		authID      sql.NullString
+		expiresAt   string
+		metadataRaw sql.NullString
+	)
+
+	if err := s.Scan(
+		&record.ID,
+		&authID,
+		&record.Subject,
+		&record.Tenant,
+		&expiresAt,
+		&metadataRaw,
+	); err != nil {
+		return storage.SessionRecord{}, err
+	}
+
+	var err error
+	record.AuthID = authID.String
+	if record.ExpiresAt, err = parseTime(expiresAt); err != nil {
+		return storage.SessionRecord{}, err
+	}
+
+	record.Metadata = map[string]string{}
+	if !metadataRaw.Valid || metadataRaw.String == "" {
+		return record, nil
+	}
+	if err := json.Unmarshal([]byte(metadataRaw.String), &record.Metadata); err != nil {
+		return storage.SessionRecord{}, err
+	}
+	return record, nil
+}
@@ -0,0 +1,142 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/porthorian/openauth/pkg/storage"
+)
+
+const (
+	putSubjectAuthQuery = `
+INSERT INTO subject_auth (
+  id, auth_id, subject, date_added, date_modified
+) VALUES (?, ?, ?, ?, ?)
+ON CONFLICT (auth_id) DO UPDATE
+SET
+  subject = excluded.subject,
+  date_modified = excluded.date_modified
+`
+
+	listSubjectAuthBySubjectQuery = `
+SELECT
+  id, date_added, date_modified, auth_id, subject
+FROM subject_auth
+WHERE subject = ?
+`
+
+	listSubjectAuthByAuthIDQuery = `
+SELECT
+  id, date_added, date_modified, auth_id, subject
+FROM subject_auth
+WHERE auth_id = ?
+`
+
+	deleteSubjectAuthQuery = `DELETE FROM subject_auth WHERE id = ?`
+
+	deleteSubjectAuthByAuthIDQuery = `DELETE FROM subject_auth WHERE auth_id = ?`
+)
+
+func (a *Adapter) PutSubjectAuth(ctx context.Context, record storage.SubjectAuthRecord) error {
+	if err := a.requirePreparedStatements(); err != nil {
+		return err
+	}
+
+	dateAdded := record.DateAdded
+	if dateAdded.IsZero() {
+		dateAdded = time.Now().UTC()
+	}
+
+	dateModified := time.Now().UTC()
+	if record.DateModified != nil {
+		dateModified = record.DateModified.UTC()
+	}
+
+	stmt, release := a.bind(ctx, a.stmts.putSubjectAuth)
+	defer release()
+
+	_, err := stmt.ExecContext(
+		ctx,
+		record.ID,
+		record.AuthID,
+		record.Subject,
+		formatTime(dateAdded),
+		formatTime(dateModified),
+	)
+	return err
+}
+
+func (a *Adapter) ListSubjectAuthBySubject(ctx context.Context, subject string) ([]storage.SubjectAuthRecord, error) {
+	if err := a.requirePreparedStatements(); err != nil {
+		return nil, err
+	}
+
+	return a.listSubjectAuth(ctx, a.stmts.listSubjectAuthBySubject, subject)
+}
+
+func (a *Adapter) ListSubjectAuthByAuthID(ctx context.Context, authID string) ([]storage.SubjectAuthRecord, error) {
+	if err := a.requirePreparedStatements(); err != nil {
+		return nil, err
+	}
+
+	return a.listSubjectAuth(ctx, a.stmts.listSubjectAuthByAuthID, authID)
+}
+
+func (a *Adapter) DeleteSubjectAuth(ctx context.Context, id string) error {
+	if err := a.requirePreparedStatements(); err != nil {
+		return err
+	}
+
+	stmt, release := a.bind(ctx, a.stmts.deleteSubjectAuthByID)
+	defer release()
+
+	_, err := stmt.ExecContext(ctx, id)
+	return err
+}
+
+func (a *Adapter) listSubjectAuth(ctx context.Context, prepared *sql.Stmt, arg string) ([]storage.SubjectAuthRecord, error) {
+	stmt, release := a.bind(ctx, prepared)
+	defer release()
+
+	rows, err := stmt.QueryContext(ctx, arg)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := []storage.SubjectAuthRecord{}
+	for rows.Next() {
+		record, scanErr := scanSubjectAuth(rows)
+		if scanErr != nil {
+			return nil, scanErr
+		}
+		records = append(records, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+func scanSubjectAuth(s scanner) (storage.SubjectAuthRecord, error) {
+	var (
+		record       storage.SubjectAuthRecord
+		dateAdded    string
+		dateModified sql.NullString
+	)
+
+	if err := s.Scan(&record.ID, &dateAdded, &dateModified, &record.AuthID, &record.Subject); err != nil {
+		return storage.SubjectAuthRecord{}, err
+	}
+
+	var err error
+	if record.DateAdded, err = parseTime(dateAdded); err != nil {
+		return storage.SubjectAuthRecord{}, err
+	}
+	if record.DateModified, err = parseNullableTime(dateModified); err != nil {
+		return storage.SubjectAuthRecord{}, err
+	}
+	return record, nil
+}
@@ -13,6 +13,12 @@ type TokenValidator interface {
 	Validate(ctx context.Context, token string) (openauth.Principal, error)
 }
 
+// APIKeyAuthenticator resolves the principal an API key was issued to.
+// *openauth.Client implements it.
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(ctx context.Context, key string) (openauth.Principal, error)
+}
+
 type PermissionChecker interface {
 	HasAllPermissions(principal openauth.Principal, permissionKeys ...string) (bool, error)
 	HasAnyPermissions(principal openauth.Principal, permissionKeys ...string) (bool, error)
@@ -35,8 +41,13 @@ type MiddlewareConfig struct {
 	CookieName         string
 	FailureStatusCode  int
 	InternalStatusCode int
-	ErrorWriter        ErrorWriter
-	Skipper            func(r *http.Request) bool
+	// APIKeys authenticates requests that carry APIKeyHeader, which then
+	// take precedence over any token. Nil disables API keys.
+	APIKeys APIKeyAuthenticator
+	// APIKeyHeader names the header holding the API key. Empty uses X-API-Key.
+	APIKeyHeader string
+	ErrorWriter  ErrorWriter
+	Skipper      func(r *http.Request) bool
 }
 
 var (
@@ -51,6 +62,7 @@ func DefaultConfig() MiddlewareConfig {
 		CookieName:         "",
 		FailureStatusCode:  http.StatusUnauthorized,
 		InternalStatusCode: http.StatusInternalServerError,
+		APIKeyHeader:       "X-API-Key",
 	}
 }
 
@@ -68,6 +80,10 @@ func Middleware(validator TokenValidator, config MiddlewareConfig) func(http.Han
 	if config.InternalStatusCode > 0 {
 		cfg.InternalStatusCode = config.InternalStatusCode
 	}
+	if strings.TrimSpace(config.APIKeyHeader) != "" {
+		cfg.APIKeyHeader = strings.TrimSpace(config.APIKeyHeader)
+	}
+	cfg.APIKeys = config.APIKeys
 	if config.ErrorWriter != nil {
 		cfg.ErrorWriter = config.ErrorWriter
 	} else {
@@ -82,20 +98,9 @@ func Middleware(validator TokenValidator, config MiddlewareConfig) func(http.Han
 				return
 			}
 
-			if validator == nil {
-				cfg.ErrorWriter(w, r, cfg.InternalStatusCode, ErrNilValidator)
-				return
-			}
-
-			token, err := extractToken(r, cfg)
+			principal, statusCode, err := authenticate(r, validator, cfg)
 			if err != nil {
-				cfg.ErrorWriter(w, r, cfg.FailureStatusCode, err)
-				return
-			}
-
-			principal, err := validator.Validate(r.Context(), token)
-			if err != nil {
-				cfg.ErrorWriter(w, r, cfg.FailureStatusCode, err)
+				cfg.ErrorWriter(w, r, statusCode, err)
 				return
 			}
 
@@ -167,6 +172,38 @@ func extractToken(r *http.Request, config MiddlewareConfig) (string, error) {
 	return "", ErrMissingToken
 }
 
+// authenticate prefers an API key when API keys are configured and the
+// request carries one, and falls back to the token otherwise. The status
+// code is only meaningful with an error.
+func authenticate(r *http.Request, validator TokenValidator, cfg MiddlewareConfig) (openauth.Principal, int, error) {
+	if cfg.APIKeys != nil {
+		if key := strings.TrimSpace(r.Header.Get(cfg.APIKeyHeader)); key != "" {
+			principal, err := cfg.APIKeys.AuthenticateAPIKey(r.Context(), key)
+			if err != nil {
+				return openauth.Principal{}, cfg.FailureStatusCode, err
+			}
+			return principal, 0, nil
+		}
+	}
+
+	if validator == nil {
+		if cfg.APIKeys != nil {
+			return openauth.Principal{}, cfg.FailureStatusCode, ErrMissingToken
+		}
+		return openauth.Principal{}, cfg.InternalStatusCode, ErrNilValidator
+	}
+
+	token, err := extractToken(r, cfg)
+	if err != nil {
+		return openauth.Principal{}, cfg.FailureStatusCode, err
+	}
+	principal, err := validator.Validate(r.Context(), token)
+	if err != nil {
+		return openauth.Principal{}, cfg.FailureStatusCode, err
+	}
+	return principal, 0, nil
+}
+
 func parseBearerToken(value string) (string, bool) {
 	parts := strings.Fields(strings.TrimSpace(value))
 	if len(parts) != 2 {
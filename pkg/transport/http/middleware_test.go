@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/porthorian/openauth"
+	oerrors "github.com/porthorian/openauth/pkg/errors"
 )
 
 type staticValidator struct {
@@ -17,6 +18,21 @@ type staticValidator struct {
 	token     string
 }
 
+type staticAPIKeys struct {
+	principal openauth.Principal
+	err       error
+	key       string
+}
+
+func (a *staticAPIKeys) AuthenticateAPIKey(ctx context.Context, key string) (openauth.Principal, error) {
+	_ = ctx
+	a.key = key
+	if a.err != nil {
+		return openauth.Principal{}, a.err
+	}
+	return a.principal, nil
+}
+
 type staticPermissionChecker struct {
 	allPermissionAllowed bool
 	anyPermissionAllowed bool
@@ -208,6 +224,49 @@ func TestMiddlewareUsesCustomHeaderRawToken(t *testing.T) {
 	}
 }
 
+func TestMiddlewareAuthenticatesAPIKeyHeader(t *testing.T) {
+	validator := &staticValidator{principal: openauth.Principal{Subject: "user-1"}}
+	apiKeys := &staticAPIKeys{principal: openauth.Principal{Subject: "service-1"}}
+	cfg := DefaultConfig()
+	cfg.APIKeys = apiKeys
+
+	var subject string
+	handler := Middleware(validator, cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, _ := PrincipalFromContext(r.Context())
+		subject = principal.Subject
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-API-Key", "oak_lookup_secret")
+	req.Header.Set("Authorization", "Bearer token-123")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK || subject != "service-1" || apiKeys.key != "oak_lookup_secret" {
+		t.Fatalf("unexpected api key result: code=%d subject=%q key=%q", rr.Code, subject, apiKeys.key)
+	}
+	if validator.called {
+		t.Fatalf("did not expect token validator to be called when an api key is present")
+	}
+
+	apiKeys.err = oerrors.New(oerrors.CodeUnauthenticated, "failed to authenticate api key")
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusUnauthorized {
+		t.Fatalf("unexpected status code for rejected api key: %d", rr.Code)
+	}
+
+	keyOnly := Middleware(nil, MiddlewareConfig{APIKeys: apiKeys, APIKeyHeader: "X-Service-Key"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatalf("next handler should not be called")
+	}))
+	rr = httptest.NewRecorder()
+	keyOnly.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rr.Code != http.StatusUnauthorized {
+		t.Fatalf("expected missing api key to be unauthorized, got %d", rr.Code)
+	}
+}
+
 func TestMiddlewareSkipper(t *testing.T) {
 	validator := &staticValidator{principal: openauth.Principal{Subject: "user-1"}, err: errors.New("should not be called")}
 	cfg := DefaultConfig()
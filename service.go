@@ -4,34 +4,53 @@ import (
 	"context"
 	"errors"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/google/uuid"
+	"github.com/porthorian/openauth/pkg/apikey"
 	"github.com/porthorian/openauth/pkg/approach"
 	"github.com/porthorian/openauth/pkg/authz"
 	ocache "github.com/porthorian/openauth/pkg/cache"
 	ocrypto "github.com/porthorian/openauth/pkg/crypto"
 	oerrors "github.com/porthorian/openauth/pkg/errors"
+	"github.com/porthorian/openauth/pkg/password"
 	"github.com/porthorian/openauth/pkg/storage"
 )
 
+const authMetadataProfile = "auth_profile"
+
 type AuthService struct {
 	authStore            storage.AuthMaterial
 	authdStore           storage.AuthdMaterial
+	oauthClients         storage.OAuthClientStore
 	cacheStore           ocache.Dependencies
 	logger               logr.Logger
 	hasher               ocrypto.Hasher
 	policyMatrix         storage.PersistencePolicyMatrix
 	defaultPolicy        storage.AuthProfile
+	tokenProfile         storage.AuthProfile
 	authzRegistry        *authz.Registry
 	defaultTenant        string
 	approachRegistry     *approach.Registry
 	defaultTokenApproach string
+	lockout              LockoutPolicy
+	mfa                  MFAConfig
+	webAuthn             WebAuthnConfig
+	apiKeys              APIKeyConfig
+	passwordPolicy       password.Policy
+	passwordHistory      int
+
+	timingHashOnce sync.Once
+	timingHash     string
 }
 
 type createAuthWrite struct {
+	authID       string // authID is generated when empty.
 	userID       string
+	status       storage.AuthStatus       // status defaults to StatusActive.
+	materialType storage.AuthMaterialType // materialType defaults to AuthMaterialTypePassword.
 	materialHash string
 	expiresAt    *time.Time
 	metadata     map[string]string
@@ -40,6 +59,7 @@ type createAuthWrite struct {
 var _ Authenticator = (*AuthService)(nil)
 var _ AuthorizationManager = (*AuthService)(nil)
 var _ AuthorizationChecker = (*AuthService)(nil)
+var _ LockoutManager = (*AuthService)(nil)
 
 func NewAuthService(config Config) (*AuthService, error) {
 	logger := resolveLogger(config.Logger)
@@ -57,18 +77,42 @@ func NewAuthService(config Config) (*AuthService, error) {
 		defaultTenant = "default"
 	}
 
+	policyMatrix := config.PolicyMatrix
+	if policyMatrix == nil {
+		policyMatrix = storage.DefaultPersistencePolicyMatrix()
+	}
+
+	apiKeys := config.APIKeys.normalize()
+	if err := apikey.ValidatePrefix(apiKeys.Prefix); err != nil {
+		return nil, oerrors.Wrap(oerrors.CodeUnknown, "invalid api key config", err)
+	}
+
+	defaultTokenApproach := strings.TrimSpace(config.DefaultTokenApproach)
+	tokenProfile := config.TokenProfile
+	if tokenProfile == "" {
+		tokenProfile = tokenProfileForApproach(defaultTokenApproach, config.DefaultPolicy)
+	}
+
 	return &AuthService{
 		authStore:            config.AuthStore,
 		authdStore:           config.AuthdStore,
+		oauthClients:         config.OAuthClientStore,
 		cacheStore:           config.CacheStore,
 		logger:               logger,
 		hasher:               config.Hasher,
-		policyMatrix:         config.PolicyMatrix,
+		policyMatrix:         policyMatrix,
 		defaultPolicy:        config.DefaultPolicy,
+		tokenProfile:         tokenProfile,
 		authzRegistry:        compiledRegistry,
 		defaultTenant:        defaultTenant,
 		approachRegistry:     config.ApproachRegistry,
-		defaultTokenApproach: strings.TrimSpace(config.DefaultTokenApproach),
+		defaultTokenApproach: defaultTokenApproach,
+		lockout:              config.Lockout.normalize(),
+		mfa:                  config.MFA.normalize(),
+		webAuthn:             config.WebAuthn.normalize(),
+		apiKeys:              apiKeys,
+		passwordPolicy:       config.PasswordPolicy,
+		passwordHistory:      max(config.PasswordHistory, 0),
 	}, nil
 }
 
@@ -86,11 +130,21 @@ func (s *AuthService) Authorize(ctx context.Context, input AuthInput) (Principal
 		return Principal{}, oerrors.New(oerrors.CodeUnknown, "authorization registry is not configured")
 	}
 
+	source := strings.TrimSpace(input.Source)
+	if input.Type == InputTypeAPIKey {
+		return s.authorizeAPIKey(ctx, input.Value, strings.TrimSpace(input.UserID), source)
+	}
+	if err := s.checkLockout(ctx, input.UserID, source); err != nil {
+		return Principal{}, err
+	}
+
 	subjects, err := s.authStore.SubjectAuth.ListSubjectAuthBySubject(ctx, input.UserID)
 	if err != nil {
 		return Principal{}, oerrors.Wrap(oerrors.CodeStorageUnavailable, "failed to lookup subject auth records", err)
 	}
 	if len(subjects) < 1 {
+		s.equalizeVerifyTiming(input.Value)
+		s.recordAuthFailure(ctx, input.UserID, source)
 		return Principal{}, oerrors.New(oerrors.CodeNotFound, "user_id not found")
 	}
 
@@ -111,6 +165,12 @@ func (s *AuthService) Authorize(ctx context.Context, input AuthInput) (Principal
 	if materialType == "" {
 		return Principal{}, oerrors.New(oerrors.CodeInvalidCredentials, "unsupported auth input type")
 	}
+	if materialType == storage.AuthMaterialTypeTOTP || materialType == storage.AuthMaterialTypeRecoveryCode {
+		return s.authorizeSecondFactor(ctx, input, source, records, materialType)
+	}
+	if materialType == storage.AuthMaterialTypeWebAuthn {
+		return s.authorizeWebAuthn(ctx, input, source, records)
+	}
 
 	var selectedRecord *storage.AuthRecord
 	for _, record := range records {
@@ -123,42 +183,71 @@ func (s *AuthService) Authorize(ctx context.Context, input AuthInput) (Principal
 		}
 	}
 	if selectedRecord == nil {
+		s.equalizeVerifyTiming(input.Value)
+		s.recordAuthFailure(ctx, input.UserID, source)
 		return Principal{}, oerrors.New(oerrors.CodeInvalidCredentials, "no valid input auth record found for user_id")
 	}
 
-	if selectedRecord.ExpiresAt != nil && selectedRecord.ExpiresAt.Before(time.Now().UTC()) {
+	// A stored hash that cannot be verified counts as a failed attempt, so a
+	// corrupt record cannot be probed without tripping lockout.
+	ok, verifyErr := s.verifyInputMaterial(materialType, input.Value, selectedRecord.MaterialHash)
+	if verifyErr != nil || !ok {
+		s.logAuthEventWith(ctx, s.authStore, selectedRecord.ID, input.UserID, storage.AuthLogEventFailed, sourceMetadata(source))
+		s.recordAuthFailure(ctx, input.UserID, source)
+		if verifyErr != nil {
+			return Principal{}, verifyErr
+		}
+		return Principal{}, oerrors.New(oerrors.CodeInvalidCredentials, "authentication failed")
+	}
+
+	// Expiry is only reported once the value matched, so it reveals nothing
+	// to a caller who does not know the credential.
+	if now := time.Now().UTC(); selectedRecord.ExpiresAt != nil && selectedRecord.ExpiresAt.Before(now) {
 		selectedRecord.Status = storage.StatusExpired
+		selectedRecord.DateModified = &now
 		if err := s.authStore.Auth.PutAuth(ctx, *selectedRecord); err != nil {
 			s.logger.Error(err, "failed to persist expired auth status", "auth_id", selectedRecord.ID, "subject", input.UserID)
 		}
+		s.logAuthEventWith(ctx, s.authStore, selectedRecord.ID, input.UserID, storage.AuthLogEventExpired, sourceMetadata(source))
 		return Principal{}, oerrors.New(oerrors.CodeCredentialsExpired, "credentials have expired")
 	}
 
-	ok, verifyErr := s.verifyInputMaterial(materialType, input.Value, selectedRecord.MaterialHash)
-	if verifyErr != nil {
-		return Principal{}, verifyErr
-	}
+	authenticatedAt := time.Now().UTC()
+	s.rehashIfOutdated(ctx, *selectedRecord, input.UserID, input.Value)
 
-	if !ok {
-		s.logAuthEvent(ctx, selectedRecord.ID, input.UserID, storage.AuthLogEventFailed)
-		return Principal{}, oerrors.New(oerrors.CodeInvalidCredentials, "authentication failed")
+	challenge, required, err := s.issueMFAChallenge(ctx, input.UserID, input.Tenant, records)
+	if err != nil {
+		return Principal{}, err
+	}
+	if required {
+		// Only a completed second step ends a failure streak, so the password
+		// is logged as validated rather than used.
+		s.logAuthEventWith(ctx, s.authStore, selectedRecord.ID, input.UserID, storage.AuthLogEventValidated, sourceMetadata(source))
+		return Principal{}, mfaRequired(challenge)
 	}
 
-	authenticatedAt := time.Now().UTC()
-	s.logAuthEvent(ctx, selectedRecord.ID, input.UserID, storage.AuthLogEventUsed)
+	s.logAuthEventWith(ctx, s.authStore, selectedRecord.ID, input.UserID, storage.AuthLogEventUsed, sourceMetadata(source))
+	s.resetAuthFailures(ctx, input.UserID, source)
+	return s.loginPrincipal(ctx, input.UserID, input.Tenant, authenticatedAt)
+}
 
-	tenant := s.resolveTenant(input.Tenant)
-	roleMask, permissionMask, err := s.resolveAuthorization(ctx, input.UserID, tenant)
+// loginPrincipal resolves the principal for a completed interactive login.
+func (s *AuthService) loginPrincipal(ctx context.Context, subject string, rawTenant string, authenticatedAt time.Time) (Principal, error) {
+	tenant := s.resolveTenant(rawTenant)
+	policy, _ := s.policyFor(storage.AuthProfilePasswordBasic)
+	authzPolicy := newAuthorizationPolicy(storage.AuthProfilePasswordBasic, policy, time.Time{}, authenticatedAt)
+	roleMask, permissionMask, degraded, err := s.resolveAuthorizationWithPolicy(ctx, subject, tenant, authzPolicy)
 	if err != nil {
 		return Principal{}, err
 	}
 
 	return Principal{
-		Subject:         input.UserID,
+		Subject:         subject,
 		Tenant:          tenant,
 		RoleMask:        roleMask,
 		PermissionMask:  permissionMask,
 		AuthenticatedAt: authenticatedAt,
+		Degraded:        degraded,
 	}, nil
 }
 
@@ -174,6 +263,13 @@ func (s *AuthService) CreateAuth(ctx context.Context, input CreateAuthInput) err
 	if err := input.Validate(); err != nil {
 		return err
 	}
+	expiresAt, err := s.passwordExpiry(input.Profile, input.ExpiresAt, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	if err := s.checkPasswordPolicy(input.Value, input.UserID); err != nil {
+		return err
+	}
 
 	auths, err := s.authStore.SubjectAuth.ListSubjectAuthBySubject(ctx, input.UserID)
 	if err != nil {
@@ -193,21 +289,8 @@ func (s *AuthService) CreateAuth(ctx context.Context, input CreateAuthInput) err
 		if err != nil {
 			return oerrors.Wrap(oerrors.CodeStorageUnavailable, "failed to retrieve existing auth records for subject", err)
 		}
-		for _, record := range records {
-			if record.Status != storage.StatusActive {
-				continue
-			}
-			if record.MaterialType != InputTypePassword.GetMaterialType() {
-				continue
-			}
-
-			match, verifyErr := s.hasher.Verify(input.Value, record.MaterialHash)
-			if verifyErr != nil {
-				return oerrors.Wrap(oerrors.CodeUnknown, "unable to verify credentials against existing auth record", verifyErr)
-			}
-			if match {
-				return oerrors.New(oerrors.CodeInvalidCredentials, "auth with the same value already exists for user_id")
-			}
+		if err := s.checkPasswordReuse(records, input.Value); err != nil {
+			return err
 		}
 	}
 
@@ -219,28 +302,56 @@ func (s *AuthService) CreateAuth(ctx context.Context, input CreateAuthInput) err
 	write := createAuthWrite{
 		userID:       input.UserID,
 		materialHash: materialHash,
-		expiresAt:    input.ExpiresAt,
-		metadata:     input.Metadata,
+		expiresAt:    expiresAt,
+		metadata:     withAuthProfile(input.Metadata, input.Profile),
 	}
 
-	writeAuth := func(stores storage.AuthMaterial, transactional bool) error {
+	return s.withAuthMaterial(ctx, "create auth", func(stores storage.AuthMaterial, transactional bool) error {
 		request := write
 		return s.createAuthWithStores(ctx, stores, transactional, request)
+	})
+}
+
+// passwordExpiry checks that profile persists password material in the
+// source of truth and returns the expiry its policy allows for expiresAt.
+func (s *AuthService) passwordExpiry(profile storage.AuthProfile, expiresAt *time.Time, now time.Time) (*time.Time, error) {
+	policy, ok := s.policyFor(profile)
+	if !ok {
+		return nil, oerrors.New(oerrors.CodeInvalidCredentials, "no persistence policy for auth profile: "+string(profile))
+	}
+	if !policy.PersistInSourceOfTruth {
+		return nil, oerrors.New(oerrors.CodeInvalidCredentials, "auth profile is not persisted in the source of truth: "+string(profile))
+	}
+	if policy.MaterialType != storage.AuthMaterialTypePassword {
+		return nil, oerrors.New(oerrors.CodeInvalidCredentials, "auth profile does not hold password material: "+string(profile))
+	}
+	if expiresAt != nil || policy.AllowNonExpiring {
+		return expiresAt, nil
+	}
+	if policy.DefaultTTL <= 0 {
+		return nil, oerrors.New(oerrors.CodeInvalidCredentials, "expires_at is required for auth profile: "+string(profile))
 	}
+	defaulted := now.Add(policy.DefaultTTL)
+	return &defaulted, nil
+}
 
-	if txRunner, ok := s.authStore.Auth.(storage.AuthMaterialTransactor); ok {
-		if err := txRunner.WithAuthMaterialTx(ctx, func(stores storage.AuthMaterial) error {
-			return writeAuth(stores, true)
-		}); err != nil {
-			if oerrors.IsCode(err, oerrors.CodeStorageUnavailable) || oerrors.IsCode(err, oerrors.CodeInvalidCredentials) || oerrors.IsCode(err, oerrors.CodeUnknown) {
-				return err
-			}
-			return oerrors.Wrap(oerrors.CodeStorageUnavailable, "failed to run create auth transaction", err)
-		}
-		return nil
+// withAuthProfile records profile on a copy of metadata so rotations keep
+// the policy the auth was created under.
+func withAuthProfile(metadata map[string]string, profile storage.AuthProfile) map[string]string {
+	withProfile := make(map[string]string, len(metadata)+1)
+	for key, value := range metadata {
+		withProfile[key] = value
 	}
+	withProfile[authMetadataProfile] = string(profile)
+	return withProfile
+}
 
-	return writeAuth(s.authStore, false)
+// recordAuthProfile is the profile record was created under.
+func recordAuthProfile(record storage.AuthRecord) storage.AuthProfile {
+	if profile := record.Metadata[authMetadataProfile]; profile != "" {
+		return storage.AuthProfile(profile)
+	}
+	return storage.AuthProfilePasswordBasic
 }
 
 func (s *AuthService) ValidateToken(ctx context.Context, token string) (Principal, error) {
@@ -254,6 +365,12 @@ func (s *AuthService) ValidateToken(ctx context.Context, token string) (Principa
 		return Principal{}, oerrors.New(oerrors.CodeStorageUnavailable, "authorization storage is not configured")
 	}
 
+	now := time.Now().UTC()
+	policy, _ := s.policyFor(s.tokenProfile)
+	if principal, ok := s.cachedTokenPrincipal(ctx, policy, token, now); ok {
+		return principal, nil
+	}
+
 	result, err := s.approachRegistry.Validate(ctx, s.defaultTokenApproach, token)
 	if err != nil {
 		return Principal{}, oerrors.Wrap(oerrors.CodeInvalidToken, "token validation failed", err)
@@ -269,19 +386,25 @@ func (s *AuthService) ValidateToken(ctx context.Context, token string) (Principa
 		return Principal{}, oerrors.New(oerrors.CodeInvalidToken, "token tenant claim is required")
 	}
 
-	roleMask, permissionMask, err := s.resolveAuthorization(ctx, subject, tenant)
+	authzPolicy := newAuthorizationPolicy(s.tokenProfile, policy, result.ExpiresAt, now)
+	roleMask, permissionMask, degraded, err := s.resolveAuthorizationWithPolicy(ctx, subject, tenant, authzPolicy)
 	if err != nil {
 		return Principal{}, err
 	}
 
-	return Principal{
+	principal := Principal{
 		Subject:         subject,
 		Tenant:          tenant,
 		RoleMask:        roleMask,
 		PermissionMask:  permissionMask,
 		Claims:          cloneClaims(result.Claims),
-		AuthenticatedAt: time.Now().UTC(),
-	}, nil
+		AuthenticatedAt: now,
+		Degraded:        degraded,
+	}
+	if !degraded {
+		s.storeTokenPrincipal(ctx, authzPolicy, token, principal, result.ExpiresAt)
+	}
+	return principal, nil
 }
 
 func (s *AuthService) SetSubjectRoles(ctx context.Context, input SetSubjectRolesInput) error {
@@ -304,6 +427,7 @@ func (s *AuthService) SetSubjectRoles(ctx context.Context, input SetSubjectRoles
 	if err := s.authdStore.Role.ReplaceSubjectRoles(ctx, input.Subject, tenant, input.RoleKeys); err != nil {
 		return oerrors.Wrap(oerrors.CodeStorageUnavailable, "failed to replace subject roles", err)
 	}
+	s.invalidateAuthorization(ctx, input.Subject, tenant)
 	return nil
 }
 
@@ -355,6 +479,7 @@ func (s *AuthService) SetSubjectPermissionOverrides(ctx context.Context, input S
 	if err := s.authdStore.Permission.ReplaceSubjectPermissionOverrides(ctx, input.Subject, s.resolveTenant(input.Tenant), overrides); err != nil {
 		return oerrors.Wrap(oerrors.CodeStorageUnavailable, "failed to replace subject permission overrides", err)
 	}
+	s.invalidateAuthorization(ctx, input.Subject, s.resolveTenant(input.Tenant))
 	return nil
 }
 
@@ -447,7 +572,71 @@ func (s *AuthService) verifyInputMaterial(materialType storage.AuthMaterialType,
 	}
 }
 
-func (s *AuthService) resolveAuthorization(ctx context.Context, subject string, tenant string) (RoleMask, PermissionMask, error) {
+// equalizeVerifyTiming runs a verification that always fails against a hash
+// made by the configured hasher, so rejecting an unknown subject or missing
+// material costs as much as rejecting a wrong password.
+func (s *AuthService) equalizeVerifyTiming(value string) {
+	s.timingHashOnce.Do(func() {
+		encoded, err := s.hasher.Hash(uuid.NewString())
+		if err != nil {
+			s.logger.Error(err, "failed to prepare timing equalization hash")
+			return
+		}
+		s.timingHash = encoded
+	})
+	if s.timingHash == "" {
+		return
+	}
+	_, _ = s.hasher.Verify(value, s.timingHash)
+}
+
+// rehashIfOutdated replaces the stored hash of a just-verified secret when the
+// configured hasher reports it was produced by another algorithm or with
+// weaker parameters. The write is conditional on the record being unchanged
+// since it was read, so a concurrent revoke or rotation wins. Failures are
+// logged and never fail the login.
+func (s *AuthService) rehashIfOutdated(ctx context.Context, record storage.AuthRecord, subject string, value string) {
+	rehasher, ok := s.hasher.(ocrypto.Rehasher)
+	if !ok || !rehasher.NeedsRehash(record.MaterialHash) {
+		return
+	}
+
+	materialHash, err := s.hasher.Hash(value)
+	if err != nil {
+		s.logger.Error(err, "failed to rehash outdated auth material", "auth_id", record.ID, "subject", subject)
+		return
+	}
+
+	now := time.Now().UTC()
+	lastModified := record.DateModified
+	record.MaterialHash = materialHash
+	record.DateModified = &now
+	swapped, err := s.authStore.Auth.SwapAuth(ctx, record, lastModified)
+	if err != nil {
+		s.logger.Error(err, "failed to persist rehashed auth material", "auth_id", record.ID, "subject", subject)
+		return
+	}
+	if !swapped {
+		s.logger.Info("skipped rehash of auth material modified concurrently", "auth_id", record.ID, "subject", subject)
+		return
+	}
+	s.logger.Info("upgraded outdated auth material hash", "auth_id", record.ID, "subject", subject, "material_type", record.MaterialType)
+}
+
+func (s *AuthService) resolveAuthorizationWithPolicy(ctx context.Context, subject string, tenant string, authzPolicy authorizationPolicy) (RoleMask, PermissionMask, bool, error) {
+	roleMask, permissionMask, err := s.resolveAuthorization(ctx, subject, tenant, authzPolicy)
+	if err == nil {
+		return roleMask, permissionMask, false, nil
+	}
+
+	roleMask, permissionMask, ok := s.degradedAuthorization(ctx, authzPolicy, subject, tenant, err)
+	if !ok {
+		return RoleMask{}, PermissionMask{}, false, err
+	}
+	return roleMask, permissionMask, true, nil
+}
+
+func (s *AuthService) resolveAuthorization(ctx context.Context, subject string, tenant string, cachePolicy authorizationPolicy) (RoleMask, PermissionMask, error) {
 	if s.authzRegistry == nil {
 		return RoleMask{}, PermissionMask{}, oerrors.New(oerrors.CodeUnknown, "authorization registry is not configured")
 	}
@@ -455,6 +644,10 @@ func (s *AuthService) resolveAuthorization(ctx context.Context, subject string,
 		return RoleMask{}, PermissionMask{}, oerrors.New(oerrors.CodeStorageUnavailable, "authorization storage is not configured")
 	}
 
+	if roleMask, permissionMask, ok := s.cachedAuthorization(ctx, cachePolicy, subject, tenant); ok {
+		return roleMask, permissionMask, nil
+	}
+
 	roleRecords, err := s.authdStore.Role.ListSubjectRoles(ctx, subject, tenant)
 	if err != nil {
 		return RoleMask{}, PermissionMask{}, oerrors.Wrap(oerrors.CodeStorageUnavailable, "failed to list subject roles", err)
@@ -486,6 +679,7 @@ func (s *AuthService) resolveAuthorization(ctx context.Context, subject string,
 	if err != nil {
 		return RoleMask{}, PermissionMask{}, s.mapAuthzError(err)
 	}
+	s.storeAuthorization(ctx, cachePolicy, subject, tenant, roleMask, permissionMask)
 	return roleMask, permissionMask, nil
 }
 
@@ -538,18 +732,43 @@ func (s *AuthService) resolveTenant(rawTenant string) string {
 	return "default"
 }
 
+// withAuthMaterial runs fn inside an auth material transaction when the auth
+// store supports one, and directly against the configured stores otherwise.
+func (s *AuthService) withAuthMaterial(ctx context.Context, operation string, fn func(stores storage.AuthMaterial, transactional bool) error) error {
+	txRunner, ok := s.authStore.Auth.(storage.AuthMaterialTransactor)
+	if !ok {
+		return fn(s.authStore, false)
+	}
+
+	if err := txRunner.WithAuthMaterialTx(ctx, func(stores storage.AuthMaterial) error {
+		return fn(stores, true)
+	}); err != nil {
+		var typed *oerrors.Error
+		if errors.As(err, &typed) {
+			return err
+		}
+		return oerrors.Wrap(oerrors.CodeStorageUnavailable, "failed to run "+operation+" transaction", err)
+	}
+	return nil
+}
+
 func (s *AuthService) logAuthEvent(ctx context.Context, authID string, subject string, event storage.AuthLogEvent) {
-	if s.authStore.AuthLog == nil {
+	s.logAuthEventWith(ctx, s.authStore, authID, subject, event, nil)
+}
+
+func (s *AuthService) logAuthEventWith(ctx context.Context, stores storage.AuthMaterial, authID string, subject string, event storage.AuthLogEvent, metadata map[string]string) {
+	if stores.AuthLog == nil {
 		return
 	}
 	now := time.Now().UTC()
-	if err := s.authStore.AuthLog.PutAuthLog(ctx, storage.AuthLogRecord{
+	if err := stores.AuthLog.PutAuthLog(ctx, storage.AuthLogRecord{
 		ID:         uuid.NewString(),
 		DateAdded:  now,
 		AuthID:     authID,
 		Subject:    subject,
 		Event:      event,
 		OccurredAt: now,
+		Metadata:   metadata,
 	}); err != nil {
 		s.logger.Error(err, "failed to write auth log record", "auth_id", authID, "subject", subject, "event", event)
 	}
@@ -561,13 +780,24 @@ func (s *AuthService) createAuthWithStores(ctx context.Context, stores storage.A
 	}
 
 	now := time.Now().UTC()
-	authID := uuid.NewString()
+	authID := request.authID
+	if authID == "" {
+		authID = uuid.NewString()
+	}
+	status := request.status
+	if status == "" {
+		status = storage.StatusActive
+	}
+	materialType := request.materialType
+	if materialType == "" {
+		materialType = storage.AuthMaterialTypePassword
+	}
 
 	if err := stores.Auth.PutAuth(ctx, storage.AuthRecord{
 		ID:           authID,
-		Status:       storage.StatusActive,
+		Status:       status,
 		DateAdded:    now,
-		MaterialType: storage.AuthMaterialTypePassword,
+		MaterialType: materialType,
 		MaterialHash: request.materialHash,
 		ExpiresAt:    request.expiresAt,
 		Metadata:     request.metadata,
@@ -0,0 +1,184 @@
+package openauth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/porthorian/openauth/pkg/apikey"
+	ocrypto "github.com/porthorian/openauth/pkg/crypto"
+	oerrors "github.com/porthorian/openauth/pkg/errors"
+	"github.com/porthorian/openauth/pkg/storage"
+)
+
+const (
+	apiKeyMetadataName    = "api_key_name"
+	apiKeyMetadataDisplay = "api_key_display"
+	apiKeyMetadataTenant  = "tenant"
+)
+
+var _ APIKeyManager = (*AuthService)(nil)
+
+// APIKeyConfig shapes issued API keys.
+type APIKeyConfig struct {
+	// Prefix starts every issued key so scanners can spot leaked keys. It
+	// must be 1-16 lowercase letters or digits. Empty uses apikey.DefaultPrefix.
+	Prefix string
+}
+
+func (c APIKeyConfig) normalize() APIKeyConfig {
+	c.Prefix = strings.TrimSpace(c.Prefix)
+	if c.Prefix == "" {
+		c.Prefix = apikey.DefaultPrefix
+	}
+	return c
+}
+
+// CreateAPIKey issues a new API key for input.UserID. The returned Key is
+// only available here; storage keeps a SHA-256 digest of its secret, and the
+// auth ID embedded in the key finds the record without a scan.
+func (s *AuthService) CreateAPIKey(ctx context.Context, input CreateAPIKeyInput) (APIKey, error) {
+	if s == nil || s.authStore.Auth == nil || s.authStore.SubjectAuth == nil {
+		return APIKey{}, oerrors.New(oerrors.CodeStorageUnavailable, "auth storage is not configured")
+	}
+
+	input = input.Normalize()
+	if err := input.Validate(); err != nil {
+		return APIKey{}, err
+	}
+
+	authID := uuid.NewString()
+	key, err := apikey.Generate(s.apiKeys.Prefix, apiKeyLookup(authID))
+	if err != nil {
+		return APIKey{}, oerrors.Wrap(oerrors.CodeUnknown, "failed to generate api key", err)
+	}
+
+	metadata := make(map[string]string, len(input.Metadata)+3)
+	for k, v := range input.Metadata {
+		metadata[k] = v
+	}
+	metadata[apiKeyMetadataDisplay] = key.Display()
+	metadata[apiKeyMetadataTenant] = s.resolveTenant(input.Tenant)
+	if input.Name != "" {
+		metadata[apiKeyMetadataName] = input.Name
+	}
+
+	write := createAuthWrite{
+		authID:       authID,
+		userID:       input.UserID,
+		materialType: storage.AuthMaterialTypeAPIKey,
+		materialHash: ocrypto.DigestSecret(key.Secret),
+		expiresAt:    input.ExpiresAt,
+		metadata:     metadata,
+	}
+	if err := s.withAuthMaterial(ctx, "create api key", func(stores storage.AuthMaterial, transactional bool) error {
+		return s.createAuthWithStores(ctx, stores, transactional, write)
+	}); err != nil {
+		return APIKey{}, err
+	}
+
+	return APIKey{
+		Key:       key.String(),
+		AuthID:    authID,
+		Display:   key.Display(),
+		ExpiresAt: input.ExpiresAt,
+	}, nil
+}
+
+// AuthenticateAPIKey resolves the principal an API key was issued to, in
+// the tenant it was issued for.
+func (s *AuthService) AuthenticateAPIKey(ctx context.Context, key string) (Principal, error) {
+	if s == nil || s.authStore.Auth == nil || s.authStore.SubjectAuth == nil {
+		return Principal{}, oerrors.New(oerrors.CodeStorageUnavailable, "auth storage is not configured")
+	}
+	if s.authdStore.Role == nil || s.authdStore.Permission == nil {
+		return Principal{}, oerrors.New(oerrors.CodeStorageUnavailable, "authorization storage is not configured")
+	}
+
+	return s.authorizeAPIKey(ctx, key, "", "")
+}
+
+// authorizeAPIKey verifies value and, when subject is set, that the key
+// belongs to it. API keys carry enough entropy that guessing is not a
+// concern, so the secret is checked against a fast digest rather than the
+// password hasher, and failures are logged but do not count towards
+// lockout.
+func (s *AuthService) authorizeAPIKey(ctx context.Context, value string, subject string, source string) (Principal, error) {
+	key, err := apikey.Parse(value)
+	if err != nil || key.Prefix != s.apiKeys.Prefix {
+		return Principal{}, oerrors.New(oerrors.CodeInvalidCredentials, "malformed api key")
+	}
+	parsedID, err := uuid.Parse(key.Lookup)
+	if err != nil {
+		return Principal{}, oerrors.New(oerrors.CodeInvalidCredentials, "malformed api key")
+	}
+	authID := parsedID.String()
+
+	record, err := s.authStore.Auth.GetAuth(ctx, authID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return Principal{}, oerrors.New(oerrors.CodeInvalidCredentials, "api key not found")
+	}
+	if err != nil {
+		return Principal{}, oerrors.Wrap(oerrors.CodeStorageUnavailable, "failed to retrieve api key record", err)
+	}
+	if record.MaterialType != storage.AuthMaterialTypeAPIKey {
+		return Principal{}, oerrors.New(oerrors.CodeInvalidCredentials, "api key not found")
+	}
+
+	owner, err := s.authSubject(ctx, authID)
+	if oerrors.IsCode(err, oerrors.CodeNotFound) || (err == nil && subject != "" && owner != subject) {
+		return Principal{}, oerrors.New(oerrors.CodeInvalidCredentials, "api key not found")
+	}
+	if err != nil {
+		return Principal{}, err
+	}
+
+	if !ocrypto.VerifySecretDigest(key.Secret, record.MaterialHash) {
+		s.logAuthEventWith(ctx, s.authStore, record.ID, owner, storage.AuthLogEventFailed, sourceMetadata(source))
+		return Principal{}, oerrors.New(oerrors.CodeInvalidCredentials, "api key verification failed")
+	}
+
+	now := time.Now().UTC()
+	switch {
+	case record.Status != storage.StatusActive:
+		return Principal{}, oerrors.New(oerrors.CodeInvalidCredentials, "api key is no longer active")
+	case record.ExpiresAt != nil && !record.ExpiresAt.After(now):
+		record.Status = storage.StatusExpired
+		record.DateModified = &now
+		if err := s.authStore.Auth.PutAuth(ctx, record); err != nil {
+			s.logger.Error(err, "failed to persist expired auth status", "auth_id", record.ID, "subject", owner)
+		}
+		s.logAuthEvent(ctx, record.ID, owner, storage.AuthLogEventExpired)
+		return Principal{}, oerrors.New(oerrors.CodeCredentialsExpired, "api key has expired")
+	}
+
+	var expiresAt time.Time
+	if record.ExpiresAt != nil {
+		expiresAt = *record.ExpiresAt
+	}
+	tenant := s.resolveTenant(record.Metadata[apiKeyMetadataTenant])
+	policy, _ := s.policyFor(storage.AuthProfileAPIKey)
+	authzPolicy := newAuthorizationPolicy(storage.AuthProfileAPIKey, policy, expiresAt, now)
+	roleMask, permissionMask, degraded, err := s.resolveAuthorizationWithPolicy(ctx, owner, tenant, authzPolicy)
+	if err != nil {
+		return Principal{}, err
+	}
+
+	s.logAuthEventWith(ctx, s.authStore, record.ID, owner, storage.AuthLogEventUsed, sourceMetadata(source))
+	return Principal{
+		Subject:         owner,
+		Tenant:          tenant,
+		RoleMask:        roleMask,
+		PermissionMask:  permissionMask,
+		AuthenticatedAt: now,
+		Degraded:        degraded,
+	}, nil
+}
+
+// apiKeyLookup is the auth ID without separators, which uuid.Parse accepts
+// back.
+func apiKeyLookup(authID string) string {
+	return strings.ReplaceAll(authID, "-", "")
+}
@@ -0,0 +1,109 @@
+package openauth
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/porthorian/openauth/pkg/apikey"
+	ocrypto "github.com/porthorian/openauth/pkg/crypto"
+	oerrors "github.com/porthorian/openauth/pkg/errors"
+	"github.com/porthorian/openauth/pkg/storage"
+)
+
+func TestAPIKeyAuthenticatesWithoutSubject(t *testing.T) {
+	service, authStore, logStore := newRefreshTestService(t)
+	ctx := context.Background()
+
+	issued, err := service.CreateAPIKey(ctx, CreateAPIKeyInput{UserID: "user-1", Tenant: "tenant-a", Name: "ci"})
+	if err != nil {
+		t.Fatalf("CreateAPIKey returned error: %v", err)
+	}
+	if !strings.HasPrefix(issued.Key, issued.Display) || !strings.HasPrefix(issued.Display, apikey.DefaultPrefix+"_") {
+		t.Fatalf("unexpected api key %q with display %q", issued.Key, issued.Display)
+	}
+	stored := authStore.records[issued.AuthID]
+	if stored.MaterialType != storage.AuthMaterialTypeAPIKey || stored.ExpiresAt != nil {
+		t.Fatalf("stored record = %+v, want non-expiring api key material", stored)
+	}
+	if !strings.HasPrefix(stored.MaterialHash, ocrypto.SHA256DigestPrefix) || stored.Metadata[apiKeyMetadataName] != "ci" {
+		t.Fatalf("stored record = %+v, want digested secret and name", stored)
+	}
+
+	principal, err := service.AuthenticateAPIKey(ctx, issued.Key)
+	if err != nil {
+		t.Fatalf("AuthenticateAPIKey returned error: %v", err)
+	}
+	if principal.Subject != "user-1" || principal.Tenant != "tenant-a" {
+		t.Fatalf("principal = %+v, want user-1 in tenant-a", principal)
+	}
+	if ok, _ := service.HasAllRoles(principal, "viewer"); !ok {
+		t.Fatalf("expected viewer role on api key principal")
+	}
+
+	if _, err := service.Authorize(ctx, AuthInput{Type: InputTypeAPIKey, Value: issued.Key}); err != nil {
+		t.Fatalf("Authorize with api key returned error: %v", err)
+	}
+	if _, err := service.Authorize(ctx, AuthInput{UserID: "user-2", Type: InputTypeAPIKey, Value: issued.Key}); !oerrors.IsCode(err, oerrors.CodeInvalidCredentials) {
+		t.Fatalf("Authorize with another subject's api key error = %v, want invalid credentials", err)
+	}
+	if logStore.count(storage.AuthLogEventUsed) != 2 {
+		t.Fatalf("expected two used events, got %d", logStore.count(storage.AuthLogEventUsed))
+	}
+
+	forged, err := apikey.Generate(apikey.DefaultPrefix, apiKeyLookup(issued.AuthID))
+	if err != nil {
+		t.Fatalf("Generate returned error: %v", err)
+	}
+	if _, err := service.AuthenticateAPIKey(ctx, forged.String()); !oerrors.IsCode(err, oerrors.CodeInvalidCredentials) {
+		t.Fatalf("AuthenticateAPIKey with forged secret error = %v, want invalid credentials", err)
+	}
+	if logStore.count(storage.AuthLogEventFailed) != 1 {
+		t.Fatalf("expected one failed event, got %d", logStore.count(storage.AuthLogEventFailed))
+	}
+	damaged := issued.Key[:len(issued.Key)-1] + "a"
+	if strings.HasSuffix(issued.Key, "a") {
+		damaged = issued.Key[:len(issued.Key)-1] + "b"
+	}
+	if _, err := service.AuthenticateAPIKey(ctx, damaged); !oerrors.IsCode(err, oerrors.CodeInvalidCredentials) {
+		t.Fatalf("AuthenticateAPIKey with bad checksum error = %v, want invalid credentials", err)
+	}
+
+	if err := service.RevokeAuth(ctx, issued.AuthID, "leaked"); err != nil {
+		t.Fatalf("RevokeAuth returned error: %v", err)
+	}
+	if _, err := service.AuthenticateAPIKey(ctx, issued.Key); !oerrors.IsCode(err, oerrors.CodeInvalidCredentials) {
+		t.Fatalf("AuthenticateAPIKey with revoked key error = %v, want invalid credentials", err)
+	}
+}
+
+func TestAPIKeyExpiry(t *testing.T) {
+	service, authStore, _ := newRefreshTestService(t)
+	ctx := context.Background()
+
+	expiresAt := time.Now().Add(time.Hour)
+	issued, err := service.CreateAPIKey(ctx, CreateAPIKeyInput{UserID: "user-1", Tenant: "tenant-a", ExpiresAt: &expiresAt})
+	if err != nil {
+		t.Fatalf("CreateAPIKey returned error: %v", err)
+	}
+
+	record := authStore.records[issued.AuthID]
+	past := time.Now().UTC().Add(-time.Minute)
+	record.ExpiresAt = &past
+	authStore.records[issued.AuthID] = record
+
+	if _, err := service.AuthenticateAPIKey(ctx, issued.Key); !oerrors.IsCode(err, oerrors.CodeCredentialsExpired) {
+		t.Fatalf("AuthenticateAPIKey with expired key error = %v, want credentials expired", err)
+	}
+	if authStore.records[issued.AuthID].Status != storage.StatusExpired {
+		t.Fatalf("expected expired key to be marked expired")
+	}
+
+	if _, err := service.CreateAPIKey(ctx, CreateAPIKeyInput{UserID: "user-1", ExpiresAt: &past}); !oerrors.IsCode(err, oerrors.CodeInvalidCredentials) {
+		t.Fatalf("CreateAPIKey with past expiry error = %v, want invalid credentials", err)
+	}
+	if _, err := NewAuthService(Config{APIKeys: APIKeyConfig{Prefix: "Not_Valid"}}); err == nil {
+		t.Fatalf("expected invalid api key prefix to be rejected")
+	}
+}
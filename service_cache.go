@@ -0,0 +1,254 @@
+package openauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/porthorian/openauth/pkg/approach"
+	ocache "github.com/porthorian/openauth/pkg/cache"
+	oerrors "github.com/porthorian/openauth/pkg/errors"
+	"github.com/porthorian/openauth/pkg/storage"
+)
+
+// authorizationPolicy is the persistence policy applied to a single
+// resolution: the cache role of the active profile, a TTL already capped by
+// MaxCacheTTL and the token expiry, and the failure mode. A zero value
+// disables caching and fails closed.
+type authorizationPolicy struct {
+	profile     storage.AuthProfile
+	role        storage.CacheRole
+	ttl         time.Duration
+	staleTTL    time.Duration
+	failureMode storage.FailureMode
+}
+
+func newAuthorizationPolicy(profile storage.AuthProfile, policy storage.PersistencePolicy, expiresAt time.Time, now time.Time) authorizationPolicy {
+	plan := authorizationPolicy{profile: profile, failureMode: policy.FailureMode}
+	if policy.CacheRole == "" || policy.CacheRole == storage.CacheRoleNone || policy.MaxCacheTTL <= 0 {
+		return plan
+	}
+
+	ttl := policy.MaxCacheTTL
+	remaining := time.Duration(0)
+	if !expiresAt.IsZero() {
+		remaining = expiresAt.Sub(now)
+		if remaining < ttl {
+			ttl = remaining
+		}
+	}
+	if ttl <= 0 {
+		return plan
+	}
+
+	plan.role = policy.CacheRole
+	plan.ttl = ttl
+	plan.staleTTL = ttl
+	// Fail-open profiles keep the principal snapshot for the rest of the
+	// token lifetime so it can stand in while storage is unavailable. It is
+	// only served fresh for ttl.
+	if plan.failOpen() && remaining > ttl {
+		plan.staleTTL = remaining
+	}
+	return plan
+}
+
+func (p authorizationPolicy) enabled() bool {
+	return p.ttl > 0
+}
+
+func (p authorizationPolicy) failOpen() bool {
+	return p.failureMode == storage.FailureModeOpen
+}
+
+func tokenProfileForApproach(approachName string, fallback storage.AuthProfile) storage.AuthProfile {
+	switch approachName {
+	case approach.NameDirectJWT, approach.NamePhantomToken:
+		return storage.AuthProfileAccessJWT
+	case approach.NameOpaqueIntrospection:
+		return storage.AuthProfileAccessOpaqueRemote
+	default:
+		return fallback
+	}
+}
+
+func (s *AuthService) policyFor(profile storage.AuthProfile) (storage.PersistencePolicy, bool) {
+	if s.policyMatrix == nil {
+		return storage.PersistencePolicy{}, false
+	}
+	if profile != "" {
+		if policy, ok := s.policyMatrix.Policy(profile); ok {
+			return policy, true
+		}
+	}
+	if s.defaultPolicy != "" {
+		return s.policyMatrix.Policy(s.defaultPolicy)
+	}
+	return storage.PersistencePolicy{}, false
+}
+
+func (s *AuthService) cachedTokenPrincipal(ctx context.Context, policy storage.PersistencePolicy, token string, now time.Time) (Principal, bool) {
+	if policy.CacheRole != storage.CacheRoleIntrospection || s.cacheStore.Token == nil {
+		return Principal{}, false
+	}
+
+	snapshot, ok, err := s.cacheStore.Token.GetToken(ctx, tokenCacheKey(token))
+	if err != nil {
+		s.logger.Error(err, "failed to read token cache", "profile", s.tokenProfile)
+		return Principal{}, false
+	}
+	if !ok {
+		return Principal{}, false
+	}
+	if isStaleSnapshot(snapshot, now) {
+		return Principal{}, false
+	}
+
+	return Principal{
+		Subject:         snapshot.Subject,
+		Tenant:          snapshot.Tenant,
+		RoleMask:        snapshot.RoleMask,
+		PermissionMask:  snapshot.PermissionMask,
+		Claims:          cloneClaims(snapshot.Claims),
+		AuthenticatedAt: now,
+	}, true
+}
+
+func (s *AuthService) storeTokenPrincipal(ctx context.Context, cachePolicy authorizationPolicy, token string, principal Principal, expiresAt time.Time) {
+	if !cachePolicy.enabled() || cachePolicy.role != storage.CacheRoleIntrospection || s.cacheStore.Token == nil {
+		return
+	}
+
+	if err := s.cacheStore.Token.SetToken(ctx, tokenCacheKey(token), ocache.PrincipalSnapshot{
+		Subject:        principal.Subject,
+		Tenant:         principal.Tenant,
+		RoleMask:       principal.RoleMask,
+		PermissionMask: principal.PermissionMask,
+		Claims:         cloneClaims(principal.Claims),
+		ExpiresAt:      expiresAt,
+	}, cachePolicy.ttl); err != nil {
+		s.logger.Error(err, "failed to write token cache", "subject", principal.Subject, "tenant", principal.Tenant)
+	}
+}
+
+// cachedAuthorization only reports a hit when both the principal snapshot and
+// the permission mask are cached, so invalidating either forces a reload.
+func (s *AuthService) cachedAuthorization(ctx context.Context, cachePolicy authorizationPolicy, subject string, tenant string) (RoleMask, PermissionMask, bool) {
+	if cachePolicy.role != storage.CacheRoleReadThrough || s.cacheStore.Principal == nil || s.cacheStore.Permission == nil {
+		return RoleMask{}, PermissionMask{}, false
+	}
+
+	key := principalCacheKey(subject, tenant)
+	snapshot, ok, err := s.cacheStore.Principal.GetPrincipal(ctx, key)
+	if err != nil {
+		s.logger.Error(err, "failed to read principal cache", "subject", subject, "tenant", tenant)
+		return RoleMask{}, PermissionMask{}, false
+	}
+	if !ok || isStaleSnapshot(snapshot, time.Now().UTC()) {
+		return RoleMask{}, PermissionMask{}, false
+	}
+
+	permissionMask, ok, err := s.cacheStore.Permission.GetPermissionMask(ctx, key)
+	if err != nil {
+		s.logger.Error(err, "failed to read permission cache", "subject", subject, "tenant", tenant)
+		return RoleMask{}, PermissionMask{}, false
+	}
+	if !ok {
+		return RoleMask{}, PermissionMask{}, false
+	}
+
+	return snapshot.RoleMask, permissionMask, true
+}
+
+func (s *AuthService) storeAuthorization(ctx context.Context, cachePolicy authorizationPolicy, subject string, tenant string, roleMask RoleMask, permissionMask PermissionMask) {
+	if !cachePolicy.enabled() || s.cacheStore.Principal == nil {
+		return
+	}
+	if cachePolicy.role != storage.CacheRoleReadThrough && !cachePolicy.failOpen() {
+		return
+	}
+
+	key := principalCacheKey(subject, tenant)
+	if err := s.cacheStore.Principal.SetPrincipal(ctx, key, ocache.PrincipalSnapshot{
+		Subject:        subject,
+		Tenant:         tenant,
+		RoleMask:       roleMask,
+		PermissionMask: permissionMask,
+		Claims:         map[string]any{},
+		ExpiresAt:      time.Now().UTC().Add(cachePolicy.ttl),
+	}, cachePolicy.staleTTL); err != nil {
+		s.logger.Error(err, "failed to write principal cache", "subject", subject, "tenant", tenant)
+		return
+	}
+	if cachePolicy.role != storage.CacheRoleReadThrough || s.cacheStore.Permission == nil {
+		return
+	}
+	if err := s.cacheStore.Permission.SetPermissionMask(ctx, key, permissionMask, cachePolicy.ttl); err != nil {
+		s.logger.Error(err, "failed to write permission cache", "subject", subject, "tenant", tenant)
+	}
+}
+
+func (s *AuthService) invalidateAuthorization(ctx context.Context, subject string, tenant string) {
+	key := principalCacheKey(subject, tenant)
+	if s.cacheStore.Principal != nil {
+		if err := s.cacheStore.Principal.DeletePrincipal(ctx, key); err != nil {
+			s.logger.Error(err, "failed to invalidate principal cache", "subject", subject, "tenant", tenant)
+		}
+	}
+	if s.cacheStore.Permission != nil {
+		if err := s.cacheStore.Permission.DeletePermissionMask(ctx, key); err != nil {
+			s.logger.Error(err, "failed to invalidate permission cache", "subject", subject, "tenant", tenant)
+		}
+	}
+}
+
+// degradedAuthorization applies the failure mode of the active profile to a
+// failed resolution. Only storage outages are eligible: fail-open profiles
+// fall back to the last cached principal snapshot, everything else rejects.
+func (s *AuthService) degradedAuthorization(ctx context.Context, cachePolicy authorizationPolicy, subject string, tenant string, cause error) (RoleMask, PermissionMask, bool) {
+	if !oerrors.IsCode(cause, oerrors.CodeStorageUnavailable) {
+		return RoleMask{}, PermissionMask{}, false
+	}
+
+	failureMode := cachePolicy.failureMode
+	if failureMode == "" {
+		failureMode = storage.FailureModeClosed
+	}
+	if !cachePolicy.failOpen() {
+		s.logger.Info("rejecting authorization while storage is unavailable", "profile", cachePolicy.profile, "failure_mode", failureMode, "subject", subject, "tenant", tenant)
+		return RoleMask{}, PermissionMask{}, false
+	}
+	if s.cacheStore.Principal == nil {
+		s.logger.Info("rejecting authorization while storage is unavailable: no principal cache configured", "profile", cachePolicy.profile, "failure_mode", failureMode, "subject", subject, "tenant", tenant)
+		return RoleMask{}, PermissionMask{}, false
+	}
+
+	snapshot, ok, err := s.cacheStore.Principal.GetPrincipal(ctx, principalCacheKey(subject, tenant))
+	if err != nil {
+		s.logger.Error(err, "failed to read principal cache for degraded authorization", "profile", cachePolicy.profile, "subject", subject, "tenant", tenant)
+		return RoleMask{}, PermissionMask{}, false
+	}
+	if !ok {
+		s.logger.Info("rejecting authorization while storage is unavailable: no cached principal snapshot", "profile", cachePolicy.profile, "failure_mode", failureMode, "subject", subject, "tenant", tenant)
+		return RoleMask{}, PermissionMask{}, false
+	}
+
+	s.logger.Info("serving degraded principal from cache while storage is unavailable", "profile", cachePolicy.profile, "failure_mode", failureMode, "subject", subject, "tenant", tenant, "snapshot_expires_at", snapshot.ExpiresAt)
+	return snapshot.RoleMask, snapshot.PermissionMask, true
+}
+
+func isStaleSnapshot(snapshot ocache.PrincipalSnapshot, now time.Time) bool {
+	return !snapshot.ExpiresAt.IsZero() && !now.Before(snapshot.ExpiresAt)
+}
+
+// tokenCacheKey hashes the token so raw bearer material never lands in the
+// cache keyspace.
+func tokenCacheKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func principalCacheKey(subject string, tenant string) string {
+	return tenant + ":" + subject
+}
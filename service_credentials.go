@@ -0,0 +1,245 @@
+package openauth
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	oerrors "github.com/porthorian/openauth/pkg/errors"
+	"github.com/porthorian/openauth/pkg/storage"
+)
+
+const authLogMetadataReason = "reason"
+
+var _ CredentialManager = (*AuthService)(nil)
+
+// ListCredentials returns the auth material linked to subject without its
+// hashes. Pending login challenges are not credentials and are left out.
+func (s *AuthService) ListCredentials(ctx context.Context, subject string) ([]Credential, error) {
+	if s == nil || s.authStore.Auth == nil || s.authStore.SubjectAuth == nil {
+		return nil, oerrors.New(oerrors.CodeStorageUnavailable, "auth storage is not configured")
+	}
+
+	subject = strings.TrimSpace(subject)
+	if subject == "" {
+		return nil, oerrors.New(oerrors.CodeInvalidCredentials, "subject is required")
+	}
+
+	records, err := s.subjectAuthRecords(ctx, subject)
+	if err != nil {
+		return nil, err
+	}
+
+	credentials := make([]Credential, 0, len(records))
+	for _, record := range records {
+		switch record.MaterialType {
+		case storage.AuthMaterialTypeMFAChallenge, storage.AuthMaterialTypeWebAuthnChallenge:
+			continue
+		}
+		credentials = append(credentials, credentialFromRecord(subject, record))
+	}
+	return credentials, nil
+}
+
+// RevokeAuth marks authID revoked, for example after a suspected
+// compromise. Revoking revoked material is a no-op.
+func (s *AuthService) RevokeAuth(ctx context.Context, authID string, reason string) error {
+	var metadata map[string]string
+	if reason = strings.TrimSpace(reason); reason != "" {
+		metadata = map[string]string{authLogMetadataReason: reason}
+	}
+
+	return s.updateAuthStatus(ctx, "revoke auth", authID, func(record storage.AuthRecord, now time.Time) (storage.AuthRecord, bool, error) {
+		if record.Status == storage.StatusRevoked {
+			return record, false, nil
+		}
+		record.Status = storage.StatusRevoked
+		record.RevokedAt = &now
+		return record, true, nil
+	}, storage.AuthLogEventRevoked, metadata)
+}
+
+// DeactivateAuth retires active material without marking it compromised.
+// Deactivating inactive material is a no-op; revoked material cannot be
+// deactivated.
+func (s *AuthService) DeactivateAuth(ctx context.Context, authID string) error {
+	return s.updateAuthStatus(ctx, "deactivate auth", authID, func(record storage.AuthRecord, _ time.Time) (storage.AuthRecord, bool, error) {
+		switch record.Status {
+		case storage.StatusInActive:
+			return record, false, nil
+		case storage.StatusActive:
+			record.Status = storage.StatusInActive
+			return record, true, nil
+		}
+		return storage.AuthRecord{}, false, oerrors.New(oerrors.CodeInvalidCredentials, "only active auth can be deactivated")
+	}, storage.AuthLogEventDeactivated, nil)
+}
+
+// RotateAuth replaces the active password input.AuthID with input.Value
+// without knowing the old value, as an administrator would. The old record
+// is deactivated and the new one created in one auth material transaction.
+func (s *AuthService) RotateAuth(ctx context.Context, input RotateAuthInput) (Credential, error) {
+	if s == nil || s.authStore.Auth == nil || s.authStore.SubjectAuth == nil {
+		return Credential{}, oerrors.New(oerrors.CodeStorageUnavailable, "auth storage is not configured")
+	}
+	if s.hasher == nil {
+		return Credential{}, oerrors.New(oerrors.CodeUnknown, "hasher is not configured")
+	}
+
+	input = input.Normalize()
+	if err := input.Validate(); err != nil {
+		return Credential{}, err
+	}
+
+	subject, err := s.authSubject(ctx, input.AuthID)
+	if err != nil {
+		return Credential{}, err
+	}
+	records, err := s.subjectAuthRecords(ctx, subject)
+	if err != nil {
+		return Credential{}, err
+	}
+
+	var current *storage.AuthRecord
+	for i, record := range records {
+		if record.ID == input.AuthID {
+			current = &records[i]
+			break
+		}
+	}
+	if current == nil {
+		return Credential{}, oerrors.New(oerrors.CodeNotFound, "auth_id not found")
+	}
+	if current.MaterialType != storage.AuthMaterialTypePassword || current.Status != storage.StatusActive {
+		return Credential{}, oerrors.New(oerrors.CodeInvalidCredentials, "only active password auth can be rotated")
+	}
+
+	if err := s.checkPasswordPolicy(input.Value, subject); err != nil {
+		return Credential{}, err
+	}
+	if err := s.checkPasswordReuse(records, input.Value); err != nil {
+		return Credential{}, err
+	}
+	profile := recordAuthProfile(*current)
+	expiresAt, err := s.passwordExpiry(profile, input.ExpiresAt, time.Now().UTC())
+	if err != nil {
+		return Credential{}, err
+	}
+	materialHash, err := s.hasher.Hash(input.Value)
+	if err != nil {
+		return Credential{}, oerrors.Wrap(oerrors.CodeUnknown, "failed to hash auth value", err)
+	}
+
+	write := createAuthWrite{
+		authID:       uuid.NewString(),
+		userID:       subject,
+		materialHash: materialHash,
+		expiresAt:    expiresAt,
+		metadata:     withAuthProfile(input.Metadata, profile),
+	}
+	if err := s.replaceAuth(ctx, "rotate auth", current.ID, write); err != nil {
+		return Credential{}, err
+	}
+
+	created, err := s.authStore.Auth.GetAuth(ctx, write.authID)
+	if err != nil {
+		return Credential{}, oerrors.Wrap(oerrors.CodeStorageUnavailable, "failed to retrieve rotated auth record", err)
+	}
+	return credentialFromRecord(subject, created), nil
+}
+
+// replaceAuth deactivates oldID and creates write in one auth material
+// transaction. oldID must still be active when the transaction reads it.
+func (s *AuthService) replaceAuth(ctx context.Context, operation string, oldID string, write createAuthWrite) error {
+	now := time.Now().UTC()
+	return s.withAuthMaterial(ctx, operation, func(stores storage.AuthMaterial, transactional bool) error {
+		old, err := stores.Auth.GetAuth(ctx, oldID)
+		if err != nil {
+			return oerrors.Wrap(oerrors.CodeStorageUnavailable, "failed to retrieve current auth record", err)
+		}
+		if old.Status != storage.StatusActive {
+			return oerrors.New(oerrors.CodeInvalidCredentials, "current auth is no longer active")
+		}
+		old.Status = storage.StatusInActive
+		old.DateModified = &now
+		if err := stores.Auth.PutAuth(ctx, old); err != nil {
+			return oerrors.Wrap(oerrors.CodeStorageUnavailable, "failed to deactivate current auth record", err)
+		}
+		if err := s.createAuthWithStores(ctx, stores, transactional, write); err != nil {
+			return err
+		}
+		s.logAuthEventWith(ctx, stores, old.ID, write.userID, storage.AuthLogEventDeactivated, map[string]string{authLogMetadataReplacedBy: write.authID})
+		return nil
+	})
+}
+
+// updateAuthStatus applies change to authID inside an auth material
+// transaction and logs event when change reports an update.
+func (s *AuthService) updateAuthStatus(
+	ctx context.Context,
+	operation string,
+	authID string,
+	change func(record storage.AuthRecord, now time.Time) (storage.AuthRecord, bool, error),
+	event storage.AuthLogEvent,
+	metadata map[string]string,
+) error {
+	if s == nil || s.authStore.Auth == nil || s.authStore.SubjectAuth == nil {
+		return oerrors.New(oerrors.CodeStorageUnavailable, "auth storage is not configured")
+	}
+
+	authID = strings.TrimSpace(authID)
+	if authID == "" {
+		return oerrors.New(oerrors.CodeInvalidCredentials, "auth_id is required")
+	}
+	subject, err := s.authSubject(ctx, authID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	return s.withAuthMaterial(ctx, operation, func(stores storage.AuthMaterial, transactional bool) error {
+		_ = transactional
+
+		record, err := stores.Auth.GetAuth(ctx, authID)
+		if err != nil {
+			return oerrors.Wrap(oerrors.CodeStorageUnavailable, "failed to retrieve auth record", err)
+		}
+		record, changed, err := change(record, now)
+		if err != nil || !changed {
+			return err
+		}
+		record.DateModified = &now
+		if err := stores.Auth.PutAuth(ctx, record); err != nil {
+			return oerrors.Wrap(oerrors.CodeStorageUnavailable, "failed to update auth record", err)
+		}
+		s.logAuthEventWith(ctx, stores, authID, subject, event, metadata)
+		return nil
+	})
+}
+
+// authSubject returns the subject authID is linked to.
+func (s *AuthService) authSubject(ctx context.Context, authID string) (string, error) {
+	links, err := s.authStore.SubjectAuth.ListSubjectAuthByAuthID(ctx, authID)
+	if err != nil {
+		return "", oerrors.Wrap(oerrors.CodeStorageUnavailable, "failed to lookup auth subject", err)
+	}
+	if len(links) != 1 {
+		return "", oerrors.New(oerrors.CodeNotFound, "auth_id not found")
+	}
+	return links[0].Subject, nil
+}
+
+func credentialFromRecord(subject string, record storage.AuthRecord) Credential {
+	return Credential{
+		AuthID:       record.ID,
+		Subject:      subject,
+		Type:         record.MaterialType,
+		Status:       record.Status,
+		DateAdded:    record.DateAdded,
+		DateModified: record.DateModified,
+		ExpiresAt:    record.ExpiresAt,
+		RevokedAt:    record.RevokedAt,
+		Metadata:     record.Metadata,
+	}
+}
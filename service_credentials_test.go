@@ -0,0 +1,100 @@
+package openauth
+
+import (
+	"context"
+	"testing"
+
+	oerrors "github.com/porthorian/openauth/pkg/errors"
+	"github.com/porthorian/openauth/pkg/storage"
+)
+
+func onlyCredential(t *testing.T, service *AuthService, subject string, status storage.AuthStatus) Credential {
+	t.Helper()
+
+	credentials, err := service.ListCredentials(context.Background(), subject)
+	if err != nil {
+		t.Fatalf("ListCredentials returned error: %v", err)
+	}
+	var found []Credential
+	for _, credential := range credentials {
+		if credential.Status == status {
+			found = append(found, credential)
+		}
+	}
+	if len(found) != 1 {
+		t.Fatalf("expected one %s credential, got %+v", status, credentials)
+	}
+	return found[0]
+}
+
+func TestCredentialLifecycle(t *testing.T) {
+	service, _, logStore := newPasswordTestService(t, 0)
+	ctx := context.Background()
+
+	first := onlyCredential(t, service, "user-1", storage.StatusActive)
+	if first.Type != storage.AuthMaterialTypePassword || first.Subject != "user-1" {
+		t.Fatalf("unexpected credential: %+v", first)
+	}
+
+	rotated, err := service.RotateAuth(ctx, RotateAuthInput{AuthID: first.AuthID, Value: "second-password"})
+	if err != nil {
+		t.Fatalf("RotateAuth returned error: %v", err)
+	}
+	if rotated.AuthID == first.AuthID || rotated.Status != storage.StatusActive {
+		t.Fatalf("unexpected rotated credential: %+v", rotated)
+	}
+	if retired := onlyCredential(t, service, "user-1", storage.StatusInActive); retired.AuthID != first.AuthID || retired.DateModified == nil {
+		t.Fatalf("expected rotated-out credential to be inactive with DateModified, got %+v", retired)
+	}
+	if _, err := service.RotateAuth(ctx, RotateAuthInput{AuthID: first.AuthID, Value: "third-password"}); !oerrors.IsCode(err, oerrors.CodeInvalidCredentials) {
+		t.Fatalf("RotateAuth of inactive auth error = %v, want invalid credentials", err)
+	}
+
+	if err := service.RevokeAuth(ctx, rotated.AuthID, "reported compromised"); err != nil {
+		t.Fatalf("RevokeAuth returned error: %v", err)
+	}
+	if err := service.RevokeAuth(ctx, rotated.AuthID, "again"); err != nil {
+		t.Fatalf("RevokeAuth of revoked auth returned error: %v", err)
+	}
+	revoked := onlyCredential(t, service, "user-1", storage.StatusRevoked)
+	if revoked.RevokedAt == nil || revoked.DateModified == nil {
+		t.Fatalf("expected revoked credential to carry RevokedAt and DateModified, got %+v", revoked)
+	}
+	if err := service.DeactivateAuth(ctx, revoked.AuthID); !oerrors.IsCode(err, oerrors.CodeInvalidCredentials) {
+		t.Fatalf("DeactivateAuth of revoked auth error = %v, want invalid credentials", err)
+	}
+	if _, err := service.Authorize(ctx, AuthInput{UserID: "user-1", Type: InputTypePassword, Value: "second-password"}); !oerrors.IsCode(err, oerrors.CodeInvalidCredentials) {
+		t.Fatalf("Authorize with revoked password error = %v, want invalid credentials", err)
+	}
+
+	if logStore.count(storage.AuthLogEventRevoked) != 1 || logStore.count(storage.AuthLogEventDeactivated) != 1 {
+		t.Fatalf("unexpected auth log events: %+v", logStore.records)
+	}
+	for _, record := range logStore.records {
+		if record.Event == storage.AuthLogEventRevoked && record.Metadata[authLogMetadataReason] != "reported compromised" {
+			t.Fatalf("expected revoke reason in auth log, got %+v", record.Metadata)
+		}
+	}
+
+	if err := service.RevokeAuth(ctx, "missing", ""); !oerrors.IsCode(err, oerrors.CodeNotFound) {
+		t.Fatalf("RevokeAuth of unknown auth error = %v, want not found", err)
+	}
+}
+
+func TestDeactivateAuthIsIdempotent(t *testing.T) {
+	service, _, logStore := newPasswordTestService(t, 0)
+	ctx := context.Background()
+
+	active := onlyCredential(t, service, "user-1", storage.StatusActive)
+	for range 2 {
+		if err := service.DeactivateAuth(ctx, active.AuthID); err != nil {
+			t.Fatalf("DeactivateAuth returned error: %v", err)
+		}
+	}
+	if logStore.count(storage.AuthLogEventDeactivated) != 1 {
+		t.Fatalf("expected one deactivated event, got %d", logStore.count(storage.AuthLogEventDeactivated))
+	}
+	if _, err := service.Authorize(ctx, AuthInput{UserID: "user-1", Type: InputTypePassword, Value: "first-password"}); !oerrors.IsCode(err, oerrors.CodeInvalidCredentials) {
+		t.Fatalf("Authorize with deactivated password error = %v, want invalid credentials", err)
+	}
+}
@@ -0,0 +1,272 @@
+package openauth
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	oerrors "github.com/porthorian/openauth/pkg/errors"
+	"github.com/porthorian/openauth/pkg/storage"
+)
+
+const lockoutMetadataSource = "source"
+
+// LockoutPolicy throttles repeated Authorize failures. Failures are counted
+// per subject and per subject and source; once a counter reaches its limit
+// the key is locked for LockDuration, doubling on every further failure up
+// to MaxLockDuration. A counter restarts once Window passes without a
+// failure.
+type LockoutPolicy struct {
+	Disabled          bool
+	MaxFailures       int
+	MaxSourceFailures int
+	Window            time.Duration
+	LockDuration      time.Duration
+	MaxLockDuration   time.Duration
+}
+
+func DefaultLockoutPolicy() LockoutPolicy {
+	return LockoutPolicy{
+		MaxFailures:       10,
+		MaxSourceFailures: 5,
+		Window:            15 * time.Minute,
+		LockDuration:      time.Minute,
+		MaxLockDuration:   15 * time.Minute,
+	}
+}
+
+func (p LockoutPolicy) normalize() LockoutPolicy {
+	if p.Disabled {
+		return p
+	}
+	if p == (LockoutPolicy{}) {
+		return DefaultLockoutPolicy()
+	}
+
+	defaults := DefaultLockoutPolicy()
+	if p.Window <= 0 {
+		p.Window = defaults.Window
+	}
+	if p.LockDuration <= 0 {
+		p.LockDuration = defaults.LockDuration
+	}
+	if p.MaxLockDuration < p.LockDuration {
+		p.MaxLockDuration = p.LockDuration
+	}
+	// Counters must outlive the lock so the next failure escalates it.
+	if p.MaxLockDuration > p.Window {
+		p.MaxLockDuration = p.Window
+	}
+	if p.LockDuration > p.MaxLockDuration {
+		p.LockDuration = p.MaxLockDuration
+	}
+	return p
+}
+
+func (p LockoutPolicy) enabled() bool {
+	return !p.Disabled && (p.MaxFailures > 0 || p.MaxSourceFailures > 0)
+}
+
+// lockFor returns the lock duration after failures reached a limit of max.
+func (p LockoutPolicy) lockFor(failures int64, max int) time.Duration {
+	lock := p.LockDuration
+	for extra := failures - int64(max); extra > 0 && lock < p.MaxLockDuration; extra-- {
+		lock *= 2
+	}
+	if lock > p.MaxLockDuration {
+		lock = p.MaxLockDuration
+	}
+	return lock
+}
+
+type lockoutKey struct {
+	key         string
+	maxFailures int
+}
+
+func (s *AuthService) lockoutKeys(subject string, source string) []lockoutKey {
+	keys := make([]lockoutKey, 0, 2)
+	if s.lockout.MaxFailures > 0 {
+		keys = append(keys, lockoutKey{key: "subject:" + subject, maxFailures: s.lockout.MaxFailures})
+	}
+	if source != "" && s.lockout.MaxSourceFailures > 0 {
+		keys = append(keys, lockoutKey{key: "source:" + subject + "|" + source, maxFailures: s.lockout.MaxSourceFailures})
+	}
+	return keys
+}
+
+// UnlockSubject clears the subject-wide lock and failure counter, and the
+// ones for input.Source when set. An unlocked log event is written so the
+// storage fallback also forgets earlier failures.
+func (s *AuthService) UnlockSubject(ctx context.Context, input UnlockSubjectInput) error {
+	if s == nil || s.authStore.SubjectAuth == nil {
+		return oerrors.New(oerrors.CodeStorageUnavailable, "auth storage is not configured")
+	}
+
+	input = input.Normalize()
+	if err := input.Validate(); err != nil {
+		return err
+	}
+
+	if attempts := s.cacheStore.Attempt; attempts != nil {
+		keys := []string{"subject:" + input.Subject}
+		if input.Source != "" {
+			keys = append(keys, "source:"+input.Subject+"|"+input.Source)
+		}
+		for _, key := range keys {
+			if err := attempts.DeleteLock(ctx, key); err != nil {
+				return oerrors.Wrap(oerrors.CodeStorageUnavailable, "failed to clear lockout", err)
+			}
+			if err := attempts.ResetAttempts(ctx, key); err != nil {
+				return oerrors.Wrap(oerrors.CodeStorageUnavailable, "failed to clear lockout", err)
+			}
+		}
+	}
+
+	subjects, err := s.authStore.SubjectAuth.ListSubjectAuthBySubject(ctx, input.Subject)
+	if err != nil {
+		return oerrors.Wrap(oerrors.CodeStorageUnavailable, "failed to lookup subject auth records", err)
+	}
+	if len(subjects) > 0 {
+		s.logAuthEventWith(ctx, s.authStore, subjects[0].AuthID, input.Subject, storage.AuthLogEventUnlocked, sourceMetadata(input.Source))
+	}
+	return nil
+}
+
+// checkLockout returns CodeAccountLocked while subject or subject and source
+// are locked. Lock state comes from the attempt cache and is rebuilt from
+// failed auth log events when no cache is configured or it fails.
+func (s *AuthService) checkLockout(ctx context.Context, subject string, source string) error {
+	if !s.lockout.enabled() {
+		return nil
+	}
+
+	keys := s.lockoutKeys(subject, source)
+	now := time.Now().UTC()
+	var until time.Time
+
+	cached := s.cacheStore.Attempt != nil
+	if cached {
+		for _, key := range keys {
+			lockedUntil, ok, err := s.cacheStore.Attempt.GetLock(ctx, key.key)
+			if err != nil {
+				s.logger.Error(err, "failed to read lockout from cache; falling back to auth logs", "subject", subject)
+				cached = false
+				break
+			}
+			if ok && lockedUntil.After(until) {
+				until = lockedUntil
+			}
+		}
+	}
+	if !cached {
+		until = s.lockedUntilFromLogs(ctx, subject, source, now)
+	}
+
+	if !until.After(now) {
+		return nil
+	}
+	lockErr := oerrors.New(oerrors.CodeAccountLocked, "account is temporarily locked")
+	lockErr.RetryAfter = until.Sub(now)
+	return lockErr
+}
+
+func (s *AuthService) recordAuthFailure(ctx context.Context, subject string, source string) {
+	if !s.lockout.enabled() || s.cacheStore.Attempt == nil {
+		return
+	}
+
+	now := time.Now().UTC()
+	for _, key := range s.lockoutKeys(subject, source) {
+		failures, err := s.cacheStore.Attempt.IncrementAttempts(ctx, key.key, s.lockout.Window)
+		if err != nil {
+			s.logger.Error(err, "failed to record auth failure", "subject", subject)
+			continue
+		}
+		if failures < int64(key.maxFailures) {
+			continue
+		}
+		lock := s.lockout.lockFor(failures, key.maxFailures)
+		if err := s.cacheStore.Attempt.SetLock(ctx, key.key, now.Add(lock)); err != nil {
+			s.logger.Error(err, "failed to lock subject", "subject", subject)
+			continue
+		}
+		s.logger.Info("locked subject after repeated auth failures", "subject", subject, "source", source, "failures", failures, "lock", lock)
+	}
+}
+
+func (s *AuthService) resetAuthFailures(ctx context.Context, subject string, source string) {
+	if !s.lockout.enabled() || s.cacheStore.Attempt == nil {
+		return
+	}
+
+	for _, key := range s.lockoutKeys(subject, source) {
+		if err := s.cacheStore.Attempt.ResetAttempts(ctx, key.key); err != nil {
+			s.logger.Error(err, "failed to reset auth failures", "subject", subject)
+		}
+	}
+}
+
+// lockedUntilFromLogs replays the last Window of the subject's auth log.
+// Successful logins and unlocks end a failure streak, as does a gap longer
+// than the window. Bounding the read keeps the fallback cheap; it means
+// only failures inside the window count, which can miss a slow streak the
+// attempt cache would still be tracking.
+func (s *AuthService) lockedUntilFromLogs(ctx context.Context, subject string, source string, now time.Time) time.Time {
+	if s.authStore.AuthLog == nil {
+		return time.Time{}
+	}
+
+	records, err := s.authStore.AuthLog.ListAuthLogsBySubjectSince(ctx, subject, now.Add(-s.lockout.Window))
+	if err != nil {
+		s.logger.Error(err, "failed to read auth logs for lockout", "subject", subject)
+		return time.Time{}
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].OccurredAt.Before(records[j].OccurredAt)
+	})
+
+	var until time.Time
+	for _, key := range s.lockoutKeys(subject, source) {
+		sourceOnly := strings.HasPrefix(key.key, "source:")
+		var failures int64
+		var lastFailure time.Time
+		for _, record := range records {
+			recordSource := record.Metadata[lockoutMetadataSource]
+			switch record.Event {
+			case storage.AuthLogEventUsed:
+				if !sourceOnly || recordSource == source {
+					failures = 0
+				}
+			case storage.AuthLogEventUnlocked:
+				if !sourceOnly || recordSource == "" || recordSource == source {
+					failures = 0
+				}
+			case storage.AuthLogEventFailed:
+				if sourceOnly && recordSource != source {
+					continue
+				}
+				if failures > 0 && record.OccurredAt.Sub(lastFailure) > s.lockout.Window {
+					failures = 0
+				}
+				failures++
+				lastFailure = record.OccurredAt
+			}
+		}
+		if failures < int64(key.maxFailures) || now.Sub(lastFailure) > s.lockout.Window {
+			continue
+		}
+		if lockedUntil := lastFailure.Add(s.lockout.lockFor(failures, key.maxFailures)); lockedUntil.After(until) {
+			until = lockedUntil
+		}
+	}
+	return until
+}
+
+func sourceMetadata(source string) map[string]string {
+	if source == "" {
+		return nil
+	}
+	return map[string]string{lockoutMetadataSource: source}
+}
@@ -0,0 +1,177 @@
+package openauth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	ocache "github.com/porthorian/openauth/pkg/cache"
+	memorycache "github.com/porthorian/openauth/pkg/cache/memory"
+	oerrors "github.com/porthorian/openauth/pkg/errors"
+	"github.com/porthorian/openauth/pkg/storage"
+)
+
+func newLockoutTestService(t *testing.T, attempts ocache.AttemptCache, logStore *recordingAuthLogStore) *AuthService {
+	t.Helper()
+
+	authStore := &memoryAuthStore{
+		records: map[string]storage.AuthRecord{
+			"auth-1": {
+				ID:           "auth-1",
+				Status:       storage.StatusActive,
+				MaterialType: storage.AuthMaterialTypePassword,
+				MaterialHash: "pass-123",
+			},
+		},
+	}
+	subjectStore := &memorySubjectAuthStore{}
+	if err := subjectStore.PutSubjectAuth(context.Background(), storage.SubjectAuthRecord{ID: "link-1", Subject: "user-1", AuthID: "auth-1"}); err != nil {
+		t.Fatalf("PutSubjectAuth returned error: %v", err)
+	}
+
+	service, err := NewAuthService(Config{
+		AuthStore: storage.AuthMaterial{
+			Auth:        authStore,
+			SubjectAuth: subjectStore,
+			AuthLog:     logStore,
+		},
+		AuthdStore: storage.AuthdMaterial{
+			Role:       &memoryRoleStore{},
+			Permission: &memoryPermissionStore{},
+		},
+		CacheStore: ocache.Dependencies{Attempt: attempts},
+		Hasher:     staticHasher{},
+		Lockout: LockoutPolicy{
+			MaxFailures:       4,
+			MaxSourceFailures: 2,
+			Window:            time.Hour,
+			LockDuration:      time.Minute,
+			MaxLockDuration:   10 * time.Minute,
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewAuthService returned error: %v", err)
+	}
+	return service
+}
+
+func TestAuthorizeLocksSourceThenSubject(t *testing.T) {
+	service := newLockoutTestService(t, memorycache.NewAdapter(), &recordingAuthLogStore{})
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		_, err := service.Authorize(ctx, AuthInput{UserID: "user-1", Type: InputTypePassword, Value: "wrong", Source: "10.0.0.1"})
+		if !oerrors.IsCode(err, oerrors.CodeInvalidCredentials) {
+			t.Fatalf("attempt %d error = %v, want invalid credentials", i, err)
+		}
+	}
+
+	// Login handlers see the lock through Client.Authorize with its retry hint.
+	client := &Client{auth: service}
+	_, err := client.Authorize(ctx, AuthInput{UserID: "user-1", Type: InputTypePassword, Value: "pass-123", Source: "10.0.0.1"})
+	if !oerrors.IsCode(err, oerrors.CodeAccountLocked) {
+		t.Fatalf("locked source error = %v, want account locked", err)
+	}
+	if retryAfter, ok := oerrors.RetryAfter(err); !ok || retryAfter > time.Minute {
+		t.Fatalf("RetryAfter = (%v, %v), want at most one minute", retryAfter, ok)
+	}
+
+	for i := 0; i < 2; i++ {
+		_, _ = service.Authorize(ctx, AuthInput{UserID: "user-1", Type: InputTypePassword, Value: "wrong", Source: "10.0.0.2"})
+	}
+	_, err = service.Authorize(ctx, AuthInput{UserID: "user-1", Type: InputTypePassword, Value: "pass-123", Source: "10.0.0.3"})
+	if !oerrors.IsCode(err, oerrors.CodeAccountLocked) {
+		t.Fatalf("locked subject error = %v, want account locked", err)
+	}
+
+	if err := service.UnlockSubject(ctx, UnlockSubjectInput{Subject: "user-1"}); err != nil {
+		t.Fatalf("UnlockSubject returned error: %v", err)
+	}
+	if _, err := service.Authorize(ctx, AuthInput{UserID: "user-1", Type: InputTypePassword, Value: "pass-123", Source: "10.0.0.3"}); err != nil {
+		t.Fatalf("Authorize after unlock returned error: %v", err)
+	}
+	if _, err := service.Authorize(ctx, AuthInput{UserID: "user-1", Type: InputTypePassword, Value: "pass-123", Source: "10.0.0.1"}); !oerrors.IsCode(err, oerrors.CodeAccountLocked) {
+		t.Fatalf("source lock should survive a subject-only unlock, got %v", err)
+	}
+}
+
+func TestAuthorizeLockoutFallsBackToAuthLogs(t *testing.T) {
+	logStore := &recordingAuthLogStore{}
+	service := newLockoutTestService(t, nil, logStore)
+	ctx := context.Background()
+
+	for i := 0; i < 4; i++ {
+		_, _ = service.Authorize(ctx, AuthInput{UserID: "user-1", Type: InputTypePassword, Value: "wrong"})
+	}
+	if logStore.count(storage.AuthLogEventFailed) != 4 {
+		t.Fatalf("failed events = %d, want 4", logStore.count(storage.AuthLogEventFailed))
+	}
+
+	_, err := service.Authorize(ctx, AuthInput{UserID: "user-1", Type: InputTypePassword, Value: "pass-123"})
+	if !oerrors.IsCode(err, oerrors.CodeAccountLocked) {
+		t.Fatalf("error = %v, want account locked from auth logs", err)
+	}
+
+	if err := service.UnlockSubject(ctx, UnlockSubjectInput{Subject: "user-1"}); err != nil {
+		t.Fatalf("UnlockSubject returned error: %v", err)
+	}
+	if logStore.count(storage.AuthLogEventUnlocked) != 1 {
+		t.Fatalf("unlocked events = %d, want 1", logStore.count(storage.AuthLogEventUnlocked))
+	}
+	if _, err := service.Authorize(ctx, AuthInput{UserID: "user-1", Type: InputTypePassword, Value: "pass-123"}); err != nil {
+		t.Fatalf("Authorize after unlock returned error: %v", err)
+	}
+}
+
+type corruptHashHasher struct {
+	staticHasher
+}
+
+func (h corruptHashHasher) Verify(password string, encodedHash string) (bool, error) {
+	_ = password
+	_ = encodedHash
+	return false, errors.New("malformed hash")
+}
+
+func TestAuthorizeCountsUnverifiableHashesAsFailures(t *testing.T) {
+	logStore := &recordingAuthLogStore{}
+	service := newLockoutTestService(t, memorycache.NewAdapter(), logStore)
+	service.hasher = corruptHashHasher{}
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		_, err := service.Authorize(ctx, AuthInput{UserID: "user-1", Type: InputTypePassword, Value: "pass-123", Source: "10.0.0.1"})
+		if !oerrors.IsCode(err, oerrors.CodeInvalidCredentials) {
+			t.Fatalf("attempt %d error = %v, want invalid credentials", i, err)
+		}
+	}
+	if logStore.count(storage.AuthLogEventFailed) != 2 {
+		t.Fatalf("failed events = %d, want 2", logStore.count(storage.AuthLogEventFailed))
+	}
+
+	_, err := service.Authorize(ctx, AuthInput{UserID: "user-1", Type: InputTypePassword, Value: "pass-123", Source: "10.0.0.1"})
+	if !oerrors.IsCode(err, oerrors.CodeAccountLocked) {
+		t.Fatalf("error = %v, want account locked", err)
+	}
+}
+
+func TestLockoutPolicyBackoff(t *testing.T) {
+	policy := LockoutPolicy{MaxFailures: 3, Window: 10 * time.Minute, LockDuration: time.Minute, MaxLockDuration: time.Hour}.normalize()
+	if policy.MaxLockDuration != 10*time.Minute {
+		t.Fatalf("MaxLockDuration = %v, want capped at window", policy.MaxLockDuration)
+	}
+
+	for failures, want := range map[int64]time.Duration{3: time.Minute, 4: 2 * time.Minute, 6: 8 * time.Minute, 9: 10 * time.Minute} {
+		if got := policy.lockFor(failures, 3); got != want {
+			t.Fatalf("lockFor(%d) = %v, want %v", failures, got, want)
+		}
+	}
+
+	if (LockoutPolicy{Disabled: true}).normalize().enabled() {
+		t.Fatalf("expected disabled policy to stay disabled")
+	}
+	if !(LockoutPolicy{}).normalize().enabled() {
+		t.Fatalf("expected zero policy to use defaults")
+	}
+}
@@ -0,0 +1,548 @@
+package openauth
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	ocrypto "github.com/porthorian/openauth/pkg/crypto"
+	oerrors "github.com/porthorian/openauth/pkg/errors"
+	"github.com/porthorian/openauth/pkg/mfa/totp"
+	"github.com/porthorian/openauth/pkg/storage"
+)
+
+const (
+	defaultMFAChallengeTTL = 5 * time.Minute
+	// mfaChallengeMaxFailures bounds wrong second factors per challenge so
+	// a code cannot be guessed within one challenge, whatever Config.Lockout
+	// says.
+	mfaChallengeMaxFailures = 5
+	mfaMetadataTenant       = "tenant"
+	mfaMetadataFailures     = "failed_attempts"
+	totpMetadataLastStep    = "totp_last_step"
+	totpMetadataDigits      = "totp_digits"
+	totpMetadataPeriod      = "totp_period"
+)
+
+var _ MFAManager = (*AuthService)(nil)
+
+// MFAConfig configures second factors. A password Authorize for a subject
+// with an active second factor returns a CodeMFARequired error carrying an
+// MFAChallenge instead of a Principal.
+type MFAConfig struct {
+	Issuer       string        // Issuer labels TOTP enrolments in authenticator apps.
+	ChallengeTTL time.Duration // ChallengeTTL bounds the second step. Zero uses five minutes.
+	TOTP         totp.Options  // TOTP applies to new enrolments; existing ones keep their digits and period.
+	// SecretCipher encrypts TOTP secrets at rest. TOTP enrolment and
+	// verification fail while it is nil.
+	SecretCipher ocrypto.SecretCipher
+}
+
+func (c MFAConfig) normalize() MFAConfig {
+	defaults := totp.DefaultOptions()
+	if c.ChallengeTTL <= 0 {
+		c.ChallengeTTL = defaultMFAChallengeTTL
+	}
+	if c.TOTP.Digits == 0 {
+		c.TOTP.Digits = defaults.Digits
+	}
+	if c.TOTP.Period == 0 {
+		c.TOTP.Period = defaults.Period
+	}
+	if c.TOTP.Skew == 0 {
+		c.TOTP.Skew = defaults.Skew
+	}
+	return c
+}
+
+// MFAChallenge is the pending state between a verified password and its
+// second factor. Pass Token back as AuthInput.Challenge.
+type MFAChallenge struct {
+	Token     string
+	Subject   string
+	Methods   []InputType
+	ExpiresAt time.Time
+}
+
+// MFARequiredError is the cause of CodeMFARequired errors returned by
+// Authorize. Use MFAChallengeFromError to read the challenge.
+type MFARequiredError struct {
+	Challenge MFAChallenge
+}
+
+func (e *MFARequiredError) Error() string {
+	return "second factor required"
+}
+
+func MFAChallengeFromError(err error) (MFAChallenge, bool) {
+	var required *MFARequiredError
+	if !errors.As(err, &required) {
+		return MFAChallenge{}, false
+	}
+	return required.Challenge, true
+}
+
+// EnrollTOTP creates a pending TOTP factor for input.UserID. The factor is
+// not required at login until ConfirmTOTP proves the user can produce codes.
+func (s *AuthService) EnrollTOTP(ctx context.Context, input EnrollTOTPInput) (TOTPEnrollment, error) {
+	if s == nil || s.authStore.Auth == nil || s.authStore.SubjectAuth == nil {
+		return TOTPEnrollment{}, oerrors.New(oerrors.CodeStorageUnavailable, "auth storage is not configured")
+	}
+	if s.mfa.SecretCipher == nil {
+		return TOTPEnrollment{}, oerrors.New(oerrors.CodeNotImplemented, "totp secret cipher is not configured")
+	}
+
+	input = input.Normalize()
+	if err := input.Validate(); err != nil {
+		return TOTPEnrollment{}, err
+	}
+
+	links, err := s.authStore.SubjectAuth.ListSubjectAuthBySubject(ctx, input.UserID)
+	if err != nil {
+		return TOTPEnrollment{}, oerrors.Wrap(oerrors.CodeStorageUnavailable, "failed to lookup subject auth records", err)
+	}
+	if len(links) < 1 {
+		return TOTPEnrollment{}, oerrors.New(oerrors.CodeNotFound, "user_id not found")
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return TOTPEnrollment{}, oerrors.Wrap(oerrors.CodeUnknown, "failed to generate totp secret", err)
+	}
+	uri, err := totp.KeyURI(s.mfa.Issuer, input.AccountName, secret, s.mfa.TOTP)
+	if err != nil {
+		return TOTPEnrollment{}, oerrors.Wrap(oerrors.CodeUnknown, "failed to build totp key uri", err)
+	}
+
+	authID := uuid.NewString()
+	sealed, err := s.mfa.SecretCipher.Encrypt(secret, []byte(authID))
+	if err != nil {
+		return TOTPEnrollment{}, oerrors.Wrap(oerrors.CodeUnknown, "failed to encrypt totp secret", err)
+	}
+
+	write := createAuthWrite{
+		authID:       authID,
+		userID:       input.UserID,
+		status:       storage.StatusInActive,
+		materialType: storage.AuthMaterialTypeTOTP,
+		materialHash: sealed,
+		metadata: map[string]string{
+			totpMetadataDigits: strconv.Itoa(s.mfa.TOTP.Digits),
+			totpMetadataPeriod: strconv.Itoa(int(s.mfa.TOTP.Period / time.Second)),
+		},
+	}
+	if err := s.withAuthMaterial(ctx, "enroll totp", func(stores storage.AuthMaterial, transactional bool) error {
+		return s.createAuthWithStores(ctx, stores, transactional, write)
+	}); err != nil {
+		return TOTPEnrollment{}, err
+	}
+
+	return TOTPEnrollment{
+		AuthID: authID,
+		Secret: totp.EncodeSecret(secret),
+		URI:    uri,
+	}, nil
+}
+
+// ConfirmTOTP activates a pending TOTP factor once input.Code verifies.
+func (s *AuthService) ConfirmTOTP(ctx context.Context, input ConfirmTOTPInput) error {
+	if s == nil || s.authStore.Auth == nil || s.authStore.SubjectAuth == nil {
+		return oerrors.New(oerrors.CodeStorageUnavailable, "auth storage is not configured")
+	}
+	if s.mfa.SecretCipher == nil {
+		return oerrors.New(oerrors.CodeNotImplemented, "totp secret cipher is not configured")
+	}
+
+	input = input.Normalize()
+	if err := input.Validate(); err != nil {
+		return err
+	}
+
+	links, err := s.authStore.SubjectAuth.ListSubjectAuthByAuthID(ctx, input.AuthID)
+	if err != nil {
+		return oerrors.Wrap(oerrors.CodeStorageUnavailable, "failed to lookup totp enrolment subject", err)
+	}
+	if len(links) != 1 || links[0].Subject != input.UserID {
+		return oerrors.New(oerrors.CodeNotFound, "totp enrolment not found")
+	}
+
+	record, err := s.authStore.Auth.GetAuth(ctx, input.AuthID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return oerrors.New(oerrors.CodeNotFound, "totp enrolment not found")
+	}
+	if err != nil {
+		return oerrors.Wrap(oerrors.CodeStorageUnavailable, "failed to retrieve totp enrolment", err)
+	}
+	if record.MaterialType != storage.AuthMaterialTypeTOTP {
+		return oerrors.New(oerrors.CodeNotFound, "totp enrolment not found")
+	}
+	if record.Status != storage.StatusInActive || record.Metadata[totpMetadataLastStep] != "" {
+		return oerrors.New(oerrors.CodeInvalidCredentials, "totp enrolment is not pending confirmation")
+	}
+
+	now := time.Now().UTC()
+	step, ok, err := s.verifyTOTPCode(record, input.Code, now)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		s.logAuthEvent(ctx, record.ID, input.UserID, storage.AuthLogEventFailed)
+		return oerrors.New(oerrors.CodeInvalidCredentials, "totp code is invalid")
+	}
+
+	record.Status = storage.StatusActive
+	record.DateModified = &now
+	record.Metadata = withTOTPLastStep(record.Metadata, step)
+	if err := s.authStore.Auth.PutAuth(ctx, record); err != nil {
+		return oerrors.Wrap(oerrors.CodeStorageUnavailable, "failed to activate totp enrolment", err)
+	}
+	s.logAuthEvent(ctx, record.ID, input.UserID, storage.AuthLogEventValidated)
+	return nil
+}
+
+// secondFactorMatch is a factor that verified during the second step.
+// consume re-checks the freshly read record inside the transaction and
+// returns it updated so the factor cannot be used again; consumeFactor
+// writes it back only if no concurrent login changed the record first.
+type secondFactorMatch struct {
+	authID  string
+	consume func(current storage.AuthRecord, now time.Time) (storage.AuthRecord, error)
+}
+
+// mfaMethods lists the second factors usable by a subject. Recovery codes
+// are only offered alongside another factor; on their own they never make a
+// password login require a second step.
+func mfaMethods(records []storage.AuthRecord, now time.Time) []InputType {
+	var totpEnrolled, recoveryCodes bool
+	for _, record := range records {
+		if !isUsableRecord(record, now) {
+			continue
+		}
+		switch record.MaterialType {
+		case storage.AuthMaterialTypeTOTP:
+			totpEnrolled = true
+		case storage.AuthMaterialTypeRecoveryCode:
+			recoveryCodes = true
+		}
+	}
+
+	methods := make([]InputType, 0, 2)
+	if totpEnrolled {
+		methods = append(methods, InputTypeTOTP)
+	}
+	if len(methods) > 0 && recoveryCodes {
+		methods = append(methods, InputTypeRecoveryCode)
+	}
+	return methods
+}
+
+// issueMFAChallenge starts the second step when records hold an active
+// second factor. It reports false when the password alone is sufficient.
+// Expired challenges left behind by abandoned logins are deleted first.
+func (s *AuthService) issueMFAChallenge(ctx context.Context, subject string, tenant string, records []storage.AuthRecord) (MFAChallenge, bool, error) {
+	now := time.Now().UTC()
+	methods := mfaMethods(records, now)
+	if len(methods) == 0 {
+		return MFAChallenge{}, false, nil
+	}
+
+	secret, err := newTokenSecret()
+	if err != nil {
+		return MFAChallenge{}, false, oerrors.Wrap(oerrors.CodeUnknown, "failed to generate mfa challenge", err)
+	}
+
+	expiresAt := now.Add(s.mfa.ChallengeTTL)
+	write := createAuthWrite{
+		authID:       uuid.NewString(),
+		userID:       subject,
+		materialType: storage.AuthMaterialTypeMFAChallenge,
+		materialHash: ocrypto.DigestSecret(secret),
+		expiresAt:    &expiresAt,
+		metadata:     map[string]string{mfaMetadataTenant: s.resolveTenant(tenant)},
+	}
+	if err := s.withAuthMaterial(ctx, "issue mfa challenge", func(stores storage.AuthMaterial, transactional bool) error {
+		s.pruneAuthMaterial(ctx, stores, subject, records, func(record storage.AuthRecord) bool {
+			return record.MaterialType == storage.AuthMaterialTypeMFAChallenge && !isUsableRecord(record, now)
+		})
+		return s.createAuthWithStores(ctx, stores, transactional, write)
+	}); err != nil {
+		return MFAChallenge{}, false, err
+	}
+
+	return MFAChallenge{
+		Token:     write.authID + tokenSeparator + secret,
+		Subject:   subject,
+		Methods:   methods,
+		ExpiresAt: expiresAt,
+	}, true, nil
+}
+
+// authorizeSecondFactor completes a login started by a password Authorize.
+// The challenge and the factor are consumed in one auth material
+// transaction so neither can be replayed.
+func (s *AuthService) authorizeSecondFactor(ctx context.Context, input AuthInput, source string, records []storage.AuthRecord, materialType storage.AuthMaterialType) (Principal, error) {
+	challengeID, challengeSecret, ok := parseSecretToken(input.Challenge)
+	if !ok {
+		return Principal{}, oerrors.New(oerrors.CodeInvalidCredentials, "mfa challenge is required")
+	}
+
+	now := time.Now().UTC()
+	var challenge *storage.AuthRecord
+	factors := make([]storage.AuthRecord, 0, 1)
+	for i, record := range records {
+		switch {
+		case record.ID == challengeID && record.MaterialType == storage.AuthMaterialTypeMFAChallenge:
+			challenge = &records[i]
+		case record.MaterialType == materialType && isUsableRecord(record, now):
+			factors = append(factors, record)
+		}
+	}
+	if challenge == nil || !isUsableRecord(*challenge, now) {
+		return Principal{}, oerrors.New(oerrors.CodeInvalidCredentials, "mfa challenge is invalid or expired")
+	}
+	if !ocrypto.VerifySecretDigest(challengeSecret, challenge.MaterialHash) {
+		return Principal{}, oerrors.New(oerrors.CodeInvalidCredentials, "mfa challenge is invalid or expired")
+	}
+	if len(factors) == 0 {
+		return Principal{}, oerrors.New(oerrors.CodeInvalidCredentials, "no valid input auth record found for user_id")
+	}
+
+	var matched *secondFactorMatch
+	var err error
+	switch materialType {
+	case storage.AuthMaterialTypeTOTP:
+		matched, err = s.matchTOTP(factors, input.Value, now)
+	case storage.AuthMaterialTypeRecoveryCode:
+		matched, err = s.matchRecoveryCode(factors, input.Value)
+	default:
+		err = oerrors.New(oerrors.CodeNotImplemented, "auth input type is not implemented")
+	}
+	if err != nil {
+		return Principal{}, err
+	}
+	if matched == nil {
+		s.logAuthEventWith(ctx, s.authStore, factors[0].ID, input.UserID, storage.AuthLogEventFailed, sourceMetadata(source))
+		s.recordAuthFailure(ctx, input.UserID, source)
+		if err := s.recordChallengeFailure(ctx, challenge.ID, now); err != nil {
+			s.logger.Error(err, "failed to record mfa challenge failure", "auth_id", challenge.ID, "subject", input.UserID)
+		}
+		return Principal{}, oerrors.New(oerrors.CodeInvalidCredentials, "authentication failed")
+	}
+
+	err = s.consumeFactor(ctx, "verify second factor", challenge.ID, *matched, input.UserID, source, storage.AuthLogEventUsed, now)
+	if err != nil {
+		if oerrors.IsCode(err, oerrors.CodeInvalidCredentials) {
+			s.recordAuthFailure(ctx, input.UserID, source)
+		}
+		return Principal{}, err
+	}
+
+	s.resetAuthFailures(ctx, input.UserID, source)
+	return s.loginPrincipal(ctx, input.UserID, challenge.Metadata[mfaMetadataTenant], now)
+}
+
+// consumeFactor consumes a login challenge and applies matched.consume to
+// the factor in one auth material transaction, logging factorEvent for the
+// factor.
+func (s *AuthService) consumeFactor(ctx context.Context, operation string, challengeID string, matched secondFactorMatch, subject string, source string, factorEvent storage.AuthLogEvent, now time.Time) error {
+	return s.withAuthMaterial(ctx, operation, func(stores storage.AuthMaterial, transactional bool) error {
+		_ = transactional
+
+		if err := consumeChallenge(ctx, stores, challengeID, now); err != nil {
+			return err
+		}
+
+		current, err := stores.Auth.GetAuth(ctx, matched.authID)
+		if err != nil {
+			return oerrors.Wrap(oerrors.CodeStorageUnavailable, "failed to retrieve factor record", err)
+		}
+		factor, err := matched.consume(current, now)
+		if err != nil {
+			return err
+		}
+		factor.DateModified = &now
+		swapped, err := stores.Auth.SwapAuth(ctx, factor, current.DateModified)
+		if err != nil {
+			return oerrors.Wrap(oerrors.CodeStorageUnavailable, "failed to record factor use", err)
+		}
+		if !swapped {
+			return oerrors.New(oerrors.CodeInvalidCredentials, "factor was used concurrently")
+		}
+
+		s.logAuthEventWith(ctx, stores, factor.ID, subject, factorEvent, sourceMetadata(source))
+		return nil
+	})
+}
+
+// recordChallengeFailure counts a wrong second factor on the challenge and
+// consumes it after mfaChallengeMaxFailures. A lost update means another
+// attempt raced this one, so the challenge is consumed rather than retried.
+func (s *AuthService) recordChallengeFailure(ctx context.Context, challengeID string, now time.Time) error {
+	return s.withAuthMaterial(ctx, "record mfa challenge failure", func(stores storage.AuthMaterial, transactional bool) error {
+		_ = transactional
+
+		current, err := stores.Auth.GetAuth(ctx, challengeID)
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return oerrors.Wrap(oerrors.CodeStorageUnavailable, "failed to retrieve mfa challenge", err)
+		}
+
+		failures, _ := strconv.Atoi(current.Metadata[mfaMetadataFailures])
+		failures++
+		if failures < mfaChallengeMaxFailures {
+			updated := current
+			updated.Metadata = withMetadataValue(current.Metadata, mfaMetadataFailures, strconv.Itoa(failures))
+			updated.DateModified = &now
+			swapped, err := stores.Auth.SwapAuth(ctx, updated, current.DateModified)
+			if err != nil {
+				return oerrors.Wrap(oerrors.CodeStorageUnavailable, "failed to record mfa challenge failure", err)
+			}
+			if swapped {
+				return nil
+			}
+		}
+
+		err = consumeChallenge(ctx, stores, challengeID, now)
+		if oerrors.IsCode(err, oerrors.CodeInvalidCredentials) {
+			return nil
+		}
+		return err
+	})
+}
+
+// consumeChallenge deactivates a challenge with a conditional status swap,
+// so a challenge completes at most one ceremony even under concurrent use,
+// and then deletes it.
+func consumeChallenge(ctx context.Context, stores storage.AuthMaterial, challengeID string, now time.Time) error {
+	swapped, err := stores.Auth.SwapAuthStatus(ctx, challengeID, storage.StatusActive, storage.StatusInActive, now)
+	if err != nil {
+		return oerrors.Wrap(oerrors.CodeStorageUnavailable, "failed to consume challenge", err)
+	}
+	if !swapped {
+		return oerrors.New(oerrors.CodeInvalidCredentials, "challenge is invalid or expired")
+	}
+	if err := deleteAuthMaterial(ctx, stores, challengeID); err != nil {
+		return oerrors.Wrap(oerrors.CodeStorageUnavailable, "failed to delete consumed challenge", err)
+	}
+	return nil
+}
+
+// deleteAuthMaterial deletes an auth record and its subject links. The SQL
+// adapters cascade the links as well; deleting them first keeps stores that
+// do not cascade consistent.
+func deleteAuthMaterial(ctx context.Context, stores storage.AuthMaterial, authID string) error {
+	links, err := stores.SubjectAuth.ListSubjectAuthByAuthID(ctx, authID)
+	if err != nil {
+		return err
+	}
+	for _, link := range links {
+		if err := stores.SubjectAuth.DeleteSubjectAuth(ctx, link.ID); err != nil {
+			return err
+		}
+	}
+	return stores.Auth.DeleteAuth(ctx, authID)
+}
+
+// pruneAuthMaterial deletes the records of subject selected by stale.
+// Pruning is housekeeping: failures are logged and never fail the caller.
+func (s *AuthService) pruneAuthMaterial(ctx context.Context, stores storage.AuthMaterial, subject string, records []storage.AuthRecord, stale func(storage.AuthRecord) bool) {
+	for _, record := range records {
+		if !stale(record) {
+			continue
+		}
+		if err := deleteAuthMaterial(ctx, stores, record.ID); err != nil {
+			s.logger.Error(err, "failed to prune stale auth record", "auth_id", record.ID, "subject", subject, "material_type", record.MaterialType)
+		}
+	}
+}
+
+func (s *AuthService) matchTOTP(factors []storage.AuthRecord, code string, now time.Time) (*secondFactorMatch, error) {
+	if s.mfa.SecretCipher == nil {
+		return nil, oerrors.New(oerrors.CodeNotImplemented, "totp secret cipher is not configured")
+	}
+
+	for _, factor := range factors {
+		step, ok, err := s.verifyTOTPCode(factor, code, now)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		return &secondFactorMatch{
+			authID: factor.ID,
+			consume: func(current storage.AuthRecord, now time.Time) (storage.AuthRecord, error) {
+				if last, ok := totpLastStep(current); ok && step <= last {
+					return storage.AuthRecord{}, oerrors.New(oerrors.CodeInvalidCredentials, "totp code was already used")
+				}
+				current.Metadata = withTOTPLastStep(current.Metadata, step)
+				return current, nil
+			},
+		}, nil
+	}
+	return nil, nil
+}
+
+// verifyTOTPCode checks code against record and rejects time steps at or
+// before the last accepted one.
+func (s *AuthService) verifyTOTPCode(record storage.AuthRecord, code string, now time.Time) (int64, bool, error) {
+	secret, err := s.mfa.SecretCipher.Decrypt(record.MaterialHash, []byte(record.ID))
+	if err != nil {
+		return 0, false, oerrors.Wrap(oerrors.CodeUnknown, "failed to decrypt totp secret", err)
+	}
+
+	options := s.mfa.TOTP
+	if digits, err := strconv.Atoi(record.Metadata[totpMetadataDigits]); err == nil {
+		options.Digits = digits
+	}
+	if period, err := strconv.Atoi(record.Metadata[totpMetadataPeriod]); err == nil {
+		options.Period = time.Duration(period) * time.Second
+	}
+
+	step, ok, err := totp.Verify(secret, code, now, options)
+	if err != nil {
+		return 0, false, oerrors.Wrap(oerrors.CodeUnknown, "failed to verify totp code", err)
+	}
+	if !ok {
+		return 0, false, nil
+	}
+	if last, found := totpLastStep(record); found && step <= last {
+		return 0, false, nil
+	}
+	return step, true, nil
+}
+
+func totpLastStep(record storage.AuthRecord) (int64, bool) {
+	last, err := strconv.ParseInt(record.Metadata[totpMetadataLastStep], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return last, true
+}
+
+func withTOTPLastStep(metadata map[string]string, step int64) map[string]string {
+	return withMetadataValue(metadata, totpMetadataLastStep, strconv.FormatInt(step, 10))
+}
+
+// withMetadataValue returns a copy of metadata with key set, leaving the
+// caller's map untouched.
+func withMetadataValue(metadata map[string]string, key string, value string) map[string]string {
+	updated := make(map[string]string, len(metadata)+1)
+	for k, v := range metadata {
+		updated[k] = v
+	}
+	updated[key] = value
+	return updated
+}
+
+func isUsableRecord(record storage.AuthRecord, now time.Time) bool {
+	return record.Status == storage.StatusActive && (record.ExpiresAt == nil || record.ExpiresAt.After(now))
+}
+
+func mfaRequired(challenge MFAChallenge) error {
+	return oerrors.Wrap(oerrors.CodeMFARequired, "second factor required", &MFARequiredError{Challenge: challenge})
+}
@@ -0,0 +1,223 @@
+package openauth
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	ocrypto "github.com/porthorian/openauth/pkg/crypto"
+	oerrors "github.com/porthorian/openauth/pkg/errors"
+	"github.com/porthorian/openauth/pkg/mfa/totp"
+	"github.com/porthorian/openauth/pkg/session"
+	"github.com/porthorian/openauth/pkg/session/jwt"
+	"github.com/porthorian/openauth/pkg/storage"
+)
+
+func newMFATestService(t *testing.T) (*AuthService, *memoryAuthStore) {
+	t.Helper()
+
+	keyring, err := jwt.NewKeyring(jwt.KeyringConfig{Keys: []jwt.KeyringEntry{{
+		Key:       session.Key{ID: "mfa-1", Algorithm: "HS256", Material: []byte("mfa-secret-key")},
+		NotBefore: time.Now().Add(-time.Minute),
+	}}})
+	if err != nil {
+		t.Fatalf("NewKeyring returned error: %v", err)
+	}
+	cipher, err := ocrypto.NewAESGCMCipher(keyring)
+	if err != nil {
+		t.Fatalf("NewAESGCMCipher returned error: %v", err)
+	}
+
+	authStore := &memoryAuthStore{
+		records: map[string]storage.AuthRecord{
+			"auth-1": {
+				ID:           "auth-1",
+				Status:       storage.StatusActive,
+				MaterialType: storage.AuthMaterialTypePassword,
+				MaterialHash: "pass-123",
+			},
+		},
+	}
+	subjectStore := &memorySubjectAuthStore{}
+	if err := subjectStore.PutSubjectAuth(context.Background(), storage.SubjectAuthRecord{ID: "link-1", Subject: "user-1", AuthID: "auth-1"}); err != nil {
+		t.Fatalf("PutSubjectAuth returned error: %v", err)
+	}
+
+	service, err := NewAuthService(Config{
+		AuthStore: storage.AuthMaterial{
+			Auth:        authStore,
+			SubjectAuth: subjectStore,
+			AuthLog:     &recordingAuthLogStore{},
+		},
+		AuthdStore: storage.AuthdMaterial{
+			Role: &memoryRoleStore{
+				data: map[string][]string{"user-1|tenant-a": {"viewer"}},
+			},
+			Permission: &memoryPermissionStore{},
+		},
+		Hasher: staticHasher{},
+		Authorization: AuthorizationConfig{
+			Registry: AuthorizationRegistry{
+				Permissions: []PermissionDefinition{{Key: "read", Bit: 0}},
+				Roles:       []RoleDefinition{{Key: "viewer", Bit: 0, Permissions: []string{"read"}}},
+			},
+		},
+		MFA: MFAConfig{Issuer: "Example", SecretCipher: cipher},
+	})
+	if err != nil {
+		t.Fatalf("NewAuthService returned error: %v", err)
+	}
+	return service, authStore
+}
+
+func TestTOTPEnrollmentAndTwoStepAuthorize(t *testing.T) {
+	service, authStore := newMFATestService(t)
+	ctx := context.Background()
+	password := AuthInput{UserID: "user-1", Tenant: "tenant-a", Type: InputTypePassword, Value: "pass-123"}
+
+	enrollment, err := service.EnrollTOTP(ctx, EnrollTOTPInput{UserID: "user-1", AccountName: "user@example.com"})
+	if err != nil {
+		t.Fatalf("EnrollTOTP returned error: %v", err)
+	}
+	if !strings.HasPrefix(enrollment.URI, "otpauth://totp/Example:user@example.com?") {
+		t.Fatalf("unexpected enrolment URI: %s", enrollment.URI)
+	}
+	stored := authStore.records[enrollment.AuthID]
+	if stored.Status != storage.StatusInActive || strings.Contains(stored.MaterialHash, enrollment.Secret) {
+		t.Fatalf("stored totp record = %+v, want pending with encrypted secret", stored)
+	}
+
+	if _, err := service.Authorize(ctx, password); err != nil {
+		t.Fatalf("unconfirmed enrolment must not require mfa, got %v", err)
+	}
+
+	secret, err := totp.DecodeSecret(enrollment.Secret)
+	if err != nil {
+		t.Fatalf("DecodeSecret returned error: %v", err)
+	}
+	now := time.Now()
+	code, _ := totp.Code(secret, now, totp.DefaultOptions())
+	if err := service.ConfirmTOTP(ctx, ConfirmTOTPInput{UserID: "user-1", AuthID: enrollment.AuthID, Code: "000000" + code}); !oerrors.IsCode(err, oerrors.CodeInvalidCredentials) {
+		t.Fatalf("ConfirmTOTP with wrong code error = %v, want invalid credentials", err)
+	}
+	if err := service.ConfirmTOTP(ctx, ConfirmTOTPInput{UserID: "user-1", AuthID: enrollment.AuthID, Code: code}); err != nil {
+		t.Fatalf("ConfirmTOTP returned error: %v", err)
+	}
+
+	_, err = service.Authorize(ctx, password)
+	if !oerrors.IsCode(err, oerrors.CodeMFARequired) {
+		t.Fatalf("password Authorize error = %v, want mfa required", err)
+	}
+	challenge, ok := MFAChallengeFromError(err)
+	if !ok || challenge.Subject != "user-1" || len(challenge.Methods) != 1 || challenge.Methods[0] != InputTypeTOTP {
+		t.Fatalf("challenge = %+v, want totp challenge for user-1", challenge)
+	}
+	challengeID, _, _ := parseSecretToken(challenge.Token)
+	if stored := authStore.records[challengeID]; !strings.HasPrefix(stored.MaterialHash, ocrypto.SHA256DigestPrefix) {
+		t.Fatalf("stored challenge = %+v, want digested challenge material", stored)
+	}
+
+	second := AuthInput{UserID: "user-1", Type: InputTypeTOTP, Value: code, Challenge: challenge.Token}
+	if _, err := service.Authorize(ctx, AuthInput{UserID: "user-1", Type: InputTypeTOTP, Value: code}); !oerrors.IsCode(err, oerrors.CodeInvalidCredentials) {
+		t.Fatalf("totp without challenge error = %v, want invalid credentials", err)
+	}
+	if _, err := service.Authorize(ctx, second); !oerrors.IsCode(err, oerrors.CodeInvalidCredentials) {
+		t.Fatalf("replayed confirmation code error = %v, want invalid credentials", err)
+	}
+
+	second.Value, _ = totp.Code(secret, now.Add(30*time.Second), totp.DefaultOptions())
+	principal, err := service.Authorize(ctx, second)
+	if err != nil {
+		t.Fatalf("second step Authorize returned error: %v", err)
+	}
+	if principal.Subject != "user-1" || principal.Tenant != "tenant-a" {
+		t.Fatalf("principal = %+v, want user-1 in the challenge tenant", principal)
+	}
+	if ok, _ := service.HasAllRoles(principal, "viewer"); !ok {
+		t.Fatalf("expected viewer role after second step")
+	}
+
+	if _, err := service.Authorize(ctx, second); !oerrors.IsCode(err, oerrors.CodeInvalidCredentials) {
+		t.Fatalf("reused challenge error = %v, want invalid credentials", err)
+	}
+
+	_, err = service.Authorize(ctx, password)
+	fresh, _ := MFAChallengeFromError(err)
+	second.Challenge = fresh.Token
+	if _, err := service.Authorize(ctx, second); !oerrors.IsCode(err, oerrors.CodeInvalidCredentials) {
+		t.Fatalf("replayed code with fresh challenge error = %v, want invalid credentials", err)
+	}
+}
+
+func TestEnrollTOTPRequiresCipher(t *testing.T) {
+	service, _ := newMFATestService(t)
+	service.mfa.SecretCipher = nil
+
+	if _, err := service.EnrollTOTP(context.Background(), EnrollTOTPInput{UserID: "user-1"}); !oerrors.IsCode(err, oerrors.CodeNotImplemented) {
+		t.Fatalf("EnrollTOTP without cipher error = %v, want not implemented", err)
+	}
+}
+
+func TestIssueMFAChallengePrunesExpiredChallenges(t *testing.T) {
+	service, authStore := newMFATestService(t)
+	subjectStore := service.authStore.SubjectAuth.(*memorySubjectAuthStore)
+	ctx := context.Background()
+	password := AuthInput{UserID: "user-1", Type: InputTypePassword, Value: "pass-123"}
+	enrollConfirmedTOTP(t, service)
+
+	_, err := service.Authorize(ctx, password)
+	abandoned, _ := MFAChallengeFromError(err)
+	abandonedID, _, _ := parseSecretToken(abandoned.Token)
+	record := authStore.records[abandonedID]
+	past := time.Now().UTC().Add(-time.Minute)
+	record.ExpiresAt = &past
+	authStore.records[abandonedID] = record
+
+	_, err = service.Authorize(ctx, password)
+	fresh, _ := MFAChallengeFromError(err)
+	freshID, _, _ := parseSecretToken(fresh.Token)
+	if _, ok := authStore.records[abandonedID]; ok {
+		t.Fatalf("expected the expired challenge to be deleted")
+	}
+	if links := subjectStore.byAuthID[abandonedID]; len(links) != 0 {
+		t.Fatalf("expected the expired challenge's subject link to be deleted, got %+v", links)
+	}
+	if authStore.records[freshID].Status != storage.StatusActive {
+		t.Fatalf("expected the fresh challenge to be active")
+	}
+}
+
+func TestMFAChallengeIsConsumedAfterRepeatedFailures(t *testing.T) {
+	service, authStore := newMFATestService(t)
+	service.lockout = LockoutPolicy{Disabled: true}
+	ctx := context.Background()
+	secret := enrollConfirmedTOTP(t, service)
+
+	_, err := service.Authorize(ctx, AuthInput{UserID: "user-1", Type: InputTypePassword, Value: "pass-123"})
+	challenge, ok := MFAChallengeFromError(err)
+	if !ok {
+		t.Fatalf("password Authorize error = %v, want mfa required", err)
+	}
+	challengeID, _, _ := parseSecretToken(challenge.Token)
+
+	wrong := AuthInput{UserID: "user-1", Type: InputTypeTOTP, Value: "12345", Challenge: challenge.Token}
+	for attempt := 1; attempt <= mfaChallengeMaxFailures; attempt++ {
+		if _, err := service.Authorize(ctx, wrong); !oerrors.IsCode(err, oerrors.CodeInvalidCredentials) {
+			t.Fatalf("attempt %d: wrong code error = %v, want invalid credentials", attempt, err)
+		}
+		record, exists := authStore.records[challengeID]
+		if attempt < mfaChallengeMaxFailures && (!exists || record.Status != storage.StatusActive) {
+			t.Fatalf("attempt %d: expected the challenge to stay active, got %+v", attempt, record)
+		}
+		if attempt == mfaChallengeMaxFailures && exists {
+			t.Fatalf("expected the challenge to be consumed after %d failures, got %+v", attempt, record)
+		}
+	}
+
+	code, _ := totp.Code(secret, time.Now().Add(30*time.Second), totp.DefaultOptions())
+	right := AuthInput{UserID: "user-1", Type: InputTypeTOTP, Value: code, Challenge: challenge.Token}
+	if _, err := service.Authorize(ctx, right); !oerrors.IsCode(err, oerrors.CodeInvalidCredentials) {
+		t.Fatalf("correct code on a consumed challenge error = %v, want invalid credentials", err)
+	}
+}
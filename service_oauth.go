@@ -0,0 +1,299 @@
+package openauth
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	ocrypto "github.com/porthorian/openauth/pkg/crypto"
+	oerrors "github.com/porthorian/openauth/pkg/errors"
+	"github.com/porthorian/openauth/pkg/storage"
+)
+
+const (
+	// OAuthClientSubjectPrefix keeps OAuth clients out of the user subject
+	// namespace. CreateAuth rejects user IDs that start with it.
+	OAuthClientSubjectPrefix   = "client:"
+	clientSecretMetadataTenant = "tenant"
+
+	oauthClaimClientID  = "client_id"
+	oauthClaimGrantType = "grant_type"
+	oauthClaimScope     = "scope"
+)
+
+var _ OAuthClientManager = (*AuthService)(nil)
+
+// OAuthClientSubject is the subject a client's secrets are linked to and its
+// roles and permissions are assigned to.
+func OAuthClientSubject(clientID string) string {
+	return OAuthClientSubjectPrefix + clientID
+}
+
+// RegisterOAuthClient stores a new client. Secrets are issued separately
+// with CreateClientSecret.
+func (s *AuthService) RegisterOAuthClient(ctx context.Context, input RegisterOAuthClientInput) (OAuthClient, error) {
+	if s == nil || s.oauthClients == nil {
+		return OAuthClient{}, oerrors.New(oerrors.CodeStorageUnavailable, "oauth client storage is not configured")
+	}
+
+	input = input.Normalize()
+	if err := input.Validate(); err != nil {
+		return OAuthClient{}, err
+	}
+	if input.ClientID == "" {
+		input.ClientID = uuid.NewString()
+	}
+
+	_, err := s.oauthClients.GetOAuthClient(ctx, input.ClientID)
+	if err == nil {
+		return OAuthClient{}, oerrors.New(oerrors.CodeInvalidCredentials, "client_id is already registered")
+	}
+	if !errors.Is(err, storage.ErrNotFound) {
+		return OAuthClient{}, oerrors.Wrap(oerrors.CodeStorageUnavailable, "failed to lookup oauth client", err)
+	}
+
+	record := storage.OAuthClientRecord{
+		ID:           input.ClientID,
+		Name:         input.Name,
+		Tenant:       s.resolveTenant(input.Tenant),
+		GrantTypes:   input.GrantTypes,
+		Scopes:       input.Scopes,
+		RedirectURIs: input.RedirectURIs,
+		DateAdded:    time.Now().UTC(),
+	}
+	if err := s.oauthClients.PutOAuthClient(ctx, record); err != nil {
+		return OAuthClient{}, oerrors.Wrap(oerrors.CodeStorageUnavailable, "failed to store oauth client", err)
+	}
+	return oauthClientFromRecord(record), nil
+}
+
+func (s *AuthService) GetOAuthClient(ctx context.Context, clientID string) (OAuthClient, error) {
+	record, err := s.lookupOAuthClient(ctx, clientID)
+	if err != nil {
+		return OAuthClient{}, err
+	}
+	return oauthClientFromRecord(record), nil
+}
+
+// DeleteOAuthClient revokes the client's secrets before removing it, so a
+// client registered again under the same ID starts without any.
+func (s *AuthService) DeleteOAuthClient(ctx context.Context, clientID string) error {
+	record, err := s.lookupOAuthClient(ctx, clientID)
+	if err != nil {
+		return err
+	}
+
+	if s.authStore.Auth != nil && s.authStore.SubjectAuth != nil {
+		secrets, err := s.subjectAuthRecords(ctx, OAuthClientSubject(record.ID))
+		if err != nil && !oerrors.IsCode(err, oerrors.CodeNotFound) {
+			return err
+		}
+		for _, secret := range secrets {
+			if secret.MaterialType != storage.AuthMaterialTypeClientSecret || secret.Status == storage.StatusRevoked {
+				continue
+			}
+			if err := s.RevokeAuth(ctx, secret.ID, "oauth client deleted"); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := s.oauthClients.DeleteOAuthClient(ctx, record.ID); err != nil {
+		return oerrors.Wrap(oerrors.CodeStorageUnavailable, "failed to delete oauth client", err)
+	}
+	return nil
+}
+
+// CreateClientSecret issues a secret for a registered client. The returned
+// Secret is only available here; storage keeps a SHA-256 digest of it.
+// Earlier secrets stay valid until they expire or are revoked, which allows
+// rotation without downtime.
+func (s *AuthService) CreateClientSecret(ctx context.Context, input CreateClientSecretInput) (ClientSecret, error) {
+	if s == nil || s.authStore.Auth == nil || s.authStore.SubjectAuth == nil {
+		return ClientSecret{}, oerrors.New(oerrors.CodeStorageUnavailable, "auth storage is not configured")
+	}
+
+	input = input.Normalize()
+	if err := input.Validate(); err != nil {
+		return ClientSecret{}, err
+	}
+	client, err := s.lookupOAuthClient(ctx, input.ClientID)
+	if err != nil {
+		return ClientSecret{}, err
+	}
+
+	ttl := input.TTL
+	if ttl == 0 {
+		policy, _ := s.policyFor(storage.AuthProfileClientSecret)
+		ttl = policy.DefaultTTL
+	}
+	if ttl <= 0 {
+		return ClientSecret{}, oerrors.New(oerrors.CodeInvalidCredentials, "client secret ttl is required")
+	}
+	secret, err := newTokenSecret()
+	if err != nil {
+		return ClientSecret{}, oerrors.Wrap(oerrors.CodeUnknown, "failed to generate client secret", err)
+	}
+
+	expiresAt := time.Now().UTC().Add(ttl)
+	write := createAuthWrite{
+		authID:       uuid.NewString(),
+		userID:       OAuthClientSubject(client.ID),
+		materialType: storage.AuthMaterialTypeClientSecret,
+		materialHash: ocrypto.DigestSecret(secret),
+		expiresAt:    &expiresAt,
+		metadata:     map[string]string{clientSecretMetadataTenant: client.Tenant},
+	}
+	if err := s.withAuthMaterial(ctx, "create client secret", func(stores storage.AuthMaterial, transactional bool) error {
+		return s.createAuthWithStores(ctx, stores, transactional, write)
+	}); err != nil {
+		return ClientSecret{}, err
+	}
+
+	return ClientSecret{
+		Secret:    write.authID + tokenSeparator + secret,
+		AuthID:    write.authID,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// AuthenticateClient verifies a client secret and returns a principal for
+// the client itself, scoped to the requested grant type and scopes. Like
+// API keys, secrets carry enough entropy that they are checked against a
+// fast digest and failures do not count towards lockout.
+func (s *AuthService) AuthenticateClient(ctx context.Context, input AuthenticateClientInput) (Principal, error) {
+	if s == nil || s.authStore.Auth == nil || s.authStore.SubjectAuth == nil {
+		return Principal{}, oerrors.New(oerrors.CodeStorageUnavailable, "auth storage is not configured")
+	}
+	if s.authdStore.Role == nil || s.authdStore.Permission == nil {
+		return Principal{}, oerrors.New(oerrors.CodeStorageUnavailable, "authorization storage is not configured")
+	}
+
+	input = input.Normalize()
+	if err := input.Validate(); err != nil {
+		return Principal{}, err
+	}
+	client, err := s.lookupOAuthClient(ctx, input.ClientID)
+	if oerrors.IsCode(err, oerrors.CodeNotFound) {
+		return Principal{}, oerrors.New(oerrors.CodeInvalidCredentials, "invalid client credentials")
+	}
+	if err != nil {
+		return Principal{}, err
+	}
+
+	authID, secret, ok := parseSecretToken(input.Secret)
+	if !ok {
+		return Principal{}, oerrors.New(oerrors.CodeInvalidCredentials, "malformed client secret")
+	}
+	record, err := s.authStore.Auth.GetAuth(ctx, authID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return Principal{}, oerrors.New(oerrors.CodeInvalidCredentials, "invalid client credentials")
+	}
+	if err != nil {
+		return Principal{}, oerrors.Wrap(oerrors.CodeStorageUnavailable, "failed to retrieve client secret record", err)
+	}
+	if record.MaterialType != storage.AuthMaterialTypeClientSecret {
+		return Principal{}, oerrors.New(oerrors.CodeInvalidCredentials, "invalid client credentials")
+	}
+	subject := OAuthClientSubject(client.ID)
+	owner, err := s.authSubject(ctx, authID)
+	if oerrors.IsCode(err, oerrors.CodeNotFound) || (err == nil && owner != subject) {
+		return Principal{}, oerrors.New(oerrors.CodeInvalidCredentials, "invalid client credentials")
+	}
+	if err != nil {
+		return Principal{}, err
+	}
+
+	if !ocrypto.VerifySecretDigest(secret, record.MaterialHash) {
+		s.logAuthEventWith(ctx, s.authStore, record.ID, subject, storage.AuthLogEventFailed, sourceMetadata(input.Source))
+		return Principal{}, oerrors.New(oerrors.CodeInvalidCredentials, "invalid client credentials")
+	}
+
+	now := time.Now().UTC()
+	switch {
+	case record.Status != storage.StatusActive:
+		return Principal{}, oerrors.New(oerrors.CodeInvalidCredentials, "client secret is no longer active")
+	case record.ExpiresAt != nil && !record.ExpiresAt.After(now):
+		record.Status = storage.StatusExpired
+		record.DateModified = &now
+		if err := s.authStore.Auth.PutAuth(ctx, record); err != nil {
+			s.logger.Error(err, "failed to persist expired auth status", "auth_id", record.ID, "subject", subject)
+		}
+		s.logAuthEvent(ctx, record.ID, subject, storage.AuthLogEventExpired)
+		return Principal{}, oerrors.New(oerrors.CodeCredentialsExpired, "client secret has expired")
+	}
+
+	if !slices.Contains(client.GrantTypes, input.GrantType) {
+		return Principal{}, oerrors.New(oerrors.CodePermissionDenied, "grant type is not allowed for client")
+	}
+	scopes := client.Scopes
+	if len(input.Scopes) > 0 {
+		for _, scope := range input.Scopes {
+			if !slices.Contains(client.Scopes, scope) {
+				return Principal{}, oerrors.New(oerrors.CodePermissionDenied, "scope is not allowed for client: "+scope)
+			}
+		}
+		scopes = input.Scopes
+	}
+
+	var expiresAt time.Time
+	if record.ExpiresAt != nil {
+		expiresAt = *record.ExpiresAt
+	}
+	policy, _ := s.policyFor(storage.AuthProfileClientSecret)
+	authzPolicy := newAuthorizationPolicy(storage.AuthProfileClientSecret, policy, expiresAt, now)
+	roleMask, permissionMask, degraded, err := s.resolveAuthorizationWithPolicy(ctx, subject, client.Tenant, authzPolicy)
+	if err != nil {
+		return Principal{}, err
+	}
+
+	s.logAuthEventWith(ctx, s.authStore, record.ID, subject, storage.AuthLogEventUsed, sourceMetadata(input.Source))
+	return Principal{
+		Subject:        subject,
+		Tenant:         client.Tenant,
+		RoleMask:       roleMask,
+		PermissionMask: permissionMask,
+		Claims: Claims{
+			oauthClaimClientID:  client.ID,
+			oauthClaimGrantType: input.GrantType,
+			oauthClaimScope:     strings.Join(scopes, " "),
+		},
+		AuthenticatedAt: now,
+		Degraded:        degraded,
+	}, nil
+}
+
+func (s *AuthService) lookupOAuthClient(ctx context.Context, clientID string) (storage.OAuthClientRecord, error) {
+	if s == nil || s.oauthClients == nil {
+		return storage.OAuthClientRecord{}, oerrors.New(oerrors.CodeStorageUnavailable, "oauth client storage is not configured")
+	}
+
+	clientID = strings.TrimSpace(clientID)
+	if clientID == "" {
+		return storage.OAuthClientRecord{}, oerrors.New(oerrors.CodeInvalidCredentials, "client_id is required")
+	}
+	record, err := s.oauthClients.GetOAuthClient(ctx, clientID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return storage.OAuthClientRecord{}, oerrors.New(oerrors.CodeNotFound, "client_id not found")
+	}
+	if err != nil {
+		return storage.OAuthClientRecord{}, oerrors.Wrap(oerrors.CodeStorageUnavailable, "failed to lookup oauth client", err)
+	}
+	return record, nil
+}
+
+func oauthClientFromRecord(record storage.OAuthClientRecord) OAuthClient {
+	return OAuthClient{
+		ClientID:     record.ID,
+		Name:         record.Name,
+		Tenant:       record.Tenant,
+		GrantTypes:   record.GrantTypes,
+		Scopes:       record.Scopes,
+		RedirectURIs: record.RedirectURIs,
+		DateAdded:    record.DateAdded,
+		DateModified: record.DateModified,
+	}
+}
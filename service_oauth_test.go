@@ -0,0 +1,171 @@
+package openauth
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	ocrypto "github.com/porthorian/openauth/pkg/crypto"
+	oerrors "github.com/porthorian/openauth/pkg/errors"
+	"github.com/porthorian/openauth/pkg/protocol/oauth"
+	"github.com/porthorian/openauth/pkg/storage"
+)
+
+type memoryOAuthClientStore struct {
+	records map[string]storage.OAuthClientRecord
+}
+
+func (m *memoryOAuthClientStore) PutOAuthClient(_ context.Context, record storage.OAuthClientRecord) error {
+	if m.records == nil {
+		m.records = map[string]storage.OAuthClientRecord{}
+	}
+	m.records[record.ID] = record
+	return nil
+}
+
+func (m *memoryOAuthClientStore) GetOAuthClient(_ context.Context, id string) (storage.OAuthClientRecord, error) {
+	record, ok := m.records[id]
+	if !ok {
+		return storage.OAuthClientRecord{}, storage.ErrNotFound
+	}
+	return record, nil
+}
+
+func (m *memoryOAuthClientStore) DeleteOAuthClient(_ context.Context, id string) error {
+	delete(m.records, id)
+	return nil
+}
+
+func TestAuthenticateClientWithSecret(t *testing.T) {
+	service, authStore, logStore := newRefreshTestService(t)
+	service.oauthClients = &memoryOAuthClientStore{}
+	ctx := context.Background()
+
+	client, err := service.RegisterOAuthClient(ctx, RegisterOAuthClientInput{
+		ClientID: "billing-worker",
+		Tenant:   "tenant-a",
+		Scopes:   []string{"invoices:read", "invoices:write"},
+	})
+	if err != nil {
+		t.Fatalf("RegisterOAuthClient returned error: %v", err)
+	}
+	if len(client.GrantTypes) != 1 || client.GrantTypes[0] != oauth.GrantTypeClientCredentials {
+		t.Fatalf("GrantTypes = %v, want client_credentials only", client.GrantTypes)
+	}
+	if _, err := service.RegisterOAuthClient(ctx, RegisterOAuthClientInput{ClientID: "billing-worker"}); !oerrors.IsCode(err, oerrors.CodeInvalidCredentials) {
+		t.Fatalf("RegisterOAuthClient with duplicate id error = %v, want invalid credentials", err)
+	}
+
+	secret, err := service.CreateClientSecret(ctx, CreateClientSecretInput{ClientID: client.ClientID})
+	if err != nil {
+		t.Fatalf("CreateClientSecret returned error: %v", err)
+	}
+	stored := authStore.records[secret.AuthID]
+	if stored.MaterialType != storage.AuthMaterialTypeClientSecret || !strings.HasPrefix(stored.MaterialHash, ocrypto.SHA256DigestPrefix) || stored.ExpiresAt == nil {
+		t.Fatalf("stored record = %+v, want digested, expiring client secret material", stored)
+	}
+
+	principal, err := service.AuthenticateClient(ctx, AuthenticateClientInput{ClientID: client.ClientID, Secret: secret.Secret, Scopes: []string{"invoices:read"}})
+	if err != nil {
+		t.Fatalf("AuthenticateClient returned error: %v", err)
+	}
+	if principal.Subject != OAuthClientSubject("billing-worker") || principal.Tenant != "tenant-a" {
+		t.Fatalf("principal = %+v, want client:billing-worker in tenant-a", principal)
+	}
+	if principal.Claims[oauthClaimScope] != "invoices:read" || principal.Claims[oauthClaimGrantType] != oauth.GrantTypeClientCredentials {
+		t.Fatalf("unexpected claims: %+v", principal.Claims)
+	}
+	if logStore.count(storage.AuthLogEventUsed) != 1 {
+		t.Fatalf("expected one used event, got %d", logStore.count(storage.AuthLogEventUsed))
+	}
+
+	for _, tc := range []struct {
+		name  string
+		input AuthenticateClientInput
+		want  oerrors.Code
+	}{
+		{"unknown client", AuthenticateClientInput{ClientID: "other", Secret: secret.Secret}, oerrors.CodeInvalidCredentials},
+		{"wrong secret", AuthenticateClientInput{ClientID: client.ClientID, Secret: secret.AuthID + ".wrong"}, oerrors.CodeInvalidCredentials},
+		{"grant type", AuthenticateClientInput{ClientID: client.ClientID, Secret: secret.Secret, GrantType: oauth.GrantTypeRefreshToken}, oerrors.CodePermissionDenied},
+		{"scope", AuthenticateClientInput{ClientID: client.ClientID, Secret: secret.Secret, Scopes: []string{"admin"}}, oerrors.CodePermissionDenied},
+	} {
+		if _, err := service.AuthenticateClient(ctx, tc.input); !oerrors.IsCode(err, tc.want) {
+			t.Fatalf("%s: AuthenticateClient error = %v, want %s", tc.name, err, tc.want)
+		}
+	}
+	if logStore.count(storage.AuthLogEventFailed) != 1 {
+		t.Fatalf("expected one failed event, got %d", logStore.count(storage.AuthLogEventFailed))
+	}
+
+	record := authStore.records[secret.AuthID]
+	past := time.Now().UTC().Add(-time.Minute)
+	record.ExpiresAt = &past
+	authStore.records[secret.AuthID] = record
+	if _, err := service.AuthenticateClient(ctx, AuthenticateClientInput{ClientID: client.ClientID, Secret: secret.Secret}); !oerrors.IsCode(err, oerrors.CodeCredentialsExpired) {
+		t.Fatalf("AuthenticateClient with expired secret error = %v, want credentials expired", err)
+	}
+
+	rotated, err := service.CreateClientSecret(ctx, CreateClientSecretInput{ClientID: client.ClientID, TTL: time.Hour})
+	if err != nil {
+		t.Fatalf("CreateClientSecret returned error: %v", err)
+	}
+	if err := service.DeleteOAuthClient(ctx, client.ClientID); err != nil {
+		t.Fatalf("DeleteOAuthClient returned error: %v", err)
+	}
+	if authStore.records[rotated.AuthID].Status != storage.StatusRevoked {
+		t.Fatalf("expected deleting the client to revoke its secrets")
+	}
+	if _, err := service.GetOAuthClient(ctx, client.ClientID); !oerrors.IsCode(err, oerrors.CodeNotFound) {
+		t.Fatalf("GetOAuthClient after delete error = %v, want not found", err)
+	}
+}
+
+func TestOAuthClientsDoNotShareUserSubjects(t *testing.T) {
+	service, _, _ := newRefreshTestService(t)
+	service.oauthClients = &memoryOAuthClientStore{}
+	ctx := context.Background()
+
+	client, err := service.RegisterOAuthClient(ctx, RegisterOAuthClientInput{ClientID: "user-1", Tenant: "tenant-a"})
+	if err != nil {
+		t.Fatalf("RegisterOAuthClient returned error: %v", err)
+	}
+	secret, err := service.CreateClientSecret(ctx, CreateClientSecretInput{ClientID: client.ClientID})
+	if err != nil {
+		t.Fatalf("CreateClientSecret returned error: %v", err)
+	}
+
+	principal, err := service.AuthenticateClient(ctx, AuthenticateClientInput{ClientID: client.ClientID, Secret: secret.Secret})
+	if err != nil {
+		t.Fatalf("AuthenticateClient returned error: %v", err)
+	}
+	if ok, _ := service.HasAnyRoles(principal, "viewer"); ok {
+		t.Fatalf("client must not inherit the roles of user-1")
+	}
+	if credentials, err := service.ListCredentials(ctx, "user-1"); err == nil && len(credentials) > 0 {
+		t.Fatalf("client secrets must not be listed for user-1, got %+v", credentials)
+	}
+
+	if err := service.CreateAuth(ctx, CreateAuthInput{UserID: OAuthClientSubject("user-1"), Value: "first-password"}); !oerrors.IsCode(err, oerrors.CodeInvalidCredentials) {
+		t.Fatalf("CreateAuth with a client subject error = %v, want invalid credentials", err)
+	}
+}
+
+func TestRegisterOAuthClientValidation(t *testing.T) {
+	for _, input := range []RegisterOAuthClientInput{
+		{ClientID: "has space"},
+		{GrantTypes: []string{"password"}},
+		{GrantTypes: []string{oauth.GrantTypeAuthorizationCode}},
+		{GrantTypes: []string{oauth.GrantTypeAuthorizationCode}, RedirectURIs: []string{"/callback"}},
+		{GrantTypes: []string{oauth.GrantTypeAuthorizationCode}, RedirectURIs: []string{"https://app.example/cb#frag"}},
+	} {
+		if err := input.Normalize().Validate(); !oerrors.IsCode(err, oerrors.CodeInvalidCredentials) {
+			t.Fatalf("Validate(%+v) error = %v, want invalid credentials", input, err)
+		}
+	}
+
+	valid := RegisterOAuthClientInput{GrantTypes: []string{oauth.GrantTypeAuthorizationCode}, RedirectURIs: []string{"https://app.example/cb"}}
+	if err := valid.Normalize().Validate(); err != nil {
+		t.Fatalf("Validate returned error: %v", err)
+	}
+}
@@ -0,0 +1,167 @@
+package openauth
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	oerrors "github.com/porthorian/openauth/pkg/errors"
+	"github.com/porthorian/openauth/pkg/password"
+	"github.com/porthorian/openauth/pkg/storage"
+)
+
+const authLogMetadataReplacedBy = "replaced_by"
+
+var _ PasswordManager = (*AuthService)(nil)
+
+// RotatePassword replaces the active password of input.UserID after
+// verifying input.CurrentValue. The old record is deactivated and the new
+// one created in the same auth material transaction.
+func (s *AuthService) RotatePassword(ctx context.Context, input RotatePasswordInput) error {
+	if s == nil || s.authStore.Auth == nil || s.authStore.SubjectAuth == nil {
+		return oerrors.New(oerrors.CodeStorageUnavailable, "auth storage is not configured")
+	}
+	if s.hasher == nil {
+		return oerrors.New(oerrors.CodeUnknown, "hasher is not configured")
+	}
+
+	input = input.Normalize()
+	if err := input.Validate(); err != nil {
+		return err
+	}
+	if err := s.checkPasswordPolicy(input.NewValue, input.UserID); err != nil {
+		return err
+	}
+	if err := s.checkLockout(ctx, input.UserID, ""); err != nil {
+		return err
+	}
+
+	records, err := s.subjectAuthRecords(ctx, input.UserID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	var current *storage.AuthRecord
+	for i, record := range records {
+		if record.MaterialType != storage.AuthMaterialTypePassword || !isUsableRecord(record, now) {
+			continue
+		}
+		match, err := s.hasher.Verify(input.CurrentValue, record.MaterialHash)
+		if err != nil {
+			return oerrors.Wrap(oerrors.CodeUnknown, "unable to verify current password", err)
+		}
+		if match {
+			current = &records[i]
+			break
+		}
+	}
+	if current == nil {
+		s.recordAuthFailure(ctx, input.UserID, "")
+		return oerrors.New(oerrors.CodeInvalidCredentials, "current password is invalid")
+	}
+
+	if err := s.checkPasswordReuse(records, input.NewValue); err != nil {
+		return err
+	}
+	profile := recordAuthProfile(*current)
+	expiresAt, err := s.passwordExpiry(profile, input.ExpiresAt, now)
+	if err != nil {
+		return err
+	}
+	materialHash, err := s.hasher.Hash(input.NewValue)
+	if err != nil {
+		return oerrors.Wrap(oerrors.CodeUnknown, "failed to hash auth value", err)
+	}
+
+	write := createAuthWrite{
+		authID:       uuid.NewString(),
+		userID:       input.UserID,
+		materialHash: materialHash,
+		expiresAt:    expiresAt,
+		metadata:     withAuthProfile(input.Metadata, profile),
+	}
+	if err := s.replaceAuth(ctx, "rotate password", current.ID, write); err != nil {
+		return err
+	}
+
+	s.resetAuthFailures(ctx, input.UserID, "")
+	return nil
+}
+
+func (s *AuthService) checkPasswordPolicy(value string, subject string) error {
+	if s.passwordPolicy == nil {
+		return nil
+	}
+	if violations := s.passwordPolicy.Check(value, subject); len(violations) > 0 {
+		return &oerrors.Error{
+			Code:       oerrors.CodePolicyViolation,
+			Message:    "password does not meet policy",
+			Violations: violations,
+		}
+	}
+	return nil
+}
+
+// checkPasswordReuse rejects value when it matches an active password or
+// one of the last passwordHistory retired passwords in records.
+func (s *AuthService) checkPasswordReuse(records []storage.AuthRecord, value string) error {
+	for _, record := range passwordHistory(records, s.passwordHistory) {
+		match, err := s.hasher.Verify(value, record.MaterialHash)
+		if err != nil {
+			return oerrors.Wrap(oerrors.CodeUnknown, "unable to verify credentials against existing auth record", err)
+		}
+		if !match {
+			continue
+		}
+		if record.Status == storage.StatusActive {
+			return oerrors.New(oerrors.CodeInvalidCredentials, "auth with the same value already exists for user_id")
+		}
+		return &oerrors.Error{
+			Code:    oerrors.CodePolicyViolation,
+			Message: "password was used recently",
+			Violations: []oerrors.Violation{{
+				Rule:    password.RuleHistory,
+				Message: fmt.Sprintf("password must differ from the last %d passwords", s.passwordHistory),
+			}},
+		}
+	}
+	return nil
+}
+
+// passwordHistory returns the active password records followed by the
+// depth most recently retired ones.
+func passwordHistory(records []storage.AuthRecord, depth int) []storage.AuthRecord {
+	active := make([]storage.AuthRecord, 0, 1)
+	retired := make([]storage.AuthRecord, 0, len(records))
+	for _, record := range records {
+		if record.MaterialType != storage.AuthMaterialTypePassword {
+			continue
+		}
+		if record.Status == storage.StatusActive {
+			active = append(active, record)
+			continue
+		}
+		retired = append(retired, record)
+	}
+
+	slices.SortFunc(retired, func(a, b storage.AuthRecord) int {
+		return retiredAt(b).Compare(retiredAt(a))
+	})
+	return append(active, retired[:min(depth, len(retired))]...)
+}
+
+// retiredAt approximates when a record stopped being usable.
+func retiredAt(record storage.AuthRecord) time.Time {
+	switch {
+	case record.RevokedAt != nil:
+		return *record.RevokedAt
+	case record.DateModified != nil:
+		return *record.DateModified
+	case record.ExpiresAt != nil:
+		return *record.ExpiresAt
+	}
+	return record.DateAdded
+}
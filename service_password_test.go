@@ -0,0 +1,201 @@
+package openauth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	oerrors "github.com/porthorian/openauth/pkg/errors"
+	"github.com/porthorian/openauth/pkg/password"
+	"github.com/porthorian/openauth/pkg/storage"
+)
+
+func newPasswordTestService(t *testing.T, history int) (*AuthService, *transactionalAuthStore, *recordingAuthLogStore) {
+	t.Helper()
+
+	authStore := &memoryAuthStore{}
+	logStore := &recordingAuthLogStore{}
+	material := storage.AuthMaterial{
+		Auth:        authStore,
+		SubjectAuth: &memorySubjectAuthStore{},
+		AuthLog:     logStore,
+	}
+	txStore := &transactionalAuthStore{memoryAuthStore: authStore, material: material}
+	material.Auth = txStore
+
+	service, err := NewAuthService(Config{
+		AuthStore: material,
+		AuthdStore: storage.AuthdMaterial{
+			Role:       &memoryRoleStore{},
+			Permission: &memoryPermissionStore{},
+		},
+		Hasher:          staticHasher{},
+		PasswordHistory: history,
+		Lockout:         LockoutPolicy{Disabled: true},
+	})
+	if err != nil {
+		t.Fatalf("NewAuthService returned error: %v", err)
+	}
+	if err := service.CreateAuth(context.Background(), CreateAuthInput{UserID: "user-1", Value: "first-password"}); err != nil {
+		t.Fatalf("CreateAuth returned error: %v", err)
+	}
+	return service, txStore, logStore
+}
+
+func TestRotatePasswordReplacesActiveRecordAtomically(t *testing.T) {
+	service, txStore, logStore := newPasswordTestService(t, 2)
+	ctx := context.Background()
+
+	wrong := RotatePasswordInput{UserID: "user-1", CurrentValue: "not-the-password", NewValue: "second-password"}
+	if err := service.RotatePassword(ctx, wrong); !oerrors.IsCode(err, oerrors.CodeInvalidCredentials) {
+		t.Fatalf("RotatePassword with wrong current value error = %v, want invalid credentials", err)
+	}
+	same := RotatePasswordInput{UserID: "user-1", CurrentValue: "first-password", NewValue: "first-password"}
+	if err := service.RotatePassword(ctx, same); !oerrors.IsCode(err, oerrors.CodeInvalidCredentials) {
+		t.Fatalf("RotatePassword to the active value error = %v, want invalid credentials", err)
+	}
+
+	txsBefore := txStore.txs
+	if err := service.RotatePassword(ctx, RotatePasswordInput{UserID: "user-1", CurrentValue: "first-password", NewValue: "second-password"}); err != nil {
+		t.Fatalf("RotatePassword returned error: %v", err)
+	}
+	if txStore.txs != txsBefore+1 {
+		t.Fatalf("expected rotation to run in one transaction, got %d", txStore.txs-txsBefore)
+	}
+
+	statuses := map[string]storage.AuthStatus{}
+	for _, record := range txStore.records {
+		statuses[record.MaterialHash] = record.Status
+		if record.MaterialHash == "first-password" && record.DateModified == nil {
+			t.Fatalf("expected deactivated record to carry DateModified")
+		}
+	}
+	if statuses["first-password"] != storage.StatusInActive || statuses["second-password"] != storage.StatusActive {
+		t.Fatalf("statuses after rotation = %v", statuses)
+	}
+	if logStore.count(storage.AuthLogEventDeactivated) != 1 {
+		t.Fatalf("expected one deactivated event, got %d", logStore.count(storage.AuthLogEventDeactivated))
+	}
+
+	if _, err := service.Authorize(ctx, AuthInput{UserID: "user-1", Type: InputTypePassword, Value: "first-password"}); !oerrors.IsCode(err, oerrors.CodeInvalidCredentials) {
+		t.Fatalf("Authorize with the rotated password error = %v, want invalid credentials", err)
+	}
+	if _, err := service.Authorize(ctx, AuthInput{UserID: "user-1", Type: InputTypePassword, Value: "second-password"}); err != nil {
+		t.Fatalf("Authorize with the new password returned error: %v", err)
+	}
+}
+
+func TestPasswordHistoryRejectsRecentPasswords(t *testing.T) {
+	service, txStore, _ := newPasswordTestService(t, 2)
+	ctx := context.Background()
+
+	current := "first-password"
+	for _, next := range []string{"second-password", "third-password", "fourth-password"} {
+		if err := service.RotatePassword(ctx, RotatePasswordInput{UserID: "user-1", CurrentValue: current, NewValue: next}); err != nil {
+			t.Fatalf("RotatePassword(%s) returned error: %v", next, err)
+		}
+		current = next
+		// Records retired in the same instant would tie; keep them ordered.
+		for id, record := range txStore.records {
+			if record.DateModified != nil {
+				modified := record.DateModified.Add(-time.Second)
+				record.DateModified = &modified
+				txStore.records[id] = record
+			}
+		}
+	}
+
+	for _, reused := range []string{"third-password", "second-password"} {
+		err := service.RotatePassword(ctx, RotatePasswordInput{UserID: "user-1", CurrentValue: current, NewValue: reused})
+		violations, _ := oerrors.Violations(err)
+		if !oerrors.IsCode(err, oerrors.CodePolicyViolation) || len(violations) != 1 || violations[0].Rule != password.RuleHistory {
+			t.Fatalf("RotatePassword to %s error = %v, want history violation", reused, err)
+		}
+	}
+	if err := service.CreateAuth(ctx, CreateAuthInput{UserID: "user-1", Value: "third-password"}); !oerrors.IsCode(err, oerrors.CodePolicyViolation) {
+		t.Fatalf("CreateAuth with a recent password error = %v, want history violation", err)
+	}
+
+	if err := service.RotatePassword(ctx, RotatePasswordInput{UserID: "user-1", CurrentValue: current, NewValue: "first-password"}); err != nil {
+		t.Fatalf("password beyond the history depth should be accepted, got %v", err)
+	}
+}
+
+func TestCreateAuthEnforcesPersistencePolicy(t *testing.T) {
+	policies := storage.DefaultPersistencePolicies()
+	policies["password_service"] = storage.PersistencePolicy{
+		MaterialType:           storage.AuthMaterialTypePassword,
+		Authority:              storage.AuthoritySourceOfTruth,
+		PersistInSourceOfTruth: true,
+		AllowNonExpiring:       true,
+	}
+	policies["password_strict"] = storage.PersistencePolicy{
+		MaterialType:           storage.AuthMaterialTypePassword,
+		Authority:              storage.AuthoritySourceOfTruth,
+		PersistInSourceOfTruth: true,
+	}
+
+	authStore := &memoryAuthStore{}
+	service, err := NewAuthService(Config{
+		AuthStore:     storage.AuthMaterial{Auth: authStore, SubjectAuth: &memorySubjectAuthStore{}},
+		Hasher:        staticHasher{},
+		PolicyMatrix:  storage.NewStaticPolicyMatrix(policies),
+		DefaultPolicy: "password_service",
+	})
+	if err != nil {
+		t.Fatalf("NewAuthService returned error: %v", err)
+	}
+	ctx := context.Background()
+	onlyRecord := func(subject string) storage.AuthRecord {
+		t.Helper()
+		records, err := service.subjectAuthRecords(ctx, subject)
+		if err != nil || len(records) != 1 {
+			t.Fatalf("expected one auth record for %s, got %+v (%v)", subject, records, err)
+		}
+		return records[0]
+	}
+
+	if err := service.CreateAuth(ctx, CreateAuthInput{UserID: "basic", Value: "first-password"}); err != nil {
+		t.Fatalf("CreateAuth returned error: %v", err)
+	}
+	basic := onlyRecord("basic")
+	if basic.ExpiresAt == nil || basic.ExpiresAt.Before(time.Now().Add(364*24*time.Hour)) {
+		t.Fatalf("ExpiresAt = %v, want password_basic DefaultTTL", basic.ExpiresAt)
+	}
+	if basic.Metadata[authMetadataProfile] != string(storage.AuthProfilePasswordBasic) {
+		t.Fatalf("expected profile in metadata, got %+v", basic.Metadata)
+	}
+
+	if err := service.CreateAuth(ctx, CreateAuthInput{UserID: "service", Value: "first-password", Profile: "unknown"}); err != nil {
+		t.Fatalf("CreateAuth with unknown profile returned error: %v", err)
+	}
+	if record := onlyRecord("service"); record.ExpiresAt != nil {
+		t.Fatalf("ExpiresAt = %v, want DefaultPolicy to allow non-expiring material", record.ExpiresAt)
+	}
+
+	for _, tc := range []struct {
+		name    string
+		profile storage.AuthProfile
+	}{
+		{"no default ttl", "password_strict"},
+		{"not persisted", storage.AuthProfileAccessJWT},
+		{"not password material", storage.AuthProfileAPIKey},
+	} {
+		if err := service.CreateAuth(ctx, CreateAuthInput{UserID: "rejected", Value: "first-password", Profile: tc.profile}); !oerrors.IsCode(err, oerrors.CodeInvalidCredentials) {
+			t.Fatalf("%s: CreateAuth error = %v, want invalid credentials", tc.name, err)
+		}
+	}
+	expiresAt := time.Now().Add(time.Hour)
+	if err := service.CreateAuth(ctx, CreateAuthInput{UserID: "strict", Value: "first-password", Profile: "password_strict", ExpiresAt: &expiresAt}); err != nil {
+		t.Fatalf("CreateAuth with explicit expiry returned error: %v", err)
+	}
+	past := time.Now().Add(-time.Hour)
+	if err := service.CreateAuth(ctx, CreateAuthInput{UserID: "past", Value: "first-password", ExpiresAt: &past}); !oerrors.IsCode(err, oerrors.CodeInvalidCredentials) {
+		t.Fatalf("CreateAuth with past expiry error = %v, want invalid credentials", err)
+	}
+
+	strict := onlyRecord("strict")
+	if _, err := service.RotateAuth(ctx, RotateAuthInput{AuthID: strict.ID, Value: "second-password"}); !oerrors.IsCode(err, oerrors.CodeInvalidCredentials) {
+		t.Fatalf("RotateAuth without expiry under password_strict error = %v, want invalid credentials", err)
+	}
+}
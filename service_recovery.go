@@ -0,0 +1,189 @@
+package openauth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"strings"
+	"time"
+
+	oerrors "github.com/porthorian/openauth/pkg/errors"
+	"github.com/porthorian/openauth/pkg/storage"
+)
+
+const (
+	defaultRecoveryCodeCount = 10
+	maxRecoveryCodeCount     = 50
+	recoveryCodeLength       = 10 // 50 bits from base32.
+	recoveryCodeGroup        = 5
+)
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateRecoveryCodes replaces the recovery codes of input.UserID with a
+// new batch. Each code is stored as its own hashed auth record, and codes
+// from earlier batches are revoked in the same transaction.
+func (s *AuthService) GenerateRecoveryCodes(ctx context.Context, input GenerateRecoveryCodesInput) (RecoveryCodes, error) {
+	if s == nil || s.authStore.Auth == nil || s.authStore.SubjectAuth == nil {
+		return RecoveryCodes{}, oerrors.New(oerrors.CodeStorageUnavailable, "auth storage is not configured")
+	}
+	if s.hasher == nil {
+		return RecoveryCodes{}, oerrors.New(oerrors.CodeUnknown, "hasher is not configured")
+	}
+
+	input = input.Normalize()
+	if err := input.Validate(); err != nil {
+		return RecoveryCodes{}, err
+	}
+
+	records, err := s.subjectAuthRecords(ctx, input.UserID)
+	if err != nil {
+		return RecoveryCodes{}, err
+	}
+	now := time.Now().UTC()
+	if len(mfaMethods(records, now)) == 0 {
+		return RecoveryCodes{}, oerrors.New(oerrors.CodeInvalidCredentials, "recovery codes require an active second factor")
+	}
+
+	codes := make([]string, 0, input.Count)
+	hashes := make([]string, 0, input.Count)
+	for len(codes) < input.Count {
+		code, err := newRecoveryCode()
+		if err != nil {
+			return RecoveryCodes{}, oerrors.Wrap(oerrors.CodeUnknown, "failed to generate recovery code", err)
+		}
+		materialHash, err := s.hasher.Hash(normalizeRecoveryCode(code))
+		if err != nil {
+			return RecoveryCodes{}, oerrors.Wrap(oerrors.CodeUnknown, "failed to hash recovery code", err)
+		}
+		codes = append(codes, code)
+		hashes = append(hashes, materialHash)
+	}
+
+	err = s.withAuthMaterial(ctx, "generate recovery codes", func(stores storage.AuthMaterial, transactional bool) error {
+		for _, record := range records {
+			if record.MaterialType != storage.AuthMaterialTypeRecoveryCode || record.Status != storage.StatusActive {
+				continue
+			}
+			record.Status = storage.StatusRevoked
+			record.RevokedAt = &now
+			record.DateModified = &now
+			if err := stores.Auth.PutAuth(ctx, record); err != nil {
+				return oerrors.Wrap(oerrors.CodeStorageUnavailable, "failed to revoke recovery code", err)
+			}
+			s.logAuthEventWith(ctx, stores, record.ID, input.UserID, storage.AuthLogEventRevoked, nil)
+		}
+
+		for _, materialHash := range hashes {
+			if err := s.createAuthWithStores(ctx, stores, transactional, createAuthWrite{
+				userID:       input.UserID,
+				materialType: storage.AuthMaterialTypeRecoveryCode,
+				materialHash: materialHash,
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return RecoveryCodes{}, err
+	}
+
+	return RecoveryCodes{
+		Codes:     codes,
+		Remaining: len(codes),
+	}, nil
+}
+
+// CountRecoveryCodes reports how many unused recovery codes userID has left.
+func (s *AuthService) CountRecoveryCodes(ctx context.Context, userID string) (int, error) {
+	if s == nil || s.authStore.Auth == nil || s.authStore.SubjectAuth == nil {
+		return 0, oerrors.New(oerrors.CodeStorageUnavailable, "auth storage is not configured")
+	}
+
+	userID = strings.TrimSpace(userID)
+	if userID == "" {
+		return 0, oerrors.New(oerrors.CodeInvalidCredentials, "user_id is required")
+	}
+
+	records, err := s.subjectAuthRecords(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now().UTC()
+	remaining := 0
+	for _, record := range records {
+		if record.MaterialType == storage.AuthMaterialTypeRecoveryCode && isUsableRecord(record, now) {
+			remaining++
+		}
+	}
+	return remaining, nil
+}
+
+// matchRecoveryCode finds the unused code matching value. Consuming it
+// deactivates the record, so a code works once even when two logins race.
+func (s *AuthService) matchRecoveryCode(factors []storage.AuthRecord, value string) (*secondFactorMatch, error) {
+	code := normalizeRecoveryCode(value)
+	if code == "" {
+		return nil, nil
+	}
+
+	for _, factor := range factors {
+		ok, err := s.hasher.Verify(code, factor.MaterialHash)
+		if err != nil {
+			return nil, oerrors.Wrap(oerrors.CodeInvalidCredentials, "unable to verify recovery code", err)
+		}
+		if !ok {
+			continue
+		}
+		return &secondFactorMatch{
+			authID: factor.ID,
+			consume: func(current storage.AuthRecord, now time.Time) (storage.AuthRecord, error) {
+				if current.Status != storage.StatusActive {
+					return storage.AuthRecord{}, oerrors.New(oerrors.CodeInvalidCredentials, "recovery code was already used")
+				}
+				current.Status = storage.StatusInActive
+				return current, nil
+			},
+		}, nil
+	}
+	return nil, nil
+}
+
+func (s *AuthService) subjectAuthRecords(ctx context.Context, subject string) ([]storage.AuthRecord, error) {
+	links, err := s.authStore.SubjectAuth.ListSubjectAuthBySubject(ctx, subject)
+	if err != nil {
+		return nil, oerrors.Wrap(oerrors.CodeStorageUnavailable, "failed to lookup subject auth records", err)
+	}
+	if len(links) < 1 {
+		return nil, oerrors.New(oerrors.CodeNotFound, "user_id not found")
+	}
+
+	authIDs := make([]string, 0, len(links))
+	for _, link := range links {
+		authIDs = append(authIDs, link.AuthID)
+	}
+	records, err := s.authStore.Auth.GetAuths(ctx, authIDs)
+	if err != nil {
+		return nil, oerrors.Wrap(oerrors.CodeStorageUnavailable, "failed to retrieve auth records", err)
+	}
+	return records, nil
+}
+
+// newRecoveryCode returns a code such as "k3vq7-m2xpa".
+func newRecoveryCode() (string, error) {
+	raw := make([]byte, 8)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	code := strings.ToLower(recoveryCodeEncoding.EncodeToString(raw))[:recoveryCodeLength]
+	return code[:recoveryCodeGroup] + "-" + code[recoveryCodeGroup:], nil
+}
+
+// normalizeRecoveryCode drops separators and case so codes read back from
+// paper still match.
+func normalizeRecoveryCode(value string) string {
+	replacer := strings.NewReplacer("-", "", " ", "")
+	return strings.ToLower(replacer.Replace(strings.TrimSpace(value)))
+}
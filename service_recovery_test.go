@@ -0,0 +1,170 @@
+package openauth
+
+import (
+	"context"
+	"maps"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	oerrors "github.com/porthorian/openauth/pkg/errors"
+	"github.com/porthorian/openauth/pkg/mfa/totp"
+	"github.com/porthorian/openauth/pkg/storage"
+)
+
+// transactionalAuthStore runs auth material transactions against the memory
+// stores and restores auth records when the transaction fails.
+type transactionalAuthStore struct {
+	*memoryAuthStore
+	material storage.AuthMaterial
+	txs      int
+}
+
+func (s *transactionalAuthStore) WithAuthMaterialTx(ctx context.Context, fn func(material storage.AuthMaterial) error) error {
+	_ = ctx
+	s.txs++
+	snapshot := maps.Clone(s.records)
+	if err := fn(s.material); err != nil {
+		s.records = snapshot
+		return err
+	}
+	return nil
+}
+
+func enrollConfirmedTOTP(t *testing.T, service *AuthService) []byte {
+	t.Helper()
+
+	ctx := context.Background()
+	enrollment, err := service.EnrollTOTP(ctx, EnrollTOTPInput{UserID: "user-1"})
+	if err != nil {
+		t.Fatalf("EnrollTOTP returned error: %v", err)
+	}
+	secret, err := totp.DecodeSecret(enrollment.Secret)
+	if err != nil {
+		t.Fatalf("DecodeSecret returned error: %v", err)
+	}
+	code, _ := totp.Code(secret, time.Now(), totp.DefaultOptions())
+	if err := service.ConfirmTOTP(ctx, ConfirmTOTPInput{UserID: "user-1", AuthID: enrollment.AuthID, Code: code}); err != nil {
+		t.Fatalf("ConfirmTOTP returned error: %v", err)
+	}
+	return secret
+}
+
+func TestRecoveryCodesCompleteSecondStepOnce(t *testing.T) {
+	service, authStore := newMFATestService(t)
+	logStore := service.authStore.AuthLog.(*recordingAuthLogStore)
+	txStore := &transactionalAuthStore{memoryAuthStore: authStore, material: service.authStore}
+	service.authStore.Auth = txStore
+	ctx := context.Background()
+
+	if _, err := service.GenerateRecoveryCodes(ctx, GenerateRecoveryCodesInput{UserID: "user-1"}); !oerrors.IsCode(err, oerrors.CodeInvalidCredentials) {
+		t.Fatalf("GenerateRecoveryCodes without a second factor error = %v, want invalid credentials", err)
+	}
+	enrollConfirmedTOTP(t, service)
+
+	generated, err := service.GenerateRecoveryCodes(ctx, GenerateRecoveryCodesInput{UserID: "user-1", Count: 3})
+	if err != nil {
+		t.Fatalf("GenerateRecoveryCodes returned error: %v", err)
+	}
+	if len(generated.Codes) != 3 || generated.Remaining != 3 {
+		t.Fatalf("generated = %+v, want 3 codes", generated)
+	}
+	format := regexp.MustCompile(`^[a-z2-7]{5}-[a-z2-7]{5}$`)
+	for _, code := range generated.Codes {
+		if !format.MatchString(code) {
+			t.Fatalf("unexpected recovery code format: %q", code)
+		}
+	}
+	for _, record := range authStore.records {
+		if record.MaterialType == storage.AuthMaterialTypeRecoveryCode && strings.Contains(record.MaterialHash, "-") {
+			t.Fatalf("expected recovery codes to be stored normalized and hashed, got %q", record.MaterialHash)
+		}
+	}
+
+	_, err = service.Authorize(ctx, AuthInput{UserID: "user-1", Type: InputTypePassword, Value: "pass-123"})
+	challenge, ok := MFAChallengeFromError(err)
+	if !ok || len(challenge.Methods) != 2 || challenge.Methods[1] != InputTypeRecoveryCode {
+		t.Fatalf("challenge = %+v, want totp and recovery code methods", challenge)
+	}
+
+	used := generated.Codes[1]
+	txsBefore := txStore.txs
+	usedBefore := logStore.count(storage.AuthLogEventUsed)
+	second := AuthInput{UserID: "user-1", Type: InputTypeRecoveryCode, Value: strings.ToUpper(used), Challenge: challenge.Token}
+	if _, err := service.Authorize(ctx, second); err != nil {
+		t.Fatalf("Authorize with recovery code returned error: %v", err)
+	}
+	if txStore.txs != txsBefore+1 {
+		t.Fatalf("expected recovery code to be consumed in one transaction, got %d", txStore.txs-txsBefore)
+	}
+	if got := logStore.count(storage.AuthLogEventUsed) - usedBefore; got != 1 {
+		t.Fatalf("used events = %d, want one for the code", got)
+	}
+	if challengeID, _, _ := parseSecretToken(challenge.Token); authStore.records[challengeID].ID != "" {
+		t.Fatalf("expected the consumed challenge to be deleted")
+	}
+	if remaining, err := service.CountRecoveryCodes(ctx, "user-1"); err != nil || remaining != 2 {
+		t.Fatalf("CountRecoveryCodes = (%d, %v), want 2", remaining, err)
+	}
+
+	_, err = service.Authorize(ctx, AuthInput{UserID: "user-1", Type: InputTypePassword, Value: "pass-123"})
+	fresh, _ := MFAChallengeFromError(err)
+	second.Challenge = fresh.Token
+	if _, err := service.Authorize(ctx, second); !oerrors.IsCode(err, oerrors.CodeInvalidCredentials) {
+		t.Fatalf("reused recovery code error = %v, want invalid credentials", err)
+	}
+
+	regenerated, err := service.GenerateRecoveryCodes(ctx, GenerateRecoveryCodesInput{UserID: "user-1", Count: 4})
+	if err != nil {
+		t.Fatalf("GenerateRecoveryCodes returned error: %v", err)
+	}
+	if remaining, _ := service.CountRecoveryCodes(ctx, "user-1"); remaining != len(regenerated.Codes) {
+		t.Fatalf("remaining after regeneration = %d, want %d", remaining, len(regenerated.Codes))
+	}
+	if logStore.count(storage.AuthLogEventRevoked) != 2 {
+		t.Fatalf("revoked events = %d, want the 2 unused codes of the first batch", logStore.count(storage.AuthLogEventRevoked))
+	}
+
+	second.Value = generated.Codes[0]
+	if _, err := service.Authorize(ctx, second); !oerrors.IsCode(err, oerrors.CodeInvalidCredentials) {
+		t.Fatalf("revoked recovery code error = %v, want invalid credentials", err)
+	}
+}
+
+func TestRecoveryCodeConcurrentUseSucceedsOnce(t *testing.T) {
+	service, authStore := newMFATestService(t)
+	racing := &racingAuthStore{memoryAuthStore: authStore}
+	service.authStore.Auth = racing
+	ctx := context.Background()
+
+	enrollConfirmedTOTP(t, service)
+	generated, err := service.GenerateRecoveryCodes(ctx, GenerateRecoveryCodesInput{UserID: "user-1", Count: 1})
+	if err != nil {
+		t.Fatalf("GenerateRecoveryCodes returned error: %v", err)
+	}
+
+	login := func() AuthInput {
+		_, err := service.Authorize(ctx, AuthInput{UserID: "user-1", Type: InputTypePassword, Value: "pass-123"})
+		challenge, ok := MFAChallengeFromError(err)
+		if !ok {
+			t.Fatalf("Authorize error = %v, want mfa required", err)
+		}
+		return AuthInput{UserID: "user-1", Type: InputTypeRecoveryCode, Value: generated.Codes[0], Challenge: challenge.Token}
+	}
+	first, second := login(), login()
+
+	var racerErr error
+	racing.beforeWrite = func() {
+		_, racerErr = service.Authorize(ctx, second)
+	}
+	if _, err := service.Authorize(ctx, first); !oerrors.IsCode(err, oerrors.CodeInvalidCredentials) {
+		t.Fatalf("losing concurrent use error = %v, want invalid credentials", err)
+	}
+	if racerErr != nil {
+		t.Fatalf("winning concurrent use returned error: %v", racerErr)
+	}
+	if remaining, _ := service.CountRecoveryCodes(ctx, "user-1"); remaining != 0 {
+		t.Fatalf("remaining = %d, want the code consumed once", remaining)
+	}
+}
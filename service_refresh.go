@@ -0,0 +1,372 @@
+package openauth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	ocrypto "github.com/porthorian/openauth/pkg/crypto"
+	oerrors "github.com/porthorian/openauth/pkg/errors"
+	"github.com/porthorian/openauth/pkg/storage"
+)
+
+const (
+	defaultRefreshTokenTTL   = 30 * 24 * time.Hour
+	tokenSecretBytes         = 32
+	tokenSeparator           = "."
+	refreshMetadataFamilyID  = "refresh_family_id"
+	refreshMetadataParentID  = "refresh_parent_id"
+	refreshMetadataRotatedTo = "refresh_rotated_to"
+	refreshMetadataTenant    = "tenant"
+)
+
+// errRefreshTokenReused reports, from inside the rotation transaction, that
+// the presented token had already been rotated.
+var errRefreshTokenReused = errors.New("refresh token reused")
+
+var _ RefreshTokenManager = (*AuthService)(nil)
+
+// IssueRefreshToken starts a new refresh token family for input.UserID. The
+// returned Token is only available here; storage keeps a SHA-256 digest of
+// its secret.
+// Refresh tokens of the subject that can no longer be presented are deleted
+// first.
+func (s *AuthService) IssueRefreshToken(ctx context.Context, input IssueRefreshTokenInput) (RefreshToken, error) {
+	if s == nil || s.authStore.Auth == nil || s.authStore.SubjectAuth == nil {
+		return RefreshToken{}, oerrors.New(oerrors.CodeStorageUnavailable, "auth storage is not configured")
+	}
+
+	input = input.Normalize()
+	if err := input.Validate(); err != nil {
+		return RefreshToken{}, err
+	}
+
+	ttl := input.TTL
+	if ttl == 0 {
+		ttl = defaultRefreshTokenTTL
+	}
+
+	metadata := make(map[string]string, len(input.Metadata)+2)
+	for key, value := range input.Metadata {
+		metadata[key] = value
+	}
+	metadata[refreshMetadataFamilyID] = uuid.NewString()
+	metadata[refreshMetadataTenant] = s.resolveTenant(input.Tenant)
+
+	records, err := s.subjectAuthRecords(ctx, input.UserID)
+	if err != nil && !oerrors.IsCode(err, oerrors.CodeNotFound) {
+		return RefreshToken{}, err
+	}
+
+	now := time.Now().UTC()
+	var issued RefreshToken
+	err = s.withAuthMaterial(ctx, "issue refresh token", func(stores storage.AuthMaterial, transactional bool) error {
+		s.pruneAuthMaterial(ctx, stores, input.UserID, records, func(record storage.AuthRecord) bool {
+			return staleRefreshToken(record, now)
+		})
+		token, err := s.putRefreshToken(ctx, stores, transactional, input.UserID, now.Add(ttl), metadata)
+		if err != nil {
+			return err
+		}
+		issued = token
+		return nil
+	})
+	if err != nil {
+		return RefreshToken{}, err
+	}
+	return issued, nil
+}
+
+// RefreshAuth exchanges a refresh token for a new one in the same family and
+// the principal it was issued to. Presenting a token that was already
+// rotated revokes every token in its family.
+func (s *AuthService) RefreshAuth(ctx context.Context, token string) (RefreshResult, error) {
+	if s == nil || s.authStore.Auth == nil || s.authStore.SubjectAuth == nil {
+		return RefreshResult{}, oerrors.New(oerrors.CodeStorageUnavailable, "auth storage is not configured")
+	}
+	if s.authdStore.Role == nil || s.authdStore.Permission == nil {
+		return RefreshResult{}, oerrors.New(oerrors.CodeStorageUnavailable, "authorization storage is not configured")
+	}
+
+	authID, secret, ok := parseSecretToken(token)
+	if !ok {
+		return RefreshResult{}, oerrors.New(oerrors.CodeInvalidToken, "malformed refresh token")
+	}
+
+	record, err := s.authStore.Auth.GetAuth(ctx, authID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return RefreshResult{}, oerrors.New(oerrors.CodeInvalidToken, "refresh token not found")
+	}
+	if err != nil {
+		return RefreshResult{}, oerrors.Wrap(oerrors.CodeStorageUnavailable, "failed to retrieve refresh token record", err)
+	}
+	if record.MaterialType != storage.AuthMaterialTypeRefreshToken {
+		return RefreshResult{}, oerrors.New(oerrors.CodeInvalidToken, "refresh token not found")
+	}
+
+	subject, err := s.refreshTokenSubject(ctx, authID)
+	if err != nil {
+		return RefreshResult{}, err
+	}
+
+	if !ocrypto.VerifySecretDigest(secret, record.MaterialHash) {
+		s.logAuthEvent(ctx, record.ID, subject, storage.AuthLogEventFailed)
+		return RefreshResult{}, oerrors.New(oerrors.CodeInvalidToken, "refresh token verification failed")
+	}
+
+	now := time.Now().UTC()
+	var rotated RefreshToken
+	expired := false
+	err = s.withAuthMaterial(ctx, "rotate refresh token", func(stores storage.AuthMaterial, transactional bool) error {
+		// Status is read again here because a concurrent refresh may have
+		// rotated the token since it was verified.
+		current, err := stores.Auth.GetAuth(ctx, authID)
+		if err != nil {
+			return oerrors.Wrap(oerrors.CodeStorageUnavailable, "failed to retrieve refresh token record", err)
+		}
+
+		switch {
+		case current.Status == storage.StatusInActive && current.Metadata[refreshMetadataRotatedTo] != "":
+			return errRefreshTokenReused
+		case current.Status != storage.StatusActive:
+			return oerrors.New(oerrors.CodeInvalidToken, "refresh token is no longer active")
+		case current.ExpiresAt != nil && !current.ExpiresAt.After(now):
+			if _, err := stores.Auth.SwapAuthStatus(ctx, current.ID, storage.StatusActive, storage.StatusExpired, now); err != nil {
+				return oerrors.Wrap(oerrors.CodeStorageUnavailable, "failed to persist expired auth status", err)
+			}
+			s.logAuthEventWith(ctx, stores, current.ID, subject, storage.AuthLogEventExpired, nil)
+			expired = true
+			return nil
+		}
+
+		token, err := s.rotateRefreshToken(ctx, stores, transactional, subject, current, now)
+		if err != nil {
+			return err
+		}
+		rotated = token
+		return nil
+	})
+	if errors.Is(err, errRefreshTokenReused) {
+		if err := s.revokeRefreshFamily(ctx, subject, record.Metadata[refreshMetadataFamilyID], now); err != nil {
+			return RefreshResult{}, err
+		}
+		s.logger.Info("refresh token reuse detected, revoked token family", "auth_id", record.ID, "subject", subject, "family_id", record.Metadata[refreshMetadataFamilyID])
+		return RefreshResult{}, oerrors.New(oerrors.CodeInvalidToken, "refresh token reuse detected")
+	}
+	if err != nil {
+		return RefreshResult{}, err
+	}
+	if expired {
+		return RefreshResult{}, oerrors.New(oerrors.CodeCredentialsExpired, "refresh token has expired")
+	}
+
+	tenant := s.resolveTenant(record.Metadata[refreshMetadataTenant])
+	policy, _ := s.policyFor(storage.AuthProfileRefreshRotating)
+	authzPolicy := newAuthorizationPolicy(storage.AuthProfileRefreshRotating, policy, rotated.ExpiresAt, now)
+	roleMask, permissionMask, degraded, err := s.resolveAuthorizationWithPolicy(ctx, subject, tenant, authzPolicy)
+	if err != nil {
+		return RefreshResult{}, err
+	}
+
+	return RefreshResult{
+		Principal: Principal{
+			Subject:         subject,
+			Tenant:          tenant,
+			RoleMask:        roleMask,
+			PermissionMask:  permissionMask,
+			AuthenticatedAt: now,
+			Degraded:        degraded,
+		},
+		RefreshToken: rotated,
+	}, nil
+}
+
+// rotateRefreshToken retires record and issues its successor. The successor
+// inherits the family, tenant and expiry of record, so rotation never extends
+// the lifetime of a family.
+func (s *AuthService) rotateRefreshToken(ctx context.Context, stores storage.AuthMaterial, transactional bool, subject string, record storage.AuthRecord, now time.Time) (RefreshToken, error) {
+	if record.ExpiresAt == nil {
+		return RefreshToken{}, oerrors.New(oerrors.CodeInvalidToken, "refresh token has no expiry")
+	}
+
+	// Retiring first is the compare-and-swap that settles concurrent
+	// refreshes: whoever loses saw a token that was already rotated.
+	swapped, err := stores.Auth.SwapAuthStatus(ctx, record.ID, storage.StatusActive, storage.StatusInActive, now)
+	if err != nil {
+		return RefreshToken{}, oerrors.Wrap(oerrors.CodeStorageUnavailable, "failed to retire rotated refresh token", err)
+	}
+	if !swapped {
+		return RefreshToken{}, errRefreshTokenReused
+	}
+
+	metadata := make(map[string]string, len(record.Metadata)+1)
+	for key, value := range record.Metadata {
+		if key == refreshMetadataRotatedTo {
+			continue
+		}
+		metadata[key] = value
+	}
+	metadata[refreshMetadataParentID] = record.ID
+
+	successor, err := s.putRefreshToken(ctx, stores, transactional, subject, *record.ExpiresAt, metadata)
+	if err != nil {
+		return RefreshToken{}, err
+	}
+
+	retired := record
+	retired.Status = storage.StatusInActive
+	retired.DateModified = &now
+	retired.Metadata = make(map[string]string, len(record.Metadata)+1)
+	for key, value := range record.Metadata {
+		retired.Metadata[key] = value
+	}
+	retired.Metadata[refreshMetadataRotatedTo] = successor.AuthID
+	if err := stores.Auth.PutAuth(ctx, retired); err != nil {
+		return RefreshToken{}, oerrors.Wrap(oerrors.CodeStorageUnavailable, "failed to retire rotated refresh token", err)
+	}
+
+	s.logAuthEventWith(ctx, stores, record.ID, subject, storage.AuthLogEventUsed, nil)
+	return successor, nil
+}
+
+func (s *AuthService) putRefreshToken(ctx context.Context, stores storage.AuthMaterial, transactional bool, subject string, expiresAt time.Time, metadata map[string]string) (RefreshToken, error) {
+	if stores.Auth == nil || stores.SubjectAuth == nil {
+		return RefreshToken{}, oerrors.New(oerrors.CodeStorageUnavailable, "auth storage is not configured")
+	}
+
+	secret, err := newTokenSecret()
+	if err != nil {
+		return RefreshToken{}, oerrors.Wrap(oerrors.CodeUnknown, "failed to generate refresh token", err)
+	}
+	materialHash := ocrypto.DigestSecret(secret)
+
+	now := time.Now().UTC()
+	authID := uuid.NewString()
+	if err := stores.Auth.PutAuth(ctx, storage.AuthRecord{
+		ID:           authID,
+		Status:       storage.StatusActive,
+		DateAdded:    now,
+		MaterialType: storage.AuthMaterialTypeRefreshToken,
+		MaterialHash: materialHash,
+		ExpiresAt:    &expiresAt,
+		Metadata:     metadata,
+	}); err != nil {
+		return RefreshToken{}, oerrors.Wrap(oerrors.CodeStorageUnavailable, "failed to create refresh token record", err)
+	}
+
+	if err := stores.SubjectAuth.PutSubjectAuth(ctx, storage.SubjectAuthRecord{
+		ID:        uuid.NewString(),
+		DateAdded: now,
+		Subject:   subject,
+		AuthID:    authID,
+	}); err != nil {
+		if !transactional {
+			if deleteErr := stores.Auth.DeleteAuth(ctx, authID); deleteErr != nil {
+				s.logger.Error(deleteErr, "failed to cleanup refresh token record after subject link failure", "auth_id", authID, "subject", subject)
+			}
+		}
+		return RefreshToken{}, oerrors.Wrap(oerrors.CodeStorageUnavailable, "failed to link refresh token to subject", err)
+	}
+
+	s.logAuthEventWith(ctx, stores, authID, subject, storage.AuthLogEventCreated, nil)
+
+	return RefreshToken{
+		Token:     authID + tokenSeparator + secret,
+		AuthID:    authID,
+		FamilyID:  metadata[refreshMetadataFamilyID],
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// staleRefreshToken reports whether record is a refresh token that can no
+// longer be presented. Rotated tokens are kept until they expire so reuse
+// of them is still detected.
+func staleRefreshToken(record storage.AuthRecord, now time.Time) bool {
+	if record.MaterialType != storage.AuthMaterialTypeRefreshToken {
+		return false
+	}
+	if record.Status == storage.StatusRevoked || record.Status == storage.StatusExpired {
+		return true
+	}
+	return record.ExpiresAt != nil && !record.ExpiresAt.After(now)
+}
+
+// revokeRefreshFamily revokes every refresh token of subject that belongs to
+// familyID and logs a revoked event for each one.
+func (s *AuthService) revokeRefreshFamily(ctx context.Context, subject string, familyID string, now time.Time) error {
+	if familyID == "" {
+		return oerrors.New(oerrors.CodeInvalidToken, "refresh token has no family")
+	}
+
+	return s.withAuthMaterial(ctx, "revoke refresh token family", func(stores storage.AuthMaterial, transactional bool) error {
+		_ = transactional
+
+		links, err := stores.SubjectAuth.ListSubjectAuthBySubject(ctx, subject)
+		if err != nil {
+			return oerrors.Wrap(oerrors.CodeStorageUnavailable, "failed to lookup subject auth records", err)
+		}
+		authIDs := make([]string, 0, len(links))
+		for _, link := range links {
+			authIDs = append(authIDs, link.AuthID)
+		}
+
+		records, err := stores.Auth.GetAuths(ctx, authIDs)
+		if err != nil {
+			return oerrors.Wrap(oerrors.CodeStorageUnavailable, "failed to retrieve refresh token family", err)
+		}
+
+		for _, record := range records {
+			if record.MaterialType != storage.AuthMaterialTypeRefreshToken || record.Metadata[refreshMetadataFamilyID] != familyID {
+				continue
+			}
+			if record.Status == storage.StatusRevoked {
+				continue
+			}
+
+			record.Status = storage.StatusRevoked
+			record.RevokedAt = &now
+			record.DateModified = &now
+			if err := stores.Auth.PutAuth(ctx, record); err != nil {
+				return oerrors.Wrap(oerrors.CodeStorageUnavailable, "failed to revoke refresh token", err)
+			}
+			s.logAuthEventWith(ctx, stores, record.ID, subject, storage.AuthLogEventRevoked, nil)
+		}
+		return nil
+	})
+}
+
+func (s *AuthService) refreshTokenSubject(ctx context.Context, authID string) (string, error) {
+	links, err := s.authStore.SubjectAuth.ListSubjectAuthByAuthID(ctx, authID)
+	if err != nil {
+		return "", oerrors.Wrap(oerrors.CodeStorageUnavailable, "failed to lookup refresh token subject", err)
+	}
+	if len(links) != 1 {
+		return "", oerrors.New(oerrors.CodeInvalidToken, "refresh token is not linked to a single subject")
+	}
+	return links[0].Subject, nil
+}
+
+// newTokenSecret and parseSecretToken handle the <auth_id>.<secret> tokens
+// used for refresh tokens and MFA challenges.
+func newTokenSecret() (string, error) {
+	raw := make([]byte, tokenSecretBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+func parseSecretToken(token string) (string, string, bool) {
+	authID, secret, found := strings.Cut(strings.TrimSpace(token), tokenSeparator)
+	if !found || authID == "" || secret == "" {
+		return "", "", false
+	}
+	if _, err := uuid.Parse(authID); err != nil {
+		return "", "", false
+	}
+	return authID, secret, true
+}
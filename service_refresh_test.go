@@ -0,0 +1,301 @@
+package openauth
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	ocrypto "github.com/porthorian/openauth/pkg/crypto"
+	oerrors "github.com/porthorian/openauth/pkg/errors"
+	"github.com/porthorian/openauth/pkg/storage"
+)
+
+type recordingAuthLogStore struct {
+	records []storage.AuthLogRecord
+}
+
+func (s *recordingAuthLogStore) PutAuthLog(ctx context.Context, record storage.AuthLogRecord) error {
+	_ = ctx
+	s.records = append(s.records, record)
+	return nil
+}
+
+func (s *recordingAuthLogStore) ListAuthLogsByAuthID(ctx context.Context, authID string) ([]storage.AuthLogRecord, error) {
+	_ = ctx
+	_ = authID
+	return nil, nil
+}
+
+func (s *recordingAuthLogStore) ListAuthLogsBySubject(ctx context.Context, subject string) ([]storage.AuthLogRecord, error) {
+	_ = ctx
+	var out []storage.AuthLogRecord
+	for _, record := range s.records {
+		if record.Subject == subject {
+			out = append(out, record)
+		}
+	}
+	return out, nil
+}
+
+func (s *recordingAuthLogStore) ListAuthLogsBySubjectSince(ctx context.Context, subject string, since time.Time) ([]storage.AuthLogRecord, error) {
+	records, err := s.ListAuthLogsBySubject(ctx, subject)
+	return slices.DeleteFunc(records, func(record storage.AuthLogRecord) bool { return record.OccurredAt.Before(since) }), err
+}
+
+func (s *recordingAuthLogStore) count(event storage.AuthLogEvent) int {
+	total := 0
+	for _, record := range s.records {
+		if record.Event == event {
+			total++
+		}
+	}
+	return total
+}
+
+func newRefreshTestService(t *testing.T) (*AuthService, *memoryAuthStore, *recordingAuthLogStore) {
+	t.Helper()
+
+	authStore := &memoryAuthStore{}
+	logStore := &recordingAuthLogStore{}
+	service, err := NewAuthService(Config{
+		AuthStore: storage.AuthMaterial{
+			Auth:        authStore,
+			SubjectAuth: &memorySubjectAuthStore{},
+			AuthLog:     logStore,
+		},
+		AuthdStore: storage.AuthdMaterial{
+			Role: &memoryRoleStore{
+				data: map[string][]string{"user-1|tenant-a": {"viewer"}},
+			},
+			Permission: &memoryPermissionStore{},
+		},
+		Hasher: staticHasher{},
+		Authorization: AuthorizationConfig{
+			Registry: AuthorizationRegistry{
+				Permissions: []PermissionDefinition{{Key: "read", Bit: 0}},
+				Roles:       []RoleDefinition{{Key: "viewer", Bit: 0, Permissions: []string{"read"}}},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewAuthService returned error: %v", err)
+	}
+	return service, authStore, logStore
+}
+
+func TestRefreshAuthRotatesWithinFamily(t *testing.T) {
+	service, authStore, _ := newRefreshTestService(t)
+	ctx := context.Background()
+
+	issued, err := service.IssueRefreshToken(ctx, IssueRefreshTokenInput{UserID: "user-1", Tenant: "tenant-a", TTL: time.Hour})
+	if err != nil {
+		t.Fatalf("IssueRefreshToken returned error: %v", err)
+	}
+	if stored := authStore.records[issued.AuthID]; !strings.HasPrefix(stored.MaterialHash, ocrypto.SHA256DigestPrefix) || stored.MaterialType != storage.AuthMaterialTypeRefreshToken {
+		t.Fatalf("stored record = %+v, want digested refresh token material", stored)
+	}
+
+	result, err := service.RefreshAuth(ctx, issued.Token)
+	if err != nil {
+		t.Fatalf("RefreshAuth returned error: %v", err)
+	}
+	rotated := result.RefreshToken
+	if rotated.Token == issued.Token || rotated.FamilyID != issued.FamilyID {
+		t.Fatalf("rotated token = %+v, want new token in family %s", rotated, issued.FamilyID)
+	}
+	if !rotated.ExpiresAt.Equal(issued.ExpiresAt) {
+		t.Fatalf("rotated expiry = %v, want family expiry %v", rotated.ExpiresAt, issued.ExpiresAt)
+	}
+	if result.Principal.Subject != "user-1" || result.Principal.Tenant != "tenant-a" {
+		t.Fatalf("principal = %+v, want user-1 in tenant-a", result.Principal)
+	}
+	if ok, _ := service.HasAllRoles(result.Principal, "viewer"); !ok {
+		t.Fatalf("expected viewer role on refreshed principal")
+	}
+
+	retired := authStore.records[issued.AuthID]
+	if retired.Status != storage.StatusInActive || retired.Metadata[refreshMetadataRotatedTo] != rotated.AuthID {
+		t.Fatalf("retired record = %+v, want inactive and pointing at successor", retired)
+	}
+	if parent := authStore.records[rotated.AuthID].Metadata[refreshMetadataParentID]; parent != issued.AuthID {
+		t.Fatalf("successor parent = %q, want %q", parent, issued.AuthID)
+	}
+
+	if _, err := service.RefreshAuth(ctx, rotated.Token); err != nil {
+		t.Fatalf("RefreshAuth with rotated token returned error: %v", err)
+	}
+}
+
+func TestRefreshAuthReuseRevokesFamily(t *testing.T) {
+	service, authStore, logStore := newRefreshTestService(t)
+	ctx := context.Background()
+
+	issued, err := service.IssueRefreshToken(ctx, IssueRefreshTokenInput{UserID: "user-1", TTL: time.Hour})
+	if err != nil {
+		t.Fatalf("IssueRefreshToken returned error: %v", err)
+	}
+	other, err := service.IssueRefreshToken(ctx, IssueRefreshTokenInput{UserID: "user-1", TTL: time.Hour})
+	if err != nil {
+		t.Fatalf("IssueRefreshToken returned error: %v", err)
+	}
+
+	first, err := service.RefreshAuth(ctx, issued.Token)
+	if err != nil {
+		t.Fatalf("RefreshAuth returned error: %v", err)
+	}
+	second, err := service.RefreshAuth(ctx, first.RefreshToken.Token)
+	if err != nil {
+		t.Fatalf("RefreshAuth returned error: %v", err)
+	}
+
+	_, err = service.RefreshAuth(ctx, issued.Token)
+	if !oerrors.IsCode(err, oerrors.CodeInvalidToken) {
+		t.Fatalf("reused token error = %v, want invalid token", err)
+	}
+
+	for _, authID := range []string{issued.AuthID, first.RefreshToken.AuthID, second.RefreshToken.AuthID} {
+		record := authStore.records[authID]
+		if record.Status != storage.StatusRevoked || record.RevokedAt == nil {
+			t.Fatalf("record %s = %+v, want revoked", authID, record)
+		}
+	}
+	if logStore.count(storage.AuthLogEventRevoked) != 3 {
+		t.Fatalf("revoked events = %d, want 3", logStore.count(storage.AuthLogEventRevoked))
+	}
+
+	if _, err := service.RefreshAuth(ctx, second.RefreshToken.Token); !oerrors.IsCode(err, oerrors.CodeInvalidToken) {
+		t.Fatalf("latest token after family revocation error = %v, want invalid token", err)
+	}
+	if _, err := service.RefreshAuth(ctx, other.Token); err != nil {
+		t.Fatalf("unrelated family should stay usable, got %v", err)
+	}
+}
+
+// racingAuthStore runs beforeSwap once, ahead of the first status swap, and
+// beforeWrite once, ahead of the first conditional record write, to let a
+// concurrent caller win the race in between.
+type racingAuthStore struct {
+	*memoryAuthStore
+	beforeSwap  func()
+	beforeWrite func()
+}
+
+func (s *racingAuthStore) SwapAuthStatus(ctx context.Context, id string, from storage.AuthStatus, to storage.AuthStatus, modifiedAt time.Time) (bool, error) {
+	if hook := s.beforeSwap; hook != nil {
+		s.beforeSwap = nil
+		hook()
+	}
+	return s.memoryAuthStore.SwapAuthStatus(ctx, id, from, to, modifiedAt)
+}
+
+func (s *racingAuthStore) SwapAuth(ctx context.Context, record storage.AuthRecord, lastModified *time.Time) (bool, error) {
+	if hook := s.beforeWrite; hook != nil {
+		s.beforeWrite = nil
+		hook()
+	}
+	return s.memoryAuthStore.SwapAuth(ctx, record, lastModified)
+}
+
+func TestRefreshAuthConcurrentRotationRevokesFamily(t *testing.T) {
+	service, authStore, _ := newRefreshTestService(t)
+	ctx := context.Background()
+
+	issued, err := service.IssueRefreshToken(ctx, IssueRefreshTokenInput{UserID: "user-1", TTL: time.Hour})
+	if err != nil {
+		t.Fatalf("IssueRefreshToken returned error: %v", err)
+	}
+
+	var winner RefreshResult
+	var winnerErr error
+	racing := &racingAuthStore{memoryAuthStore: authStore}
+	racing.beforeSwap = func() { winner, winnerErr = service.RefreshAuth(ctx, issued.Token) }
+	service.authStore.Auth = racing
+
+	if _, err := service.RefreshAuth(ctx, issued.Token); !oerrors.IsCode(err, oerrors.CodeInvalidToken) {
+		t.Fatalf("losing concurrent refresh error = %v, want invalid token", err)
+	}
+	if winnerErr != nil {
+		t.Fatalf("winning concurrent refresh returned error: %v", winnerErr)
+	}
+	if status := authStore.records[winner.RefreshToken.AuthID].Status; status != storage.StatusRevoked {
+		t.Fatalf("winner's successor status = %s, want the forked family revoked", status)
+	}
+}
+
+func TestRefreshAuthRejectsInvalidTokens(t *testing.T) {
+	service, authStore, logStore := newRefreshTestService(t)
+	ctx := context.Background()
+
+	issued, err := service.IssueRefreshToken(ctx, IssueRefreshTokenInput{UserID: "user-1", TTL: time.Hour})
+	if err != nil {
+		t.Fatalf("IssueRefreshToken returned error: %v", err)
+	}
+
+	if _, err := service.RefreshAuth(ctx, "not-a-token"); !oerrors.IsCode(err, oerrors.CodeInvalidToken) {
+		t.Fatalf("malformed token error = %v, want invalid token", err)
+	}
+	if _, err := service.RefreshAuth(ctx, issued.AuthID+".wrong-secret"); !oerrors.IsCode(err, oerrors.CodeInvalidToken) {
+		t.Fatalf("wrong secret error = %v, want invalid token", err)
+	}
+	if logStore.count(storage.AuthLogEventFailed) != 1 {
+		t.Fatalf("failed events = %d, want 1", logStore.count(storage.AuthLogEventFailed))
+	}
+
+	record := authStore.records[issued.AuthID]
+	expired := time.Now().UTC().Add(-time.Minute)
+	record.ExpiresAt = &expired
+	authStore.records[issued.AuthID] = record
+	if _, err := service.RefreshAuth(ctx, issued.Token); !oerrors.IsCode(err, oerrors.CodeCredentialsExpired) {
+		t.Fatalf("expired token error = %v, want credentials expired", err)
+	}
+	if authStore.records[issued.AuthID].Status != storage.StatusExpired {
+		t.Fatalf("expected expired status to be persisted")
+	}
+
+	if _, err := service.IssueRefreshToken(ctx, IssueRefreshTokenInput{UserID: " "}); !oerrors.IsCode(err, oerrors.CodeInvalidCredentials) {
+		t.Fatalf("IssueRefreshToken without user error = %v, want invalid credentials", err)
+	}
+}
+
+func TestIssueRefreshTokenPrunesStaleTokens(t *testing.T) {
+	service, authStore, _ := newRefreshTestService(t)
+	ctx := context.Background()
+
+	expired, err := service.IssueRefreshToken(ctx, IssueRefreshTokenInput{UserID: "user-1", TTL: time.Hour})
+	if err != nil {
+		t.Fatalf("IssueRefreshToken returned error: %v", err)
+	}
+	revoked, err := service.IssueRefreshToken(ctx, IssueRefreshTokenInput{UserID: "user-1", TTL: time.Hour})
+	if err != nil {
+		t.Fatalf("IssueRefreshToken returned error: %v", err)
+	}
+	rotated, err := service.IssueRefreshToken(ctx, IssueRefreshTokenInput{UserID: "user-1", TTL: time.Hour})
+	if err != nil {
+		t.Fatalf("IssueRefreshToken returned error: %v", err)
+	}
+	if _, err := service.RefreshAuth(ctx, rotated.Token); err != nil {
+		t.Fatalf("RefreshAuth returned error: %v", err)
+	}
+
+	record := authStore.records[expired.AuthID]
+	past := time.Now().UTC().Add(-time.Minute)
+	record.ExpiresAt = &past
+	authStore.records[expired.AuthID] = record
+	record = authStore.records[revoked.AuthID]
+	record.Status = storage.StatusRevoked
+	authStore.records[revoked.AuthID] = record
+
+	if _, err := service.IssueRefreshToken(ctx, IssueRefreshTokenInput{UserID: "user-1", TTL: time.Hour}); err != nil {
+		t.Fatalf("IssueRefreshToken returned error: %v", err)
+	}
+	for _, id := range []string{expired.AuthID, revoked.AuthID} {
+		if _, ok := authStore.records[id]; ok {
+			t.Fatalf("expected stale refresh token %s to be deleted", id)
+		}
+	}
+	if authStore.records[rotated.AuthID].Status != storage.StatusInActive {
+		t.Fatalf("expected the rotated token to be kept for reuse detection")
+	}
+}
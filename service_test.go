@@ -3,11 +3,17 @@ package openauth
 import (
 	"context"
 	"errors"
+	"slices"
 	"testing"
 	"time"
 
+	"github.com/go-logr/logr"
 	"github.com/porthorian/openauth/pkg/approach"
+	ocache "github.com/porthorian/openauth/pkg/cache"
+	memorycache "github.com/porthorian/openauth/pkg/cache/memory"
+	ocrypto "github.com/porthorian/openauth/pkg/crypto"
 	oerrors "github.com/porthorian/openauth/pkg/errors"
+	"github.com/porthorian/openauth/pkg/password"
 	"github.com/porthorian/openauth/pkg/storage"
 )
 
@@ -38,7 +44,7 @@ func (s *memoryAuthStore) GetAuth(ctx context.Context, id string) (storage.AuthR
 	_ = ctx
 	record, ok := s.records[id]
 	if !ok {
-		return storage.AuthRecord{}, errors.New("not found")
+		return storage.AuthRecord{}, storage.ErrNotFound
 	}
 	return record, nil
 }
@@ -62,6 +68,35 @@ func (s *memoryAuthStore) DeleteAuth(ctx context.Context, id string) error {
 	return nil
 }
 
+func (s *memoryAuthStore) SwapAuthStatus(ctx context.Context, id string, from storage.AuthStatus, to storage.AuthStatus, modifiedAt time.Time) (bool, error) {
+	_ = ctx
+	record, ok := s.records[id]
+	if !ok || record.Status != from {
+		return false, nil
+	}
+	record.Status = to
+	record.DateModified = &modifiedAt
+	s.records[id] = record
+	return true, nil
+}
+
+func (s *memoryAuthStore) SwapAuth(ctx context.Context, record storage.AuthRecord, lastModified *time.Time) (bool, error) {
+	_ = ctx
+	current, ok := s.records[record.ID]
+	if !ok || !sameTime(current.DateModified, lastModified) {
+		return false, nil
+	}
+	s.records[record.ID] = record
+	return true, nil
+}
+
+func sameTime(a *time.Time, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
 type memorySubjectAuthStore struct {
 	bySubject map[string][]storage.SubjectAuthRecord
 	byAuthID  map[string][]storage.SubjectAuthRecord
@@ -92,7 +127,15 @@ func (s *memorySubjectAuthStore) ListSubjectAuthByAuthID(ctx context.Context, au
 
 func (s *memorySubjectAuthStore) DeleteSubjectAuth(ctx context.Context, id string) error {
 	_ = ctx
-	_ = id
+	remove := func(links []storage.SubjectAuthRecord) []storage.SubjectAuthRecord {
+		return slices.DeleteFunc(links, func(link storage.SubjectAuthRecord) bool { return link.ID == id })
+	}
+	for subject, links := range s.bySubject {
+		s.bySubject[subject] = remove(links)
+	}
+	for authID, links := range s.byAuthID {
+		s.byAuthID[authID] = remove(links)
+	}
 	return nil
 }
 
@@ -116,8 +159,17 @@ func (s noopAuthLogStore) ListAuthLogsBySubject(ctx context.Context, subject str
 	return nil, nil
 }
 
+func (s noopAuthLogStore) ListAuthLogsBySubjectSince(ctx context.Context, subject string, since time.Time) ([]storage.AuthLogRecord, error) {
+	_ = ctx
+	_ = subject
+	_ = since
+	return nil, nil
+}
+
 type memoryRoleStore struct {
-	data map[string][]string
+	data    map[string][]string
+	lookups int
+	err     error
 }
 
 func (s *memoryRoleStore) ReplaceSubjectRoles(ctx context.Context, subject string, tenant string, roleKeys []string) error {
@@ -132,6 +184,10 @@ func (s *memoryRoleStore) ReplaceSubjectRoles(ctx context.Context, subject strin
 
 func (s *memoryRoleStore) ListSubjectRoles(ctx context.Context, subject string, tenant string) ([]storage.SubjectRoleRecord, error) {
 	_ = ctx
+	s.lookups++
+	if s.err != nil {
+		return nil, s.err
+	}
 	key := subject + "|" + tenant
 	roleKeys := s.data[key]
 	records := make([]storage.SubjectRoleRecord, 0, len(roleKeys))
@@ -173,6 +229,7 @@ type staticApproachHandler struct {
 	name   string
 	result approach.Result
 	err    error
+	calls  *int
 }
 
 func (h staticApproachHandler) Name() string {
@@ -182,6 +239,9 @@ func (h staticApproachHandler) Name() string {
 func (h staticApproachHandler) Validate(ctx context.Context, token string) (approach.Result, error) {
 	_ = ctx
 	_ = token
+	if h.calls != nil {
+		*h.calls++
+	}
 	if h.err != nil {
 		return approach.Result{}, h.err
 	}
@@ -352,6 +412,239 @@ func TestValidateTokenRequiresTenantClaim(t *testing.T) {
 	}
 }
 
+type recordingTokenCache struct {
+	*memorycache.Adapter
+	ttls []time.Duration
+}
+
+func (c *recordingTokenCache) SetToken(ctx context.Context, key string, snapshot ocache.PrincipalSnapshot, ttl time.Duration) error {
+	c.ttls = append(c.ttls, ttl)
+	return c.Adapter.SetToken(ctx, key, snapshot, ttl)
+}
+
+func newCachingTokenService(t *testing.T, profile storage.AuthProfile, handler staticApproachHandler, roleStore *memoryRoleStore, cacheStore ocache.Dependencies) *AuthService {
+	t.Helper()
+	return newCachingTokenServiceWithPolicies(t, profile, nil, handler, roleStore, cacheStore)
+}
+
+func newCachingTokenServiceWithPolicies(t *testing.T, profile storage.AuthProfile, policyMatrix storage.PersistencePolicyMatrix, handler staticApproachHandler, roleStore *memoryRoleStore, cacheStore ocache.Dependencies) *AuthService {
+	t.Helper()
+
+	registry, err := approach.NewRegistry(handler)
+	if err != nil {
+		t.Fatalf("approach.NewRegistry returned error: %v", err)
+	}
+
+	service, err := NewAuthService(Config{
+		AuthdStore: storage.AuthdMaterial{
+			Role:       roleStore,
+			Permission: &memoryPermissionStore{},
+		},
+		CacheStore:   cacheStore,
+		PolicyMatrix: policyMatrix,
+		Authorization: AuthorizationConfig{
+			Registry: AuthorizationRegistry{
+				Permissions: []PermissionDefinition{
+					{Key: "read", Bit: 0},
+					{Key: "write", Bit: 1},
+				},
+				Roles: []RoleDefinition{
+					{Key: "viewer", Bit: 0, Permissions: []string{"read"}},
+					{Key: "admin", Bit: 1, Permissions: []string{"read", "write"}},
+				},
+			},
+		},
+		ApproachRegistry:     registry,
+		DefaultTokenApproach: handler.name,
+		TokenProfile:         profile,
+	})
+	if err != nil {
+		t.Fatalf("NewAuthService returned error: %v", err)
+	}
+	return service
+}
+
+func TestValidateTokenReadThroughCachesAuthorization(t *testing.T) {
+	adapter := memorycache.NewAdapter()
+	roleStore := &memoryRoleStore{
+		data: map[string][]string{"user-1|tenant-a": {"viewer"}},
+	}
+	handler := staticApproachHandler{
+		name: "opaque_local",
+		result: approach.Result{
+			Subject:   "user-1",
+			Tenant:    "tenant-a",
+			ExpiresAt: time.Now().UTC().Add(time.Hour),
+		},
+	}
+	service := newCachingTokenService(t, storage.AuthProfileAccessOpaqueLocal, handler, roleStore, ocache.Dependencies{
+		Token:      adapter,
+		Principal:  adapter,
+		Permission: adapter,
+	})
+
+	for i := 0; i < 3; i++ {
+		if _, err := service.ValidateToken(context.Background(), "token-1"); err != nil {
+			t.Fatalf("ValidateToken returned error: %v", err)
+		}
+	}
+	if roleStore.lookups != 1 {
+		t.Fatalf("expected 1 role lookup, got %d", roleStore.lookups)
+	}
+
+	if err := service.SetSubjectRoles(context.Background(), SetSubjectRolesInput{
+		Subject:  "user-1",
+		Tenant:   "tenant-a",
+		RoleKeys: []string{"admin"},
+	}); err != nil {
+		t.Fatalf("SetSubjectRoles returned error: %v", err)
+	}
+
+	principal, err := service.ValidateToken(context.Background(), "token-1")
+	if err != nil {
+		t.Fatalf("ValidateToken returned error: %v", err)
+	}
+	if roleStore.lookups != 2 {
+		t.Fatalf("expected role change to invalidate cache, got %d lookups", roleStore.lookups)
+	}
+	if ok, _ := service.HasAllPermissions(principal, "write"); !ok {
+		t.Fatalf("expected refreshed principal to carry admin permissions")
+	}
+}
+
+func TestValidateTokenIntrospectionCacheNeverOutlivesToken(t *testing.T) {
+	tokenCache := &recordingTokenCache{Adapter: memorycache.NewAdapter()}
+	roleStore := &memoryRoleStore{
+		data: map[string][]string{"user-1|tenant-a": {"viewer"}},
+	}
+	calls := 0
+	handler := staticApproachHandler{
+		name:  approach.NameOpaqueIntrospection,
+		calls: &calls,
+		result: approach.Result{
+			Subject:   "user-1",
+			Tenant:    "tenant-a",
+			Claims:    map[string]any{"scope": "read"},
+			ExpiresAt: time.Now().UTC().Add(10 * time.Second),
+		},
+	}
+	service := newCachingTokenService(t, "", handler, roleStore, ocache.Dependencies{Token: tokenCache})
+
+	for i := 0; i < 2; i++ {
+		principal, err := service.ValidateToken(context.Background(), "token-1")
+		if err != nil {
+			t.Fatalf("ValidateToken returned error: %v", err)
+		}
+		if principal.Claims["scope"] != "read" {
+			t.Fatalf("unexpected claims: %+v", principal.Claims)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("expected introspection to be cached, got %d calls", calls)
+	}
+	if len(tokenCache.ttls) != 1 || tokenCache.ttls[0] > 10*time.Second {
+		t.Fatalf("expected token ttl capped at token expiry, got %v", tokenCache.ttls)
+	}
+}
+
+func TestValidateTokenSkipsCacheForSelfContainedProfile(t *testing.T) {
+	adapter := memorycache.NewAdapter()
+	roleStore := &memoryRoleStore{
+		data: map[string][]string{"user-1|tenant-a": {"viewer"}},
+	}
+	handler := staticApproachHandler{
+		name: approach.NameDirectJWT,
+		result: approach.Result{
+			Subject:   "user-1",
+			Tenant:    "tenant-a",
+			ExpiresAt: time.Now().UTC().Add(time.Hour),
+		},
+	}
+	service := newCachingTokenService(t, "", handler, roleStore, ocache.Dependencies{
+		Token:      adapter,
+		Principal:  adapter,
+		Permission: adapter,
+	})
+
+	for i := 0; i < 2; i++ {
+		if _, err := service.ValidateToken(context.Background(), "token-1"); err != nil {
+			t.Fatalf("ValidateToken returned error: %v", err)
+		}
+	}
+	if roleStore.lookups != 2 {
+		t.Fatalf("expected uncached role lookups, got %d", roleStore.lookups)
+	}
+}
+
+func TestValidateTokenFailureMode(t *testing.T) {
+	tests := []struct {
+		name        string
+		failureMode storage.FailureMode
+		wantErr     bool
+	}{
+		{name: "fail open serves cached snapshot", failureMode: storage.FailureModeOpen},
+		{name: "fail closed rejects", failureMode: storage.FailureModeClosed, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policies := storage.DefaultPersistencePolicies()
+			policy := policies[storage.AuthProfileAccessOpaqueLocal]
+			policy.FailureMode = tt.failureMode
+			policies[storage.AuthProfileAccessOpaqueLocal] = policy
+
+			adapter := memorycache.NewAdapter()
+			roleStore := &memoryRoleStore{
+				data: map[string][]string{"user-1|tenant-a": {"viewer"}},
+			}
+			handler := staticApproachHandler{
+				name: "opaque_local",
+				result: approach.Result{
+					Subject:   "user-1",
+					Tenant:    "tenant-a",
+					ExpiresAt: time.Now().UTC().Add(time.Hour),
+				},
+			}
+			service := newCachingTokenServiceWithPolicies(t, storage.AuthProfileAccessOpaqueLocal, storage.NewStaticPolicyMatrix(policies), handler, roleStore, ocache.Dependencies{
+				Token:      adapter,
+				Principal:  adapter,
+				Permission: adapter,
+			})
+
+			principal, err := service.ValidateToken(context.Background(), "token-1")
+			if err != nil {
+				t.Fatalf("ValidateToken returned error: %v", err)
+			}
+			if principal.Degraded {
+				t.Fatalf("expected healthy principal")
+			}
+
+			// Force a cache miss so resolution has to reach the failing store.
+			if err := adapter.DeletePermissionMask(context.Background(), principalCacheKey("user-1", "tenant-a")); err != nil {
+				t.Fatalf("DeletePermissionMask returned error: %v", err)
+			}
+			roleStore.err = errors.New("connection refused")
+
+			principal, err = service.ValidateToken(context.Background(), "token-1")
+			if tt.wantErr {
+				if !oerrors.IsCode(err, oerrors.CodeStorageUnavailable) {
+					t.Fatalf("expected storage unavailable error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ValidateToken returned error: %v", err)
+			}
+			if !principal.Degraded {
+				t.Fatalf("expected degraded principal")
+			}
+			if ok, _ := service.HasAllPermissions(principal, "read"); !ok {
+				t.Fatalf("expected cached permissions on degraded principal")
+			}
+		})
+	}
+}
+
 func TestSetSubjectRolesRejectsUnknownRole(t *testing.T) {
 	service, err := NewAuthService(Config{
 		AuthdStore: storage.AuthdMaterial{
@@ -381,3 +674,262 @@ func TestSetSubjectRolesRejectsUnknownRole(t *testing.T) {
 		t.Fatalf("expected role error code, got %v", err)
 	}
 }
+
+func TestAuthorizeRehashesOutdatedMaterial(t *testing.T) {
+	legacyHash, err := ocrypto.NewBcryptHasher(ocrypto.BcryptOptions{Cost: 4}).Hash("pass-123")
+	if err != nil {
+		t.Fatalf("Hash returned error: %v", err)
+	}
+
+	authStore := &memoryAuthStore{
+		records: map[string]storage.AuthRecord{
+			"auth-1": {
+				ID:           "auth-1",
+				Status:       storage.StatusActive,
+				MaterialType: storage.AuthMaterialTypePassword,
+				MaterialHash: legacyHash,
+			},
+		},
+	}
+	subjectStore := &memorySubjectAuthStore{}
+	if err := subjectStore.PutSubjectAuth(context.Background(), storage.SubjectAuthRecord{ID: "link-1", Subject: "user-1", AuthID: "auth-1"}); err != nil {
+		t.Fatalf("PutSubjectAuth returned error: %v", err)
+	}
+
+	primary := ocrypto.NewPBKDF2Hasher(ocrypto.PBKDF2Options{Iterations: 1000})
+	hasher, err := ocrypto.NewCompositeHasher(primary, ocrypto.DefaultVerifiers())
+	if err != nil {
+		t.Fatalf("NewCompositeHasher returned error: %v", err)
+	}
+
+	service, err := NewAuthService(Config{
+		AuthStore: storage.AuthMaterial{
+			Auth:        authStore,
+			SubjectAuth: subjectStore,
+			AuthLog:     noopAuthLogStore{},
+		},
+		AuthdStore: storage.AuthdMaterial{
+			Role:       &memoryRoleStore{},
+			Permission: &memoryPermissionStore{},
+		},
+		Hasher: hasher,
+	})
+	if err != nil {
+		t.Fatalf("NewAuthService returned error: %v", err)
+	}
+
+	input := AuthInput{UserID: "user-1", Type: InputTypePassword, Value: "pass-123"}
+	if _, err := service.Authorize(context.Background(), input); err != nil {
+		t.Fatalf("Authorize returned error: %v", err)
+	}
+
+	upgraded := authStore.records["auth-1"]
+	if upgraded.MaterialHash == legacyHash || upgraded.DateModified == nil {
+		t.Fatalf("expected outdated bcrypt hash to be replaced, got %+v", upgraded)
+	}
+	if primary.NeedsRehash(upgraded.MaterialHash) {
+		t.Fatalf("expected upgraded hash to use current parameters, got %s", upgraded.MaterialHash)
+	}
+
+	if _, err := service.Authorize(context.Background(), input); err != nil {
+		t.Fatalf("Authorize with upgraded hash returned error: %v", err)
+	}
+	if authStore.records["auth-1"].MaterialHash != upgraded.MaterialHash {
+		t.Fatalf("expected current hash to be left unchanged")
+	}
+}
+
+func TestAuthorizeRehashKeepsConcurrentRevoke(t *testing.T) {
+	legacyHash, err := ocrypto.NewBcryptHasher(ocrypto.BcryptOptions{Cost: 4}).Hash("pass-123")
+	if err != nil {
+		t.Fatalf("Hash returned error: %v", err)
+	}
+
+	authStore := &memoryAuthStore{
+		records: map[string]storage.AuthRecord{
+			"auth-1": {
+				ID:           "auth-1",
+				Status:       storage.StatusActive,
+				MaterialType: storage.AuthMaterialTypePassword,
+				MaterialHash: legacyHash,
+			},
+		},
+	}
+	subjectStore := &memorySubjectAuthStore{}
+	if err := subjectStore.PutSubjectAuth(context.Background(), storage.SubjectAuthRecord{ID: "link-1", Subject: "user-1", AuthID: "auth-1"}); err != nil {
+		t.Fatalf("PutSubjectAuth returned error: %v", err)
+	}
+
+	hasher, err := ocrypto.NewCompositeHasher(ocrypto.NewPBKDF2Hasher(ocrypto.PBKDF2Options{Iterations: 1000}), ocrypto.DefaultVerifiers())
+	if err != nil {
+		t.Fatalf("NewCompositeHasher returned error: %v", err)
+	}
+	racing := &racingAuthStore{memoryAuthStore: authStore}
+	service, err := NewAuthService(Config{
+		AuthStore: storage.AuthMaterial{
+			Auth:        racing,
+			SubjectAuth: subjectStore,
+			AuthLog:     noopAuthLogStore{},
+		},
+		AuthdStore: storage.AuthdMaterial{
+			Role:       &memoryRoleStore{},
+			Permission: &memoryPermissionStore{},
+		},
+		Hasher: hasher,
+	})
+	if err != nil {
+		t.Fatalf("NewAuthService returned error: %v", err)
+	}
+
+	ctx := context.Background()
+	racing.beforeWrite = func() {
+		if err := service.RevokeAuth(ctx, "auth-1", "compromised"); err != nil {
+			t.Fatalf("RevokeAuth returned error: %v", err)
+		}
+	}
+	if _, err := service.Authorize(ctx, AuthInput{UserID: "user-1", Type: InputTypePassword, Value: "pass-123"}); err != nil {
+		t.Fatalf("Authorize returned error: %v", err)
+	}
+
+	record := authStore.records["auth-1"]
+	if record.Status != storage.StatusRevoked {
+		t.Fatalf("status = %s, want the concurrent revoke to survive the rehash", record.Status)
+	}
+	if record.MaterialHash != legacyHash {
+		t.Fatalf("expected the rehash to be skipped, got %s", record.MaterialHash)
+	}
+}
+
+type countingHasher struct {
+	staticHasher
+	verifies int
+}
+
+func (h *countingHasher) Verify(password string, encodedHash string) (bool, error) {
+	h.verifies++
+	return h.staticHasher.Verify(password, encodedHash)
+}
+
+func TestAuthorizeVerifiesForUnknownSubjectsAndMissingMaterial(t *testing.T) {
+	authStore := &memoryAuthStore{
+		records: map[string]storage.AuthRecord{
+			"auth-1": {
+				ID:           "auth-1",
+				Status:       storage.StatusInActive,
+				MaterialType: storage.AuthMaterialTypePassword,
+				MaterialHash: "pass-123",
+			},
+		},
+	}
+	subjectStore := &memorySubjectAuthStore{}
+	if err := subjectStore.PutSubjectAuth(context.Background(), storage.SubjectAuthRecord{ID: "link-1", Subject: "user-1", AuthID: "auth-1"}); err != nil {
+		t.Fatalf("PutSubjectAuth returned error: %v", err)
+	}
+
+	hasher := &countingHasher{}
+	service, err := NewAuthService(Config{
+		AuthStore: storage.AuthMaterial{
+			Auth:        authStore,
+			SubjectAuth: subjectStore,
+			AuthLog:     noopAuthLogStore{},
+		},
+		AuthdStore: storage.AuthdMaterial{
+			Role:       &memoryRoleStore{},
+			Permission: &memoryPermissionStore{},
+		},
+		Hasher: hasher,
+	})
+	if err != nil {
+		t.Fatalf("NewAuthService returned error: %v", err)
+	}
+
+	_, err = service.Authorize(context.Background(), AuthInput{UserID: "missing-user", Type: InputTypePassword, Value: "pass-123"})
+	if !oerrors.IsCode(err, oerrors.CodeNotFound) {
+		t.Fatalf("expected not found for unknown subject, got %v", err)
+	}
+	if hasher.verifies != 1 {
+		t.Fatalf("expected one dummy verification for unknown subject, got %d", hasher.verifies)
+	}
+
+	_, err = service.Authorize(context.Background(), AuthInput{UserID: "user-1", Type: InputTypePassword, Value: "pass-123"})
+	if !oerrors.IsCode(err, oerrors.CodeInvalidCredentials) {
+		t.Fatalf("expected invalid credentials without active material, got %v", err)
+	}
+	if hasher.verifies != 2 {
+		t.Fatalf("expected dummy verification for missing material, got %d", hasher.verifies)
+	}
+
+	past := time.Now().UTC().Add(-time.Minute)
+	authStore.records["auth-2"] = storage.AuthRecord{
+		ID:           "auth-2",
+		Status:       storage.StatusActive,
+		MaterialType: storage.AuthMaterialTypePassword,
+		MaterialHash: "pass-456",
+		ExpiresAt:    &past,
+	}
+	if err := subjectStore.PutSubjectAuth(context.Background(), storage.SubjectAuthRecord{ID: "link-2", Subject: "user-2", AuthID: "auth-2"}); err != nil {
+		t.Fatalf("PutSubjectAuth returned error: %v", err)
+	}
+
+	_, err = service.Authorize(context.Background(), AuthInput{UserID: "user-2", Type: InputTypePassword, Value: "wrong"})
+	if !oerrors.IsCode(err, oerrors.CodeInvalidCredentials) {
+		t.Fatalf("expected expired material to hide behind invalid credentials for a wrong password, got %v", err)
+	}
+	if hasher.verifies != 3 || authStore.records["auth-2"].Status != storage.StatusActive {
+		t.Fatalf("expected a verification and no expiry for a wrong password, got %d verifies", hasher.verifies)
+	}
+
+	_, err = service.Authorize(context.Background(), AuthInput{UserID: "user-2", Type: InputTypePassword, Value: "pass-456"})
+	if !oerrors.IsCode(err, oerrors.CodeCredentialsExpired) {
+		t.Fatalf("expected credentials expired after a matching password, got %v", err)
+	}
+	if authStore.records["auth-2"].Status != storage.StatusExpired {
+		t.Fatalf("expected expired material to be marked expired")
+	}
+}
+
+func TestCreateAuthEnforcesPasswordPolicy(t *testing.T) {
+	authStore := &memoryAuthStore{}
+	service, err := NewAuthService(Config{
+		AuthStore: storage.AuthMaterial{
+			Auth:        authStore,
+			SubjectAuth: &memorySubjectAuthStore{},
+			AuthLog:     noopAuthLogStore{},
+		},
+		Hasher: staticHasher{},
+		PasswordPolicy: password.Rules{
+			MinLength:            12,
+			Banned:               password.NewBannedList([]string{"letmein-please"}),
+			MaxSubjectSimilarity: 0.7,
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewAuthService returned error: %v", err)
+	}
+
+	client := newClient(ClientDependencies{Authenticator: service}, logr.Discard(), nil)
+	for value, want := range map[string][]string{
+		"short":           {password.RuleMinLength},
+		"LetMeIn-Please":  {password.RuleBanned},
+		"alice-in-chains": {password.RuleSubjectSimilarity},
+	} {
+		err := client.CreateAuth(context.Background(), CreateAuthInput{UserID: "alice", Value: value})
+		if !oerrors.IsCode(err, oerrors.CodePolicyViolation) {
+			t.Fatalf("CreateAuth(%q) error = %v, want policy violation", value, err)
+		}
+		violations, ok := oerrors.Violations(err)
+		if !ok || len(violations) != len(want) || violations[0].Rule != want[0] || violations[0].Message == "" {
+			t.Fatalf("CreateAuth(%q) violations = %+v, want %v", value, violations, want)
+		}
+	}
+	if len(authStore.records) != 0 {
+		t.Fatalf("rejected passwords must not be stored, got %d records", len(authStore.records))
+	}
+
+	if err := client.CreateAuth(context.Background(), CreateAuthInput{UserID: "alice", Value: "correct horse battery"}); err != nil {
+		t.Fatalf("CreateAuth with compliant password returned error: %v", err)
+	}
+	if len(authStore.records) != 1 {
+		t.Fatalf("expected compliant password to be stored, got %d records", len(authStore.records))
+	}
+}
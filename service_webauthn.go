@@ -0,0 +1,406 @@
+package openauth
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	ocrypto "github.com/porthorian/openauth/pkg/crypto"
+	oerrors "github.com/porthorian/openauth/pkg/errors"
+	"github.com/porthorian/openauth/pkg/mfa/webauthn"
+	"github.com/porthorian/openauth/pkg/storage"
+)
+
+const (
+	defaultWebAuthnChallengeTTL = 5 * time.Minute
+
+	webAuthnMetadataCeremony    = "webauthn_ceremony"
+	webAuthnMetadataCredential  = "webauthn_credential_id"
+	webAuthnMetadataSignCount   = "webauthn_sign_count"
+	webAuthnMetadataAlgorithm   = "webauthn_algorithm"
+	webAuthnMetadataAAGUID      = "webauthn_aaguid"
+	webAuthnMetadataAttestation = "webauthn_attestation"
+
+	webAuthnCeremonyRegistration = "registration"
+	webAuthnCeremonyLogin        = "login"
+
+	webAuthnUserHandleContext = "openauth webauthn user handle:"
+)
+
+var _ WebAuthnManager = (*AuthService)(nil)
+
+// WebAuthnConfig enables passkey registration and passwordless Authorize
+// with InputTypeWebAuthn. WebAuthn stays disabled until RelyingParty.ID and
+// RelyingParty.Origins are set. Passwordless login needs an assertion with
+// user verification unless the subject has another second factor; set
+// RelyingParty.UserVerification to required to ask for it on every login.
+type WebAuthnConfig struct {
+	RelyingParty webauthn.RelyingParty
+	ChallengeTTL time.Duration // ChallengeTTL bounds each ceremony. Zero uses five minutes.
+}
+
+func (c WebAuthnConfig) normalize() WebAuthnConfig {
+	if c.ChallengeTTL <= 0 {
+		c.ChallengeTTL = defaultWebAuthnChallengeTTL
+	}
+	return c
+}
+
+func (c WebAuthnConfig) enabled() bool {
+	return c.RelyingParty.ID != "" && len(c.RelyingParty.Origins) > 0
+}
+
+// BeginWebAuthnRegistration starts registering a new credential for
+// input.UserID.
+func (s *AuthService) BeginWebAuthnRegistration(ctx context.Context, input BeginWebAuthnRegistrationInput) (WebAuthnRegistration, error) {
+	if err := s.checkWebAuthn(); err != nil {
+		return WebAuthnRegistration{}, err
+	}
+
+	input = input.Normalize()
+	if err := input.Validate(); err != nil {
+		return WebAuthnRegistration{}, err
+	}
+
+	records, err := s.subjectAuthRecords(ctx, input.UserID)
+	if err != nil {
+		return WebAuthnRegistration{}, err
+	}
+
+	now := time.Now().UTC()
+	token, challenge, expiresAt, err := s.issueWebAuthnChallenge(ctx, input.UserID, webAuthnCeremonyRegistration, records, now)
+	if err != nil {
+		return WebAuthnRegistration{}, err
+	}
+
+	user := webauthn.UserEntity{
+		ID:          webAuthnUserHandle(input.UserID),
+		Name:        input.UserName,
+		DisplayName: input.DisplayName,
+	}
+	options := s.webAuthn.RelyingParty.CreationOptions(user, challenge, webAuthnCredentialIDs(records, now))
+	options.Timeout = s.webAuthn.ChallengeTTL.Milliseconds()
+
+	return WebAuthnRegistration{
+		Challenge: token,
+		Options:   options,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// FinishWebAuthnRegistration verifies the authenticator's attestation and
+// stores the credential public key. The challenge is consumed in the same
+// transaction that creates the credential.
+func (s *AuthService) FinishWebAuthnRegistration(ctx context.Context, input FinishWebAuthnRegistrationInput) (WebAuthnCredential, error) {
+	if err := s.checkWebAuthn(); err != nil {
+		return WebAuthnCredential{}, err
+	}
+
+	input = input.Normalize()
+	if err := input.Validate(); err != nil {
+		return WebAuthnCredential{}, err
+	}
+
+	response, err := webauthn.ParseRegistrationResponse([]byte(input.Response))
+	if err != nil {
+		return WebAuthnCredential{}, oerrors.Wrap(oerrors.CodeInvalidCredentials, "invalid webauthn registration response", err)
+	}
+
+	records, err := s.subjectAuthRecords(ctx, input.UserID)
+	if err != nil {
+		return WebAuthnCredential{}, err
+	}
+
+	now := time.Now().UTC()
+	challenge, challengeBytes, err := s.resolveWebAuthnChallenge(records, input.Challenge, webAuthnCeremonyRegistration, now)
+	if err != nil {
+		return WebAuthnCredential{}, err
+	}
+
+	credential, err := s.webAuthn.RelyingParty.VerifyRegistration(challengeBytes, response)
+	if err != nil {
+		s.logAuthEvent(ctx, challenge.ID, input.UserID, storage.AuthLogEventFailed)
+		return WebAuthnCredential{}, oerrors.Wrap(oerrors.CodeInvalidCredentials, "webauthn registration failed", err)
+	}
+
+	credentialID := base64.RawURLEncoding.EncodeToString(credential.ID)
+	for _, record := range records {
+		if record.MaterialType == storage.AuthMaterialTypeWebAuthn && record.Status != storage.StatusRevoked &&
+			record.Metadata[webAuthnMetadataCredential] == credentialID {
+			return WebAuthnCredential{}, oerrors.New(oerrors.CodeInvalidCredentials, "webauthn credential is already registered")
+		}
+	}
+
+	write := createAuthWrite{
+		authID:       uuid.NewString(),
+		userID:       input.UserID,
+		materialType: storage.AuthMaterialTypeWebAuthn,
+		materialHash: base64.RawURLEncoding.EncodeToString(credential.PublicKey),
+		metadata: map[string]string{
+			webAuthnMetadataCredential:  credentialID,
+			webAuthnMetadataSignCount:   strconv.FormatUint(uint64(credential.SignCount), 10),
+			webAuthnMetadataAlgorithm:   strconv.FormatInt(int64(credential.Algorithm), 10),
+			webAuthnMetadataAAGUID:      hex.EncodeToString(credential.AAGUID),
+			webAuthnMetadataAttestation: credential.Attestation.Format,
+		},
+	}
+	err = s.withAuthMaterial(ctx, "finish webauthn registration", func(stores storage.AuthMaterial, transactional bool) error {
+		if err := consumeChallenge(ctx, stores, challenge.ID, now); err != nil {
+			return err
+		}
+		return s.createAuthWithStores(ctx, stores, transactional, write)
+	})
+	if err != nil {
+		return WebAuthnCredential{}, err
+	}
+
+	return WebAuthnCredential{
+		AuthID:       write.authID,
+		CredentialID: credentialID,
+	}, nil
+}
+
+// BeginWebAuthnLogin starts an authentication ceremony limited to the
+// credentials registered for input.UserID.
+func (s *AuthService) BeginWebAuthnLogin(ctx context.Context, input BeginWebAuthnLoginInput) (WebAuthnLogin, error) {
+	if err := s.checkWebAuthn(); err != nil {
+		return WebAuthnLogin{}, err
+	}
+
+	input = input.Normalize()
+	if err := input.Validate(); err != nil {
+		return WebAuthnLogin{}, err
+	}
+
+	records, err := s.subjectAuthRecords(ctx, input.UserID)
+	if err != nil {
+		return WebAuthnLogin{}, err
+	}
+
+	now := time.Now().UTC()
+	allow := webAuthnCredentialIDs(records, now)
+	if len(allow) == 0 {
+		return WebAuthnLogin{}, oerrors.New(oerrors.CodeInvalidCredentials, "no webauthn credentials registered for user_id")
+	}
+
+	token, challenge, expiresAt, err := s.issueWebAuthnChallenge(ctx, input.UserID, webAuthnCeremonyLogin, records, now)
+	if err != nil {
+		return WebAuthnLogin{}, err
+	}
+	options := s.webAuthn.RelyingParty.RequestOptions(challenge, allow)
+	options.Timeout = s.webAuthn.ChallengeTTL.Milliseconds()
+
+	return WebAuthnLogin{
+		Challenge: token,
+		Options:   options,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// authorizeWebAuthn completes a login started by BeginWebAuthnLogin. An
+// assertion without user verification only counts as one factor, so
+// subjects with another second factor are sent to the MFA step and subjects
+// without one are rejected.
+func (s *AuthService) authorizeWebAuthn(ctx context.Context, input AuthInput, source string, records []storage.AuthRecord) (Principal, error) {
+	if !s.webAuthn.enabled() {
+		return Principal{}, oerrors.New(oerrors.CodeNotImplemented, "webauthn relying party is not configured")
+	}
+
+	now := time.Now().UTC()
+	challenge, challengeBytes, err := s.resolveWebAuthnChallenge(records, input.Challenge, webAuthnCeremonyLogin, now)
+	if err != nil {
+		return Principal{}, err
+	}
+
+	response, err := webauthn.ParseAuthenticationResponse([]byte(input.Value))
+	if err != nil {
+		s.recordAuthFailure(ctx, input.UserID, source)
+		return Principal{}, oerrors.Wrap(oerrors.CodeInvalidCredentials, "invalid webauthn authentication response", err)
+	}
+
+	credentialID := base64.RawURLEncoding.EncodeToString(response.RawID)
+	var factor *storage.AuthRecord
+	for i, record := range records {
+		if record.MaterialType == storage.AuthMaterialTypeWebAuthn && isUsableRecord(record, now) &&
+			record.Metadata[webAuthnMetadataCredential] == credentialID {
+			factor = &records[i]
+			break
+		}
+	}
+	if factor == nil {
+		s.logAuthEventWith(ctx, s.authStore, challenge.ID, input.UserID, storage.AuthLogEventFailed, sourceMetadata(source))
+		s.recordAuthFailure(ctx, input.UserID, source)
+		return Principal{}, oerrors.New(oerrors.CodeInvalidCredentials, "webauthn credential is not registered for user_id")
+	}
+
+	credential, err := webAuthnCredential(*factor)
+	if err != nil {
+		return Principal{}, err
+	}
+	assertion, err := s.webAuthn.RelyingParty.VerifyAssertion(challengeBytes, credential, response)
+	if err == nil && len(assertion.UserHandle) > 0 && !bytes.Equal(assertion.UserHandle, webAuthnUserHandle(input.UserID)) {
+		err = webauthn.ErrCredentialMismatch
+	}
+	if err != nil {
+		if errors.Is(err, webauthn.ErrSignCountRegression) {
+			s.logger.Info("webauthn signature counter did not increase; the authenticator may be cloned", "auth_id", factor.ID, "subject", input.UserID)
+		}
+		s.logAuthEventWith(ctx, s.authStore, factor.ID, input.UserID, storage.AuthLogEventFailed, sourceMetadata(source))
+		s.recordAuthFailure(ctx, input.UserID, source)
+		return Principal{}, oerrors.Wrap(oerrors.CodeInvalidCredentials, "webauthn assertion failed", err)
+	}
+
+	methods := []InputType(nil)
+	if !assertion.UserVerified {
+		methods = mfaMethods(records, now)
+		if len(methods) == 0 {
+			return Principal{}, oerrors.New(oerrors.CodeInvalidCredentials, "webauthn login requires user verification")
+		}
+	}
+	event := storage.AuthLogEventUsed
+	if len(methods) > 0 {
+		event = storage.AuthLogEventValidated
+	}
+
+	matched := secondFactorMatch{
+		authID: factor.ID,
+		consume: func(current storage.AuthRecord, now time.Time) (storage.AuthRecord, error) {
+			if current.Status != storage.StatusActive {
+				return storage.AuthRecord{}, oerrors.New(oerrors.CodeInvalidCredentials, "webauthn credential is not active")
+			}
+			if err := webauthn.CheckSignCount(webAuthnSignCount(current), assertion.SignCount); err != nil {
+				return storage.AuthRecord{}, oerrors.Wrap(oerrors.CodeInvalidCredentials, "webauthn assertion failed", err)
+			}
+			current.Metadata = withMetadataValue(current.Metadata, webAuthnMetadataSignCount, strconv.FormatUint(uint64(assertion.SignCount), 10))
+			return current, nil
+		},
+	}
+	if err := s.consumeFactor(ctx, "verify webauthn assertion", challenge.ID, matched, input.UserID, source, event, now); err != nil {
+		if oerrors.IsCode(err, oerrors.CodeInvalidCredentials) {
+			s.recordAuthFailure(ctx, input.UserID, source)
+		}
+		return Principal{}, err
+	}
+
+	if len(methods) > 0 {
+		mfaChallenge, _, err := s.issueMFAChallenge(ctx, input.UserID, input.Tenant, records)
+		if err != nil {
+			return Principal{}, err
+		}
+		return Principal{}, mfaRequired(mfaChallenge)
+	}
+
+	s.resetAuthFailures(ctx, input.UserID, source)
+	return s.loginPrincipal(ctx, input.UserID, input.Tenant, now)
+}
+
+func (s *AuthService) checkWebAuthn() error {
+	if s == nil || s.authStore.Auth == nil || s.authStore.SubjectAuth == nil {
+		return oerrors.New(oerrors.CodeStorageUnavailable, "auth storage is not configured")
+	}
+	if !s.webAuthn.enabled() {
+		return oerrors.New(oerrors.CodeNotImplemented, "webauthn relying party is not configured")
+	}
+	return nil
+}
+
+// issueWebAuthnChallenge stores a digest of a ceremony challenge. The returned
+// token is the record ID and the base64url challenge, so the raw challenge
+// is never stored. Expired challenges among records are deleted first.
+func (s *AuthService) issueWebAuthnChallenge(ctx context.Context, subject string, ceremony string, records []storage.AuthRecord, now time.Time) (string, []byte, time.Time, error) {
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		return "", nil, time.Time{}, oerrors.Wrap(oerrors.CodeUnknown, "failed to generate webauthn challenge", err)
+	}
+	secret := base64.RawURLEncoding.EncodeToString(challenge)
+
+	expiresAt := now.Add(s.webAuthn.ChallengeTTL)
+	write := createAuthWrite{
+		authID:       uuid.NewString(),
+		userID:       subject,
+		materialType: storage.AuthMaterialTypeWebAuthnChallenge,
+		materialHash: ocrypto.DigestSecret(secret),
+		expiresAt:    &expiresAt,
+		metadata:     map[string]string{webAuthnMetadataCeremony: ceremony},
+	}
+	if err := s.withAuthMaterial(ctx, "issue webauthn challenge", func(stores storage.AuthMaterial, transactional bool) error {
+		s.pruneAuthMaterial(ctx, stores, subject, records, func(record storage.AuthRecord) bool {
+			return record.MaterialType == storage.AuthMaterialTypeWebAuthnChallenge && !isUsableRecord(record, now)
+		})
+		return s.createAuthWithStores(ctx, stores, transactional, write)
+	}); err != nil {
+		return "", nil, time.Time{}, err
+	}
+	return write.authID + tokenSeparator + secret, challenge, expiresAt, nil
+}
+
+// resolveWebAuthnChallenge finds the usable challenge for token among
+// records and returns it with the raw challenge bytes.
+func (s *AuthService) resolveWebAuthnChallenge(records []storage.AuthRecord, token string, ceremony string, now time.Time) (storage.AuthRecord, []byte, error) {
+	challengeID, secret, ok := parseSecretToken(token)
+	if !ok {
+		return storage.AuthRecord{}, nil, oerrors.New(oerrors.CodeInvalidCredentials, "webauthn challenge is required")
+	}
+
+	for _, record := range records {
+		if record.ID != challengeID || record.MaterialType != storage.AuthMaterialTypeWebAuthnChallenge ||
+			record.Metadata[webAuthnMetadataCeremony] != ceremony || !isUsableRecord(record, now) {
+			continue
+		}
+		challenge, decodeErr := base64.RawURLEncoding.DecodeString(secret)
+		if !ocrypto.VerifySecretDigest(secret, record.MaterialHash) || decodeErr != nil {
+			break
+		}
+		return record, challenge, nil
+	}
+	return storage.AuthRecord{}, nil, oerrors.New(oerrors.CodeInvalidCredentials, "webauthn challenge is invalid or expired")
+}
+
+func webAuthnCredentialIDs(records []storage.AuthRecord, now time.Time) [][]byte {
+	ids := make([][]byte, 0, len(records))
+	for _, record := range records {
+		if record.MaterialType != storage.AuthMaterialTypeWebAuthn || !isUsableRecord(record, now) {
+			continue
+		}
+		id, err := base64.RawURLEncoding.DecodeString(record.Metadata[webAuthnMetadataCredential])
+		if err != nil || len(id) == 0 {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func webAuthnCredential(record storage.AuthRecord) (webauthn.Credential, error) {
+	id, idErr := base64.RawURLEncoding.DecodeString(record.Metadata[webAuthnMetadataCredential])
+	publicKey, keyErr := base64.RawURLEncoding.DecodeString(record.MaterialHash)
+	if err := errors.Join(idErr, keyErr); err != nil {
+		return webauthn.Credential{}, oerrors.Wrap(oerrors.CodeUnknown, "stored webauthn credential is malformed", err)
+	}
+	return webauthn.Credential{
+		ID:        id,
+		PublicKey: publicKey,
+		SignCount: webAuthnSignCount(record),
+	}, nil
+}
+
+func webAuthnSignCount(record storage.AuthRecord) uint32 {
+	count, err := strconv.ParseUint(record.Metadata[webAuthnMetadataSignCount], 10, 32)
+	if err != nil {
+		return 0
+	}
+	return uint32(count)
+}
+
+// webAuthnUserHandle derives the user handle from the subject so
+// authenticators never see the subject itself, which may be an email.
+func webAuthnUserHandle(subject string) []byte {
+	sum := sha256.Sum256([]byte(webAuthnUserHandleContext + subject))
+	return sum[:]
+}
@@ -0,0 +1,180 @@
+package openauth
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+
+	oerrors "github.com/porthorian/openauth/pkg/errors"
+	"github.com/porthorian/openauth/pkg/mfa/webauthn"
+	"github.com/porthorian/openauth/pkg/mfa/webauthn/webauthntest"
+	"github.com/porthorian/openauth/pkg/storage"
+)
+
+func newWebAuthnTestService(t *testing.T) (*AuthService, *memoryAuthStore, *webauthntest.Authenticator) {
+	t.Helper()
+
+	service, authStore := newMFATestService(t)
+	service.authStore.Auth = &transactionalAuthStore{memoryAuthStore: authStore, material: service.authStore}
+	service.webAuthn = WebAuthnConfig{RelyingParty: webauthn.RelyingParty{
+		ID:      "example.com",
+		Name:    "Example",
+		Origins: []string{"https://example.com"},
+	}}.normalize()
+	return service, authStore, webauthntest.New("example.com", "https://example.com")
+}
+
+func registerPasskey(t *testing.T, service *AuthService, authenticator *webauthntest.Authenticator) WebAuthnCredential {
+	t.Helper()
+
+	ctx := context.Background()
+	registration, err := service.BeginWebAuthnRegistration(ctx, BeginWebAuthnRegistrationInput{UserID: "user-1"})
+	if err != nil {
+		t.Fatalf("BeginWebAuthnRegistration returned error: %v", err)
+	}
+	response, err := authenticator.Register(registration.Options.Challenge, registration.Options.User.ID)
+	if err != nil {
+		t.Fatalf("Register returned error: %v", err)
+	}
+	credential, err := service.FinishWebAuthnRegistration(ctx, FinishWebAuthnRegistrationInput{
+		UserID:    "user-1",
+		Challenge: registration.Challenge,
+		Response:  string(response),
+	})
+	if err != nil {
+		t.Fatalf("FinishWebAuthnRegistration returned error: %v", err)
+	}
+	return credential
+}
+
+func passkeyLogin(t *testing.T, service *AuthService, authenticator *webauthntest.Authenticator, credentialID string) AuthInput {
+	t.Helper()
+
+	login, err := service.BeginWebAuthnLogin(context.Background(), BeginWebAuthnLoginInput{UserID: "user-1"})
+	if err != nil {
+		t.Fatalf("BeginWebAuthnLogin returned error: %v", err)
+	}
+	id, _ := base64.RawURLEncoding.DecodeString(credentialID)
+	response, err := authenticator.Login(login.Options.Challenge, id)
+	if err != nil {
+		t.Fatalf("Login returned error: %v", err)
+	}
+	return AuthInput{UserID: "user-1", Tenant: "tenant-a", Type: InputTypeWebAuthn, Value: string(response), Challenge: login.Challenge}
+}
+
+func TestWebAuthnRegistrationAndPasswordlessAuthorize(t *testing.T) {
+	service, authStore, authenticator := newWebAuthnTestService(t)
+	authenticator.Attestation = webauthntest.AttestationPackedSelf
+	ctx := context.Background()
+
+	if _, err := service.BeginWebAuthnLogin(ctx, BeginWebAuthnLoginInput{UserID: "user-1"}); !oerrors.IsCode(err, oerrors.CodeInvalidCredentials) {
+		t.Fatalf("BeginWebAuthnLogin without passkeys error = %v, want invalid credentials", err)
+	}
+
+	registration, err := service.BeginWebAuthnRegistration(ctx, BeginWebAuthnRegistrationInput{UserID: "user-1"})
+	if err != nil {
+		t.Fatalf("BeginWebAuthnRegistration returned error: %v", err)
+	}
+	if string(registration.Options.User.ID) == "user-1" || registration.Options.User.Name != "user-1" {
+		t.Fatalf("user entity = %+v, want opaque handle named after the subject", registration.Options.User)
+	}
+	response, err := authenticator.Register(registration.Options.Challenge, registration.Options.User.ID)
+	if err != nil {
+		t.Fatalf("Register returned error: %v", err)
+	}
+	finish := FinishWebAuthnRegistrationInput{UserID: "user-1", Challenge: registration.Challenge, Response: string(response)}
+	credential, err := service.FinishWebAuthnRegistration(ctx, finish)
+	if err != nil {
+		t.Fatalf("FinishWebAuthnRegistration returned error: %v", err)
+	}
+	stored := authStore.records[credential.AuthID]
+	if stored.MaterialType != storage.AuthMaterialTypeWebAuthn || stored.Metadata[webAuthnMetadataAttestation] != webauthn.AttestationFormatPacked {
+		t.Fatalf("stored credential = %+v", stored)
+	}
+	if _, err := service.FinishWebAuthnRegistration(ctx, finish); !oerrors.IsCode(err, oerrors.CodeInvalidCredentials) {
+		t.Fatalf("reused registration challenge error = %v, want invalid credentials", err)
+	}
+
+	next, err := service.BeginWebAuthnRegistration(ctx, BeginWebAuthnRegistrationInput{UserID: "user-1"})
+	if err != nil || len(next.Options.ExcludeCredentials) != 1 {
+		t.Fatalf("second registration = (%+v, %v), want the passkey excluded", next.Options.ExcludeCredentials, err)
+	}
+
+	input := passkeyLogin(t, service, authenticator, credential.CredentialID)
+	principal, err := service.Authorize(ctx, input)
+	if err != nil {
+		t.Fatalf("Authorize with passkey returned error: %v", err)
+	}
+	if principal.Subject != "user-1" || principal.Tenant != "tenant-a" {
+		t.Fatalf("principal = %+v, want user-1 in tenant-a", principal)
+	}
+	if webAuthnSignCount(authStore.records[credential.AuthID]) != 2 {
+		t.Fatalf("expected the stored sign count to advance, metadata = %v", authStore.records[credential.AuthID].Metadata)
+	}
+	if _, err := service.Authorize(ctx, input); !oerrors.IsCode(err, oerrors.CodeInvalidCredentials) {
+		t.Fatalf("replayed assertion error = %v, want invalid credentials", err)
+	}
+
+	id, _ := base64.RawURLEncoding.DecodeString(credential.CredentialID)
+	if err := authenticator.SetSignCount(id, 1); err != nil {
+		t.Fatalf("SetSignCount returned error: %v", err)
+	}
+	if _, err := service.Authorize(ctx, passkeyLogin(t, service, authenticator, credential.CredentialID)); !oerrors.IsCode(err, oerrors.CodeInvalidCredentials) {
+		t.Fatalf("cloned authenticator error = %v, want invalid credentials", err)
+	}
+
+	other := webauthntest.New("example.com", "https://example.com")
+	registerPasskey(t, service, other)
+	input = passkeyLogin(t, service, authenticator, credential.CredentialID)
+	forged, err := other.Login([]byte("not the issued challenge"), other.Credentials()[0])
+	if err != nil {
+		t.Fatalf("Login returned error: %v", err)
+	}
+	input.Value = string(forged)
+	if _, err := service.Authorize(ctx, input); !oerrors.IsCode(err, oerrors.CodeInvalidCredentials) {
+		t.Fatalf("assertion for another challenge error = %v, want invalid credentials", err)
+	}
+}
+
+func TestWebAuthnWithoutUserVerificationRequiresSecondFactor(t *testing.T) {
+	service, _, authenticator := newWebAuthnTestService(t)
+	credential := registerPasskey(t, service, authenticator)
+	enrollConfirmedTOTP(t, service)
+
+	authenticator.UserVerified = false
+	_, err := service.Authorize(context.Background(), passkeyLogin(t, service, authenticator, credential.CredentialID))
+	challenge, ok := MFAChallengeFromError(err)
+	if !ok || challenge.Methods[0] != InputTypeTOTP {
+		t.Fatalf("Authorize without user verification error = %v, want totp challenge", err)
+	}
+
+	authenticator.UserVerified = true
+	if _, err := service.Authorize(context.Background(), passkeyLogin(t, service, authenticator, credential.CredentialID)); err != nil {
+		t.Fatalf("Authorize with user verification returned error: %v", err)
+	}
+}
+
+func TestWebAuthnWithoutUserVerificationOrSecondFactorIsRejected(t *testing.T) {
+	service, _, authenticator := newWebAuthnTestService(t)
+	credential := registerPasskey(t, service, authenticator)
+
+	authenticator.UserVerified = false
+	_, err := service.Authorize(context.Background(), passkeyLogin(t, service, authenticator, credential.CredentialID))
+	if !oerrors.IsCode(err, oerrors.CodeInvalidCredentials) {
+		t.Fatalf("Authorize without user verification error = %v, want invalid credentials", err)
+	}
+	if _, ok := MFAChallengeFromError(err); ok {
+		t.Fatalf("expected no mfa challenge without another second factor")
+	}
+}
+
+func TestWebAuthnRequiresRelyingParty(t *testing.T) {
+	service, _ := newMFATestService(t)
+
+	if _, err := service.BeginWebAuthnRegistration(context.Background(), BeginWebAuthnRegistrationInput{UserID: "user-1"}); !oerrors.IsCode(err, oerrors.CodeNotImplemented) {
+		t.Fatalf("BeginWebAuthnRegistration without relying party error = %v, want not implemented", err)
+	}
+	if _, err := service.Authorize(context.Background(), AuthInput{UserID: "user-1", Type: InputTypeWebAuthn, Value: "{}"}); !oerrors.IsCode(err, oerrors.CodeNotImplemented) {
+		t.Fatalf("Authorize without relying party error = %v, want not implemented", err)
+	}
+}